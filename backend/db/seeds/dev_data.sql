@@ -1,22 +1,4 @@
--- Insert storage locations with real GPS coordinates
-INSERT INTO storages (name, address, latitude, longitude) VALUES
-('Main Warehouse Lisboa', 'Av. da Liberdade, 1250-096 Lisboa, Portugal', 38.7223, -9.1393),
-('Distribution Center Porto', 'Rua de Santa Catarina, 4000-442 Porto, Portugal', 41.1496, -8.6109),
-('Regional Hub Coimbra', 'Praça da República, 3000-343 Coimbra, Portugal', 40.2033, -8.4103),
-('Logistics Center Faro', 'Av. da República, 8000-078 Faro, Portugal', 37.0194, -7.9304),
-('Distribution Hub Braga', 'Praça da República, 4710-305 Braga, Portugal', 41.5454, -8.4265),
-('Regional Center Évora', 'Praça do Giraldo, 7000-508 Évora, Portugal', 38.5714, -7.9087),
-('Logistics Hub Aveiro', 'Av. Dr. Lourenço Peixinho, 3800-167 Aveiro, Portugal', 40.6443, -8.6455),
-('Distribution Center Setúbal', 'Av. Luísa Todi, 2900-456 Setúbal, Portugal', 38.5244, -8.8882),
-('Regional Hub Viseu', 'Rua Formosa, 3500-161 Viseu, Portugal', 40.6566, -7.9122),
-('Madeira Hub Funchal', 'Av. Arriaga, 9000-060 Funchal, Madeira', 32.6669, -16.9241),
-('Açores Hub Ponta Delgada', 'Av. Infante Dom Henrique, 9500-150 Ponta Delgada, Açores', 37.7412, -25.6756),
-('Lisbon Airport (Cargo)', 'Aeroporto Humberto Delgado, 1700-111 Lisboa, Portugal', 38.7742, -9.1342),
-('Porto Airport (Cargo)', 'Aeroporto Francisco Sá Carneiro, 4470-558 Maia, Portugal', 41.2481, -8.6814),
-('Funchal Airport (Cargo)', 'Aeroporto da Madeira, 9100-105 Santa Cruz, Madeira', 32.6979, -16.7745),
-('Ponta Delgada Airport (Cargo)', 'Aeroporto João Paulo II, 9500-749 Ponta Delgada, Açores', 37.7412, -25.6980),
-('Faro Airport (Cargo)', 'Aeroporto Gago Coutinho, 8006-901 Faro, Portugal', 37.0142, -7.9659),
-('Beja Airport (Cargo)', 'Aeroporto de Beja, EM528-2, 7800-745 Beja, Portugal', 38.0775, -7.9317);
+-- Sample orders for the development database (the storages are created by the migrations)
 
 -- Insert sample orders
 INSERT INTO orders (customer_id, seller_id, seller_address, seller_latitude, seller_longitude, tracking_code, delivery_estimate, delivery_address, delivery_latitude, delivery_longitude)
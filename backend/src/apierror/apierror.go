@@ -0,0 +1,70 @@
+// Package apierror writes the error responses of the API. Every error has the same JSON body:
+//
+//	{
+//	  "code": "VALIDATION_FAILED",
+//	  "message": "Invalid request",
+//	  "error": "Invalid request",
+//	  "details": [{"field": "products[0].quantity", "message": "must be greater than 0"}],
+//	  "request_id": "8c7f..."
+//	}
+//
+// code is machine readable, details lists the invalid fields (only for validation errors) and request_id is the
+// X-Request-ID of the request. error repeats the message for the clients written against the previous format
+package apierror
+
+import (
+	"app/audit"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes that do not follow from the HTTP status. Other errors use the status text, e.g. NOT_FOUND or BAD_GATEWAY
+const (
+	CodeValidation    = "VALIDATION_FAILED"
+	CodeMalformedBody = "MALFORMED_BODY"
+)
+
+// FieldError is an invalid field of the request, named by its JSON path
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Response is the body of the error responses
+type Response struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Error     string       `json:"error"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// New builds the body of an error response to the request
+func New(c *gin.Context, code, message string, details ...FieldError) Response {
+	requestID := audit.FromContext(c).RequestID
+	if c.Writer.Header().Get(audit.RequestIDHeader) == "" {
+		c.Header(audit.RequestIDHeader, requestID)
+	}
+	return Response{Code: code, Message: message, Error: message, Details: details, RequestID: requestID}
+}
+
+// Respond writes an error with the code of the HTTP status and aborts the request
+func Respond(c *gin.Context, status int, message string) {
+	RespondCode(c, status, StatusCode(status), message)
+}
+
+// RespondCode writes an error with its own code and aborts the request
+func RespondCode(c *gin.Context, status int, code, message string, details ...FieldError) {
+	c.AbortWithStatusJSON(status, New(c, code, message, details...))
+}
+
+// StatusCode is the code of the errors that have no code of their own, e.g. NOT_FOUND for 404
+func StatusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
@@ -0,0 +1,128 @@
+package apierror
+
+import (
+	"app/audit"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderLine struct {
+	ProductID uint `json:"product_id" binding:"required"`
+	Quantity  uint `json:"quantity" binding:"gt=0"`
+}
+
+type location struct {
+	Latitude float64 `json:"latitude" binding:"latitude"`
+}
+
+type testRequest struct {
+	location
+	Address string      `json:"address" binding:"required"`
+	Lines   []orderLine `json:"lines" binding:"min=1,dive"`
+	Date    string      `json:"date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// bind binds the body to a testRequest, responding with the validation error
+func bind(body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Use(audit.Middleware(audit.SourceHTTP))
+	r.POST("/", func(c *gin.Context) {
+		var request testRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			Validation(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(audit.RequestIDHeader, "req-1")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) Response {
+	t.Helper()
+	var response Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestRespond(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	Respond(c, http.StatusNotFound, "Order not found")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.True(t, c.IsAborted())
+	response := decode(t, w)
+	assert.Equal(t, "NOT_FOUND", response.Code)
+	assert.Equal(t, "Order not found", response.Message)
+	assert.Equal(t, "Order not found", response.Error, "kept for the previous clients")
+	assert.NotEmpty(t, response.RequestID)
+	assert.Equal(t, response.RequestID, w.Header().Get(audit.RequestIDHeader))
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, "BAD_REQUEST", StatusCode(http.StatusBadRequest))
+	assert.Equal(t, "INTERNAL_SERVER_ERROR", StatusCode(http.StatusInternalServerError))
+	assert.Equal(t, "BAD_GATEWAY", StatusCode(http.StatusBadGateway))
+	assert.Equal(t, "ERROR", StatusCode(599))
+}
+
+func TestValidation_FieldDetails(t *testing.T) {
+	w := bind(`{"latitude": 91, "lines": [{"product_id": 3, "quantity": 0}], "date": "01/05/2025"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response := decode(t, w)
+	assert.Equal(t, CodeValidation, response.Code)
+	assert.Equal(t, "req-1", response.RequestID)
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "latitude", Message: "must be a latitude between -90 and 90"},
+		{Field: "address", Message: "is required"},
+		{Field: "lines[0].quantity", Message: "must be greater than 0"},
+		{Field: "date", Message: "must be a date formatted as YYYY-MM-DD"},
+	}, response.Details)
+}
+
+func TestValidation_EmptyList(t *testing.T) {
+	w := bind(`{"address": "Rua Nova"}`)
+
+	response := decode(t, w)
+	assert.Equal(t, []FieldError{{Field: "lines", Message: "must have at least 1 item"}}, response.Details)
+}
+
+func TestValidation_WrongType(t *testing.T) {
+	w := bind(`{"address": "Rua Nova", "lines": [{"product_id": "ten", "quantity": 1}]}`)
+
+	response := decode(t, w)
+	assert.Equal(t, CodeValidation, response.Code)
+	require.Len(t, response.Details, 1)
+	assert.Equal(t, "must be a whole number", response.Details[0].Message)
+}
+
+func TestValidation_MalformedBody(t *testing.T) {
+	w := bind(`not-a-json`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	response := decode(t, w)
+	assert.Equal(t, CodeMalformedBody, response.Code)
+	assert.Empty(t, response.Details)
+}
+
+func TestValidation_ValidRequest(t *testing.T) {
+	w := bind(`{"latitude": 41.1, "address": "Rua Nova", "lines": [{"product_id": 3, "quantity": 1}], "date": "2025-05-01"}`)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
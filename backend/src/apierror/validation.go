@@ -0,0 +1,127 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// embedded is the name of the embedded structs in the validation errors, they are flattened in the JSON
+const embedded = "_"
+
+func init() {
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(jsonName)
+	}
+}
+
+// jsonName names the fields of the validation errors after their JSON key
+func jsonName(field reflect.StructField) string {
+	if field.Anonymous && field.Tag.Get("json") == "" {
+		return embedded
+	}
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// Validation writes the error of a request body that could not be bound: the invalid fields for the validation
+// errors (binding tags of the request models), a malformed body error otherwise
+func Validation(c *gin.Context, err error) {
+	if details := FieldErrors(err); details != nil {
+		RespondCode(c, http.StatusBadRequest, CodeValidation, "Invalid request", details...)
+		return
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) {
+		RespondCode(c, http.StatusBadRequest, CodeValidation, "Invalid request",
+			FieldError{Field: typeError.Field, Message: fmt.Sprintf("must be a %s", typeName(typeError.Type))})
+		return
+	}
+
+	RespondCode(c, http.StatusBadRequest, CodeMalformedBody, "Malformed JSON body")
+}
+
+// FieldErrors returns the invalid fields of the validation errors of the binding rules, nil for other errors
+func FieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+	details := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		details = append(details, FieldError{Field: fieldPath(fieldError), Message: fieldMessage(fieldError)})
+	}
+	return details
+}
+
+// fieldPath is the JSON path of the field, without the name of the request model
+func fieldPath(fieldError validator.FieldError) string {
+	segments := strings.Split(fieldError.Namespace(), ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if segment != embedded {
+			path = append(path, segment)
+		}
+	}
+	return strings.Join(path, ".")
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	param := fieldError.Param()
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min", "max":
+		bound := "at least"
+		if fieldError.Tag() == "max" {
+			bound = "at most"
+		}
+		unit := map[reflect.Kind]string{reflect.Slice: "item", reflect.Array: "item", reflect.Map: "item", reflect.String: "character"}[fieldError.Kind()]
+		if unit != "" {
+			if param != "1" {
+				unit += "s"
+			}
+			return fmt.Sprintf("must have %s %s %s", bound, param, unit)
+		}
+		return fmt.Sprintf("must be %s %s", bound, param)
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", param)
+	case "latitude":
+		return "must be a latitude between -90 and 90"
+	case "longitude":
+		return "must be a longitude between -180 and 180"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(param, " ", ", "))
+	case "order_status":
+		return "must be a known order status"
+	case "datetime":
+		return fmt.Sprintf("must be a date formatted as %s", strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD").Replace(param))
+	}
+	return fmt.Sprintf("does not satisfy the %s rule", fieldError.Tag())
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "whole number"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return t.Kind().String()
+}
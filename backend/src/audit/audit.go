@@ -0,0 +1,241 @@
+package audit
+
+import (
+	"app/blockchain"
+	"app/metrics"
+	"app/models"
+	"app/repository"
+	"app/supervisor"
+	"app/telemetry"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Audited tables
+const (
+	TableOrders        = "orders"
+	TableOrderProducts = "order_products"
+)
+
+// Audit hashes are stored in the tracking contract under the order ID plus this offset, so they never mix with the
+// hashes of the order updates (the verification expects one hash per update)
+var anchorKeyOffset = new(big.Int).Lsh(big.NewInt(1), 128)
+
+// AnchorKey is the key the audit hashes of an order are stored under in the contract
+func AnchorKey(orderID uint) *big.Int {
+	return new(big.Int).Add(anchorKeyOffset, new(big.Int).SetUint64(uint64(orderID)))
+}
+
+// Change is a change to a row of an audited table
+type Change struct {
+	OrderID  uint
+	Table    string
+	RecordID uint
+	Action   string
+	Before   interface{} // nil when the row is created
+	After    interface{} // nil when the row is deleted
+}
+
+// Logger stores the audit events. When it has a blockchain client the events are also anchored to the chain, once
+// the transaction that recorded them is committed (see Batch). A nil Logger stores the events without anchoring them
+type Logger struct {
+	Client *blockchain.Client
+	// Tasks tracks the anchoring of the events, which runs after the response (nil runs it untracked)
+	Tasks *supervisor.Tasks
+}
+
+// NewLogger creates a logger that anchors the events when the anchoring is enabled and the blockchain is configured
+func NewLogger(client *blockchain.Client, anchor bool, tasks *supervisor.Tasks) *Logger {
+	if !anchor || client == nil {
+		return &Logger{}
+	}
+	return &Logger{Client: client, Tasks: tasks}
+}
+
+// Record stores an audit event for the change, in the repository of the transaction of the change. The event is not
+// anchored, record it with a Batch to anchor it once the transaction is committed
+func (l *Logger) Record(events repository.AuditEvents, ctx context.Context, change Change) (*models.AuditEvent, error) {
+	before, err := Snapshot(change.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := Snapshot(change.After)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := FromContext(ctx)
+	event := models.AuditEvent{
+		Order_ID:   change.OrderID,
+		Table_Name: change.Table,
+		Record_ID:  change.RecordID,
+		Action:     change.Action,
+		Before:     before,
+		After:      after,
+		Actor:      metadata.Actor,
+		Source:     string(metadata.Source),
+		Request_ID: metadata.RequestID,
+		Created_At: time.Now().UTC().Truncate(time.Microsecond), // the precision of the database
+	}
+	if metadata.ClaimedActor != "" {
+		event.Claimed_Actor = &metadata.ClaimedActor
+	}
+	hash := HashEvent(&event)
+	event.Hash = hex.EncodeToString(hash[:])
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := events.Create(ctx, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// Batch collects the events recorded in a transaction, to anchor them with one chain transaction per order once it is
+// committed
+type Batch struct {
+	logger *Logger
+	events []models.AuditEvent
+}
+
+// Batch starts the batch of the events of a transaction
+func (l *Logger) Batch() *Batch {
+	return &Batch{logger: l}
+}
+
+// Record stores an audit event for the change (see Logger.Record) and adds it to the batch
+func (b *Batch) Record(events repository.AuditEvents, ctx context.Context, change Change) (*models.AuditEvent, error) {
+	event, err := b.logger.Record(events, ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	b.events = append(b.events, *event)
+	return event, nil
+}
+
+// Anchor anchors the events of the batch in the background, after the transaction that recorded them was committed,
+// with one chain transaction per order storing the BatchHash of its events. The chain transaction is then recorded
+// for the events, apart from them as they are append only. The change does not depend on the chain, the events that could not be
+// anchored stay without transaction
+func (b *Batch) Anchor(ctx context.Context, events repository.AuditEvents) {
+	if b.logger == nil || b.logger.Client == nil || len(b.events) == 0 {
+		return
+	}
+	if c, isGin := ctx.(*gin.Context); isGin {
+		// the gin context is reused once the request is answered
+		ctx = c.Request.Context()
+	}
+
+	var orders []uint
+	byOrder := map[uint][]models.AuditEvent{}
+	for _, event := range b.events {
+		if _, seen := byOrder[event.Order_ID]; !seen {
+			orders = append(orders, event.Order_ID)
+		}
+		byOrder[event.Order_ID] = append(byOrder[event.Order_ID], event)
+	}
+
+	for _, orderID := range orders {
+		batch := byOrder[orderID]
+		b.logger.Tasks.Go(ctx, "anchor audit events", func(ctx context.Context) error {
+			txHash, deployment, err := b.logger.anchor(ctx, orderID, BatchHash(batch))
+			if err != nil {
+				return fmt.Errorf("failed to anchor the audit events of order %d: %w", orderID, err)
+			}
+			ids := make([]uint, len(batch))
+			for i := range batch {
+				ids[i] = batch[i].Id
+			}
+			if err := events.RecordAnchor(ctx, ids, txHash, deployment); err != nil {
+				return fmt.Errorf("failed to record the anchor %s of the audit events of order %d: %w", txHash, orderID, err)
+			}
+			return nil
+		})
+	}
+}
+
+// anchor stores the hash in the active contract, returns the transaction and the deployment of the contract
+func (l *Logger) anchor(ctx context.Context, orderID uint, hash [32]byte) (txHash string, deploymentID *uint, err error) {
+	ctx, span := telemetry.Start(ctx, "chain anchorAuditEvent", trace.SpanKindClient, trace.WithAttributes(telemetry.OrderID(orderID)))
+	defer func() { telemetry.End(span, err) }()
+
+	deployment := l.Client.Deployment()
+	contract, err := blockchain.GetContractInstance(l.Client.EthClient, deployment.Contract_Address)
+	if err != nil {
+		return "", nil, err
+	}
+	opts := *l.Client.Auth
+	opts.Context = ctx
+
+	start := time.Now()
+	transaction, err := contract.StoreUpdateHash(&opts, AnchorKey(orderID), hash)
+	metrics.ObserveChainSubmit("audit_anchor", time.Since(start), err)
+	if err != nil {
+		return "", nil, err
+	}
+	return transaction.Hash().Hex(), deployment.Ref(), nil
+}
+
+// Snapshot converts a row into the JSON object stored in the audit event (nil stays nil)
+func Snapshot(row interface{}) (map[string]interface{}, error) {
+	if row == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("audit snapshot must be an object: %w", err)
+	}
+	return snapshot, nil
+}
+
+// BatchHash is the hash anchored for the events of a transaction: the hash of the event when there is one, the hash of
+// the hashes of the events in their order otherwise. The events anchored together share their chain transaction
+func BatchHash(events []models.AuditEvent) [32]byte {
+	if len(events) == 1 {
+		return HashEvent(&events[0])
+	}
+	digest := sha256.New()
+	for i := range events {
+		hash := HashEvent(&events[i])
+		digest.Write(hash[:])
+	}
+	var hash [32]byte
+	copy(hash[:], digest.Sum(nil))
+	return hash
+}
+
+// HashEvent hashes the content of an audit event (the snapshots are encoded with sorted keys). The claimed actor is
+// hashed when there is one, the events recorded before it existed keep their hash
+func HashEvent(event *models.AuditEvent) [32]byte {
+	before, _ := json.Marshal(event.Before)
+	after, _ := json.Marshal(event.After)
+	data := fmt.Sprintf("%d|%s|%d|%s|%s|%s|%s|%s|%s|%s",
+		event.Order_ID,
+		event.Table_Name,
+		event.Record_ID,
+		event.Action,
+		before,
+		after,
+		event.Actor,
+		event.Source,
+		event.Request_ID,
+		event.Created_At.UTC().Format(time.RFC3339Nano),
+	)
+	if event.Claimed_Actor != nil {
+		data += "|claimed:" + *event.Claimed_Actor
+	}
+	return sha256.Sum256([]byte(data))
+}
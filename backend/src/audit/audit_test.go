@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchorKey_DoesNotOverlapOrderIDs(t *testing.T) {
+	key := AnchorKey(42)
+
+	assert.Equal(t, 1, key.Cmp(new(big.Int).SetUint64(^uint64(0))), "the key is above every order ID")
+	assert.Equal(t, uint64(42), new(big.Int).Sub(key, AnchorKey(0)).Uint64())
+}
+
+func TestSnapshot(t *testing.T) {
+	snapshot, err := Snapshot(models.OrderProduct{ID: 3, Order_ID: 1, Quantity: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, snapshot["ID"])
+	assert.Equal(t, 2.0, snapshot["Quantity"])
+
+	snapshot, err = Snapshot(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	_, err = Snapshot([]int{1})
+	assert.Error(t, err, "only rows can be snapshotted")
+}
+
+func TestHashEvent(t *testing.T) {
+	event := models.AuditEvent{
+		Order_ID:   1,
+		Table_Name: TableOrders,
+		Record_ID:  1,
+		Action:     models.AuditUpdate,
+		Before:     map[string]interface{}{"Delivery_Address": "Rua Nova", "Delivery_Latitude": 41.1},
+		After:      map[string]interface{}{"Delivery_Address": "Rua Velha", "Delivery_Latitude": 41.1},
+		Actor:      AnonymousActor,
+		Source:     string(SourceHTTP),
+		Request_ID: "req-1",
+		Created_At: time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	hash := HashEvent(&event)
+
+	// the time zone the event is read in does not matter
+	local := event
+	local.Created_At = event.Created_At.In(time.FixedZone("WEST", 3600))
+	assert.Equal(t, hash, HashEvent(&local))
+
+	changed := event
+	changed.After = map[string]interface{}{"Delivery_Address": "Rua Nova", "Delivery_Latitude": 41.1}
+	assert.NotEqual(t, hash, HashEvent(&changed))
+
+	changed = event
+	changed.Actor = "seller-2"
+	assert.NotEqual(t, hash, HashEvent(&changed))
+}
+
+func TestNewLogger_AnchoringDisabledWithoutClient(t *testing.T) {
+	assert.Nil(t, NewLogger(nil, true, nil).Client)
+	assert.Nil(t, NewLogger(&blockchain.Client{}, false, nil).Client)
+}
+
+func TestBatchHash(t *testing.T) {
+	first := models.AuditEvent{Order_ID: 1, Table_Name: TableOrders, Record_ID: 1, Action: models.AuditCreate}
+	second := models.AuditEvent{Order_ID: 1, Table_Name: TableOrderProducts, Record_ID: 4, Action: models.AuditCreate}
+
+	// an event anchored alone is anchored by its own hash
+	assert.Equal(t, HashEvent(&first), BatchHash([]models.AuditEvent{first}))
+
+	firstHash, secondHash := HashEvent(&first), HashEvent(&second)
+	assert.Equal(t, sha256.Sum256(append(firstHash[:], secondHash[:]...)), BatchHash([]models.AuditEvent{first, second}))
+	assert.NotEqual(t, BatchHash([]models.AuditEvent{first, second}), BatchHash([]models.AuditEvent{second, first}))
+}
+
+func TestBatch_StoresTheEventsWithoutAnchoringWithoutClient(t *testing.T) {
+	store := repository.NewMemory()
+	batch := NewLogger(nil, true, nil).Batch()
+
+	_, err := batch.Record(store.AuditEvents(), context.Background(), Change{OrderID: 1, Table: TableOrders, RecordID: 1, Action: models.AuditCreate, After: map[string]interface{}{"Id": 1}})
+	require.NoError(t, err)
+	batch.Anchor(context.Background(), store.AuditEvents())
+
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].Blockchain_Transaction)
+}
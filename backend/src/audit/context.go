@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"app/auth"
+	"app/logging"
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Source is where a change came from
+type Source string
+
+const (
+	SourceHTTP   Source = "HTTP"
+	SourcePubSub Source = "PUBSUB"
+	SourceAdmin  Source = "ADMIN"
+	SourceGRPC   Source = "GRPC"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// ActorHeader names the user a request is made for. Anyone can set it: it is recorded as the claimed actor, the
+	// actor is the caller authenticated by its bearer token
+	ActorHeader    = "X-Actor"
+	AnonymousActor = "anonymous"
+)
+
+const metadataKey = "audit.metadata"
+
+// contextKey is the key of the metadata in the contexts that are not gin contexts (the gRPC calls)
+type contextKey struct{}
+
+// Metadata identifies who made a change and in which request
+type Metadata struct {
+	// Actor is the authenticated caller (AnonymousActor when it is not authenticated). When it is empty it is read
+	// from the identity of the request, which is authenticated after the metadata is set
+	Actor string
+	// ClaimedActor is the actor named by the caller (X-Actor), empty when it named none. It is not verified
+	ClaimedActor string
+	Source       Source
+	RequestID    string
+}
+
+// Middleware sets the audit metadata of the requests. The request ID is taken from the X-Request-ID header
+// (or generated) and sent back in the response. The actor is the caller authenticated by the next middlewares, the
+// X-Actor header is only recorded as claimed
+func Middleware(source Source) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		SetMetadata(c, Metadata{ClaimedActor: c.GetHeader(ActorHeader), Source: source, RequestID: requestID})
+		c.Header(RequestIDHeader, requestID)
+		// the logs of the request carry its ID
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), slog.String(logging.RequestIDKey, requestID)))
+		c.Next()
+	}
+}
+
+// SetMetadata sets the audit metadata of a request (used by the Pub/Sub listeners, which call the handlers directly)
+func SetMetadata(c *gin.Context, metadata Metadata) {
+	c.Set(metadataKey, metadata)
+}
+
+// NewContext returns a context with the audit metadata of a request that is not served by gin
+func NewContext(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, metadata)
+}
+
+// FromContext returns the audit metadata of the request. Requests that did not go through the middleware are
+// recorded as HTTP requests with a new request ID
+func FromContext(ctx context.Context) Metadata {
+	c, isGin := ctx.(*gin.Context)
+	if isGin && c != nil {
+		if metadata, ok := c.Get(metadataKey); ok {
+			return withActor(ctx, metadata.(Metadata))
+		}
+	} else if ctx != nil {
+		if metadata, ok := ctx.Value(contextKey{}).(Metadata); ok {
+			return withActor(ctx, metadata)
+		}
+	}
+
+	metadata := Metadata{Source: SourceHTTP, RequestID: uuid.New().String()}
+	if isGin && c != nil {
+		SetMetadata(c, metadata)
+	}
+	return withActor(ctx, metadata)
+}
+
+// withActor sets the actor of the metadata that has none: the authenticated caller of the request, or anonymous
+func withActor(ctx context.Context, metadata Metadata) Metadata {
+	if metadata.Actor != "" {
+		return metadata
+	}
+	metadata.Actor = AnonymousActor
+	if ctx == nil {
+		return metadata
+	}
+	if identity, ok := auth.FromContext(ctx); ok {
+		metadata.Actor = identity.Subject
+	}
+	return metadata
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"app/auth"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveMetadata(source Source, req *http.Request, handlers ...gin.HandlerFunc) (Metadata, *httptest.ResponseRecorder) {
+	var metadata Metadata
+	r := gin.New()
+	r.Use(Middleware(source))
+	r.Use(handlers...)
+	r.GET("/", func(c *gin.Context) {
+		metadata = FromContext(c)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return metadata, w
+}
+
+func TestMiddleware_UsesRequestHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	req.Header.Set(ActorHeader, "seller-2")
+
+	metadata, w := serveMetadata(SourceAdmin, req)
+
+	// the header is not verified, the change is made by an anonymous caller claiming to be seller-2
+	assert.Equal(t, Metadata{Actor: AnonymousActor, ClaimedActor: "seller-2", Source: SourceAdmin, RequestID: "req-1"}, metadata)
+	assert.Equal(t, "req-1", w.Header().Get(RequestIDHeader))
+}
+
+func TestMiddleware_ActorIsTheAuthenticatedCaller(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(ActorHeader, "seller-2")
+
+	metadata, _ := serveMetadata(SourceHTTP, req, func(c *gin.Context) {
+		auth.SetIdentity(c, auth.Identity{Subject: "ops-1"})
+	})
+
+	assert.Equal(t, "ops-1", metadata.Actor)
+	assert.Equal(t, "seller-2", metadata.ClaimedActor)
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	metadata, w := serveMetadata(SourceHTTP, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, AnonymousActor, metadata.Actor)
+	assert.NotEmpty(t, metadata.RequestID)
+	assert.Equal(t, metadata.RequestID, w.Header().Get(RequestIDHeader))
+}
+
+func TestFromContext_WithoutMiddleware(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	metadata := FromContext(c)
+
+	assert.Equal(t, SourceHTTP, metadata.Source)
+	assert.Equal(t, metadata, FromContext(c), "the same request ID is used for every change of the request")
+}
+
+func TestFromContext_NewContext(t *testing.T) {
+	metadata := Metadata{Actor: "checkout", Source: SourceGRPC, RequestID: "req-1"}
+
+	assert.Equal(t, metadata, FromContext(NewContext(context.Background(), metadata)))
+	assert.Equal(t, SourceHTTP, FromContext(context.Background()).Source)
+
+	// without an actor, the caller authenticated in the context
+	ctx := auth.NewContext(context.Background(), auth.Identity{Subject: "ops-1"})
+	assert.Equal(t, "ops-1", FromContext(NewContext(ctx, Metadata{ClaimedActor: "checkout", Source: SourceGRPC})).Actor)
+}
@@ -0,0 +1,137 @@
+// Package auth authenticates the callers of the API and of the gRPC service by the bearer tokens of the identity
+// provider: JWTs signed with HS256, whose subject is the caller and whose roles claim lists its roles.
+package auth
+
+import (
+	"app/config"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Roles of the callers
+const (
+	// RoleAdmin may call the admin routes (the chain, the config, the workers, the resolution of the reviews)
+	RoleAdmin = "admin"
+)
+
+var (
+	ErrNoToken      = errors.New("no bearer token")
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// Identity is an authenticated caller
+type Identity struct {
+	Subject string
+	Roles   []string
+}
+
+// HasRole reports whether the caller has the role
+func (i Identity) HasRole(role string) bool {
+	return slices.Contains(i.Roles, role)
+}
+
+// claims are the claims read from the tokens
+type claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// Verifier verifies the bearer tokens. A nil Verifier (no secret configured) authenticates no one
+type Verifier struct {
+	secret   []byte
+	issuer   string
+	audience string
+}
+
+// NewVerifier creates the verifier of the config, nil when the authentication is not configured
+func NewVerifier(cfg config.Auth) *Verifier {
+	if !cfg.Enabled() {
+		return nil
+	}
+	return &Verifier{secret: []byte(cfg.TokenSecret.Value()), issuer: cfg.Issuer, audience: cfg.Audience}
+}
+
+// Verify checks the signature, the expiry, the issuer and the audience of the token and returns its caller
+func (v *Verifier) Verify(token string) (Identity, error) {
+	if v == nil {
+		return Identity{}, fmt.Errorf("%w: the authentication is not configured", ErrInvalidToken)
+	}
+
+	var parsed claims
+	_, err := jwt.ParseWithClaims(token, &parsed, func(token *jwt.Token) (interface{}, error) {
+		// only the algorithm of the secret, a token must not choose how it is verified
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Header["alg"])
+		}
+		return v.secret, nil
+	})
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	switch {
+	case parsed.Subject == "":
+		return Identity{}, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case parsed.ExpiresAt == nil:
+		return Identity{}, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	case v.issuer != "" && !parsed.VerifyIssuer(v.issuer, true):
+		return Identity{}, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	case v.audience != "" && !parsed.VerifyAudience(v.audience, true):
+		return Identity{}, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	return Identity{Subject: parsed.Subject, Roles: parsed.Roles}, nil
+}
+
+// BearerToken returns the token of an Authorization header (Bearer <token>)
+func BearerToken(authorization string) (string, error) {
+	scheme, token, found := strings.Cut(authorization, " ")
+	switch {
+	case authorization == "":
+		return "", ErrNoToken
+	case !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "":
+		return "", fmt.Errorf("%w: the Authorization header is not Bearer <token>", ErrInvalidToken)
+	}
+	return strings.TrimSpace(token), nil
+}
+
+const identityKey = "auth.identity"
+
+// contextKey is the key of the identity in the contexts that are not gin contexts (the gRPC calls)
+type contextKey struct{}
+
+// NewContext returns a context with the identity of the caller
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// SetIdentity sets the identity of the caller of a request, in the gin context and in the context of the request
+func SetIdentity(c *gin.Context, identity Identity) {
+	c.Set(identityKey, identity)
+	if c.Request != nil {
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), identity))
+	}
+}
+
+// FromContext returns the identity of the caller, false when the caller is not authenticated
+func FromContext(ctx context.Context) (Identity, bool) {
+	if c, isGin := ctx.(*gin.Context); isGin && c != nil {
+		if identity, ok := c.Get(identityKey); ok {
+			return identity.(Identity), true
+		}
+		if c.Request == nil {
+			return Identity{}, false
+		}
+		ctx = c.Request.Context()
+	}
+	if ctx == nil {
+		return Identity{}, false
+	}
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"app/config"
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func sign(t *testing.T, key string, method jwt.SigningMethod, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(key))
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":   "ops-1",
+		"roles": []string{RoleAdmin},
+		"iss":   "https://id.madeinportugal.store",
+		"aud":   "order-tracking",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func newVerifier() *Verifier {
+	return NewVerifier(config.Auth{TokenSecret: secret, Issuer: "https://id.madeinportugal.store", Audience: "order-tracking"})
+}
+
+func TestVerify(t *testing.T) {
+	identity, err := newVerifier().Verify(sign(t, secret, jwt.SigningMethodHS256, validClaims()))
+	require.NoError(t, err)
+	assert.Equal(t, "ops-1", identity.Subject)
+	assert.True(t, identity.HasRole(RoleAdmin))
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	tests := map[string]func() string{
+		"another secret": func() string {
+			return sign(t, "another secret of thirty-two bytes", jwt.SigningMethodHS256, validClaims())
+		},
+		"another algorithm": func() string {
+			return sign(t, secret, jwt.SigningMethodHS512, validClaims())
+		},
+		"expired": func() string {
+			claims := validClaims()
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			return sign(t, secret, jwt.SigningMethodHS256, claims)
+		},
+		"no expiry": func() string {
+			claims := validClaims()
+			delete(claims, "exp")
+			return sign(t, secret, jwt.SigningMethodHS256, claims)
+		},
+		"no subject": func() string {
+			claims := validClaims()
+			delete(claims, "sub")
+			return sign(t, secret, jwt.SigningMethodHS256, claims)
+		},
+		"another issuer": func() string {
+			claims := validClaims()
+			claims["iss"] = "https://elsewhere.example.com"
+			return sign(t, secret, jwt.SigningMethodHS256, claims)
+		},
+		"another audience": func() string {
+			claims := validClaims()
+			claims["aud"] = "billing"
+			return sign(t, secret, jwt.SigningMethodHS256, claims)
+		},
+		"unsigned": func() string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			require.NoError(t, err)
+			return token
+		},
+	}
+
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newVerifier().Verify(token())
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestVerify_NotConfigured(t *testing.T) {
+	verifier := NewVerifier(config.Auth{})
+	assert.Nil(t, verifier)
+
+	_, err := verifier.Verify(sign(t, secret, jwt.SigningMethodHS256, validClaims()))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestBearerToken(t *testing.T) {
+	token, err := BearerToken("Bearer abc.def.ghi")
+	require.NoError(t, err)
+	assert.Equal(t, "abc.def.ghi", token)
+
+	_, err = BearerToken("")
+	assert.ErrorIs(t, err, ErrNoToken)
+	_, err = BearerToken("Basic dXNlcjpwYXNz")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestFromContext(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	_, ok := FromContext(c)
+	assert.False(t, ok)
+
+	identity := Identity{Subject: "ops-1", Roles: []string{RoleAdmin}}
+	SetIdentity(c, identity)
+	got, ok := FromContext(c)
+	require.True(t, ok)
+	assert.Equal(t, identity, got)
+	got, ok = FromContext(c.Request.Context())
+	require.True(t, ok, "the handlers passing the request context on")
+	assert.Equal(t, identity, got)
+
+	_, ok = FromContext(context.Background())
+	assert.False(t, ok)
+}
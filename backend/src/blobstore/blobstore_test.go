@@ -0,0 +1,45 @@
+package blobstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystemStore_PutGet(t *testing.T) {
+	store := NewFileSystemStore(t.TempDir())
+
+	err := store.Put("proofs/1/signature.png", []byte("signature"))
+	assert.NoError(t, err)
+
+	data, err := store.Get("proofs/1/signature.png")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("signature"), data)
+}
+
+func TestFileSystemStore_Overwrite(t *testing.T) {
+	store := NewFileSystemStore(t.TempDir())
+
+	assert.NoError(t, store.Put("blob", []byte("first")))
+	assert.NoError(t, store.Put("blob", []byte("second")))
+
+	data, err := store.Get("blob")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("second"), data)
+}
+
+func TestFileSystemStore_NotFound(t *testing.T) {
+	store := NewFileSystemStore(t.TempDir())
+
+	_, err := store.Get("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestFileSystemStore_InvalidKey(t *testing.T) {
+	store := NewFileSystemStore(t.TempDir())
+
+	for _, key := range []string{"", "../outside", "/etc/passwd", "a/../../outside"} {
+		assert.Error(t, store.Put(key, []byte("data")), "key %q should be rejected", key)
+	}
+}
@@ -1,103 +1,186 @@
 package blockchain
 
 import (
+	"app/config"
+	"app/models"
 	"context"
-	"crypto/ecdsa"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
-	"os"
-	"strings"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // Client represents a connection to the Ethereum blockchain
 type Client struct {
-	EthClient       *ethclient.Client
-	Auth            *bind.TransactOpts
-	ChainID         *big.Int
-	WalletAddress   common.Address
-	ContractAddress common.Address
+	EthClient     *ethclient.Client
+	Auth          *bind.TransactOpts
+	Signer        Signer
+	ChainID       *big.Int
+	WalletAddress common.Address
+
+	mu sync.RWMutex
+	// deployment is the contract the updates are notarized in, legacy the one of the updates notarized before the
+	// registry (see LoadDeployments)
+	deployment models.ContractDeployment
+	legacy     *models.ContractDeployment
+	// nodes of the other chains, dialed when an update notarized there is verified
+	chainURLs map[int64]config.Secret
+	readers   map[int64]*ethclient.Client
 }
 
-// NewClient creates a new blockchain client connected to Sepolia testnet
-func NewClient() (*Client, error) {
-	// Get RPC URL from environment
-	rpcURL := os.Getenv("BLOCKCHAIN_RPC_URL")
-	if rpcURL == "" {
-		return nil, fmt.Errorf("BLOCKCHAIN_RPC_URL environment variable not set")
+// NewClient creates a client connected to the node of the config. The node must be on the chain of the config, the
+// transactions are signed for it
+func NewClient(ctx context.Context, cfg config.Blockchain) (*Client, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("the blockchain is not configured (BLOCKCHAIN_RPC_URL is not set)")
 	}
 
 	// Connect to Ethereum node (Sepolia via Infura)
-	client, err := ethclient.Dial(rpcURL)
+	client, err := ethclient.DialContext(ctx, cfg.RPCURL.Value())
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Ethereum node: %w", err)
 	}
 
-	// Get private key from environment
-	privateKeyHex := os.Getenv("BLOCKCHAIN_PRIVATE_KEY")
-	if privateKeyHex == "" {
-		return nil, fmt.Errorf("BLOCKCHAIN_PRIVATE_KEY environment variable not set")
+	// the signer of the wallet (BLOCKCHAIN_SIGNER)
+	signer, err := NewSigner(ctx, cfg)
+	if err != nil {
+		client.Close()
+		return nil, err
 	}
+	walletAddress := signer.Address()
 
-	// Remove 0x prefix if present
-	privateKeyHex = strings.TrimPrefix(privateKeyHex, "0x")
-
-	// Parse private key
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	// a transaction signed for another chain would be rejected, a node on another chain is a misconfiguration
+	chainID := big.NewInt(cfg.ChainID)
+	nodeChainID, err := client.ChainID(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse private key: %w", err)
+		client.Close()
+		closeSigner(signer)
+		return nil, fmt.Errorf("failed to get the chain ID of the node: %w", err)
 	}
-
-	// Get public key and wallet address
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("failed to get public key")
+	if nodeChainID.Cmp(chainID) != 0 {
+		client.Close()
+		closeSigner(signer)
+		return nil, fmt.Errorf("the node is on the chain %s, the config expects the chain %s (%s)", nodeChainID, chainID, cfg.Network)
 	}
-	walletAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
-
-	// Sepolia Chain ID is 11155111
-	chainID := big.NewInt(11155111)
 
 	// Create authenticated transactor for signing transactions
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transactor: %w", err)
-	}
+	auth := NewTransactor(signer, chainID)
 
-	// Parse contract address if provided
-	var contractAddress common.Address
-	contractAddressStr := os.Getenv("BLOCKCHAIN_CONTRACT_ADDRESS")
-	if contractAddressStr != "" {
-		contractAddress = common.HexToAddress(contractAddressStr)
+	// the contract of the config until the registry is loaded (it is not registered, the updates are not pinned)
+	deployment := models.ContractDeployment{Chain_ID: cfg.ChainID, ABI_Version: ABIVersion}
+	if cfg.ContractAddress != "" {
+		deployment.Contract_Address = common.HexToAddress(cfg.ContractAddress).Hex()
 	}
 
-	log.Printf("Connected to Sepolia testnet via %s", rpcURL)
-	log.Printf("Wallet address: %s", walletAddress.Hex())
-	if contractAddressStr != "" {
-		log.Printf("Contract address: %s", contractAddress.Hex())
-	}
+	// the RPC URL is not logged, it has the API key of the node provider
+	slog.Info("Connected to the blockchain", "chain_id", chainID.String(), "wallet", walletAddress.Hex(), "signer", cfg.Signer, "contract", deployment.Contract_Address)
 
 	return &Client{
-		EthClient:       client,
-		Auth:            auth,
-		ChainID:         chainID,
-		WalletAddress:   walletAddress,
-		ContractAddress: contractAddress,
+		EthClient:     client,
+		Auth:          auth,
+		Signer:        signer,
+		ChainID:       chainID,
+		WalletAddress: walletAddress,
+		deployment:    deployment,
+		chainURLs:     cfg.ChainRPCURLs,
 	}, nil
 }
 
-// Close closes the connection to the Ethereum node
+// Close closes the connection to the Ethereum node (and to the nodes of the other chains, to the remote signer)
 func (c *Client) Close() {
+	c.mu.Lock()
+	for _, reader := range c.readers {
+		reader.Close()
+	}
+	c.readers = nil
+	c.mu.Unlock()
+	closeSigner(c.Signer)
+
 	if c.EthClient != nil {
 		c.EthClient.Close()
-		log.Println("Disconnected from Ethereum node")
+		slog.Info("Disconnected from the Ethereum node")
+	}
+}
+
+// closeSigner closes the connection of a signer that has one (the remote signer)
+func closeSigner(signer Signer) {
+	if closer, ok := signer.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
+// Deployment returns the deployment of the contract the updates are notarized in. Its Id is 0 when the contract is
+// not registered
+func (c *Client) Deployment() models.ContractDeployment {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.deployment
+}
+
+// ContractAddress returns the address of the contract the updates are notarized in
+func (c *Client) ContractAddress() common.Address {
+	return common.HexToAddress(c.Deployment().Contract_Address)
+}
+
+// UseDeployment makes the client notarize the updates in the contract of the deployment (a deployment of its chain)
+func (c *Client) UseDeployment(deployment models.ContractDeployment) error {
+	if deployment.Chain_ID != c.chainID() {
+		return fmt.Errorf("the contract %s is on the chain %d, the client is on the chain %d", deployment.Contract_Address, deployment.Chain_ID, c.chainID())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deployment = deployment
+	return nil
+}
+
+// LegacyDeployment returns the deployment of the updates notarized before the registry, the current one when none
+// is registered
+func (c *Client) LegacyDeployment() models.ContractDeployment {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.legacy != nil {
+		return *c.legacy
+	}
+	return c.deployment
+}
+
+// Reader returns the connection to a node of the chain, to read the contracts deployed there: the node of the client
+// for its chain, a node of BLOCKCHAIN_CHAIN_RPC_URLS for the other ones
+func (c *Client) Reader(ctx context.Context, chainID int64) (*ethclient.Client, error) {
+	if chainID == c.chainID() {
+		return c.EthClient, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if reader, ok := c.readers[chainID]; ok {
+		return reader, nil
+	}
+	url, ok := c.chainURLs[chainID]
+	if !ok {
+		return nil, fmt.Errorf("no node of the chain %d is configured (BLOCKCHAIN_CHAIN_RPC_URLS)", chainID)
+	}
+	reader, err := ethclient.DialContext(ctx, url.Value())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the node of the chain %d: %w", chainID, err)
+	}
+	if c.readers == nil {
+		c.readers = map[int64]*ethclient.Client{}
+	}
+	c.readers[chainID] = reader
+	return reader, nil
+}
+
+// chainID is the chain of the client (0 when it is not known)
+func (c *Client) chainID() int64 {
+	if c.ChainID == nil {
+		return 0
 	}
+	return c.ChainID.Int64()
 }
 
 // GetBalance returns the ETH balance of a given address
@@ -1,27 +1,39 @@
 package blockchain
 
 import (
-	"log"
+	"app/config"
+	"app/models"
+	"context"
+	"log/slog"
+	"time"
+
 	"github.com/ethereum/go-ethereum/common"
     "github.com/ethereum/go-ethereum/ethclient"
 )
 
-//Deploys a contract
-func DeployContract() (common.Address, error) {
-    client, err := NewClient()
+//Deploys a contract with the wallet of the config. The deployment is not registered
+func DeployContract(ctx context.Context, cfg config.Blockchain) (*models.ContractDeployment, error) {
+    client, err := NewClient(ctx, cfg)
     if err != nil {
-        return common.Address{}, err
+        return nil, err
     }
+    defer client.Close()
 	auth := client.Auth
 	ethClient := client.EthClient
     address, tx, _, err := DeployBlockchain(auth, ethClient)
     if err != nil {
-        return common.Address{}, err
+        return nil, err
     }
 
-    log.Printf("Contract deployed at: %s", address.Hex())
-    log.Printf("Transaction hash: %s", tx.Hash().Hex())
-    return address, nil
+    slog.Info("Contract deployed", "contract", address.Hex(), "transaction", tx.Hash().Hex())
+    txHash := tx.Hash().Hex()
+    return &models.ContractDeployment{
+        Chain_ID:           client.ChainID.Int64(),
+        Contract_Address:   address.Hex(),
+        Deploy_Transaction: &txHash,
+        ABI_Version:        ABIVersion,
+        Deployed_At:        time.Now(),
+    }, nil
 }
 
 // Gets a instance of a contract
@@ -0,0 +1,83 @@
+package blockchain
+
+import (
+	"app/models"
+	"app/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ABIVersion is the version of the interface of the tracking contract (contract.sol), recorded with its deployments.
+// Bump it when the interface changes
+const ABIVersion = "1"
+
+// LoadDeployments makes the client notarize the updates in the active contract of its chain in the registry.
+//
+// A configured contract (BLOCKCHAIN_CONTRACT_ADDRESS) that is not registered yet is registered and activated: that is
+// how a contract deployed outside of the service is rotated in. The first contract registered this way, in an empty
+// registry, is the legacy one: the updates notarized before the registry (not pinned to a deployment) are in it
+func LoadDeployments(ctx context.Context, deployments repository.Deployments, client *Client, configured string) error {
+	chainID := client.chainID()
+	if configured != "" {
+		if err := registerConfigured(ctx, deployments, chainID, common.HexToAddress(configured).Hex()); err != nil {
+			return err
+		}
+	}
+
+	legacy, err := deployments.Legacy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load the legacy contract: %w", err)
+	}
+	active, err := deployments.Active(ctx, chainID)
+	if err != nil {
+		return fmt.Errorf("failed to load the active contract: %w", err)
+	}
+
+	client.mu.Lock()
+	client.legacy = legacy
+	client.mu.Unlock()
+	if active == nil {
+		slog.Warn("No contract is active on the chain, the updates cannot be notarized until one is deployed", "chain_id", chainID)
+		return nil
+	}
+	if configured != "" && active.Contract_Address != common.HexToAddress(configured).Hex() {
+		slog.Warn("The active contract of the registry is not the configured one, it is used", "contract", active.Contract_Address, "configured", configured)
+	}
+	slog.Info("Notarizing in the active contract", "contract", active.Contract_Address, "deployment_id", active.Id)
+	return client.UseDeployment(*active)
+}
+
+func registerConfigured(ctx context.Context, deployments repository.Deployments, chainID int64, address string) error {
+	_, err := deployments.GetByAddress(ctx, chainID, address)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to look up the configured contract: %w", err)
+	}
+
+	registered, err := deployments.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the deployments: %w", err)
+	}
+	deployment := models.ContractDeployment{
+		Chain_ID:         chainID,
+		Contract_Address: address,
+		ABI_Version:      ABIVersion,
+		Legacy:           len(registered) == 0,
+		Deployed_At:      time.Now(),
+	}
+	if err := deployments.Create(ctx, &deployment); err != nil {
+		return fmt.Errorf("failed to register the configured contract: %w", err)
+	}
+	if _, err := deployments.Activate(ctx, deployment.Id); err != nil {
+		return fmt.Errorf("failed to activate the configured contract: %w", err)
+	}
+	slog.Info("Registered the configured contract", "contract", address, "chain_id", chainID, "legacy", deployment.Legacy)
+	return nil
+}
@@ -0,0 +1,84 @@
+package blockchain
+
+import (
+	"app/models"
+	"app/repository"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	firstContract  = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	secondContract = "0xe7f1725E7734CE288F8367e1Bb143E90bb3F0512"
+)
+
+func TestLoadDeployments_RegistersTheConfiguredContract(t *testing.T) {
+	ctx := context.Background()
+	registry := repository.NewMemory().Deployments()
+	client := &Client{ChainID: big.NewInt(11155111)}
+
+	require.NoError(t, LoadDeployments(ctx, registry, client, firstContract))
+
+	deployment := client.Deployment()
+	assert.Equal(t, firstContract, deployment.Contract_Address)
+	assert.True(t, deployment.Active)
+	assert.True(t, deployment.Legacy, "the updates notarized before the registry are in the first contract")
+	assert.Nil(t, deployment.Deploy_Transaction)
+	assert.Equal(t, deployment, client.LegacyDeployment())
+
+	// the next boots find it registered
+	require.NoError(t, LoadDeployments(ctx, registry, client, firstContract))
+	deployments, err := registry.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, deployments, 1)
+}
+
+func TestLoadDeployments_RotatesByConfig(t *testing.T) {
+	ctx := context.Background()
+	registry := repository.NewMemory().Deployments()
+	client := &Client{ChainID: big.NewInt(11155111)}
+	require.NoError(t, LoadDeployments(ctx, registry, client, firstContract))
+
+	require.NoError(t, LoadDeployments(ctx, registry, client, secondContract))
+
+	assert.Equal(t, secondContract, client.Deployment().Contract_Address)
+	assert.False(t, client.Deployment().Legacy)
+	assert.Equal(t, firstContract, client.LegacyDeployment().Contract_Address)
+}
+
+func TestLoadDeployments_TheRegistryWins(t *testing.T) {
+	ctx := context.Background()
+	registry := repository.NewMemory().Deployments()
+	client := &Client{ChainID: big.NewInt(11155111)}
+	require.NoError(t, LoadDeployments(ctx, registry, client, firstContract))
+
+	// a contract deployed and activated through the API, the config still has the first one
+	deployed := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: secondContract}
+	require.NoError(t, registry.Create(ctx, &deployed))
+	_, err := registry.Activate(ctx, deployed.Id)
+	require.NoError(t, err)
+
+	require.NoError(t, LoadDeployments(ctx, registry, client, firstContract))
+	assert.Equal(t, deployed.Id, client.Deployment().Id)
+}
+
+func TestLoadDeployments_EachChainHasItsContract(t *testing.T) {
+	ctx := context.Background()
+	registry := repository.NewMemory().Deployments()
+	sepolia := &Client{ChainID: big.NewInt(11155111)}
+	require.NoError(t, LoadDeployments(ctx, registry, sepolia, firstContract))
+
+	// moved to another chain without a contract there yet
+	mainnet := &Client{ChainID: big.NewInt(1)}
+	require.NoError(t, LoadDeployments(ctx, registry, mainnet, ""))
+	assert.Zero(t, mainnet.Deployment().Id)
+	assert.Equal(t, int64(11155111), mainnet.LegacyDeployment().Chain_ID)
+
+	assert.Error(t, mainnet.UseDeployment(sepolia.Deployment()), "a contract of another chain")
+	_, err := mainnet.Reader(ctx, 11155111)
+	assert.ErrorContains(t, err, "no node of the chain 11155111 is configured")
+}
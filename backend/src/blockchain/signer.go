@@ -0,0 +1,222 @@
+package blockchain
+
+import (
+	"app/config"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Signer signs the transactions of the wallet of the service.
+//
+// The key can be rotated (a new keystore, another account of the remote signer): the notarized hashes are verified by
+// their content in the contract, not by the wallet that stored them, so the updates notarized with the previous key
+// are still verified
+type Signer interface {
+	// Address is the address of the wallet
+	Address() common.Address
+	// SignTx signs the transaction for the chain
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewSigner creates the signer of the config
+func NewSigner(ctx context.Context, cfg config.Blockchain) (Signer, error) {
+	switch cfg.Signer {
+	case config.SignerKeystore:
+		passphrase := cfg.Keystore.Passphrase.Value()
+		if cfg.Keystore.PassphraseFile != "" {
+			data, err := os.ReadFile(cfg.Keystore.PassphraseFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the keystore passphrase: %w", err)
+			}
+			passphrase = strings.TrimRight(string(data), "\r\n")
+		}
+		return NewKeystoreSigner(cfg.Keystore.File, passphrase)
+	case config.SignerRemote:
+		return NewRemoteSigner(ctx, cfg.RemoteSigner.URL.Value(), common.HexToAddress(cfg.RemoteSigner.Address))
+	default:
+		slog.Warn("Signing with a raw private key, use a keystore or a remote signer outside of development")
+		return NewKeySigner(cfg.PrivateKey.Value())
+	}
+}
+
+// NewTransactor creates the options of the transactions sent with the signer on the chain
+func NewTransactor(signer Signer, chainID *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From: signer.Address(),
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != signer.Address() {
+				return nil, bind.ErrNotAuthorized
+			}
+			return signer.SignTx(context.Background(), tx, chainID)
+		},
+		Context: context.Background(),
+	}
+}
+
+// KeySigner signs with a private key held in memory
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates a signer with a raw hex private key (with or without the 0x prefix), for development
+func NewKeySigner(hexKey string) (*KeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+// NewKeystoreSigner creates a signer with the key of an encrypted go-ethereum keystore file (the passphrase is not
+// kept)
+func NewKeystoreSigner(file, passphrase string) (*KeySigner, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the keystore: %w", err)
+	}
+	key, err := keystore.DecryptKey(data, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt the keystore %s: %w", file, err)
+	}
+	return &KeySigner{key: key.PrivateKey, address: key.Address}, nil
+}
+
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// DefaultRemoteSignerTimeout bounds a signature by the remote signer (it may ask an operator to approve it)
+const DefaultRemoteSignerTimeout = 30 * time.Second
+
+// RemoteSigner signs with an account of a remote signer over JSON-RPC, with eth_signTransaction (Clef, Web3Signer or
+// a node with the account unlocked). The key never enters the service
+type RemoteSigner struct {
+	Timeout time.Duration
+
+	client  *rpc.Client
+	address common.Address
+}
+
+// NewRemoteSigner connects to the remote signer of the URL, signing with the account of the address
+func NewRemoteSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		// the URL is not reported, it may have credentials
+		return nil, fmt.Errorf("failed to connect to the remote signer: %w", err)
+	}
+	return &RemoteSigner{Timeout: DefaultRemoteSignerTimeout, client: client, address: address}, nil
+}
+
+// Close closes the connection to the remote signer
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// signTransactionArgs are the arguments of eth_signTransaction
+type signTransactionArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTransactionArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("the remote signer does not sign transactions of type %d", tx.Type())
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteSignerTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := s.client.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("the remote signer failed to sign: %w", err)
+	}
+	raw, err := signedTransaction(result)
+	if err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("the remote signer returned an invalid transaction: %w", err)
+	}
+
+	// the remote signer must have signed this transaction, with the account
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, errors.New("the remote signer signed another transaction")
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("the remote signer returned an invalid signature: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("the remote signer signed with %s instead of %s", sender.Hex(), s.address.Hex())
+	}
+	return signed, nil
+}
+
+// signedTransaction decodes the result of eth_signTransaction: the raw transaction, or an object with it in raw
+// (Clef and geth)
+func signedTransaction(result json.RawMessage) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := json.Unmarshal(result, &raw); err == nil {
+		return raw, nil
+	}
+	var object struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := json.Unmarshal(result, &object); err != nil || len(object.Raw) == 0 {
+		return nil, errors.New("the remote signer returned no raw transaction")
+	}
+	return object.Raw, nil
+}
@@ -0,0 +1,178 @@
+package blockchain
+
+import (
+	"app/config"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const devKey = "304744fdba3e9f3ac2ea259e87d5ad34325b0c04d8e57df1004ec99f41741cc4"
+
+var sepolia = big.NewInt(11155111)
+
+func unsignedTx() *types.Transaction {
+	to := common.HexToAddress(firstContract)
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   sepolia,
+		Nonce:     7,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       50000,
+		To:        &to,
+		Data:      []byte{0x01, 0x02},
+	})
+}
+
+// writeKeystore encrypts the key in a keystore file of a temporary directory
+func writeKeystore(t *testing.T, key *ecdsa.PrivateKey, passphrase string) string {
+	t.Helper()
+	encrypted, err := keystore.EncryptKey(&keystore.Key{
+		Id:         uuid.New(),
+		Address:    crypto.PubkeyToAddress(key.PublicKey),
+		PrivateKey: key,
+	}, passphrase, keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "wallet.json")
+	require.NoError(t, os.WriteFile(file, encrypted, 0o600))
+	return file
+}
+
+// signerServer is a remote signer answering eth_signTransaction with the key
+func signerServer(t *testing.T, key *ecdsa.PrivateKey, tx *types.Transaction) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params []signTransactionArgs
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "eth_signTransaction", request.Method)
+		require.Len(t, request.Params, 1)
+		assert.Equal(t, uint64(tx.Nonce()), uint64(request.Params[0].Nonce))
+		assert.Equal(t, sepolia, request.Params[0].ChainID.ToInt())
+
+		signed, err := types.SignTx(tx, types.LatestSignerForChainID(sepolia), key)
+		require.NoError(t, err)
+		raw, err := signed.MarshalBinary()
+		require.NoError(t, err)
+		// the result of Clef and geth: the raw transaction and its fields
+		json.NewEncoder(w).Encode(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      request.ID,
+			"result":  map[string]any{"raw": hexutil.Bytes(raw), "tx": signed},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestKeySigner_SignsForTheChain(t *testing.T) {
+	signer, err := NewKeySigner("0x" + devKey)
+	require.NoError(t, err)
+
+	signed, err := signer.SignTx(context.Background(), unsignedTx(), sepolia)
+	require.NoError(t, err)
+
+	sender, err := types.Sender(types.LatestSignerForChainID(sepolia), signed)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Address(), sender)
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	file := writeKeystore(t, key, "correct horse")
+
+	signer, err := NewKeystoreSigner(file, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+
+	_, err = NewKeystoreSigner(file, "wrong")
+	assert.ErrorIs(t, err, keystore.ErrDecrypt)
+}
+
+func TestNewSigner_KeystorePassphraseFile(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	// as written by echo or a secret manager
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("correct horse\n"), 0o600))
+
+	signer, err := NewSigner(context.Background(), config.Blockchain{
+		Signer:   config.SignerKeystore,
+		Keystore: config.Keystore{File: writeKeystore(t, key, "correct horse"), PassphraseFile: passphraseFile},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(key.PublicKey), signer.Address())
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	tx := unsignedTx()
+	server := signerServer(t, key, tx)
+
+	signer, err := NewSigner(context.Background(), config.Blockchain{
+		Signer:       config.SignerRemote,
+		RemoteSigner: config.RemoteSigner{URL: config.Secret(server.URL), Address: crypto.PubkeyToAddress(key.PublicKey).Hex()},
+	})
+	require.NoError(t, err)
+	defer closeSigner(signer)
+
+	signed, err := signer.SignTx(context.Background(), tx, sepolia)
+	require.NoError(t, err)
+	assert.Equal(t, types.LatestSignerForChainID(sepolia).Hash(tx), types.LatestSignerForChainID(sepolia).Hash(signed))
+	sender, err := types.Sender(types.LatestSignerForChainID(sepolia), signed)
+	require.NoError(t, err)
+	assert.Equal(t, signer.Address(), sender)
+}
+
+func TestRemoteSigner_RejectsAnotherAccount(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	tx := unsignedTx()
+	server := signerServer(t, other, tx)
+
+	signer, err := NewRemoteSigner(context.Background(), server.URL, crypto.PubkeyToAddress(key.PublicKey))
+	require.NoError(t, err)
+	defer signer.Close()
+
+	_, err = signer.SignTx(context.Background(), tx, sepolia)
+	assert.ErrorContains(t, err, "the remote signer signed with "+crypto.PubkeyToAddress(other.PublicKey).Hex())
+}
+
+func TestNewTransactor(t *testing.T) {
+	signer, err := NewKeySigner(devKey)
+	require.NoError(t, err)
+	auth := NewTransactor(signer, sepolia)
+	assert.Equal(t, signer.Address(), auth.From)
+
+	signed, err := auth.Signer(auth.From, unsignedTx())
+	require.NoError(t, err)
+	sender, err := types.Sender(types.LatestSignerForChainID(sepolia), signed)
+	require.NoError(t, err)
+	assert.Equal(t, auth.From, sender)
+
+	_, err = auth.Signer(common.HexToAddress(firstContract), unsignedTx())
+	assert.ErrorIs(t, err, bind.ErrNotAuthorized)
+}
@@ -0,0 +1,81 @@
+package catalog
+
+import (
+	"app/requestModels"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache keeps the most recently used products for a limited time
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[uint]*list.Element
+	order   *list.List // front is the most recently used
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	id        uint
+	product   requestModels.Product
+	expiresAt time.Time
+}
+
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[uint]*list.Element{},
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the cached product, so callers can not change the cache
+func (c *cache) get(id uint) (*requestModels.Product, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, id)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	product := entry.product
+	return &product, true
+}
+
+func (c *cache) put(id uint, product *requestModels.Product) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if element, ok := c.entries[id]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.product, entry.expiresAt = *product, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[id] = c.order.PushFront(&cacheEntry{id: id, product: *product, expiresAt: expiresAt})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).id)
+	}
+}
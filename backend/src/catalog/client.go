@@ -0,0 +1,301 @@
+package catalog
+
+import (
+	"app/config"
+	"app/metrics"
+	"app/requestModels"
+	"app/telemetry"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrCircuitOpen     = errors.New("catalogue unavailable (circuit open)")
+)
+
+// StatusError is returned when the Jumpseller API answers with an unexpected status
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("jumpseller API returned status %d", e.StatusCode)
+}
+
+// Config of the Jumpseller client
+type Config struct {
+	BaseURL          string
+	Login            string
+	Token            string
+	Timeout          time.Duration // timeout of each request
+	MaxRetries       int           // retries of failed requests (network errors, 5xx and 429)
+	RetryBackoff     time.Duration // wait before the first retry, doubled on every retry
+	BreakerThreshold int           // consecutive failures that open the circuit
+	BreakerCooldown  time.Duration // time the circuit stays open before trying again
+	CacheSize        int           // products kept in memory
+	CacheTTL         time.Duration
+	MaxConcurrency   int           // products fetched at the same time
+	PageSize         int           // products per page when listing the catalogue
+	SyncInterval     time.Duration // time between two syncs of the local catalogue
+}
+
+// DefaultConfig returns the settings used in production (without the credentials)
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          5 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+		CacheSize:        500,
+		CacheTTL:         5 * time.Minute,
+		MaxConcurrency:   4,
+		PageSize:         100,
+		SyncInterval:     15 * time.Minute,
+	}
+}
+
+// ConfigOf returns the default config with the Jumpseller URL, credentials and sync interval of the service config
+func ConfigOf(jumpseller config.Jumpseller) Config {
+	config := DefaultConfig()
+	config.BaseURL = jumpseller.BaseURL
+	config.Login = jumpseller.Login
+	config.Token = jumpseller.Token.Value()
+	if jumpseller.SyncInterval > 0 {
+		config.SyncInterval = time.Duration(jumpseller.SyncInterval)
+	}
+	return config
+}
+
+// Client is a typed client of the Jumpseller catalogue. It is safe for concurrent use
+type Client struct {
+	config  Config
+	http    *http.Client
+	breaker *breaker
+	cache   *cache
+}
+
+// NewClient creates a client with its own connection pool, circuit breaker and cache
+func NewClient(config Config) *Client {
+	if config.MaxConcurrency < 1 {
+		config.MaxConcurrency = 1
+	}
+	return &Client{
+		config:  config,
+		http:    &http.Client{Timeout: config.Timeout},
+		breaker: newBreaker(config.BreakerThreshold, config.BreakerCooldown),
+		cache:   newCache(config.CacheSize, config.CacheTTL),
+	}
+}
+
+var (
+	defaultClient *Client
+	defaultMu     sync.Mutex
+)
+
+// Default returns the client shared by the whole application, the one set at boot by SetDefault (a client without
+// base URL before)
+func Default() *Client {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultClient == nil {
+		defaultClient = NewClient(DefaultConfig())
+	}
+	return defaultClient
+}
+
+// SetDefault replaces the client shared by the whole application
+func SetDefault(client *Client) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultClient = client
+}
+
+// ListProducts retrieves every product of the store, page by page
+func (c *Client) ListProducts(ctx context.Context) ([]requestModels.ProductResponse, error) {
+	pageSize := c.config.PageSize
+	if pageSize < 1 {
+		pageSize = 100
+	}
+
+	var products []requestModels.ProductResponse
+	for page := 1; ; page++ {
+		var pageProducts []requestModels.ProductResponse
+		path := fmt.Sprintf("/products.json?page=%d&limit=%d", page, pageSize)
+		if err := c.getJSON(ctx, path, &pageProducts); err != nil {
+			return nil, err
+		}
+
+		for i := range pageProducts {
+			product := pageProducts[i].Product
+			c.cache.put(uint(product.ID), &product)
+		}
+		products = append(products, pageProducts...)
+
+		if len(pageProducts) < pageSize {
+			return products, nil
+		}
+	}
+}
+
+// GetProduct retrieves a product, using the cache when possible
+func (c *Client) GetProduct(ctx context.Context, id uint) (*requestModels.Product, error) {
+	if product, ok := c.cache.get(id); ok {
+		return product, nil
+	}
+
+	var response requestModels.ProductResponse
+	if err := c.getJSON(ctx, fmt.Sprintf("/products/%d.json", id), &response); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			return nil, fmt.Errorf("product %d: %w", id, ErrProductNotFound)
+		}
+		return nil, err
+	}
+
+	// Jumpseller answers some unknown ids with an empty product
+	if response.Product.ID == 0 {
+		return nil, fmt.Errorf("product %d: %w", id, ErrProductNotFound)
+	}
+
+	product := response.Product
+	c.cache.put(id, &product)
+	return &product, nil
+}
+
+// GetProducts retrieves several products concurrently. Products that do not exist are left out of the result,
+// the other errors fail the whole request (the first one, in the order of ids)
+func (c *Client) GetProducts(ctx context.Context, ids []uint) (map[uint]*requestModels.Product, error) {
+	unique := make([]uint, 0, len(ids))
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	products := make([]*requestModels.Product, len(unique))
+	errs := make([]error, len(unique))
+	semaphore := make(chan struct{}, c.config.MaxConcurrency)
+
+	var wg sync.WaitGroup
+	for i, id := range unique {
+		wg.Add(1)
+		go func(i int, id uint) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			products[i], errs[i] = c.GetProduct(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	result := make(map[uint]*requestModels.Product, len(unique))
+	for i, id := range unique {
+		if errors.Is(errs[i], ErrProductNotFound) {
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		result[id] = products[i]
+	}
+	return result, nil
+}
+
+// getJSON performs a GET request with retries and decodes the response into out
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+
+	var err error
+	backoff := c.config.RetryBackoff
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				c.breaker.failure()
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = c.get(ctx, path, out)
+		if err == nil || !retry {
+			break
+		}
+	}
+
+	// missing products and client errors do not mean the API is down
+	var statusErr *StatusError
+	if err == nil || errors.Is(err, ErrProductNotFound) || (errors.As(err, &statusErr) && statusErr.StatusCode < 500 && statusErr.StatusCode != http.StatusTooManyRequests) {
+		c.breaker.success()
+	} else {
+		c.breaker.failure()
+	}
+	return err
+}
+
+// get performs a single request and reports if it is worth retrying when it fails
+func (c *Client) get(ctx context.Context, path string, out interface{}) (retry bool, err error) {
+	ctx, span := telemetry.Start(ctx, "jumpseller GET", trace.SpanKindClient, trace.WithAttributes(
+		semconv.HTTPRequestMethodGet,
+		semconv.URLPath(path),
+	))
+	defer func() {
+		// a missing product is an answer, not a failure
+		if errors.Is(err, ErrProductNotFound) {
+			span.End()
+			return
+		}
+		telemetry.End(span, err)
+	}()
+
+	url := strings.TrimSuffix(c.config.BaseURL, "/") + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	req.SetBasicAuth(c.config.Login, c.config.Token)
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		metrics.ObserveJumpsellerRequest(0, time.Since(start))
+		// cancelled requests are not retried
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	metrics.ObserveJumpsellerRequest(resp.StatusCode, time.Since(start))
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, ErrProductNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		io.Copy(io.Discard, resp.Body)
+		return true, &StatusError{StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusOK:
+		return false, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, fmt.Errorf("failed to decode the jumpseller response: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,202 @@
+package catalog
+
+import (
+	"app/requestModels"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProduct_Success(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 5, Name: "Cork Wallet", Price: 19.9})
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	product, err := client.GetProduct(context.Background(), 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Cork Wallet", product.Name)
+	assert.Equal(t, 19.9, product.Price)
+}
+
+func TestGetProduct_NotFound(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	_, err := client.GetProduct(context.Background(), 42)
+
+	assert.ErrorIs(t, err, ErrProductNotFound)
+	assert.Equal(t, 1, server.Requests(), "missing products are not retried")
+}
+
+func TestGetProduct_RetriesServerErrors(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+	server.FailNext(2, http.StatusBadGateway)
+
+	client := NewClient(server.Config())
+	product, err := client.GetProduct(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Tile", product.Name)
+	assert.Equal(t, 3, server.Requests())
+}
+
+func TestGetProduct_GivesUpAfterRetries(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+	server.FailNext(10, http.StatusInternalServerError)
+
+	config := server.Config()
+	config.MaxRetries = 1
+	client := NewClient(config)
+	_, err := client.GetProduct(context.Background(), 1)
+
+	var statusErr *StatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, http.StatusInternalServerError, statusErr.StatusCode)
+	assert.Equal(t, 2, server.Requests())
+}
+
+func TestGetProduct_ClientErrorsAreNotRetried(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1})
+	defer server.Close()
+	server.FailNext(1, http.StatusUnauthorized)
+
+	client := NewClient(server.Config())
+	_, err := client.GetProduct(context.Background(), 1)
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, server.Requests())
+}
+
+func TestGetProduct_UsesCache(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	for i := 0; i < 3; i++ {
+		_, err := client.GetProduct(context.Background(), 1)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, server.Requests())
+}
+
+func TestCircuitBreaker_OpensAndRecovers(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+	server.FailNext(2, http.StatusServiceUnavailable)
+
+	config := server.Config()
+	config.MaxRetries = 0
+	config.BreakerThreshold = 2
+	config.CacheSize = 0
+	client := NewClient(config)
+
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetProduct(context.Background(), 1)
+		assert.Error(t, err)
+	}
+
+	// open: the API is not called
+	_, err := client.GetProduct(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, server.Requests())
+
+	// after the cooldown a request goes through and closes the circuit
+	now = now.Add(config.BreakerCooldown + time.Second)
+	_, err = client.GetProduct(context.Background(), 1)
+	assert.NoError(t, err)
+	_, err = client.GetProduct(context.Background(), 1)
+	assert.NoError(t, err)
+}
+
+func TestGetProducts_Concurrent(t *testing.T) {
+	server := NewFakeServer(
+		requestModels.Product{ID: 1, Name: "Tile"},
+		requestModels.Product{ID: 2, Name: "Cork Wallet"},
+		requestModels.Product{ID: 3, Name: "Sardines"},
+	)
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	products, err := client.GetProducts(context.Background(), []uint{1, 2, 3, 1})
+
+	require.NoError(t, err)
+	assert.Len(t, products, 3)
+	assert.Equal(t, "Sardines", products[3].Name)
+	assert.Equal(t, 3, server.Requests(), "duplicated ids are fetched once")
+}
+
+func TestGetProducts_MissingProduct(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	products, err := client.GetProducts(context.Background(), []uint{1, 7})
+
+	require.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.NotContains(t, products, uint(7))
+}
+
+func TestGetProducts_Unavailable(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 1, Name: "Tile"})
+	defer server.Close()
+	server.FailNext(10, http.StatusServiceUnavailable)
+
+	config := server.Config()
+	config.MaxRetries = 0
+	client := NewClient(config)
+	_, err := client.GetProducts(context.Background(), []uint{1, 7})
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrProductNotFound)
+}
+
+func TestListProducts(t *testing.T) {
+	server := NewFakeServer(requestModels.Product{ID: 2, Name: "B"}, requestModels.Product{ID: 1, Name: "A"})
+	defer server.Close()
+
+	client := NewClient(server.Config())
+	products, err := client.ListProducts(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Equal(t, "A", products[0].Product.Name)
+
+	// listed products are cached
+	_, err = client.GetProduct(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, server.Requests())
+}
+
+func TestCache_EvictsLeastRecentlyUsedAndExpired(t *testing.T) {
+	c := newCache(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.put(1, &requestModels.Product{ID: 1})
+	c.put(2, &requestModels.Product{ID: 2})
+	c.get(1)
+	c.put(3, &requestModels.Product{ID: 3})
+
+	_, ok := c.get(2)
+	assert.False(t, ok, "least recently used product is evicted")
+	_, ok = c.get(1)
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get(1)
+	assert.False(t, ok, "expired products are not returned")
+}
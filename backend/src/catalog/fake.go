@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"app/requestModels"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeServer imitates the Jumpseller products API, for tests
+type FakeServer struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	products   map[int]requestModels.Product
+	failures   int
+	failStatus int
+	requests   int
+}
+
+// NewFakeServer starts a server with the given products. Close it when done
+func NewFakeServer(products ...requestModels.Product) *FakeServer {
+	f := &FakeServer{products: map[int]requestModels.Product{}}
+	for _, product := range products {
+		f.products[product.ID] = product
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+// Config returns a client config pointing to the server, with short timeouts and backoff
+func (f *FakeServer) Config() Config {
+	config := DefaultConfig()
+	config.BaseURL = f.URL
+	config.Login, config.Token = "user", "token"
+	config.Timeout = time.Second
+	config.RetryBackoff = time.Millisecond
+	return config
+}
+
+// SetProduct adds or replaces a product
+func (f *FakeServer) SetProduct(product requestModels.Product) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.products[product.ID] = product
+}
+
+// FailNext makes the next n requests fail with the given status
+func (f *FakeServer) FailNext(n int, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures, f.failStatus = n, status
+}
+
+// Requests returns the number of requests received
+func (f *FakeServer) Requests() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func (f *FakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(f.failStatus)
+		return
+	}
+
+	if r.URL.Path == "/products.json" {
+		ids := make([]int, 0, len(f.products))
+		for id := range f.products {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		//same pagination as the API (page starts at 1)
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 {
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			if err != nil || page < 1 {
+				page = 1
+			}
+			start := min((page-1)*limit, len(ids))
+			ids = ids[start:min(start+limit, len(ids))]
+		}
+
+		response := make([]requestModels.ProductResponse, 0, len(ids))
+		for _, id := range ids {
+			response = append(response, requestModels.ProductResponse{Product: f.products[id]})
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/products/") && strings.HasSuffix(r.URL.Path, ".json") {
+		id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/products/"), ".json"))
+		if product, ok := f.products[id]; err == nil && ok {
+			json.NewEncoder(w).Encode(requestModels.ProductResponse{Product: product})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(map[string]string{"message": "Not Found"})
+}
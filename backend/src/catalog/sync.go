@@ -0,0 +1,190 @@
+package catalog
+
+import (
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Syncer mirrors the Jumpseller catalogue into the local products table
+type Syncer struct {
+	DB       *gorm.DB
+	Client   *Client
+	Interval time.Duration
+}
+
+// NewSyncer creates a syncer that runs with the interval of the client config
+func NewSyncer(db *gorm.DB, client *Client) *Syncer {
+	return &Syncer{DB: db, Client: client, Interval: client.config.SyncInterval}
+}
+
+// Run syncs the catalogue right away and then periodically. It blocks until the context is cancelled, a sync in
+// progress then is abandoned (its transaction is rolled back, the next start syncs again)
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if synced, err := s.SyncOnce(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			slog.ErrorContext(ctx, "Failed to sync the product catalogue", "error", err)
+		} else {
+			slog.InfoContext(ctx, "Product catalogue synced", "products", synced)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce copies every product (and its variants) to the database and disables the products that are no
+// longer in the catalogue. Returns the number of products synced
+func (s *Syncer) SyncOnce(ctx context.Context) (int, error) {
+	products, err := s.Client.ListProducts(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	localProducts := make([]models.Product, 0, len(products))
+	productIDs := make([]uint, 0, len(products))
+	for _, response := range products {
+		product := ToProductModel(&response.Product, now)
+		localProducts = append(localProducts, *product)
+		productIDs = append(productIDs, product.ID)
+	}
+
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range localProducts {
+			product := &localProducts[i]
+			if err := tx.Omit("Variants").Clauses(clause.OnConflict{UpdateAll: true}).Create(product).Error; err != nil {
+				return fmt.Errorf("failed to save product %d: %w", product.ID, err)
+			}
+
+			variantIDs := make([]uint, 0, len(product.Variants))
+			for j := range product.Variants {
+				if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&product.Variants[j]).Error; err != nil {
+					return fmt.Errorf("failed to save variant %d: %w", product.Variants[j].ID, err)
+				}
+				variantIDs = append(variantIDs, product.Variants[j].ID)
+			}
+
+			// variants removed from the product
+			removed := tx.Where("product_id = ?", product.ID)
+			if len(variantIDs) > 0 {
+				removed = removed.Where("id NOT IN ?", variantIDs)
+			}
+			if err := removed.Delete(&models.ProductVariant{}).Error; err != nil {
+				return err
+			}
+		}
+
+		// products removed from the catalogue are kept (old orders refer to them) but disabled
+		removed := tx.Model(&models.Product{})
+		if len(productIDs) > 0 {
+			removed = removed.Where("id NOT IN ?", productIDs)
+		} else {
+			removed = removed.Where("1 = 1")
+		}
+		return removed.Update("status", models.ProductDisabled).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(localProducts), nil
+}
+
+// ToProductModel converts a Jumpseller product into the local product model
+func ToProductModel(product *requestModels.Product, syncedAt time.Time) *models.Product {
+	local := &models.Product{
+		ID:              uint(product.ID),
+		Name:            product.Name,
+		SKU:             product.SKU,
+		Brand:           product.Brand,
+		Price:           product.Price,
+		Status:          product.Status,
+		Stock:           product.Stock,
+		Stock_Unlimited: product.StockUnlimited,
+		Weight:          product.Weight,
+		Length:          product.Length,
+		Width:           product.Width,
+		Height:          product.Height,
+		Package_Format:  product.PackageFormat,
+		Synced_At:       syncedAt,
+	}
+
+	for _, variant := range product.Variants {
+		options := make([]models.VariantOption, 0, len(variant.Options))
+		for _, option := range variant.Options {
+			options = append(options, models.VariantOption{Name: option.Name, Value: option.Value})
+		}
+		local.Variants = append(local.Variants, models.ProductVariant{
+			ID:              uint(variant.ID),
+			Product_ID:      local.ID,
+			SKU:             variant.SKU,
+			Price:           variant.Price,
+			Stock:           variant.Stock,
+			Stock_Unlimited: variant.StockUnlimited,
+			Options:         options,
+			Synced_At:       syncedAt,
+		})
+	}
+
+	return local
+}
+
+// LookupProducts returns the products with the given ids from the local catalogue. Products that were not
+// synced yet are fetched from Jumpseller, so orders can be created while Jumpseller is down as long as their
+// products are in the local catalogue. Products that do not exist are left out of the result
+func LookupProducts(ctx context.Context, products repository.Products, client *Client, ids []uint) (map[uint]*models.Product, error) {
+	result := make(map[uint]*models.Product, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	localProducts, err := products.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range localProducts {
+		result[localProducts[i].ID] = &localProducts[i]
+	}
+
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := result[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	if client == nil {
+		return result, nil
+	}
+
+	remoteProducts, err := client.GetProducts(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for id, product := range remoteProducts {
+		result[id] = ToProductModel(product, now)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package catalog
+
+import (
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open gorm with sqlmock: %v", err)
+	}
+	return gdb, mock
+}
+
+func TestToProductModel(t *testing.T) {
+	now := time.Now()
+	product := ToProductModel(&requestModels.Product{
+		ID:            3,
+		Name:          "Cork Backpack",
+		Price:         49.9,
+		Weight:        0.8,
+		Length:        40,
+		Width:         30,
+		Height:        12,
+		PackageFormat: "box",
+		Variants: []requestModels.Variant{{
+			ID:      31,
+			SKU:     "CB-BLK",
+			Price:   52.9,
+			Stock:   4,
+			Options: []requestModels.VariantOption{{Name: "Colour", Value: "Black"}},
+		}},
+	}, now)
+
+	assert.Equal(t, uint(3), product.ID)
+	assert.Equal(t, 0.8, product.Weight)
+	assert.Equal(t, 12.0, product.Height)
+	assert.Equal(t, "box", product.Package_Format)
+	require.Len(t, product.Variants, 1)
+	assert.Equal(t, uint(3), product.Variants[0].Product_ID)
+	assert.Equal(t, "Black", product.Variants[0].Options[0].Value)
+	assert.Equal(t, now, product.Variants[0].Synced_At)
+}
+
+func TestSyncOnce(t *testing.T) {
+	server := NewFakeServer(
+		requestModels.Product{ID: 1, Name: "Tile", Variants: []requestModels.Variant{{ID: 11, SKU: "T-1"}}},
+		requestModels.Product{ID: 2, Name: "Sardines"},
+	)
+	defer server.Close()
+	db, mock := setupMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "products" .* ON CONFLICT \("id"\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "product_variants" .* ON CONFLICT \("id"\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "product_variants" WHERE product_id = \$1 AND id NOT IN \(\$2\)`).
+		WithArgs(1, 11).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "products" .* ON CONFLICT \("id"\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM "product_variants" WHERE product_id = \$1`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE "products" SET "status"=\$1 WHERE id NOT IN \(\$2,\$3\)`).
+		WithArgs("disabled", 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	synced, err := NewSyncer(db, NewClient(server.Config())).SyncOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, synced)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSyncOnce_JumpsellerDown(t *testing.T) {
+	server := NewFakeServer()
+	defer server.Close()
+	server.FailNext(10, http.StatusServiceUnavailable)
+	db, mock := setupMockDB(t)
+
+	_, err := NewSyncer(db, NewClient(server.Config())).SyncOnce(context.Background())
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet(), "the local catalogue is left untouched")
+}
+
+func TestListProducts_Paginates(t *testing.T) {
+	server := NewFakeServer(
+		requestModels.Product{ID: 1}, requestModels.Product{ID: 2}, requestModels.Product{ID: 3},
+	)
+	defer server.Close()
+
+	config := server.Config()
+	config.PageSize = 2
+	products, err := NewClient(config).ListProducts(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, products, 3)
+	assert.Equal(t, 2, server.Requests())
+}
+
+func TestLookupProducts_MissingWithoutClient(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.Products().Create(context.Background(), &models.Product{ID: 3, Name: "Cork Wallet"}))
+
+	products, err := LookupProducts(context.Background(), store.Products(), nil, []uint{3, 4})
+
+	assert.NoError(t, err)
+	assert.Len(t, products, 1, "the product that is not in the local catalogue is left out")
+	assert.Equal(t, "Cork Wallet", products[3].Name)
+}
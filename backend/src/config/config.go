@@ -0,0 +1,502 @@
+// Package config is the configuration of the service. It is loaded once at boot, from an optional JSON file
+// (CONFIG_FILE) and from the environment, which overrides the file, and validated before any component starts. The
+// components are given their section of the config, they do not read the environment.
+//
+// The logs and the traces are set up from the config as soon as it is loaded, the errors of the config itself are
+// logged by the default logger.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileEnv is the variable with the path of the optional config file
+const FileEnv = "CONFIG_FILE"
+
+// Chain IDs of the networks known by name (BLOCKCHAIN_NETWORK)
+var Networks = map[string]int64{
+	"mainnet": 1,
+	"sepolia": 11155111,
+	"holesky": 17000,
+}
+
+// Config of the service
+type Config struct {
+	Environment string     `json:"environment"`
+	Log         Log        `json:"log"`
+	Tracing     Tracing    `json:"tracing"`
+	HTTP        HTTP       `json:"http"`
+	GRPC        GRPC       `json:"grpc"`
+	Auth        Auth       `json:"auth"`
+	Database    Database   `json:"database"`
+	Blockchain  Blockchain `json:"blockchain"`
+	PubSub      PubSub     `json:"pubsub"`
+	Jumpseller  Jumpseller `json:"jumpseller"`
+	Audit       Audit      `json:"audit"`
+	Storage     Storage    `json:"storage"`
+	Shipping    Shipping   `json:"shipping"`
+
+	// ShutdownTimeout is the time the requests, messages and background tasks in flight have to finish on SIGTERM
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	// PreStopDelay is the time the service keeps serving on SIGTERM once it is not ready, before it drains, so the
+	// load balancer stops routing to it first. It is waited before the ShutdownTimeout
+	PreStopDelay Duration `json:"pre_stop_delay"`
+}
+
+// Formats of the logs (LOG_FORMAT)
+const (
+	LogJSON = "json"
+	LogText = "text"
+)
+
+// Log is the config of the structured logs
+type Log struct {
+	// Level is debug, info, warn or error
+	Level  slog.Level `json:"level"`
+	Format string     `json:"format"`
+	// Redact redacts the personal data of the customers. When it is not set it is redacted in production only
+	Redact *bool `json:"redact"`
+}
+
+// Redacts reports whether the personal data is redacted
+func (l Log) Redacts() bool {
+	return l.Redact != nil && *l.Redact
+}
+
+// Exporters of the traces (OTEL_TRACES_EXPORTER)
+const (
+	ExporterNone   = "none"   // the trace context is still propagated
+	ExporterStdout = "stdout" // for local debugging
+	ExporterOTLP   = "otlp"   // to an OTLP/HTTP collector, configured by the standard OTEL_EXPORTER_OTLP_* variables
+)
+
+// Tracing is the config of the export of the traces
+type Tracing struct {
+	Exporter string `json:"exporter"`
+}
+
+// HTTP is the config of the REST API
+type HTTP struct {
+	Port string `json:"port"`
+	// CORSOrigins are the origins allowed to call the API, a * matches any part of a host or a port
+	// (e.g. https://*.run.app or http://localhost:*)
+	CORSOrigins []string `json:"cors_origins"`
+}
+
+// GRPC is the config of the gRPC tracking service
+type GRPC struct {
+	Port string `json:"port"`
+	// TLSCertFile and TLSKeyFile are the PEM certificate and key the calls are served with, in plaintext without them
+	// (behind a proxy terminating TLS, or on an internal network)
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+}
+
+// TLS reports whether the calls are served with TLS
+func (g GRPC) TLS() bool {
+	return g.TLSCertFile != "" || g.TLSKeyFile != ""
+}
+
+// Auth is the config of the authentication of the callers, by the bearer tokens (JWT) of the identity provider
+type Auth struct {
+	// TokenSecret is the HS256 key the tokens are signed with. Without it no caller is authenticated: the admin routes
+	// and the gRPC calls changing the orders are closed, and the other changes are recorded as anonymous
+	TokenSecret Secret `json:"token_secret"`
+	// Issuer and Audience are checked in the tokens when they are set
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+}
+
+// Enabled reports whether the callers can be authenticated
+func (a Auth) Enabled() bool {
+	return a.TokenSecret != ""
+}
+
+// Database is the config of the Postgres connection
+type Database struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password Secret `json:"password"`
+	Name     string `json:"name"`
+	// MigrateOnStart brings the schema up to date before serving (false leaves it to the migrate subcommand)
+	MigrateOnStart bool `json:"migrate_on_start"`
+}
+
+// DSN is the connection string of the database
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s", d.Host, d.User, d.Password.Value(), d.Name, d.Port)
+}
+
+// Signers of the transactions (BLOCKCHAIN_SIGNER)
+const (
+	SignerKey      = "key"      // a raw private key, for development
+	SignerKeystore = "keystore" // an encrypted go-ethereum keystore file
+	SignerRemote   = "remote"   // a JSON-RPC signer (eth_signTransaction), the key never enters the service
+)
+
+// Blockchain is the config of the notarization of the updates. It is disabled when RPCURL is not set
+type Blockchain struct {
+	// RPCURL has the API key of the node provider
+	RPCURL Secret `json:"rpc_url"`
+	// Signer signs the transactions of the wallet (SignerKey, SignerKeystore or SignerRemote). When it is not set it is
+	// the one whose settings are set
+	Signer       string       `json:"signer"`
+	PrivateKey   Secret       `json:"private_key"`
+	Keystore     Keystore     `json:"keystore"`
+	RemoteSigner RemoteSigner `json:"remote_signer"`
+	// ContractAddress is the tracking contract the updates are notarized in
+	ContractAddress string `json:"contract_address"`
+	Network         string `json:"network"`
+	// ChainID the node must be on and the transactions are signed for, the chain ID of Network when it is not set
+	ChainID int64 `json:"chain_id"`
+	// ChainRPCURLs are the nodes of the other chains the service notarized in before, by chain ID. The updates
+	// notarized in the contracts of those chains are verified through them
+	ChainRPCURLs map[int64]Secret `json:"chain_rpc_urls"`
+}
+
+// Enabled reports whether the blockchain is configured
+func (b Blockchain) Enabled() bool {
+	return b.RPCURL != ""
+}
+
+// Keystore is an encrypted go-ethereum keystore file, with its passphrase or the file that has it
+type Keystore struct {
+	File           string `json:"file"`
+	Passphrase     Secret `json:"passphrase"`
+	PassphraseFile string `json:"passphrase_file"`
+}
+
+// RemoteSigner signs the transactions of an account over JSON-RPC (e.g. Clef or Web3Signer)
+type RemoteSigner struct {
+	// URL may have credentials
+	URL     Secret `json:"url"`
+	Address string `json:"address"`
+}
+
+// PubSub is the config of the Pub/Sub client
+type PubSub struct {
+	Project string `json:"project"`
+	// CredentialsFile is the key of the service account, the default credentials are used when it is not set
+	CredentialsFile string `json:"credentials_file"`
+}
+
+// Jumpseller is the config of the product catalogue
+type Jumpseller struct {
+	BaseURL      string   `json:"base_url"`
+	Login        string   `json:"login"`
+	Token        Secret   `json:"token"`
+	SyncInterval Duration `json:"sync_interval"`
+}
+
+// Enabled reports whether the catalogue is configured (it is synced only then)
+func (j Jumpseller) Enabled() bool {
+	return j.BaseURL != ""
+}
+
+// Audit is the config of the audit trail
+type Audit struct {
+	// ChainAnchoring anchors the audit events to the blockchain, one transaction per order for the events of a change,
+	// submitted in the background once the change is committed
+	ChainAnchoring bool `json:"chain_anchoring"`
+}
+
+// Storage is the config of the blob storage of the delivery proofs
+type Storage struct {
+	BlobPath string `json:"blob_path"`
+}
+
+// Shipping is the config of the shipping costs
+type Shipping struct {
+	// RatesFile replaces the default rates when it is set
+	RatesFile string `json:"rates_file"`
+}
+
+// Default returns the config used when nothing is set
+func Default() *Config {
+	return &Config{
+		Environment:     "development",
+		Log:             Log{Level: slog.LevelInfo, Format: LogJSON},
+		Tracing:         Tracing{Exporter: ExporterNone},
+		ShutdownTimeout: Duration(10 * time.Second),
+		HTTP: HTTP{
+			Port: "8080",
+			CORSOrigins: []string{
+				"http://localhost",
+				"http://localhost:*",
+				"https://*.run.app",
+				"https://frontend.madeinportugal.store",
+			},
+		},
+		GRPC:       GRPC{Port: "9090"},
+		Database:   Database{Port: "5432", MigrateOnStart: true},
+		Blockchain: Blockchain{Network: "sepolia"},
+		PubSub:     PubSub{Project: "madeinportugal"},
+		Jumpseller: Jumpseller{SyncInterval: Duration(15 * time.Minute)},
+		Storage:    Storage{BlobPath: "blobs"},
+	}
+}
+
+// Load reads the config file (when CONFIG_FILE is set) and the environment over the defaults, and validates the result
+func Load() (*Config, error) {
+	config := Default()
+	if file := os.Getenv(FileEnv); file != "" {
+		if err := config.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.readEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	config.resolveChainID()
+	config.resolveSigner()
+	config.resolveLog()
+	config.resolveTracing()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *Config) readFile(file string) error {
+	reader, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to read the config file: %w", err)
+	}
+	defer reader.Close()
+
+	// a misspelled setting is an error rather than a setting silently left to its default
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// readEnv overrides the config with the variables that are set
+func (c *Config) readEnv(lookup func(string) (string, bool)) error {
+	env := envReader{lookup: lookup}
+
+	env.string("ENVIRONMENT", &c.Environment)
+	env.level("LOG_LEVEL", &c.Log.Level)
+	env.string("LOG_FORMAT", &c.Log.Format)
+	env.optionalBool("LOG_REDACT", &c.Log.Redact)
+	env.string("OTEL_TRACES_EXPORTER", &c.Tracing.Exporter)
+	env.duration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+	env.duration("PRE_STOP_DELAY", &c.PreStopDelay)
+	env.string("HTTP_PORT", &c.HTTP.Port)
+	env.list("CORS_ALLOWED_ORIGINS", &c.HTTP.CORSOrigins)
+	env.string("GRPC_PORT", &c.GRPC.Port)
+	env.string("GRPC_TLS_CERT_FILE", &c.GRPC.TLSCertFile)
+	env.string("GRPC_TLS_KEY_FILE", &c.GRPC.TLSKeyFile)
+
+	env.secret("AUTH_TOKEN_SECRET", &c.Auth.TokenSecret)
+	env.string("AUTH_ISSUER", &c.Auth.Issuer)
+	env.string("AUTH_AUDIENCE", &c.Auth.Audience)
+
+	env.string("DB_HOST", &c.Database.Host)
+	env.string("DB_PORT", &c.Database.Port)
+	env.string("DB_USER", &c.Database.User)
+	env.secret("DB_PASS", &c.Database.Password)
+	env.string("DB_NAME", &c.Database.Name)
+	env.bool("MIGRATE_ON_START", &c.Database.MigrateOnStart)
+
+	env.secret("BLOCKCHAIN_RPC_URL", &c.Blockchain.RPCURL)
+	env.string("BLOCKCHAIN_SIGNER", &c.Blockchain.Signer)
+	env.secret("BLOCKCHAIN_PRIVATE_KEY", &c.Blockchain.PrivateKey)
+	env.string("BLOCKCHAIN_KEYSTORE_FILE", &c.Blockchain.Keystore.File)
+	env.secret("BLOCKCHAIN_KEYSTORE_PASSPHRASE", &c.Blockchain.Keystore.Passphrase)
+	env.string("BLOCKCHAIN_KEYSTORE_PASSPHRASE_FILE", &c.Blockchain.Keystore.PassphraseFile)
+	env.secret("BLOCKCHAIN_SIGNER_URL", &c.Blockchain.RemoteSigner.URL)
+	env.string("BLOCKCHAIN_SIGNER_ADDRESS", &c.Blockchain.RemoteSigner.Address)
+	env.string("BLOCKCHAIN_CONTRACT_ADDRESS", &c.Blockchain.ContractAddress)
+	env.string("BLOCKCHAIN_NETWORK", &c.Blockchain.Network)
+	env.int64("BLOCKCHAIN_CHAIN_ID", &c.Blockchain.ChainID)
+	env.chainURLs("BLOCKCHAIN_CHAIN_RPC_URLS", &c.Blockchain.ChainRPCURLs)
+
+	env.string("PUBSUB_PROJECT", &c.PubSub.Project)
+	env.string("GOOGLE_APPLICATION_CREDENTIALS", &c.PubSub.CredentialsFile)
+
+	env.string("JUMPSELLER_BASE_URL", &c.Jumpseller.BaseURL)
+	env.string("LOGIN_JUMPSELLER_API", &c.Jumpseller.Login)
+	env.secret("TOKEN_JUMPSELLER_API", &c.Jumpseller.Token)
+	env.duration("CATALOG_SYNC_INTERVAL", &c.Jumpseller.SyncInterval)
+
+	env.bool("AUDIT_CHAIN_ANCHORING", &c.Audit.ChainAnchoring)
+	env.string("BLOB_STORAGE_PATH", &c.Storage.BlobPath)
+	env.string("SHIPPING_RATES_FILE", &c.Shipping.RatesFile)
+
+	return env.err()
+}
+
+// resolveChainID sets the chain ID of the network when it is not set
+func (c *Config) resolveChainID() {
+	if c.Blockchain.ChainID == 0 {
+		c.Blockchain.ChainID = Networks[strings.ToLower(c.Blockchain.Network)]
+	}
+}
+
+// resolveSigner sets the signer whose settings are set when it is not set (the private key by default)
+func (c *Config) resolveSigner() {
+	b := &c.Blockchain
+	switch {
+	case b.Signer != "":
+		b.Signer = strings.ToLower(b.Signer)
+	case b.Keystore.File != "":
+		b.Signer = SignerKeystore
+	case b.RemoteSigner.URL != "":
+		b.Signer = SignerRemote
+	default:
+		b.Signer = SignerKey
+	}
+}
+
+// resolveLog redacts the personal data in production when it is not set
+func (c *Config) resolveLog() {
+	c.Log.Format = strings.ToLower(c.Log.Format)
+	if c.Log.Redact == nil {
+		redact := c.Environment == "production"
+		c.Log.Redact = &redact
+	}
+}
+
+// resolveTracing accepts console for the stdout exporter, as the OpenTelemetry SDKs do
+func (c *Config) resolveTracing() {
+	c.Tracing.Exporter = strings.ToLower(c.Tracing.Exporter)
+	if c.Tracing.Exporter == "console" {
+		c.Tracing.Exporter = ExporterStdout
+	}
+}
+
+// AllowsOrigin reports whether the origin matches one of the CORS origins
+func (h HTTP) AllowsOrigin(origin string) bool {
+	for _, pattern := range h.CORSOrigins {
+		if matched, _ := path.Match(pattern, origin); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// envReader reads the variables into the config, collecting the values that cannot be parsed
+type envReader struct {
+	lookup func(string) (string, bool)
+	errors []error
+}
+
+func (e *envReader) value(name string) (string, bool) {
+	value, ok := e.lookup(name)
+	return strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+}
+
+func (e *envReader) string(name string, target *string) {
+	if value, ok := e.value(name); ok {
+		*target = value
+	}
+}
+
+func (e *envReader) secret(name string, target *Secret) {
+	if value, ok := e.value(name); ok {
+		*target = Secret(value)
+	}
+}
+
+func (e *envReader) list(name string, target *[]string) {
+	value, ok := e.value(name)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func (e *envReader) bool(name string, target *bool) {
+	if value, ok := e.value(name); ok {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Errorf("%s: %q is not true or false", name, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+func (e *envReader) optionalBool(name string, target **bool) {
+	if _, ok := e.value(name); ok {
+		var parsed bool
+		e.bool(name, &parsed)
+		*target = &parsed
+	}
+}
+
+func (e *envReader) level(name string, target *slog.Level) {
+	if value, ok := e.value(name); ok {
+		if err := target.UnmarshalText([]byte(value)); err != nil {
+			e.errors = append(e.errors, fmt.Errorf("%s: %q is not debug, info, warn or error", name, value))
+		}
+	}
+}
+
+func (e *envReader) int64(name string, target *int64) {
+	if value, ok := e.value(name); ok {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Errorf("%s: %q is not a number", name, value))
+			return
+		}
+		*target = parsed
+	}
+}
+
+// chainURLs reads a list of <chain ID>=<URL>
+func (e *envReader) chainURLs(name string, target *map[int64]Secret) {
+	var items []string
+	e.list(name, &items)
+	if items == nil {
+		return
+	}
+	urls := map[int64]Secret{}
+	for _, item := range items {
+		// the URLs are not reported, they have the API key of the node provider
+		chainID, url, found := strings.Cut(item, "=")
+		if !found {
+			e.errors = append(e.errors, fmt.Errorf("%s: an item is not <chain ID>=<URL>", name))
+			continue
+		}
+		parsed, err := strconv.ParseInt(strings.TrimSpace(chainID), 10, 64)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Errorf("%s: %q is not a chain ID", name, chainID))
+			continue
+		}
+		urls[parsed] = Secret(strings.TrimSpace(url))
+	}
+	*target = urls
+}
+
+func (e *envReader) duration(name string, target *Duration) {
+	if value, ok := e.value(name); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			e.errors = append(e.errors, fmt.Errorf("%s: %q is not a duration (e.g. 15m)", name, value))
+			return
+		}
+		*target = Duration(parsed)
+	}
+}
+
+func (e *envReader) err() error {
+	return joinErrors("invalid environment", e.errors)
+}
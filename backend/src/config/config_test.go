@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const privateKey = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+// setRequiredEnv sets the variables without a default
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv(FileEnv, "")
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "tracking")
+	t.Setenv("DB_NAME", "tracking")
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setRequiredEnv(t)
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "8080", config.HTTP.Port)
+	assert.Equal(t, int64(11155111), config.Blockchain.ChainID, "the chain ID of Sepolia")
+	assert.False(t, config.Blockchain.Enabled())
+	assert.True(t, config.Database.MigrateOnStart)
+	assert.Equal(t, "madeinportugal", config.PubSub.Project)
+}
+
+func TestLoad_Environment(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("BLOCKCHAIN_RPC_URL", "https://sepolia.infura.io/v3/key")
+	t.Setenv("BLOCKCHAIN_PRIVATE_KEY", privateKey)
+	t.Setenv("BLOCKCHAIN_CONTRACT_ADDRESS", "0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	t.Setenv("BLOCKCHAIN_NETWORK", "local")
+	t.Setenv("BLOCKCHAIN_CHAIN_ID", "31337")
+	t.Setenv("BLOCKCHAIN_CHAIN_RPC_URLS", "11155111=https://sepolia.infura.io/v3/key, 1=https://mainnet.infura.io/v3/key")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://shop.example.com, http://localhost:*")
+	t.Setenv("CATALOG_SYNC_INTERVAL", "1h")
+	t.Setenv("MIGRATE_ON_START", "false")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.True(t, config.Blockchain.Enabled())
+	assert.Equal(t, int64(31337), config.Blockchain.ChainID)
+	assert.Equal(t, map[int64]Secret{11155111: "https://sepolia.infura.io/v3/key", 1: "https://mainnet.infura.io/v3/key"}, config.Blockchain.ChainRPCURLs)
+	assert.Equal(t, []string{"https://shop.example.com", "http://localhost:*"}, config.HTTP.CORSOrigins)
+	assert.Equal(t, Duration(time.Hour), config.Jumpseller.SyncInterval)
+	assert.False(t, config.Database.MigrateOnStart)
+}
+
+func TestLoad_FileUnderTheEnvironment(t *testing.T) {
+	setRequiredEnv(t)
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{
+		"grpc": {"port": "9191"},
+		"pubsub": {"project": "from-file"},
+		"jumpseller": {"base_url": "https://store.jumpseller.com", "sync_interval": "30m"}
+	}`), 0o600))
+	t.Setenv(FileEnv, file)
+	t.Setenv("PUBSUB_PROJECT", "from-env")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "9191", config.GRPC.Port)
+	assert.Equal(t, "from-env", config.PubSub.Project, "the environment overrides the file")
+	assert.Equal(t, "https://store.jumpseller.com", config.Jumpseller.BaseURL)
+	assert.Equal(t, Duration(30*time.Minute), config.Jumpseller.SyncInterval)
+}
+
+func TestLoad_UnknownSettingInTheFile(t *testing.T) {
+	setRequiredEnv(t)
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"pubsub": {"projet": "typo"}}`), 0o600))
+	t.Setenv(FileEnv, file)
+
+	_, err := Load()
+	assert.ErrorContains(t, err, `unknown field "projet"`)
+}
+
+func TestLoad_ReportsEveryInvalidSetting(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_HOST", "")
+	t.Setenv("BLOCKCHAIN_RPC_URL", "https://sepolia.infura.io/v3/key")
+	t.Setenv("BLOCKCHAIN_CONTRACT_ADDRESS", "0x12")
+	t.Setenv("BLOCKCHAIN_CHAIN_ID", "1")
+	t.Setenv("JUMPSELLER_BASE_URL", "store.jumpseller.com")
+
+	_, err := Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DB_HOST: is required")
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_PRIVATE_KEY: is required when BLOCKCHAIN_RPC_URL is set")
+	assert.Contains(t, err.Error(), `BLOCKCHAIN_CONTRACT_ADDRESS: "0x12" is not an address`)
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_CHAIN_ID: 1 is not the chain ID of sepolia (11155111)")
+	assert.Contains(t, err.Error(), `JUMPSELLER_BASE_URL: "store.jumpseller.com" is not an http(s) URL`)
+	assert.NotContains(t, err.Error(), "key", "the secrets are not in the errors")
+}
+
+func TestLoad_Auth(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ENVIRONMENT", "production")
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "AUTH_TOKEN_SECRET: is required in production")
+
+	t.Setenv("AUTH_TOKEN_SECRET", "short")
+	_, err = Load()
+	assert.ErrorContains(t, err, "AUTH_TOKEN_SECRET: must have at least 32 bytes")
+
+	t.Setenv("AUTH_TOKEN_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("AUTH_ISSUER", "https://id.madeinportugal.store")
+	config, err := Load()
+	require.NoError(t, err)
+	assert.True(t, config.Auth.Enabled())
+	assert.Equal(t, "https://id.madeinportugal.store", config.Auth.Issuer)
+}
+
+func TestLoad_GRPCTLS(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("GRPC_TLS_CERT_FILE", "/secrets/grpc.crt")
+
+	_, err := Load()
+	assert.ErrorContains(t, err, "GRPC_TLS_KEY_FILE: is required when GRPC_TLS_CERT_FILE is set")
+
+	t.Setenv("GRPC_TLS_KEY_FILE", "/secrets/grpc.key")
+	config, err := Load()
+	require.NoError(t, err)
+	assert.True(t, config.GRPC.TLS())
+}
+
+func TestLoad_Signer(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("BLOCKCHAIN_RPC_URL", "https://sepolia.infura.io/v3/key")
+	t.Setenv("BLOCKCHAIN_KEYSTORE_FILE", "/secrets/keystore.json")
+	t.Setenv("BLOCKCHAIN_KEYSTORE_PASSPHRASE_FILE", "/secrets/passphrase")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, SignerKeystore, config.Blockchain.Signer, "the signer whose settings are set")
+
+	t.Setenv("BLOCKCHAIN_KEYSTORE_PASSPHRASE", "passphrase")
+	t.Setenv("BLOCKCHAIN_PRIVATE_KEY", privateKey)
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_KEYSTORE_PASSPHRASE: set it or BLOCKCHAIN_KEYSTORE_PASSPHRASE_FILE (one of them)")
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_PRIVATE_KEY: is set but the signer is keystore, remove it")
+
+	t.Setenv("BLOCKCHAIN_PRIVATE_KEY", "")
+	t.Setenv("BLOCKCHAIN_SIGNER", "remote")
+	t.Setenv("BLOCKCHAIN_SIGNER_URL", "signer:8550")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_SIGNER_URL: is not an http(s) URL")
+	assert.Contains(t, err.Error(), `BLOCKCHAIN_SIGNER_ADDRESS: "" is not an address`)
+
+	t.Setenv("BLOCKCHAIN_SIGNER", "hsm")
+	_, err = Load()
+	assert.ErrorContains(t, err, `BLOCKCHAIN_SIGNER: "hsm" is not key, keystore or remote`)
+}
+
+func TestLoad_LogAndTracing(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("AUTH_TOKEN_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_FORMAT", "TEXT")
+	t.Setenv("OTEL_TRACES_EXPORTER", "console")
+
+	config, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, config.Log.Level)
+	assert.Equal(t, LogText, config.Log.Format)
+	assert.True(t, config.Log.Redacts(), "the personal data is redacted in production by default")
+	assert.Equal(t, ExporterStdout, config.Tracing.Exporter)
+
+	t.Setenv("LOG_REDACT", "false")
+	config, err = Load()
+	require.NoError(t, err)
+	assert.False(t, config.Log.Redacts())
+
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("LOG_FORMAT", "xml")
+	t.Setenv("LOG_REDACT", "maybe")
+	t.Setenv("OTEL_TRACES_EXPORTER", "zipkin")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `LOG_LEVEL: "verbose" is not debug, info, warn or error`)
+	assert.Contains(t, err.Error(), `LOG_REDACT: "maybe" is not true or false`)
+
+	// the values that parse are validated with the rest of the config
+	t.Setenv("LOG_LEVEL", "")
+	t.Setenv("LOG_REDACT", "")
+	_, err = Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `LOG_FORMAT: "xml" is not json or text`)
+	assert.Contains(t, err.Error(), `OTEL_TRACES_EXPORTER: "zipkin" is not otlp, stdout or none`)
+}
+
+func TestLoad_UnparsableVariables(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("MIGRATE_ON_START", "sometimes")
+	t.Setenv("CATALOG_SYNC_INTERVAL", "15")
+	t.Setenv("BLOCKCHAIN_CHAIN_RPC_URLS", "sepolia=https://sepolia.infura.io/v3/key,https://mainnet.infura.io/v3/key")
+
+	_, err := Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `MIGRATE_ON_START: "sometimes" is not true or false`)
+	assert.Contains(t, err.Error(), `CATALOG_SYNC_INTERVAL: "15" is not a duration`)
+	assert.Contains(t, err.Error(), `BLOCKCHAIN_CHAIN_RPC_URLS: "sepolia" is not a chain ID`)
+	assert.Contains(t, err.Error(), "BLOCKCHAIN_CHAIN_RPC_URLS: an item is not <chain ID>=<URL>")
+	assert.NotContains(t, err.Error(), "key", "the URLs are not in the errors")
+}
+
+func TestHTTP_AllowsOrigin(t *testing.T) {
+	http := Default().HTTP
+
+	assert.True(t, http.AllowsOrigin("http://localhost"))
+	assert.True(t, http.AllowsOrigin("http://localhost:3000"))
+	assert.True(t, http.AllowsOrigin("https://tracking-frontend-abc.a.run.app"))
+	assert.True(t, http.AllowsOrigin("https://frontend.madeinportugal.store"))
+	assert.False(t, http.AllowsOrigin("https://evil.example.com"))
+	assert.False(t, http.AllowsOrigin("https://evil.example.com/x.run.app"))
+}
+
+func TestSecret_IsNeverPrinted(t *testing.T) {
+	config := Default()
+	config.Database.Password = "db-password"
+
+	assert.NotContains(t, fmt.Sprintf("%v", config.Database), "db-password")
+	assert.Contains(t, config.Database.DSN(), "password=db-password")
+}
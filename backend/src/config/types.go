@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Redacted replaces the secrets in the dumps of the config
+const Redacted = "[REDACTED]"
+
+// Secret is a setting that is never printed nor dumped: its value is only read with Value
+type Secret string
+
+// Value returns the secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String redacts the secret (the config can be logged or printed with %v)
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// MarshalJSON redacts the secret, it is read from the config file as a plain string
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Duration is a time.Duration written as in Go in the config file (e.g. "15m")
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("a duration must be a string (e.g. \"15m\"): %w", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
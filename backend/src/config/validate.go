@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Validate checks the whole config and reports every invalid setting, by the variable setting it
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT: must be positive")
+	check(c.PreStopDelay >= 0, "PRE_STOP_DELAY: must not be negative")
+	check(c.Log.Format == LogJSON || c.Log.Format == LogText, "LOG_FORMAT: %q is not %s or %s", c.Log.Format, LogJSON, LogText)
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterStdout, ExporterOTLP:
+	default:
+		check(false, "OTEL_TRACES_EXPORTER: %q is not %s, %s or %s", c.Tracing.Exporter, ExporterOTLP, ExporterStdout, ExporterNone)
+	}
+	check(validPort(c.HTTP.Port), "HTTP_PORT: %q is not a port", c.HTTP.Port)
+	check(validPort(c.GRPC.Port), "GRPC_PORT: %q is not a port", c.GRPC.Port)
+	check(c.HTTP.Port != c.GRPC.Port, "GRPC_PORT: %s is already the HTTP port", c.GRPC.Port)
+	if c.GRPC.TLS() {
+		check(c.GRPC.TLSCertFile != "", "GRPC_TLS_CERT_FILE: is required when GRPC_TLS_KEY_FILE is set")
+		check(c.GRPC.TLSKeyFile != "", "GRPC_TLS_KEY_FILE: is required when GRPC_TLS_CERT_FILE is set")
+	}
+	for _, origin := range c.HTTP.CORSOrigins {
+		_, err := path.Match(origin, "")
+		check(err == nil && strings.Contains(origin, "://"), "CORS_ALLOWED_ORIGINS: %q is not an origin (scheme://host[:port])", origin)
+	}
+
+	if c.Auth.Enabled() {
+		check(len(c.Auth.TokenSecret) >= MinTokenSecretLength, "AUTH_TOKEN_SECRET: must have at least %d bytes", MinTokenSecretLength)
+	}
+	check(c.Auth.Enabled() || c.Environment != "production", "AUTH_TOKEN_SECRET: is required in production")
+
+	check(c.Database.Host != "", "DB_HOST: is required")
+	check(c.Database.User != "", "DB_USER: is required")
+	check(c.Database.Name != "", "DB_NAME: is required")
+	check(validPort(c.Database.Port), "DB_PORT: %q is not a port", c.Database.Port)
+
+	if c.Blockchain.Enabled() {
+		check(validURL(c.Blockchain.RPCURL.Value(), "http", "https", "ws", "wss"), "BLOCKCHAIN_RPC_URL: is not an http(s) or ws(s) URL")
+		c.validateSigner(check)
+	}
+	if c.Blockchain.ContractAddress != "" {
+		check(common.IsHexAddress(c.Blockchain.ContractAddress), "BLOCKCHAIN_CONTRACT_ADDRESS: %q is not an address", c.Blockchain.ContractAddress)
+	}
+	if chainID, known := Networks[strings.ToLower(c.Blockchain.Network)]; c.Blockchain.Network != "" && known {
+		check(c.Blockchain.ChainID == chainID, "BLOCKCHAIN_CHAIN_ID: %d is not the chain ID of %s (%d)", c.Blockchain.ChainID, c.Blockchain.Network, chainID)
+	}
+	check(c.Blockchain.ChainID > 0, "BLOCKCHAIN_CHAIN_ID: is required for the network %q", c.Blockchain.Network)
+	for chainID, url := range c.Blockchain.ChainRPCURLs {
+		check(chainID > 0 && chainID != c.Blockchain.ChainID, "BLOCKCHAIN_CHAIN_RPC_URLS: %d is not another chain", chainID)
+		check(validURL(url.Value(), "http", "https", "ws", "wss"), "BLOCKCHAIN_CHAIN_RPC_URLS: the URL of the chain %d is not an http(s) or ws(s) URL", chainID)
+	}
+
+	check(c.PubSub.Project != "", "PUBSUB_PROJECT: is required")
+
+	if c.Jumpseller.Enabled() {
+		check(validURL(c.Jumpseller.BaseURL, "http", "https"), "JUMPSELLER_BASE_URL: %q is not an http(s) URL", c.Jumpseller.BaseURL)
+	}
+	check(c.Jumpseller.SyncInterval > 0, "CATALOG_SYNC_INTERVAL: must be positive")
+
+	check(c.Storage.BlobPath != "", "BLOB_STORAGE_PATH: is required")
+
+	return joinErrors("invalid config", errs)
+}
+
+// MinTokenSecretLength is the length of the shortest HS256 key accepted (the length of the hash)
+const MinTokenSecretLength = 32
+
+// validateSigner checks the settings of the signer of the transactions
+func (c *Config) validateSigner(check func(ok bool, format string, args ...any)) {
+	b := c.Blockchain
+	switch b.Signer {
+	case SignerKey:
+		check(b.PrivateKey != "", "BLOCKCHAIN_PRIVATE_KEY: is required when BLOCKCHAIN_RPC_URL is set")
+		if b.PrivateKey != "" {
+			check(validPrivateKey(b.PrivateKey.Value()), "BLOCKCHAIN_PRIVATE_KEY: is not a hex private key of 32 bytes")
+		}
+	case SignerKeystore:
+		check(b.Keystore.File != "", "BLOCKCHAIN_KEYSTORE_FILE: is required by the keystore signer")
+		check((b.Keystore.Passphrase != "") != (b.Keystore.PassphraseFile != ""),
+			"BLOCKCHAIN_KEYSTORE_PASSPHRASE: set it or BLOCKCHAIN_KEYSTORE_PASSPHRASE_FILE (one of them)")
+	case SignerRemote:
+		check(validURL(b.RemoteSigner.URL.Value(), "http", "https"), "BLOCKCHAIN_SIGNER_URL: is not an http(s) URL")
+		check(common.IsHexAddress(b.RemoteSigner.Address), "BLOCKCHAIN_SIGNER_ADDRESS: %q is not an address", b.RemoteSigner.Address)
+	default:
+		check(false, "BLOCKCHAIN_SIGNER: %q is not %s, %s or %s", b.Signer, SignerKey, SignerKeystore, SignerRemote)
+	}
+	if b.Signer != SignerKey && b.PrivateKey != "" {
+		check(false, "BLOCKCHAIN_PRIVATE_KEY: is set but the signer is %s, remove it", b.Signer)
+	}
+}
+
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number > 0 && number <= 65535
+}
+
+func validURL(raw string, schemes ...string) bool {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func validPrivateKey(key string) bool {
+	decoded, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+	return err == nil && len(decoded) == 32
+}
+
+// joinErrors reports the errors one per line, under a heading
+func joinErrors(heading string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s:\n%w", heading, errors.Join(errs...))
+}
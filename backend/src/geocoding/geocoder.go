@@ -0,0 +1,127 @@
+package geocoding
+
+import (
+	"app/utils"
+	"errors"
+	"fmt"
+)
+
+// Maximum distance (km) allowed between the coordinates sent by the client and the geocoded address
+const MaxAddressDistanceKm = 25.0
+
+var (
+	ErrAddressNotFound    = errors.New("address could not be geocoded")
+	ErrOutsideServiceArea = errors.New("location is outside the service area")
+	ErrAddressMismatch    = errors.New("coordinates do not match the address")
+)
+
+// Location represents a geocoded address
+type Location struct {
+	Latitude   float64
+	Longitude  float64
+	PostalCode string
+	Locality   string
+}
+
+// Geocoder converts an address into coordinates
+type Geocoder interface {
+	Geocode(address string) (*Location, error)
+}
+
+// Region is a part of the service area
+type Region string
+
+const (
+	RegionMainland Region = "MAINLAND"
+	RegionMadeira  Region = "MADEIRA"
+	RegionAzores   Region = "AZORES"
+)
+
+// point is a vertex of a boundary (latitude, longitude)
+type point struct{ lat, lon float64 }
+
+type area struct {
+	region   Region
+	boundary []point
+}
+
+// box is a boundary around islands, where everything around them is sea
+func box(minLat, maxLat, minLon, maxLon float64) []point {
+	return []point{{minLat, minLon}, {maxLat, minLon}, {maxLat, maxLon}, {minLat, maxLon}}
+}
+
+// mainland follows the border with Spain (the Minho, the land border, the Douro, the Tagus and the Guadiana) and runs
+// off the coast elsewhere: a bounding box of the mainland takes in Galicia, Extremadura and Andalusia (Huelva, Badajoz)
+var mainland = []point{
+	// the coast, from the mouth of the Guadiana to the mouth of the Minho
+	{37.17, -7.40}, {37.05, -7.40}, {36.90, -7.85}, {36.95, -8.30}, {36.90, -9.05}, {37.50, -8.95},
+	{38.00, -9.00}, {38.40, -9.35}, {38.78, -9.60}, {39.35, -9.55}, {39.60, -9.20}, {40.15, -9.00},
+	{40.65, -8.85}, {41.15, -8.80}, {41.70, -8.98}, {41.86, -8.95}, {41.865, -8.87},
+	// the Minho and the land border of the north
+	{41.95, -8.75}, {42.03, -8.65}, {42.07, -8.49}, {42.11, -8.26}, {42.154, -8.199}, {42.05, -8.10},
+	{41.85, -8.13}, {41.93, -7.90}, {41.87, -7.70}, {41.95, -7.55}, {41.87, -7.43}, {41.93, -7.20},
+	{41.99, -6.95}, {41.95, -6.60}, {41.82, -6.55}, {41.68, -6.30},
+	// the Douro and the land border of the east
+	{41.57, -6.19}, {41.49, -6.24}, {41.30, -6.45}, {41.10, -6.75}, {41.03, -6.93}, {40.80, -6.82},
+	{40.60, -6.79}, {40.40, -6.80}, {40.25, -6.93}, {40.00, -6.90}, {39.83, -6.97}, {39.66, -7.02},
+	// the Tagus, the Sever and the border of the Alentejo
+	{39.66, -7.53}, {39.40, -7.27}, {39.20, -7.05}, {39.05, -6.95}, {38.87, -7.04}, {38.62, -7.31},
+	{38.42, -7.26}, {38.20, -6.94}, {38.10, -6.93}, {38.02, -7.10}, {37.94, -7.26},
+	// the Chança and the Guadiana
+	{37.55, -7.45},
+}
+
+// The service area: mainland Portugal, Madeira and the Azores
+var serviceArea = []area{
+	{RegionMainland, mainland},
+	{RegionMadeira, box(29.90, 33.15, -17.30, -15.80)},
+	{RegionAzores, box(36.90, 39.80, -31.30, -24.90)},
+}
+
+// ServiceRegion returns the region that contains the coordinates
+func ServiceRegion(lat, lon float64) (Region, bool) {
+	for _, area := range serviceArea {
+		if contains(area.boundary, lat, lon) {
+			return area.region, true
+		}
+	}
+	return "", false
+}
+
+// contains reports whether the point is inside the boundary (ray casting)
+func contains(boundary []point, lat, lon float64) bool {
+	inside := false
+	for i, j := 0, len(boundary)-1; i < len(boundary); j, i = i, i+1 {
+		a, b := boundary[i], boundary[j]
+		if (a.lat > lat) != (b.lat > lat) && lon < (b.lon-a.lon)*(lat-a.lat)/(b.lat-a.lat)+a.lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ResolveLocation checks that the address and the coordinates agree and returns the coordinates to store.
+// Missing coordinates (0, 0) are filled in with the geocoded address. If geocoder is nil only the service area is checked.
+func ResolveLocation(geocoder Geocoder, address string, lat, lon float64) (float64, float64, error) {
+	missing := lat == 0 && lon == 0
+
+	if geocoder != nil {
+		location, err := geocoder.Geocode(address)
+		if err != nil {
+			// without coordinates there is nothing else to rely on
+			if missing || !errors.Is(err, ErrAddressNotFound) {
+				return 0, 0, err
+			}
+		} else if missing {
+			lat, lon = location.Latitude, location.Longitude
+		} else if distance := utils.DistanceKm(lat, lon, location.Latitude, location.Longitude); distance > MaxAddressDistanceKm {
+			return 0, 0, fmt.Errorf("%w: %.1f km away from %s", ErrAddressMismatch, distance, location.Locality)
+		}
+	}
+
+	if _, ok := ServiceRegion(lat, lon); !ok {
+		return 0, 0, fmt.Errorf("%w: (%f, %f)", ErrOutsideServiceArea, lat, lon)
+	}
+
+	return lat, lon, nil
+}
@@ -0,0 +1,136 @@
+package geocoding
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestGeocoder(t *testing.T) *OfflineGeocoder {
+	t.Helper()
+	geocoder, err := NewOfflineGeocoder()
+	if err != nil {
+		t.Fatalf("failed to load offline geocoder: %v", err)
+	}
+	return geocoder
+}
+
+func TestOfflineGeocoder_KnownPostalCode(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	location, err := geocoder.Geocode("Rua de Santa Catarina, 4000-442 Porto, Portugal")
+	assert.NoError(t, err)
+	assert.Equal(t, "4000-442", location.PostalCode)
+	assert.Equal(t, "Porto", location.Locality)
+	assert.InDelta(t, 41.1496, location.Latitude, 0.01)
+	assert.InDelta(t, -8.6109, location.Longitude, 0.01)
+}
+
+func TestOfflineGeocoder_NoPostalCode(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	_, err := geocoder.Geocode("Rua sem codigo postal")
+	assert.True(t, errors.Is(err, ErrAddressNotFound))
+}
+
+func TestOfflineGeocoder_UnknownPostalCode(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	_, err := geocoder.Geocode("Rua Desconhecida, 0001-000 Nenhures")
+	assert.True(t, errors.Is(err, ErrAddressNotFound))
+}
+
+func TestDefault_IsShared(t *testing.T) {
+	geocoder := Default()
+
+	assert.NotNil(t, geocoder, "the embedded dataset loads")
+	assert.Same(t, geocoder, Default(), "every entry point uses the same geocoder")
+}
+
+func TestServiceRegion(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		region   Region
+		ok       bool
+	}{
+		{"Lisboa", 38.7223, -9.1393, RegionMainland, true},
+		{"Funchal", 32.6669, -16.9241, RegionMadeira, true},
+		{"Ponta Delgada", 37.7412, -25.6756, RegionAzores, true},
+		{"Null Island", 0, 0, "", false},
+		{"Madrid", 40.4168, -3.7038, "", false},
+		{"Elvas", 38.8810, -7.1630, RegionMainland, true},
+		{"Vila Real de Santo António", 37.1946, -7.4165, RegionMainland, true},
+		{"Bragança", 41.8061, -6.7567, RegionMainland, true},
+		{"Huelva", 37.2614, -6.9447, "", false},
+		{"Badajoz", 38.8794, -6.9707, "", false},
+		{"Vigo", 42.2406, -8.7207, "", false},
+		{"Verín", 41.9410, -7.4380, "", false},
+		{"Salamanca", 40.9701, -5.6635, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, ok := ServiceRegion(tt.lat, tt.lon)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.region, region)
+		})
+	}
+}
+
+func TestServiceRegion_EveryPostalCode(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	for code, location := range geocoder.locations {
+		_, ok := ServiceRegion(location.Latitude, location.Longitude)
+		assert.True(t, ok, "%s %s is outside the service area", code, location.Locality)
+	}
+}
+
+func TestResolveLocation_FillsMissingCoordinates(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	lat, lon, err := ResolveLocation(geocoder, "Av. Arriaga, 9000-060 Funchal, Madeira", 0, 0)
+	assert.NoError(t, err)
+	assert.InDelta(t, 32.6669, lat, 0.01)
+	assert.InDelta(t, -16.9241, lon, 0.01)
+}
+
+func TestResolveLocation_MatchingCoordinates(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	lat, lon, err := ResolveLocation(geocoder, "Praça da República, 3000-343 Coimbra, Portugal", 40.2050, -8.4150)
+	assert.NoError(t, err)
+	assert.Equal(t, 40.2050, lat)
+	assert.Equal(t, -8.4150, lon)
+}
+
+func TestResolveLocation_Mismatch(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	// Porto address with Lisboa coordinates
+	_, _, err := ResolveLocation(geocoder, "Rua de Santa Catarina, 4000-442 Porto, Portugal", 38.7223, -9.1393)
+	assert.True(t, errors.Is(err, ErrAddressMismatch))
+}
+
+func TestResolveLocation_MissingCoordinatesUnknownAddress(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	_, _, err := ResolveLocation(geocoder, "Somewhere", 0, 0)
+	assert.True(t, errors.Is(err, ErrAddressNotFound))
+}
+
+func TestResolveLocation_UnknownAddressKeepsCoordinates(t *testing.T) {
+	geocoder := newTestGeocoder(t)
+
+	lat, lon, err := ResolveLocation(geocoder, "Somewhere", 41.15, -8.61)
+	assert.NoError(t, err)
+	assert.Equal(t, 41.15, lat)
+	assert.Equal(t, -8.61, lon)
+}
+
+func TestResolveLocation_NoGeocoderOutsideServiceArea(t *testing.T) {
+	_, _, err := ResolveLocation(nil, "Null Island", 0, 0)
+	assert.True(t, errors.Is(err, ErrOutsideServiceArea))
+}
@@ -0,0 +1,89 @@
+package geocoding
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+//go:embed postal_codes.csv
+var postalCodesCSV []byte
+
+// matches portuguese postal codes (e.g. 4000-442)
+var postalCodeRegex = regexp.MustCompile(`\b(\d{4})-\d{3}\b`)
+
+// OfflineGeocoder geocodes addresses using a local postal code dataset (first four digits of the postal code)
+type OfflineGeocoder struct {
+	locations map[string]Location
+}
+
+// NewOfflineGeocoder loads the embedded postal code dataset
+func NewOfflineGeocoder() (*OfflineGeocoder, error) {
+	reader := csv.NewReader(bytes.NewReader(postalCodesCSV))
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read postal code dataset: %w", err)
+	}
+
+	geocoder := &OfflineGeocoder{locations: make(map[string]Location)}
+
+	//skip the header
+	for _, record := range records[1:] {
+		lat, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid latitude for postal code %s: %w", record[0], err)
+		}
+		lon, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid longitude for postal code %s: %w", record[0], err)
+		}
+		geocoder.locations[record[0]] = Location{
+			Latitude:   lat,
+			Longitude:  lon,
+			PostalCode: record[0],
+			Locality:   record[1],
+		}
+	}
+
+	return geocoder, nil
+}
+
+// Geocode finds the postal code in the address and returns the coordinates of its area
+func (g *OfflineGeocoder) Geocode(address string) (*Location, error) {
+	match := postalCodeRegex.FindStringSubmatch(address)
+	if match == nil {
+		return nil, fmt.Errorf("%w: no postal code in %q", ErrAddressNotFound, address)
+	}
+
+	location, ok := g.locations[match[1]]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown postal code %s", ErrAddressNotFound, match[0])
+	}
+
+	location.PostalCode = match[0]
+	return &location, nil
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultGeocoder Geocoder
+)
+
+// Default returns the geocoder shared by the REST API, the gRPC service and the listeners: the offline geocoder, loaded
+// on first use. It is nil when the dataset could not be loaded, only the service area is validated then
+func Default() Geocoder {
+	defaultOnce.Do(func() {
+		geocoder, err := NewOfflineGeocoder()
+		if err != nil {
+			slog.Warn("Failed to load the geocoder, only the service area will be validated", "error", err)
+			return
+		}
+		defaultGeocoder = geocoder
+	})
+	return defaultGeocoder
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/audit"
+	"app/repository"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditHandler struct {
+	Store repository.Store
+}
+
+// GetOrderAuditEvents retrieves the audit trail of an order (changes to the order and to its products), oldest first.
+// The hash of each event is recomputed, events whose content no longer matches their hash are listed as tampered
+func (h *AuditHandler) GetOrderAuditEvents(c *gin.Context) {
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
+		return
+	}
+
+	events, err := h.Store.AuditEvents().ListByOrder(c, orderID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	tampered := []uint{}
+	for i := range events {
+		hash := audit.HashEvent(&events[i])
+		if hex.EncodeToString(hash[:]) != events[i].Hash {
+			tampered = append(tampered, events[i].Id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":   events,
+		"intact":   len(tampered) == 0,
+		"tampered": tampered,
+	})
+}
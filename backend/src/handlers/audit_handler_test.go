@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"app/audit"
+	"app/auth"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectAuditEvent expects the audit event of a change to order 1
+func expectAuditEvent(mock sqlmock.Sqlmock, table, action string) {
+	mock.ExpectQuery(`INSERT INTO "audit_events"`).
+		WithArgs(1, table, sqlmock.AnyArg(), action, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"blockchain_transaction", "id"}).AddRow(nil, 1))
+}
+
+func hashedEvent(event models.AuditEvent) models.AuditEvent {
+	hash := audit.HashEvent(&event)
+	event.Hash = hex.EncodeToString(hash[:])
+	return event
+}
+
+func TestGetOrderAuditEvents(t *testing.T) {
+	store := repository.NewMemory()
+	h := &AuditHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/audit/:order_id", h.GetOrderAuditEvents)
+
+	createdAt := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+	updated := hashedEvent(models.AuditEvent{Id: 2, Order_ID: 1, Table_Name: "orders", Record_ID: 1, Action: models.AuditUpdate,
+		Before: map[string]interface{}{"Delivery_Address": "Rua Nova"}, After: map[string]interface{}{"Delivery_Address": "Rua Velha"},
+		Actor: "courier-7", Source: "PUBSUB", Request_ID: "msg-1", Created_At: createdAt.Add(time.Minute)})
+	created := hashedEvent(models.AuditEvent{Id: 1, Order_ID: 1, Table_Name: "orders", Record_ID: 1, Action: models.AuditCreate,
+		After: map[string]interface{}{"Delivery_Address": "Rua Nova"}, Actor: "anonymous", Source: "HTTP", Request_ID: "req-1", Created_At: createdAt})
+	other := hashedEvent(models.AuditEvent{Id: 3, Order_ID: 2, Table_Name: "orders", Record_ID: 2, Action: models.AuditCreate, Created_At: createdAt})
+	for _, event := range []models.AuditEvent{updated, created, other} {
+		require.NoError(t, store.AuditEvents().Create(context.Background(), &event))
+	}
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/audit/1", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Events   []models.AuditEvent
+		Intact   bool
+		Tampered []uint
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Events, 2)
+	assert.Equal(t, uint(1), response.Events[0].Id, "oldest first")
+	assert.Equal(t, "Rua Velha", response.Events[1].After["Delivery_Address"])
+	assert.True(t, response.Intact)
+	assert.Empty(t, response.Tampered)
+}
+
+func TestGetOrderAuditEvents_Tampered(t *testing.T) {
+	store := repository.NewMemory()
+	h := &AuditHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/audit/:order_id", h.GetOrderAuditEvents)
+
+	event := hashedEvent(models.AuditEvent{Id: 3, Order_ID: 1, Table_Name: "order_products", Record_ID: 4, Action: models.AuditUpdate,
+		Before: map[string]interface{}{"Quantity": 1.0}, After: map[string]interface{}{"Quantity": 2.0},
+		Actor: "anonymous", Source: "HTTP", Request_ID: "req-2", Created_At: time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)})
+	// the quantity was changed in the database after the event was recorded
+	event.After = map[string]interface{}{"Quantity": 20.0}
+	require.NoError(t, store.AuditEvents().Create(context.Background(), &event))
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/audit/1", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Intact   bool
+		Tampered []uint
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.False(t, response.Intact)
+	assert.Equal(t, []uint{3}, response.Tampered)
+}
+
+func TestGetOrderAuditEvents_DBError(t *testing.T) {
+	store := repository.NewMemory()
+	store.Fail("AuditEvents.ListByOrder", assert.AnError)
+	h := &AuditHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/audit/:order_id", h.GetOrderAuditEvents)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/audit/1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestUpdateOrder_AuditsRequestMetadata(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.Use(audit.Middleware(audit.SourceHTTP))
+	r.POST("/order/update", h.UpdateOrder)
+
+	body, _ := json.Marshal(requestModels.UpdateOrderRequest{
+		OrderID:           1,
+		DeliveryAddress:   "Updated Address",
+		DeliveryLatitude:  41.1496,
+		DeliveryLongitude: -8.6109,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/order/update", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(audit.RequestIDHeader, "req-42")
+	req.Header.Set(audit.ActorHeader, "seller-2")
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "req-42", w.Header().Get(audit.RequestIDHeader))
+
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, audit.AnonymousActor, events[0].Actor, "the header is not verified")
+	require.NotNil(t, events[0].Claimed_Actor)
+	assert.Equal(t, "seller-2", *events[0].Claimed_Actor)
+	assert.Equal(t, "HTTP", events[0].Source)
+	assert.Equal(t, "req-42", events[0].Request_ID)
+}
+
+func TestUpdateOrder_AuditsTheAuthenticatedCaller(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.Use(audit.Middleware(audit.SourceHTTP), func(c *gin.Context) {
+		auth.SetIdentity(c, auth.Identity{Subject: "ops-1"})
+	})
+	r.POST("/order/update", h.UpdateOrder)
+
+	body, _ := json.Marshal(requestModels.UpdateOrderRequest{
+		OrderID:           1,
+		DeliveryAddress:   "Updated Address",
+		DeliveryLatitude:  41.1496,
+		DeliveryLongitude: -8.6109,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/order/update", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "ops-1", events[0].Actor)
+	assert.Nil(t, events[0].Claimed_Actor)
+}
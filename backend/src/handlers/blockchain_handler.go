@@ -1,28 +1,52 @@
 package handlers
 
 import (
+	"app/apierror"
 	"app/blockchain"
+	"app/config"
+	"app/metrics"
+	"app/models"
+	"app/repository"
 	"app/requestModels"
+	"app/telemetry"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"math/big"
 	"net/http"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 //helpers to make the tests easier
 var NewClient = blockchain.NewClient
 var DeployContract = blockchain.DeployContract
 
-type BlockchainHandler struct{}
+// BlockchainHandler connects to the node of the config on each request, the status reflects the node at the time.
+// The contracts it deploys are recorded in the registry of the store, the client of the service notarizes in the one
+// activated
+type BlockchainHandler struct {
+	Config config.Blockchain
+	Store  repository.Store
+	Client *blockchain.Client // nil when the blockchain is not configured
+}
 
 // GetBlockchainStatus returns the current blockchain connection status
 func (h *BlockchainHandler) GetBlockchainStatus(c *gin.Context) {
-	client, err := NewClient()
+	client, err := NewClient(c, h.Config)
 	if err != nil {
 		c.JSON(http.StatusOK, requestModels.BlockchainStatusResponse{
 			Connected: false,
-			Network:   "sepolia",
+			Network:   h.Config.Network,
 			Error:     err.Error(),
 		})
 		return
@@ -31,7 +55,7 @@ func (h *BlockchainHandler) GetBlockchainStatus(c *gin.Context) {
 
 	response := requestModels.BlockchainStatusResponse{
 		Connected:     true,
-		Network:       "sepolia",
+		Network:       h.Config.Network,
 		WalletAddress: client.WalletAddress.Hex(),
 	}
 
@@ -47,40 +71,185 @@ func (h *BlockchainHandler) GetBlockchainStatus(c *gin.Context) {
 		response.BlockNumber = blockNumber
 	}
 
-	// Add contract address if configured
-	if client.ContractAddress.Hex() != "0x0000000000000000000000000000000000000000" {
-		response.ContractAddress = client.ContractAddress.Hex()
+	// Add the contract the updates are notarized in (the active one of the registry)
+	contractAddress := client.ContractAddress()
+	if h.Client != nil {
+		contractAddress = h.Client.ContractAddress()
+	}
+	if contractAddress != (common.Address{}) {
+		response.ContractAddress = contractAddress.Hex()
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// DeployContract deploys a tracking contract and records it in the registry. It is activated when the body asks for
+// it, the updates are then notarized in it
 func (h *BlockchainHandler) DeployContract(c *gin.Context){
-	address, err := DeployContract()
+	var input requestModels.DeployContractRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&input); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Validation(c, err)
+		return
+	}
 
+	deployment, err := DeployContract(c, h.Config)
 	if err != nil {
-		c.JSON(
-		http.StatusInternalServerError,
-		gin.H{
-			"error": err.Error(),
-	})
+		apierror.Respond(c, http.StatusInternalServerError, fmt.Sprintf("Failed to deploy the contract: %v", err))
+		return
 	}
+	if err := h.Store.Deployments().Create(c, deployment); err != nil {
+		slog.ErrorContext(c, "Failed to record the deployment", "contract", deployment.Contract_Address, "error", err)
+		apierror.Respond(c, http.StatusInternalServerError, fmt.Sprintf("Contract deployed at %s but not recorded", deployment.Contract_Address))
+		return
+	}
+	if input.Activate {
+		if deployment, err = h.activate(c, deployment.Id); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, "Contract deployed but not activated")
+			return
+		}
+	}
+
 	c.JSON(
-		http.StatusOK,
+		http.StatusCreated,
 		gin.H{
-			"message": "Contract deployed",
-			"address": address,
+			"message":    "Contract deployed",
+			"address":    deployment.Contract_Address,
+			"deployment": deployment,
 	})
 
 }
 
-//stores the hash in the block chain and returns the transaction hash
-func StoreUpdateHash(auth *bind.TransactOpts, contract *blockchain.Blockchain, orderID uint64, hash [32]byte) (string, error) {
-    tx, err := contract.StoreUpdateHash(auth, big.NewInt(int64(orderID)), hash)
+// ListDeployments returns the registry of the contract deployments, newest first
+func (h *BlockchainHandler) ListDeployments(c *gin.Context) {
+	deployments, err := h.Store.Deployments().List(c)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to fetch the deployments")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deployments": deployments})
+}
+
+// ActivateDeployment makes a deployment the active one of its chain. The updates are notarized in it from now on
+// when it is on the chain of the service (the other instances switch to it when they start)
+func (h *BlockchainHandler) ActivateDeployment(c *gin.Context) {
+	id, ok := parseID(c, "deployment_id")
+	if !ok {
+		return
+	}
+
+	deployment, err := h.activate(c, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Respond(c, http.StatusNotFound, "Deployment not found")
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to activate the deployment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Deployment activated", "deployment": deployment})
+}
+
+// activate activates the deployment in the registry and in the client of the service when it is on its chain
+func (h *BlockchainHandler) activate(ctx context.Context, id uint) (*models.ContractDeployment, error) {
+	deployment, err := h.Store.Deployments().Activate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if h.Client != nil && h.Client.ChainID != nil && h.Client.ChainID.Int64() == deployment.Chain_ID {
+		if err := h.Client.UseDeployment(*deployment); err != nil {
+			return nil, err
+		}
+		slog.InfoContext(ctx, "Notarizing in the activated contract", "contract", deployment.Contract_Address, "deployment_id", deployment.Id)
+	}
+	return deployment, nil
+}
+
+//stores the hash in the block chain and returns the transaction hash. The transaction is sent with the context
+func StoreUpdateHash(ctx context.Context, auth *bind.TransactOpts, contract *blockchain.Blockchain, orderID uint64, hash [32]byte) (string, error) {
+    ctx, span := telemetry.Start(ctx, "chain storeUpdateHash", trace.SpanKindClient, trace.WithAttributes(telemetry.OrderID(uint(orderID))))
+    opts := *auth
+    opts.Context = ctx
+
+    start := time.Now()
+    tx, err := contract.StoreUpdateHash(&opts, big.NewInt(int64(orderID)), hash)
+    metrics.ObserveChainSubmit("update_hash", time.Since(start), err)
     if err != nil {
+        telemetry.End(span, err)
         return "", err
     }
+    span.SetAttributes(attribute.String("chain.transaction", tx.Hash().Hex()))
+    span.End()
     return tx.Hash().Hex(), nil
 }
 
 
+
+//hashes the main components of an order update (the same data is used when verifying the order)
+func HashOrderUpdate(update *models.OrderStatusHistory) [32]byte {
+	data := fmt.Sprintf("%d|%s|%s|%s",
+		update.Order_ID,
+		update.Order_Status,
+		update.Timestamp_History.Format(time.RFC3339),
+		update.Order_Location,
+	)
+	//shipment updates also commit to the shipment (order level updates keep the original format)
+	if update.Shipment_ID != nil {
+		data = fmt.Sprintf("%s|%d", data, *update.Shipment_ID)
+	}
+	//updates sent with the courier position also commit to it
+	if update.Latitude != nil && update.Longitude != nil {
+		data = fmt.Sprintf("%s|%.8f,%.8f", data, *update.Latitude, *update.Longitude)
+	}
+	//DELIVERED updates with a proof of delivery also commit to the proof bundle
+	if update.Proof_Hash != nil {
+		data = fmt.Sprintf("%s|%s", data, *update.Proof_Hash)
+	}
+	return sha256.Sum256([]byte(data))
+}
+
+//stores the hash of the update in the blockchain and keeps the transaction hash in the update.
+//Does nothing when the blockchain is not configured
+func NotarizeUpdate(ctx context.Context, client *blockchain.Client, update *models.OrderStatusHistory) error {
+	update.Contract_Deployment_ID = nil
+	if client == nil {
+		return nil
+	}
+
+	// the update is pinned to the contract it is notarized in, it is verified against it after a rotation
+	deployment := client.Deployment()
+	contract, err := blockchain.GetContractInstance(client.EthClient, deployment.Contract_Address)
+	if err != nil {
+		return err
+	}
+
+	txHash, err := StoreUpdateHash(ctx, client.Auth, contract, uint64(update.Order_ID), HashOrderUpdate(update))
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "Update notarized", "order_id", update.Order_ID, "transaction", txHash)
+
+	update.Blockchain_Transaction = txHash
+	update.Contract_Deployment_ID = deployment.Ref()
+	return nil
+}
+
+//notarizes an update once the transaction that stored it is committed (its hash depends on ids only known then) and
+//records the chain transaction apart (the updates are append only). A failure is only logged, the update is reported as not notarized by the verification
+func notarizeStoredUpdate(ctx context.Context, client *blockchain.Client, history repository.History, update *models.OrderStatusHistory) {
+	if client == nil {
+		return
+	}
+	if err := NotarizeUpdate(ctx, client, update); err != nil {
+		slog.ErrorContext(ctx, "Failed to notarize the update", "order_id", update.Order_ID, "update_id", update.Id, "error", err)
+		return
+	}
+	notarization := models.UpdateNotarization{
+		Order_Status_History_ID: update.Id,
+		Blockchain_Transaction:  update.Blockchain_Transaction,
+		Contract_Deployment_ID:  update.Contract_Deployment_ID,
+		Notarized_At:            time.Now(),
+	}
+	if err := history.RecordNotarization(ctx, &notarization); err != nil {
+		slog.ErrorContext(ctx, "Failed to record the notarization of the update", "order_id", update.Order_ID, "update_id", update.Id, "transaction", update.Blockchain_Transaction, "error", err)
+	}
+}
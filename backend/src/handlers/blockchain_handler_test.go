@@ -2,18 +2,26 @@ package handlers_test
 
 import (
 	"app/blockchain"
+	"app/config"
 	"app/handlers"
+	"app/models"
+	"app/repository"
 	"app/requestModels"
+	"context"
 	"encoding/json"
 	"errors"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetBlockchainStatus_ErrorConnecting(t *testing.T) {
@@ -23,11 +31,11 @@ func TestGetBlockchainStatus_ErrorConnecting(t *testing.T) {
 	
 
     // Override NewClient to simulate error
-    handlers.NewClient = func() (*blockchain.Client, error) {
+    handlers.NewClient = func(ctx context.Context, cfg config.Blockchain) (*blockchain.Client, error) {
         return nil, errors.New("connection failed")
     }
 
-    h := handlers.BlockchainHandler{}
+    h := handlers.BlockchainHandler{Config: config.Blockchain{Network: "sepolia"}}
     h.GetBlockchainStatus(c)
 
     assert.Equal(t, http.StatusOK, w.Code)
@@ -36,6 +44,7 @@ func TestGetBlockchainStatus_ErrorConnecting(t *testing.T) {
     err := json.Unmarshal(w.Body.Bytes(), &resp)
     assert.NoError(t, err)
     assert.False(t, resp.Connected)
+    assert.Equal(t, "sepolia", resp.Network)
     assert.Equal(t, "connection failed", resp.Error)
 }
 
@@ -44,11 +53,13 @@ func TestDeployContract_Error(t *testing.T) {
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
 
-    handlers.DeployContract = func() (common.Address, error) {
-        return common.Address{}, errors.New("deploy failed")
+    c.Request = httptest.NewRequest(http.MethodPost, "/api/v2/blockchain/deployments", nil)
+
+    handlers.DeployContract = func(ctx context.Context, cfg config.Blockchain) (*models.ContractDeployment, error) {
+        return nil, errors.New("deploy failed")
     }
 
-    h := handlers.BlockchainHandler{}
+    h := handlers.BlockchainHandler{Store: repository.NewMemory()}
     h.DeployContract(c)
 
     assert.Equal(t, http.StatusInternalServerError, w.Code)
@@ -59,15 +70,93 @@ func TestDeployContract_Success(t *testing.T) {
     gin.SetMode(gin.TestMode)
     w := httptest.NewRecorder()
     c, _ := gin.CreateTestContext(w)
+    c.Request = httptest.NewRequest(http.MethodPost, "/api/v2/blockchain/deployments", nil)
 
-    handlers.DeployContract = func() (common.Address, error) {
-        return common.HexToAddress("0x123"), nil
-    }
+    handlers.DeployContract = fakeDeployment(11155111, "0x123")
 
-    h := handlers.BlockchainHandler{}
+    store := repository.NewMemory()
+    h := handlers.BlockchainHandler{Store: store}
     h.DeployContract(c)
 
-    assert.Equal(t, http.StatusOK, w.Code)
+    assert.Equal(t, http.StatusCreated, w.Code)
     assert.Contains(t, w.Body.String(), "Contract deployed")
     assert.Contains(t, w.Body.String(), "0x0000000000000000000000000000000000000123")
+
+    // recorded in the registry, not activated
+    deployments, err := store.Deployments().List(context.Background())
+    require.NoError(t, err)
+    require.Len(t, deployments, 1)
+    assert.Equal(t, "0x0000000000000000000000000000000000000123", deployments[0].Contract_Address)
+    assert.Equal(t, int64(11155111), deployments[0].Chain_ID)
+    assert.False(t, deployments[0].Active)
+}
+
+// fakeDeployment deploys nothing, it returns a deployment of the address on the chain
+func fakeDeployment(chainID int64, address string) func(context.Context, config.Blockchain) (*models.ContractDeployment, error) {
+    return func(ctx context.Context, cfg config.Blockchain) (*models.ContractDeployment, error) {
+        tx := "0xdeploy"
+        return &models.ContractDeployment{
+            Chain_ID:           chainID,
+            Contract_Address:   common.HexToAddress(address).Hex(),
+            Deploy_Transaction: &tx,
+            ABI_Version:        blockchain.ABIVersion,
+            Deployed_At:        time.Now(),
+        }, nil
+    }
+}
+
+func TestDeployContract_ActivatesTheNewContract(t *testing.T) {
+    handlers.DeployContract = fakeDeployment(11155111, "0x456")
+    store := repository.NewMemory()
+    previous := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: common.HexToAddress("0x123").Hex(), Active: true}
+    require.NoError(t, store.Deployments().Create(context.Background(), &previous))
+    client := &blockchain.Client{ChainID: big.NewInt(11155111)}
+    require.NoError(t, client.UseDeployment(previous))
+
+    h := handlers.BlockchainHandler{Store: store, Client: client}
+    router := gin.New()
+    router.POST("/api/v2/blockchain/deployments", h.DeployContract)
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v2/blockchain/deployments", strings.NewReader(`{"activate": true}`)))
+
+    require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+    active, err := store.Deployments().Active(context.Background(), 11155111)
+    require.NoError(t, err)
+    assert.Equal(t, common.HexToAddress("0x456").Hex(), active.Contract_Address)
+    assert.Equal(t, active.Id, client.Deployment().Id, "the updates are notarized in the new contract")
+}
+
+func TestActivateDeployment(t *testing.T) {
+    store := repository.NewMemory()
+    ctx := context.Background()
+    current := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: common.HexToAddress("0x456").Hex(), Active: true}
+    previous := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: common.HexToAddress("0x123").Hex()}
+    otherChain := models.ContractDeployment{Chain_ID: 1, Contract_Address: common.HexToAddress("0x789").Hex()}
+    for _, deployment := range []*models.ContractDeployment{&current, &previous, &otherChain} {
+        require.NoError(t, store.Deployments().Create(ctx, deployment))
+    }
+    client := &blockchain.Client{ChainID: big.NewInt(11155111)}
+    require.NoError(t, client.UseDeployment(current))
+
+    h := handlers.BlockchainHandler{Store: store, Client: client}
+    router := gin.New()
+    router.POST("/deployments/:deployment_id/activate", h.ActivateDeployment)
+    activate := func(id string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/deployments/"+id+"/activate", nil))
+        return w
+    }
+
+    // rolled back to the previous contract
+    require.Equal(t, http.StatusOK, activate("2").Code)
+    assert.Equal(t, previous.Id, client.Deployment().Id)
+
+    // the active contract of another chain, the service keeps its contract
+    require.Equal(t, http.StatusOK, activate("3").Code)
+    assert.Equal(t, previous.Id, client.Deployment().Id)
+    active, err := store.Deployments().Active(ctx, 1)
+    require.NoError(t, err)
+    assert.Equal(t, otherChain.Id, active.Id)
+
+    assert.Equal(t, http.StatusNotFound, activate("9").Code)
 }
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"app/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigHandler serves the config the service was started with
+type ConfigHandler struct {
+	Config *config.Config
+}
+
+// GetConfig returns the config, the secrets (passwords, keys, tokens and the RPC URL) are redacted
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"config": h.Config})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"app/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfig_RedactsTheSecrets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Database.Password = "db-password"
+	cfg.Blockchain.RPCURL = "https://sepolia.infura.io/v3/api-key"
+	cfg.Blockchain.PrivateKey = "0xprivate"
+	cfg.Blockchain.ContractAddress = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+	h := &ConfigHandler{Config: cfg}
+	r := gin.New()
+	r.GET("/api/v2/admin/config", h.GetConfig)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/admin/config", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "db-password")
+	assert.NotContains(t, w.Body.String(), "api-key")
+	assert.NotContains(t, w.Body.String(), "0xprivate")
+
+	var body struct {
+		Config struct {
+			Blockchain map[string]any `json:"blockchain"`
+			Jumpseller map[string]any `json:"jumpseller"`
+		} `json:"config"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, config.Redacted, body.Config.Blockchain["private_key"])
+	assert.Equal(t, "0x5FbDB2315678afecb367f032d93F642f64180aa3", body.Config.Blockchain["contract_address"])
+	assert.Equal(t, "", body.Config.Jumpseller["token"], "an unset secret is not reported as set")
+	assert.Equal(t, "15m0s", body.Config.Jumpseller["sync_interval"])
+}
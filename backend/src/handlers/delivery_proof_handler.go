@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/blobstore"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Image formats accepted for signatures and photos
+var proofImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+type DeliveryProofHandler struct {
+	Store repository.Store
+	Blobs blobstore.BlobStore
+}
+
+// GetDeliveryProofs retrieves the proofs of delivery of an order
+func (h *DeliveryProofHandler) GetDeliveryProofs(c *gin.Context) {
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
+		return
+	}
+
+	proofs, err := h.Store.DeliveryProofs().ListByOrder(c, orderID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery_proofs": proofs})
+}
+
+// GetDeliveryProofMedia returns the signature or the photo of a proof of delivery
+func (h *DeliveryProofHandler) GetDeliveryProofMedia(c *gin.Context) {
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	media := c.Param("media")
+
+	proof, err := h.Store.DeliveryProofs().Get(c, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Proof of delivery not found")
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	var key string
+	switch media {
+	case "signature":
+		key = proof.Signature_Key
+	case "photo":
+		key = proof.Photo_Key
+	default:
+		apierror.Respond(c, http.StatusBadRequest, "Media must be signature or photo")
+		return
+	}
+
+	if key == "" {
+		apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("Proof of delivery has no %s", media))
+		return
+	}
+
+	data, err := h.Blobs.Get(key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("The %s of this proof of delivery is missing", media))
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	c.Data(http.StatusOK, http.DetectContentType(data), data)
+}
+
+// BuildDeliveryProof validates the proof sent with a DELIVERED update and returns it with its media (by blob key)
+func BuildDeliveryProof(orderID uint, input *requestModels.DeliveryProofRequest) (*models.DeliveryProof, map[string][]byte, error) {
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return nil, nil, fmt.Errorf("both latitude and longitude are required")
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90 || *input.Longitude < -180 || *input.Longitude > 180) {
+		return nil, nil, fmt.Errorf("invalid coordinates")
+	}
+
+	proof := &models.DeliveryProof{
+		Order_ID:       orderID,
+		Recipient_Name: strings.TrimSpace(input.RecipientName),
+		Latitude:       input.Latitude,
+		Longitude:      input.Longitude,
+	}
+	media := map[string][]byte{}
+
+	if input.Signature != "" {
+		data, key, hash, err := decodeProofImage(orderID, input.Signature)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid signature: %w", err)
+		}
+		proof.Signature_Key, proof.Signature_Hash = key, hash
+		media[key] = data
+	}
+
+	if input.Photo != "" {
+		data, key, hash, err := decodeProofImage(orderID, input.Photo)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid photo: %w", err)
+		}
+		proof.Photo_Key, proof.Photo_Hash = key, hash
+		media[key] = data
+	}
+
+	if proof.Recipient_Name == "" && len(media) == 0 && proof.Latitude == nil {
+		return nil, nil, fmt.Errorf("proof of delivery is empty")
+	}
+
+	proof.Proof_Hash = HashDeliveryProof(proof)
+	return proof, media, nil
+}
+
+// HashDeliveryProof hashes the proof bundle (the media is included through its hashes)
+func HashDeliveryProof(proof *models.DeliveryProof) string {
+	data := fmt.Sprintf("%s|%s|%s|%s|%s",
+		proof.Recipient_Name,
+		proof.Signature_Hash,
+		proof.Photo_Hash,
+		formatProofCoordinate(proof.Latitude),
+		formatProofCoordinate(proof.Longitude),
+	)
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+// checks that the media stored for the proof still matches the hashes of the bundle
+func verifyDeliveryProofMedia(blobs blobstore.BlobStore, proof *models.DeliveryProof) error {
+	for key, expected := range map[string]string{proof.Signature_Key: proof.Signature_Hash, proof.Photo_Key: proof.Photo_Hash} {
+		if key == "" {
+			continue
+		}
+		data, err := blobs.Get(key)
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(data)
+		if hex.EncodeToString(hash[:]) != expected {
+			return fmt.Errorf("media %s does not match the proof", key)
+		}
+	}
+	return nil
+}
+
+// decodes a base64 image (plain or data URL) and returns it with its blob key and hash
+func decodeProofImage(orderID uint, encoded string) ([]byte, string, string, error) {
+	if strings.HasPrefix(encoded, "data:") {
+		if i := strings.Index(encoded, ","); i >= 0 {
+			encoded = encoded[i+1:]
+		}
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("not valid base64")
+	}
+
+	extension, ok := proofImageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", "", fmt.Errorf("unsupported image format")
+	}
+
+	hash := sha256.Sum256(data)
+	hashHex := hex.EncodeToString(hash[:])
+	key := fmt.Sprintf("proofs/%d/%s%s", orderID, hashHex, extension)
+	return data, key, hashHex, nil
+}
+
+// coordinates are stored with 8 decimal places, the hash uses the same precision
+func formatProofCoordinate(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.8f", *value)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"app/blobstore"
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smallest data recognised as a PNG image
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestBuildDeliveryProof_Success(t *testing.T) {
+	lat, lon := 41.1496, -8.6109
+	proof, media, err := BuildDeliveryProof(1, &requestModels.DeliveryProofRequest{
+		RecipientName: " Maria Silva ",
+		Signature:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(testPNG),
+		Latitude:      &lat,
+		Longitude:     &lon,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Maria Silva", proof.Recipient_Name)
+	assert.Contains(t, proof.Signature_Key, "proofs/1/")
+	assert.Equal(t, testPNG, media[proof.Signature_Key])
+	assert.Equal(t, HashDeliveryProof(proof), proof.Proof_Hash)
+}
+
+func TestBuildDeliveryProof_InvalidMedia(t *testing.T) {
+	_, _, err := BuildDeliveryProof(1, &requestModels.DeliveryProofRequest{Signature: "not base64!"})
+	assert.Error(t, err)
+
+	_, _, err = BuildDeliveryProof(1, &requestModels.DeliveryProofRequest{Photo: base64.StdEncoding.EncodeToString([]byte("plain text"))})
+	assert.Error(t, err)
+}
+
+func TestBuildDeliveryProof_PartialCoordinates(t *testing.T) {
+	lat := 41.1496
+	_, _, err := BuildDeliveryProof(1, &requestModels.DeliveryProofRequest{RecipientName: "Maria", Latitude: &lat})
+	assert.Error(t, err)
+}
+
+func TestAddOrderUpdate_ProofRequiresDelivered(t *testing.T) {
+	h := &OrderStatusHistoryHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	w := postJSON(r, "/order/history/add", map[string]interface{}{
+		"order_id":          1,
+		"order_status":      "IN TRANSIT",
+		"order_location":    "Hub",
+		"proof_of_delivery": map[string]interface{}{"recipient_name": "Maria"},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAddOrderUpdate_DeliveredWithProof(t *testing.T) {
+	store := repository.NewMemory()
+	blobs := blobstore.NewFileSystemStore(t.TempDir())
+	h := &OrderStatusHistoryHandler{Store: store, Blobs: blobs}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	w := postJSON(r, "/order/history/add", requestModels.AddOrderUpdateRequest{
+		OrderStatusHistory: models.OrderStatusHistory{
+			Order_ID:       1,
+			Order_Status:   "DELIVERED",
+			Order_Location: "Customer Address",
+		},
+		ProofOfDelivery: &requestModels.DeliveryProofRequest{
+			RecipientName: "Maria Silva",
+			Signature:     base64.StdEncoding.EncodeToString(testPNG),
+		},
+	})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	updates, err := store.History().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	proofs, err := store.DeliveryProofs().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	assert.Equal(t, updates[0].Id, proofs[0].Order_Status_History_ID)
+	assert.Equal(t, "Maria Silva", proofs[0].Recipient_Name)
+	assert.Equal(t, proofs[0].Proof_Hash, *updates[0].Proof_Hash)
+
+	proof, _, _ := BuildDeliveryProof(1, &requestModels.DeliveryProofRequest{Signature: base64.StdEncoding.EncodeToString(testPNG)})
+	stored, err := blobs.Get(proof.Signature_Key)
+	assert.NoError(t, err)
+	assert.Equal(t, testPNG, stored)
+}
+
+func TestVerifyOrder_DeliveryProof(t *testing.T) {
+	ts := time.Now().UTC().Truncate(time.Second)
+	proof := &models.DeliveryProof{Id: 1, Order_ID: 1, Order_Status_History_ID: 2, Recipient_Name: "Maria Silva"}
+	proof.Proof_Hash = HashDeliveryProof(proof)
+
+	delivered := models.OrderStatusHistory{Id: 2, Order_ID: 1, Order_Status: "DELIVERED", Timestamp_History: ts, Order_Location: "Customer", Proof_Hash: &proof.Proof_Hash}
+
+	tests := []struct {
+		name          string
+		recipientName string
+		verified      bool
+	}{
+		{"matching proof", "Maria Silva", true},
+		{"tampered proof", "Someone Else", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := setupMockDB(t)
+			h := &VerificationHandler{Store: repository.NewPostgres(db), Client: &blockchain.Client{EthClient: &ethclient.Client{}}}
+			r := gin.Default()
+			r.GET("/order/verify/:order_id", h.VerifyOrder)
+
+			mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+				WithArgs(models.ReviewRejected, 1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "timestamp_history", "order_status", "order_location", "proof_hash"}).
+					AddRow(2, 1, ts, "DELIVERED", "Customer", proof.Proof_Hash))
+			mock.ExpectQuery(`SELECT \* FROM "delivery_proofs" WHERE order_id = \$1`).
+				WithArgs(1).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status_history_id", "recipient_name", "proof_hash"}).
+					AddRow(1, 1, 2, tt.recipientName, proof.Proof_Hash))
+
+			h.GetContractInstanceFunc = func(client *ethclient.Client, contractAddress string) (*blockchain.Blockchain, error) {
+				return &blockchain.Blockchain{}, nil
+			}
+			h.GetUpdateHashesFunc = func(contract *blockchain.Blockchain, orderID *big.Int) ([][32]byte, error) {
+				return [][32]byte{HashOrderUpdate(&delivered)}, nil
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/order/verify/1", nil)
+			w := performRequest(r, req)
+			assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			var resp requestModels.VerificationResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.True(t, resp.Verified)
+			assert.Len(t, resp.DeliveryProofs, 1)
+			assert.Equal(t, tt.verified, resp.DeliveryProofs[0].Verified, resp.DeliveryProofs[0].Message)
+			assert.Equal(t, tt.recipientName, resp.DeliveryProofs[0].RecipientName)
+		})
+	}
+}
+
+func TestGetDeliveryProofMedia(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.DeliveryProofs().Create(context.Background(), &models.DeliveryProof{Id: 1, Order_ID: 1, Signature_Key: "proofs/1/signature.png"}))
+	blobs := blobstore.NewFileSystemStore(t.TempDir())
+	assert.NoError(t, blobs.Put("proofs/1/signature.png", testPNG))
+	h := &DeliveryProofHandler{Store: store, Blobs: blobs}
+	r := gin.Default()
+	r.GET("/proof/:id/:media", h.GetDeliveryProofMedia)
+
+	req := httptest.NewRequest(http.MethodGet, "/proof/1/signature", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, testPNG, w.Body.Bytes())
+
+	for path, status := range map[string]int{"/proof/1/photo": http.StatusNotFound, "/proof/1/video": http.StatusBadRequest, "/proof/2/signature": http.StatusNotFound} {
+		w = performRequest(r, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, status, w.Code, path)
+	}
+}
+
+func TestGetDeliveryProofs(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.DeliveryProofs().Create(context.Background(), &models.DeliveryProof{Order_ID: 1, Order_Status_History_ID: 4, Recipient_Name: "Maria Silva"}))
+	require.NoError(t, store.DeliveryProofs().Create(context.Background(), &models.DeliveryProof{Order_ID: 2, Order_Status_History_ID: 5, Recipient_Name: "Rui Costa"}))
+	h := &DeliveryProofHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders/:order_id/proofs", h.GetDeliveryProofs)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders/1/proofs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		DeliveryProofs []models.DeliveryProof `json:"delivery_proofs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.DeliveryProofs, 1)
+	assert.Equal(t, "Maria Silva", resp.DeliveryProofs[0].Recipient_Name)
+
+	store.Fail("DeliveryProofs.ListByOrder", assert.AnError)
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/orders/1/proofs", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"app/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maximum distance (km) between the courier and the place an update refers to
+const (
+	StorageGeofenceKm  = 1.0
+	DeliveryGeofenceKm = 0.3
+)
+
+// ErrStorageNotFound is returned when an update refers to a storage that does not exist
+var ErrStorageNotFound = errors.New("storage not found")
+
+type LocationReviewHandler struct {
+	Store  repository.Store
+	Client *blockchain.Client
+}
+
+// GetLocationReviews lists the flagged updates (pending ones by default)
+func (h *LocationReviewHandler) GetLocationReviews(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewPending)
+	if !isReviewStatus(status) {
+		apierror.Respond(c, http.StatusBadRequest, fmt.Sprintf("Unknown review status: %s", status))
+		return
+	}
+
+	reviews, err := h.Store.LocationReviews().ListByStatus(c, status)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"location_reviews": reviews})
+}
+
+// GetLocationReviewsByOrderID lists the flagged updates of an order
+func (h *LocationReviewHandler) GetLocationReviewsByOrderID(c *gin.Context) {
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
+		return
+	}
+
+	reviews, err := h.Store.LocationReviews().ListByOrder(c, orderID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"location_reviews": reviews})
+}
+
+// ResolveLocationReview approves or rejects a flagged update. A rejected update no longer counts for the status of
+// the order (nor for its verification), the order status derived from the shipments is updated without it
+func (h *LocationReviewHandler) ResolveLocationReview(c *gin.Context) {
+	var input requestModels.ResolveLocationReviewRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	review, err := h.Store.LocationReviews().Get(c, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Location review not found")
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	if review.Review_Status != models.ReviewPending {
+		apierror.Respond(c, http.StatusConflict, fmt.Sprintf("Location review already %s", review.Review_Status))
+		return
+	}
+
+	now := time.Now()
+	review.Review_Status = input.Decision
+	review.Reviewer_Note = input.Note
+	review.Reviewed_At = &now
+
+	if err := h.Store.LocationReviews().Save(c, review); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to update location review")
+		return
+	}
+
+	if review.Review_Status == models.ReviewRejected {
+		if err := h.resyncOrderStatus(c, review.Order_ID); err != nil {
+			slog.ErrorContext(c, "Failed to update the order status after a rejected location", "order_id", review.Order_ID, "review_id", review.Id, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"location_review": review})
+}
+
+// derives the order status from its shipments again, from the location of its latest update
+func (h *LocationReviewHandler) resyncOrderStatus(ctx context.Context, orderID uint) error {
+	latest, err := h.Store.History().Latest(ctx, orderID)
+	if err != nil || latest == nil {
+		return err
+	}
+	return SyncOrderStatus(ctx, h.Store, h.Client, orderID, latest.Order_Location)
+}
+
+// CheckUpdateLocation compares the courier position sent with an update against the storage it refers to,
+// or the delivery address for DELIVERED updates. Returns a pending review when the update is outside the geofence
+// and nil when it is inside or there is nothing to compare with
+func CheckUpdateLocation(ctx context.Context, store repository.Store, update *models.OrderStatusHistory) (*models.LocationReview, error) {
+	if update.Latitude == nil || update.Longitude == nil {
+		return nil, nil
+	}
+
+	var referenceLatitude, referenceLongitude, tolerance float64
+	var place string
+
+	switch {
+	case update.Storage_ID != nil:
+		storage, err := store.Storages().Get(ctx, *update.Storage_ID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrStorageNotFound
+			}
+			return nil, err
+		}
+		referenceLatitude, referenceLongitude = storage.Latitude, storage.Longitude
+		tolerance = StorageGeofenceKm
+		place = fmt.Sprintf("storage %s", storage.Name)
+
+	case update.Order_Status == models.StatusDelivered:
+		order, err := store.Orders().Get(ctx, update.Order_ID)
+		if err != nil {
+			return nil, err
+		}
+		referenceLatitude, referenceLongitude = order.Delivery_Latitude, order.Delivery_Longitude
+		tolerance = DeliveryGeofenceKm
+		place = "the delivery address"
+
+	default:
+		return nil, nil
+	}
+
+	distance := utils.DistanceKm(*update.Latitude, *update.Longitude, referenceLatitude, referenceLongitude)
+	if distance <= tolerance {
+		return nil, nil
+	}
+
+	return &models.LocationReview{
+		Order_ID:      update.Order_ID,
+		Reason:        fmt.Sprintf("%s update sent %.2f km away from %s", update.Order_Status, distance, place),
+		Distance_Km:   distance,
+		Tolerance_Km:  tolerance,
+		Review_Status: models.ReviewPending,
+	}, nil
+}
+
+func isReviewStatus(status string) bool {
+	return status == models.ReviewPending || status == models.ReviewApproved || status == models.ReviewRejected
+}
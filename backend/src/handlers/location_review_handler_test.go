@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"app/models"
+	"app/repository"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func seedStorage(t *testing.T, store *repository.Memory, storageID uint, latitude, longitude float64) {
+	t.Helper()
+	require.NoError(t, store.Storages().Create(context.Background(), &models.Storage{Id: storageID, Name: "Porto Hub", Latitude: latitude, Longitude: longitude}))
+}
+
+func locationReviews(t *testing.T, store *repository.Memory, orderID uint) []models.LocationReview {
+	t.Helper()
+	reviews, err := store.LocationReviews().ListByOrder(context.Background(), orderID)
+	require.NoError(t, err)
+	return reviews
+}
+
+// --- AddOrderUpdate geofence Tests ---
+
+func TestAddOrderUpdate_InsideStorageGeofence(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	storageID := uint(3)
+	seedStorage(t, store, storageID, 41.1496, -8.6109)
+
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID:       1,
+		Order_Status:   models.StatusInTransit,
+		Order_Location: "Porto Hub",
+		Storage_ID:     &storageID,
+		Latitude:       floatPtr(41.1500),
+		Longitude:      floatPtr(-8.6110),
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, locationReviews(t, store, 1))
+}
+
+func TestAddOrderUpdate_OutsideStorageGeofence(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	storageID := uint(3)
+	seedStorage(t, store, storageID, 41.1496, -8.6109)
+
+	// Lisbon is ~270 km away from the Porto storage
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID:       1,
+		Order_Status:   models.StatusInTransit,
+		Order_Location: "Porto Hub",
+		Storage_ID:     &storageID,
+		Latitude:       floatPtr(38.7223),
+		Longitude:      floatPtr(-9.1393),
+	})
+
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "flagged for review")
+	assert.Contains(t, w.Body.String(), "storage Porto Hub")
+
+	update, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	reviews := locationReviews(t, store, 1)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, update.Id, reviews[0].Order_Status_History_ID)
+	assert.Equal(t, StorageGeofenceKm, reviews[0].Tolerance_Km)
+	assert.Equal(t, models.ReviewPending, reviews[0].Review_Status)
+}
+
+func TestAddOrderUpdate_DeliveredAwayFromAddress(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	order := models.Orders{Id: 1, Delivery_Latitude: 41.1496, Delivery_Longitude: -8.6109}
+	require.NoError(t, store.Orders().Create(context.Background(), &order))
+
+	// ~1 km away from the delivery address
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID:       1,
+		Order_Status:   models.StatusDelivered,
+		Order_Location: "Customer Address",
+		Latitude:       floatPtr(41.1586),
+		Longitude:      floatPtr(-8.6109),
+	})
+
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "the delivery address")
+	reviews := locationReviews(t, store, 1)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, DeliveryGeofenceKm, reviews[0].Tolerance_Km)
+}
+
+func TestAddOrderUpdate_GeofenceStorageNotFound(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	storageID := uint(99)
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID:       1,
+		Order_Status:   models.StatusInTransit,
+		Order_Location: "Unknown",
+		Storage_ID:     &storageID,
+		Latitude:       floatPtr(41.15),
+		Longitude:      floatPtr(-8.61),
+	})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	updates, err := store.History().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, updates)
+}
+
+func TestAddOrderUpdate_PartialCoordinates(t *testing.T) {
+	h := &OrderStatusHistoryHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID:       1,
+		Order_Status:   models.StatusInTransit,
+		Order_Location: "Hub",
+		Latitude:       floatPtr(41.15),
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHashOrderUpdate_IncludesCoordinates(t *testing.T) {
+	update := models.OrderStatusHistory{Order_ID: 1, Order_Status: models.StatusInTransit, Timestamp_History: time.Now(), Order_Location: "Hub"}
+	withoutCoordinates := HashOrderUpdate(&update)
+
+	update.Latitude, update.Longitude = floatPtr(41.15), floatPtr(-8.61)
+	assert.NotEqual(t, withoutCoordinates, HashOrderUpdate(&update))
+}
+
+// --- Location review Tests ---
+
+func TestGetLocationReviews_UnknownStatus(t *testing.T) {
+	h := &LocationReviewHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.GET("/reviews/locations", h.GetLocationReviews)
+
+	req := httptest.NewRequest(http.MethodGet, "/reviews/locations?status=MAYBE", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetLocationReviews_PendingByDefault(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.LocationReviews().Create(context.Background(), &models.LocationReview{Order_ID: 1, Order_Status_History_ID: 7, Reason: "pending one", Review_Status: models.ReviewPending}))
+	require.NoError(t, store.LocationReviews().Create(context.Background(), &models.LocationReview{Order_ID: 1, Order_Status_History_ID: 8, Reason: "approved one", Review_Status: models.ReviewApproved}))
+	h := &LocationReviewHandler{Store: store}
+	r := gin.Default()
+	r.GET("/reviews/locations", h.GetLocationReviews)
+
+	req := httptest.NewRequest(http.MethodGet, "/reviews/locations", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "pending one")
+	assert.NotContains(t, w.Body.String(), "approved one")
+
+	store.Fail("LocationReviews.ListByStatus", assert.AnError)
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/reviews/locations", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestGetLocationReviewsByOrderID(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.LocationReviews().Create(context.Background(), &models.LocationReview{Order_ID: 1, Order_Status_History_ID: 7, Reason: "first order", Review_Status: models.ReviewPending}))
+	require.NoError(t, store.LocationReviews().Create(context.Background(), &models.LocationReview{Order_ID: 2, Order_Status_History_ID: 8, Reason: "second order", Review_Status: models.ReviewPending}))
+	h := &LocationReviewHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders/:order_id/reviews", h.GetLocationReviewsByOrderID)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders/1/reviews", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "first order")
+	assert.NotContains(t, w.Body.String(), "second order")
+
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/orders/abc/reviews", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestResolveLocationReview(t *testing.T) {
+	tests := []struct {
+		name           string
+		payload        map[string]interface{}
+		currentStatus  string
+		expectedStatus int
+	}{
+		{"approve pending review", map[string]interface{}{"decision": "APPROVED", "note": "GPS drift"}, models.ReviewPending, http.StatusOK},
+		{"already resolved", map[string]interface{}{"decision": "REJECTED"}, models.ReviewApproved, http.StatusConflict},
+		{"unknown decision", map[string]interface{}{"decision": "MAYBE"}, models.ReviewPending, http.StatusBadRequest},
+		{"review not found", map[string]interface{}{"decision": "APPROVED"}, "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := repository.NewMemory()
+			h := &LocationReviewHandler{Store: store}
+			r := gin.Default()
+			r.POST("/reviews/locations/:id/resolve", h.ResolveLocationReview)
+
+			if tt.currentStatus != "" {
+				require.NoError(t, store.LocationReviews().Create(context.Background(), &models.LocationReview{Id: 1, Order_ID: 1, Order_Status_History_ID: 7, Review_Status: tt.currentStatus}))
+			}
+
+			w := postJSON(r, "/reviews/locations/1/resolve", tt.payload)
+
+			assert.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if tt.currentStatus == "" {
+				return
+			}
+			review, err := store.LocationReviews().Get(context.Background(), 1)
+			require.NoError(t, err)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.payload["decision"], review.Review_Status)
+				assert.Equal(t, tt.payload["note"], review.Reviewer_Note)
+				assert.NotNil(t, review.Reviewed_At)
+			} else {
+				assert.Equal(t, tt.currentStatus, review.Review_Status)
+			}
+		})
+	}
+}
+
+func TestResolveLocationReview_RejectedUpdateIsLeftOut(t *testing.T) {
+	store := repository.NewMemory()
+	updates := &OrderStatusHistoryHandler{Store: store}
+	reviews := &LocationReviewHandler{Store: store}
+	shipments := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", updates.AddOrderUpdate)
+	r.POST("/reviews/locations/:id/resolve", reviews.ResolveLocationReview)
+	r.GET("/order/shipments/:order_id", shipments.GetShipmentsByOrderID)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	require.NoError(t, store.Shipments().Create(context.Background(), &models.Shipment{Id: 1, Order_ID: 1, Tracking_Code: "SHIP1"}))
+	shipmentID := uint(1)
+
+	w := postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID: 1, Shipment_ID: &shipmentID, Order_Status: models.StatusOutForDelivery, Order_Location: "Porto",
+		Timestamp_History: time.Now().Add(-time.Hour),
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// delivered in Lisbon, far away from the delivery address
+	w = postJSON(r, "/order/history/add", models.OrderStatusHistory{
+		Order_ID: 1, Shipment_ID: &shipmentID, Order_Status: models.StatusDelivered, Order_Location: "Customer Address",
+		Latitude: floatPtr(38.7223), Longitude: floatPtr(-9.1393),
+	})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, latest.Order_Status, "pending updates count")
+
+	w = postJSON(r, "/reviews/locations/1/resolve", map[string]interface{}{"decision": models.ReviewRejected, "note": "not at the address"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// the order status is derived again without the rejected update
+	latest, err = store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOutForDelivery, latest.Order_Status)
+	assert.Nil(t, latest.Shipment_ID, "an order level update follows the shipments")
+
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/order/shipments/1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"order_status":"OUT FOR DELIVERY"`)
+	assert.NotContains(t, w.Body.String(), models.StatusDelivered)
+}
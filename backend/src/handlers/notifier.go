@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+)
+
+// CustomerNotification is a message for the customer of an order
+type CustomerNotification struct {
+	CustomerID uint
+	OrderID    uint
+	Title      string
+	Message    string
+}
+
+// Notifier delivers the notifications to the customers (through the notifications service in production)
+type Notifier interface {
+	Notify(ctx context.Context, notification CustomerNotification) error
+}
+
+// notifyCustomer sends the notification when a notifier is configured. Failures are only logged because the change
+// the customer is notified about is already stored
+func notifyCustomer(ctx context.Context, notifier Notifier, notification CustomerNotification) {
+	if notifier == nil {
+		return
+	}
+	if err := notifier.Notify(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "Failed to notify the customer", "customer_id", notification.CustomerID, "order_id", notification.OrderID, "error", err)
+	}
+}
@@ -1,82 +1,126 @@
 package handlers
 
 import (
+	"app/apierror"
+	"app/audit"
 	"app/blockchain"
+	"app/catalog"
+	"app/geocoding"
 	"app/models"
+	"app/repository"
 	"app/requestModels"
+	"app/shipping"
+	"app/supervisor"
 	"app/utils"
-	"crypto/sha256"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
+	"io"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type OrderHandler struct {
-	DB     *gorm.DB
-	Client *blockchain.Client
+	Store    repository.Store
+	Client   *blockchain.Client
+	Geocoder geocoding.Geocoder
+	Catalog  *catalog.Client
+	Audit    *audit.Logger
+	// Tasks tracks the chain transactions submitted after the response (nil runs them untracked)
+	Tasks *supervisor.Tasks
 }
 
+// errSaveUpdate aborts the order creation when its first status update cannot be stored
+var errSaveUpdate = errors.New("failed to save update")
+
 func GetUserIDByOrderID(db *gorm.DB, orderID uint) (uint, error) {
-	var order models.Orders
-	result := db.First(&order, orderID)
-	if result.Error != nil {
-		return 0, result.Error
+	order, err := repository.NewPostgres(db).Orders().Get(context.Background(), orderID)
+	if err != nil {
+		return 0, err
 	}
 
 	return order.Customer_ID, nil
 }
 
+//gets the most recent status update of an order (nil if the order has no updates)
+func GetLatestOrderStatus(db *gorm.DB, orderID uint) (*models.OrderStatusHistory, error) {
+	return repository.NewPostgres(db).History().Latest(context.Background(), orderID)
+}
+
+// parseID parses an id from the path, writing the error response when it is invalid
+func parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s", strings.ReplaceAll(param, "_", " ")))
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// bindOrderRequest binds the JSON body of a request about an order. On the routes of an order resource
+// (/api/v2/orders/:id/...) the order comes from the path and replaces the order_id of the body, which can then be empty.
+// Writes the error response and returns false when the request is invalid
+func bindOrderRequest(c *gin.Context, obj interface{}, orderID *uint) bool {
+	if c.Param("order_id") == "" {
+		if err := c.ShouldBindJSON(obj); err != nil {
+			apierror.Validation(c, err)
+			return false
+		}
+		return true
+	}
+
+	id, ok := parseID(c, "order_id")
+	if !ok {
+		return false
+	}
+	if err := json.NewDecoder(c.Request.Body).Decode(obj); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Validation(c, err)
+		return false
+	}
+	*orderID = id
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		apierror.Validation(c, err)
+		return false
+	}
+	return true
+}
+
 func (h *OrderHandler) GetOrderByID(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
 
-	var order models.Orders
-	result := h.DB.Preload("Products").First(&order, id)
+	order, err := h.Store.Orders().GetWithProducts(c, id)
 
 	//check if there was an error with the database request
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Order not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		}
-
-	} else {
-		fmt.Printf("Delivery: %s", order.Delivery_Estimate)
-		c.JSON(http.StatusOK, gin.H{"order": order})
+		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"order": order})
 }
 
 func (h *OrderHandler) GetAllOrders(c *gin.Context) {
 	order_by := c.Query("order_by")
 
-	var orders []models.Orders
-	var result *gorm.DB
-	if order_by == "oldest" {
-		result = h.DB.Order("created_at asc").
-			Preload("Products").
-			Preload("Updates", func(db *gorm.DB) *gorm.DB {
-				return db.Order("timestamp_history desc")
-			}).
-			Find(&orders)
-	} else {
-		result = h.DB.Order("created_at desc").
-			Preload("Products").
-			Preload("Updates", func(db *gorm.DB) *gorm.DB {
-				return db.Order("timestamp_history desc")
-			}).
-			Find(&orders)
-	}
-
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	orders, err := h.Store.Orders().List(c, order_by == "oldest")
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -88,10 +132,68 @@ func (h *OrderHandler) AddOrder(c *gin.Context) {
 	//get the order request
 	var input requestModels.AddOrderRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Input"})
+		apierror.Validation(c, err)
 		return
 	}
 
+	order, err := h.PlaceOrder(c, input)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Update stored successfully", "order": order, "tracking_code": order.Tracking_Code})
+}
+
+// CreateOrder creates an order (v2), responding with the created order and its tracking code
+func (h *OrderHandler) CreateOrder(c *gin.Context) {
+	var input requestModels.AddOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	order, err := h.PlaceOrder(c, input)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/api/v2/orders/%d", order.Id))
+	c.JSON(http.StatusCreated, gin.H{"order": order, "tracking_code": order.Tracking_Code})
+}
+
+// PlaceOrder creates the order with its products and its first update, returning it with its products
+func (h *OrderHandler) PlaceOrder(ctx context.Context, input requestModels.AddOrderRequest) (*models.Orders, error) {
+	//validate the seller and delivery locations (missing coordinates are filled in by the geocoder)
+	sellerLatitude, sellerLongitude, err := geocoding.ResolveLocation(h.Geocoder, input.SellerAddress, input.SellerLatitude, input.SellerLongitude)
+	if err != nil {
+		return nil, serviceError(http.StatusBadRequest, fmt.Sprintf("Invalid seller location: %v", err))
+	}
+	input.SellerLatitude, input.SellerLongitude = sellerLatitude, sellerLongitude
+
+	deliveryLatitude, deliveryLongitude, err := geocoding.ResolveLocation(h.Geocoder, input.DeliveryAddress, input.DeliveryLatitude, input.DeliveryLongitude)
+	if err != nil {
+		return nil, serviceError(http.StatusBadRequest, fmt.Sprintf("Invalid delivery location: %v", err))
+	}
+	input.DeliveryLatitude, input.DeliveryLongitude = deliveryLatitude, deliveryLongitude
+
+	//get the information about the products from the local catalogue (before opening the transaction)
+	productIDs := make([]uint, 0, len(input.Products))
+	for _, productRequest := range input.Products {
+		productIDs = append(productIDs, productRequest.ProductID)
+	}
+	products, err := catalog.LookupProducts(ctx, h.Store.Products(), h.Catalog, productIDs)
+	if err != nil {
+		return nil, serviceError(http.StatusBadGateway, "Error while processing the products")
+	}
+
+	//validate the variants and the stock of each line
+	orderProducts, lineErrors := BuildOrderProducts(0, input.Products, products)
+	if len(lineErrors) > 0 {
+		return nil, &ServiceError{Status: http.StatusBadRequest, Message: "Some products of the order are invalid", Lines: lineErrors}
+	}
+
 	//assign a unique tracking code
 	trackingCode := uuid.New().String()
 
@@ -101,7 +203,12 @@ func (h *OrderHandler) AddOrder(c *gin.Context) {
 	order.Customer_ID = input.CustomerId
 	order.Delivery_Address = input.DeliveryAddress
 
-	estimate_time := utils.EstimateDeliveryTime(input.SellerLatitude, input.SellerLongitude, input.DeliveryLatitude, input.DeliveryLongitude, 30)
+	//the shipping weight of the order (heavy parcels take longer to deliver)
+	parcel := OrderParcel(orderProducts, products)
+	order.Shipping_Weight = parcel.Weight
+	order.Volumetric_Weight = parcel.VolumetricWeight
+
+	estimate_time := utils.EstimateParcelDeliveryTime(input.SellerLatitude, input.SellerLongitude, input.DeliveryLatitude, input.DeliveryLongitude, 30, parcel.ChargeableWeight())
 	order.Delivery_Estimate = time.Now().Add(time.Duration(estimate_time * float64(time.Hour)))
 
 	order.Delivery_Latitude = input.DeliveryLatitude
@@ -112,212 +219,184 @@ func (h *OrderHandler) AddOrder(c *gin.Context) {
 	order.Seller_Longitude = input.SellerLongitude
 	order.Created_At = time.Now()
 
-	//the next operations are made inside a transaction to ensure atomicity
-	transaction := h.DB.Begin()
-
-	result := transaction.Create(&order)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not Created"})
-			transaction.Rollback()
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			transaction.Rollback()
-			return
+	//the next operations are made inside a transaction to ensure atomicity, the audit events are anchored and the first
+	//update is notarized once it is committed
+	audited := h.Audit.Batch()
+	var statusHistory models.OrderStatusHistory
+	err = h.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Orders().Create(ctx, &order); err != nil {
+			return err
 		}
-	}
-
-	//create the order products associated to the order
-	for _, productRequest := range input.Products {
-		var orderProduct models.OrderProduct
-		orderProduct.Order_ID = order.Id
-		orderProduct.Product_ID = productRequest.ProductID
-		orderProduct.Quantity = productRequest.Quantity
-
-		//get the information about the products from the Jumpseller API
-		var product *models.Product
-		product, err := GetProductByIDAPI(strconv.FormatUint(uint64(orderProduct.Product_ID), 10))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while processing the products"})
-			transaction.Rollback()
-			return
+		if _, err := audited.Record(tx.AuditEvents(), ctx, audit.Change{OrderID: order.Id, Table: audit.TableOrders, RecordID: order.Id, Action: models.AuditCreate, After: order}); err != nil {
+			return err
 		}
 
-		orderProduct.Product_Name_At_Purchase = product.Name
-		orderProduct.Product_Price_At_Purchase = product.Price
-
-		result := transaction.Create(&orderProduct)
-		if result.Error != nil {
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Order was created with errors in the products"})
-				transaction.Rollback()
-				return
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-				transaction.Rollback()
-				return
+		//create the order products associated to the order
+		for i := range orderProducts {
+			orderProduct := &orderProducts[i]
+			orderProduct.Order_ID = order.Id
+
+			if err := tx.OrderProducts().Create(ctx, orderProduct); err != nil {
+				return err
+			}
+			if _, err := audited.Record(tx.AuditEvents(), ctx, audit.Change{OrderID: order.Id, Table: audit.TableOrderProducts, RecordID: orderProduct.ID, Action: models.AuditCreate, After: orderProduct}); err != nil {
+				return err
 			}
 		}
 
-	}
-
-	//insert a first update (processing)
-	var statusHistory models.OrderStatusHistory
-
-	statusHistory.Note = "Processing the Order"
-	statusHistory.Order_ID = order.Id
-	statusHistory.Order_Location = order.Seller_Address
-	statusHistory.Timestamp_History = time.Now()
-	statusHistory.Order_Status = "PROCESSING"
-
-	if h.Client != nil {
-		//get an instance of the contract
-		auth := h.Client.Auth
-		ethClient := h.Client.EthClient
-		addr := os.Getenv("BLOCKCHAIN_CONTRACT_ADDRESS")
-		contract, err := blockchain.GetContractInstance(ethClient, addr)
+		//the order is read again, its price was just computed by the database from its products
+		stored, err := tx.Orders().Get(ctx, order.Id)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-			transaction.Rollback()
-			return
+			return err
+		}
+		order = *stored
+
+		//insert a first update (processing)
+		statusHistory.Note = "Processing the Order"
+		statusHistory.Order_ID = order.Id
+		statusHistory.Order_Location = order.Seller_Address
+		statusHistory.Timestamp_History = time.Now()
+		statusHistory.Order_Status = "PROCESSING"
+
+		//store the update into the database
+		if err := tx.History().Create(ctx, &statusHistory); err != nil {
+			return errSaveUpdate
 		}
+		return nil
+	})
+	if errors.Is(err, errSaveUpdate) {
+		return nil, serviceError(http.StatusInternalServerError, "Failed to save update")
+	} else if err != nil {
+		return nil, err
+	}
+	audited.Anchor(ctx, h.Store.AuditEvents())
 
-		//hash the main components of the request
-		data := fmt.Sprintf("%d|%s|%s|%s",
-			statusHistory.Order_ID,
-			statusHistory.Order_Status,
-			statusHistory.Timestamp_History.Format(time.RFC3339),
-			statusHistory.Order_Location,
-		)
+	//store the hash of the update in the blockchain
+	notarizeStoredUpdate(ctx, h.Client, h.Store.History(), &statusHistory)
 
-		hash := sha256.Sum256([]byte(data))
+	order.Products = orderProducts
+	return &order, nil
+}
 
-		//store the hash in the blockchain
-		txHash, err := StoreUpdateHash(auth, contract, uint64(statusHistory.Order_ID), hash)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save update"})
-			transaction.Rollback()
-			return
-		}
-		// Log the transaction hash for debugging
-		if txHash != "" {
-			fmt.Printf("Blockchain transaction hash: %s\n", txHash)
-		}
+func (h *OrderHandler) UpdateOrder(c *gin.Context) {
 
-		statusHistory.Blockchain_Transaction = txHash
+	//get the order update request
+	var input requestModels.UpdateOrderRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
 	}
 
-	//store the update into the database
-	if err := transaction.Create(&statusHistory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save update"})
-		transaction.Rollback()
+	if _, ok := h.updateOrder(c, input); !ok {
 		return
 	}
 
-	//commits the transaction
-	transaction.Commit()
-
-	c.JSON(http.StatusOK, gin.H{"message": "Update stored successfully"})
-
+	c.JSON(http.StatusOK, gin.H{"message": "Order Updated successfully"})
 }
 
-func (h *OrderHandler) UpdateOrder(c *gin.Context) {
-
-	//get the order update request
+// UpdateDeliveryAddress changes the delivery address of the order of the path (v2), responding with the order
+func (h *OrderHandler) UpdateDeliveryAddress(c *gin.Context) {
 	var input requestModels.UpdateOrderRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if !bindOrderRequest(c, &input, &input.OrderID) {
+		return
+	}
 
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Input"})
+	order, ok := h.updateOrder(c, input)
+	if !ok {
 		return
 	}
 
-	var order *models.Orders
-	result := h.DB.First(&order, input.OrderID)
+	c.JSON(http.StatusOK, gin.H{"order": order})
+}
+
+// updateOrder changes the delivery address of an order that is still being processed.
+// Writes the error response and returns false if the order could not be changed
+func (h *OrderHandler) updateOrder(c *gin.Context, input requestModels.UpdateOrderRequest) (*models.Orders, bool) {
+	order, err := h.Store.Orders().Get(c, input.OrderID)
 	//check if there was an error with the database request
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			message := fmt.Sprintf("Order with id %d not found", input.OrderID)
-			c.JSON(http.StatusNotFound, gin.H{"error": message})
-			return
+			apierror.Respond(c, http.StatusNotFound, message)
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		}
+		return nil, false
 	}
 
-	var order_update *models.OrderStatusHistory
-	result = h.DB.Where("order_id = ?", order.Id).Order("timestamp_history desc").First(&order_update)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			//no need to through an error
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
-		}
+	order_update, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return nil, false
 	}
 
 	if (order_update != nil) && (order_update.Order_Status != "PROCESSING") {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot change an order that is already shipped"})
-		return
+		apierror.Respond(c, http.StatusForbidden, "Cannot change an order that is already shipped")
+		return nil, false
 	}
 
+	//validate the new delivery location (missing coordinates are filled in by the geocoder)
+	deliveryLatitude, deliveryLongitude, err := geocoding.ResolveLocation(h.Geocoder, input.DeliveryAddress, input.DeliveryLatitude, input.DeliveryLongitude)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, fmt.Sprintf("Invalid delivery location: %v", err))
+		return nil, false
+	}
+
+	before := *order
 	order.Delivery_Address = input.DeliveryAddress
-	order.Delivery_Latitude = input.DeliveryLatitude
-	order.Delivery_Longitude = input.DeliveryLongitude
-	estimate_time := utils.EstimateDeliveryTime(order.Seller_Latitude, order.Seller_Longitude, order.Delivery_Latitude, order.Delivery_Longitude, 30)
+	order.Delivery_Latitude = deliveryLatitude
+	order.Delivery_Longitude = deliveryLongitude
+	parcel := shipping.Parcel{Weight: order.Shipping_Weight, VolumetricWeight: order.Volumetric_Weight}
+	estimate_time := utils.EstimateParcelDeliveryTime(order.Seller_Latitude, order.Seller_Longitude, order.Delivery_Latitude, order.Delivery_Longitude, 30, parcel.ChargeableWeight())
 	order.Delivery_Estimate = time.Now().Add(time.Duration(estimate_time * float64(time.Hour)))
 
-	result = h.DB.Save(&order)
-
-	//check if there was an error with the database request
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			message := fmt.Sprintf("Order with id %d could not be updated", input.OrderID)
-			c.JSON(http.StatusNotFound, gin.H{"error": message})
-			return
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
+	//the update and its audit event are stored in the same transaction
+	audited := h.Audit.Batch()
+	err = h.Store.Transaction(c, func(tx repository.Store) error {
+		if err := tx.Orders().Update(c, order, "delivery_address", "delivery_latitude", "delivery_longitude", "delivery_estimate"); err != nil {
+			return err
 		}
+		_, err := audited.Record(tx.AuditEvents(), c, audit.Change{OrderID: order.Id, Table: audit.TableOrders, RecordID: order.Id, Action: models.AuditUpdate, Before: before, After: order})
+		return err
+	})
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return nil, false
 	}
+	audited.Anchor(c, h.Store.AuditEvents())
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order Updated successfully"})
+	return order, true
 }
 
 func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	// Get the cancel order request
 	var input requestModels.CancelOrderRequest
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Input"})
+	if !bindOrderRequest(c, &input, &input.OrderID) {
 		return
 	}
 
 	// Fetch the order
-	var order *models.Orders
-	result := h.DB.First(&order, input.OrderID)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+	order, err := h.Store.Orders().Get(c, input.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
 			message := fmt.Sprintf("Order with id %d not found", input.OrderID)
-			c.JSON(http.StatusNotFound, gin.H{"error": message})
-			return
+			apierror.Respond(c, http.StatusNotFound, message)
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			return
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		}
+		return
 	}
 
 	// Get the latest order status
-	var latestStatus *models.OrderStatusHistory
-	result = h.DB.Where("order_id = ?", order.Id).Order("timestamp_history desc").First(&latestStatus)
-	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
 	// Check if order can be cancelled (only PROCESSING status allows cancellation)
 	if latestStatus != nil && latestStatus.Order_Status != "PROCESSING" {
-		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Cannot cancel order with status: %s", latestStatus.Order_Status)})
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot cancel order with status: %s", latestStatus.Order_Status))
 		return
 	}
 
@@ -329,11 +408,16 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		Order_Location:    "SYSTEM",
 		Note:              input.Reason,
 	}
+	// the hash is stored after the update is saved, the update is pinned to the contract beforehand
+	var deployment models.ContractDeployment
+	if h.Client != nil {
+		deployment = h.Client.Deployment()
+		cancelledStatus.Contract_Deployment_ID = deployment.Ref()
+	}
 
 	// Save the new status to the database
-	result = h.DB.Create(&cancelledStatus)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
+	if err := h.Store.History().Create(c, &cancelledStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to cancel order")
 		return
 	}
 
@@ -341,28 +425,23 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	if h.Client != nil {
 		auth := h.Client.Auth
 		ethClient := h.Client.EthClient
-		addr := os.Getenv("BLOCKCHAIN_CONTRACT_ADDRESS")
-		contract, err := blockchain.GetContractInstance(ethClient, addr)
+		contract, err := blockchain.GetContractInstance(ethClient, deployment.Contract_Address)
 		if err != nil {
 			// Log but don't fail - blockchain is optional
-			fmt.Printf("Warning: Failed to get blockchain contract: %v\n", err)
+			slog.WarnContext(c, "Failed to get the blockchain contract", "order_id", order.Id, "error", err)
 		} else {
 			// Hash the cancellation data
-			data := fmt.Sprintf("%d|%s|%s|%s",
-				cancelledStatus.Order_ID,
-				cancelledStatus.Order_Status,
-				cancelledStatus.Timestamp_History.Format(time.RFC3339),
-				cancelledStatus.Order_Location,
-			)
-			hash := sha256.Sum256([]byte(data))
-
-			// Store in blockchain (async to not block response)
-			go func() {
-				_, err := StoreUpdateHash(auth, contract, uint64(order.Id), hash)
-				if err != nil {
-					fmt.Printf("Warning: Failed to store cancellation in blockchain: %v\n", err)
+			hash := HashOrderUpdate(&cancelledStatus)
+
+			// Store in blockchain (async to not block response), in the trace of the request but not cancelled with it.
+			// The task is drained before the process exits
+			orderID := order.Id
+			h.Tasks.Go(c.Request.Context(), "store cancellation", func(ctx context.Context) error {
+				if _, err := StoreUpdateHash(ctx, auth, contract, uint64(orderID), hash); err != nil {
+					return fmt.Errorf("failed to store the cancellation of order %d in the blockchain: %w", orderID, err)
 				}
-			}()
+				return nil
+			})
 		}
 	}
 
@@ -3,10 +3,17 @@ package handlers
 
 import (
 
+	"app/apierror"
+	"app/catalog"
+	"app/geocoding"
+	"app/models"
+	"app/repository"
 	"app/requestModels"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -15,6 +22,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -42,6 +50,30 @@ func performRequest(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder
 	return w
 }
 
+// seedOrder stores an order with the given latest status (no updates when status is empty)
+func seedOrder(t *testing.T, store *repository.Memory, id uint, status string) models.Orders {
+	t.Helper()
+	order := models.Orders{
+		Id:                 id,
+		Customer_ID:        42,
+		Seller_Address:     "Seller",
+		Seller_Latitude:    41.2,
+		Seller_Longitude:   -8.5,
+		Created_At:         time.Date(2025, 5, 1, 10, 0, 0, int(id), time.UTC),
+		Tracking_Code:      fmt.Sprintf("TRACK%d", id),
+		Delivery_Address:   "Rua Nova",
+		Delivery_Latitude:  41.1,
+		Delivery_Longitude: -8.6,
+	}
+	require.NoError(t, store.Orders().Create(context.Background(), &order))
+	if status != "" {
+		require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{
+			Order_ID: id, Order_Status: status, Timestamp_History: time.Now(), Order_Location: "Porto",
+		}))
+	}
+	return order
+}
+
 // --- Tests ---
 
 func TestGetUserIDByOrderID_Success(t *testing.T) {
@@ -66,169 +98,157 @@ func TestGetUserIDByOrderID_NotFound(t *testing.T) {
     assert.Equal(t, uint(0), id)
 }
 func TestGetOrderByID_NotFound(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.GET("/order/:id", h.GetOrderByID)
 
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs("999", 1). // two args: id and limit
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/999", nil))
 
-	// Expect preload query on products (empty result)
-	mock.ExpectQuery(`SELECT \* FROM "products" WHERE "products"."order_id" IN \(\$1\)`).
-		WithArgs("999").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}))
+	assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+}
 
-	req := httptest.NewRequest(http.MethodGet, "/order/999", nil)
-	w := performRequest(r, req)
+func TestGetOrderByID_InvalidID(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.GET("/order/:id", h.GetOrderByID)
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d: %s", w.Code, w.Body.String())
-	}
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/abc", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestGetOrderByID_Success(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 2}))
+	h := &OrderHandler{Store: store}
 	r := gin.Default()
 	r.GET("/order/:id", h.GetOrderByID)
 
-	// Mock row
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs("1", 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "delivery_address"}).
-			AddRow(1, "Rua Nova"))
-
-		// Expect preload query on products (empty result)
-	mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."order_id" = \$1`).
-		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}))
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/1", nil))
 
-	req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
-	w := performRequest(r, req)
-
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
-	}
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct{ Order models.Orders }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Rua Nova", response.Order.Delivery_Address)
+	require.Len(t, response.Order.Products, 1)
+	assert.Equal(t, uint(10), response.Order.Products[0].Product_ID)
 }
 
-func TestAddOrder_DBCreateError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order/add", h.AddOrder)
-
-    // Expect transaction begin
-    mock.ExpectBegin()
-    // Fail on order insert
-    mock.ExpectExec(`INSERT INTO "orders"`).WillReturnError(errors.New("db error"))
-    mock.ExpectRollback()
-
-    payload := requestModels.AddOrderRequest{
-        CustomerId: 1,
-        DeliveryAddress: "Addr",
-        SellerId: 2,
-        SellerAddress: "Seller",
-        SellerLatitude: 41.1,
-        SellerLongitude: -8.6,
-        DeliveryLatitude: 41.15,
-        DeliveryLongitude: -8.61,
-        Products: []requestModels.OrderProductRequest{},
-    }
-    body, _ := json.Marshal(payload)
+func TestGetOrderByID_InternalError(t *testing.T) {
+	store := repository.NewMemory()
+	store.Fail("Orders.GetWithProducts", errors.New("db failure"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/:id", h.GetOrderByID)
 
-    req := httptest.NewRequest(http.MethodPost, "/order/add", bytes.NewBuffer(body))
-    req.Header.Set("Content-Type", "application/json")
-    w := performRequest(r, req)
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/1", nil))
 
-    assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestAddOrder_DBCreateError(t *testing.T) {
+	store := repository.NewMemory()
+	addCatalogProduct(t, store, 5)
+	store.Fail("Orders.Create", errors.New("db error"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
 
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 1}},
+	})
 
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
 
-func TestGetOrderByID_InternalError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.GET("/order/:id", h.GetOrderByID)
-
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs("1", 1).
-        WillReturnError(errors.New("db failure"))
-
-    req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
-    w := performRequest(r, req)
+func TestAddOrder_FailedUpdateRollsBack(t *testing.T) {
+	store := repository.NewMemory()
+	addCatalogProduct(t, store, 5)
+	store.Fail("History.Create", errors.New("db error"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
 
-    assert.Equal(t, http.StatusInternalServerError, w.Code)
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 1}},
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to save update")
+	orders, err := store.Orders().List(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, orders, "the order is not kept without its first update")
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
 }
 
 func TestGetAllOrders_Oldest(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.GET("/orders", h.GetAllOrders)
-
-    mock.ExpectQuery(`SELECT \* FROM "orders" ORDER BY created_at asc`).
-    WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(1))
-
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."order_id" = \$1`).
-    WithArgs(1).
-    WillReturnRows(sqlmock.NewRows([]string{"id","order_id"}).AddRow(1,1))
-
-
-    mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE "order_status_history"."order_id" = \$1 ORDER BY timestamp_history desc`).
-    WithArgs(1).
-    WillReturnRows(sqlmock.NewRows([]string{"id","order_id","order_status","timestamp_history"}).
-        AddRow(1,1,"PROCESSING",time.Now()))
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	seedOrder(t, store, 2, "")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders", h.GetAllOrders)
 
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders?order_by=oldest", nil))
 
-    req := httptest.NewRequest(http.MethodGet, "/orders?order_by=oldest", nil)
-    w := performRequest(r, req)
-    assert.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct{ Orders []models.Orders }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Orders, 2)
+	assert.Equal(t, uint(1), response.Orders[0].Id)
+	assert.Len(t, response.Orders[0].Updates, 1)
 }
 
 func TestGetAllOrders_Newest(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.GET("/orders", h.GetAllOrders)
-
-    mock.ExpectQuery(`SELECT \* FROM "orders" ORDER BY created_at desc`).
-    WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."order_id" = \$1`).
-    WithArgs(1).
-    WillReturnRows(sqlmock.NewRows([]string{"id","order_id"}).AddRow(1,1))
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	seedOrder(t, store, 2, "")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders", h.GetAllOrders)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE "order_status_history"."order_id" = \$1 ORDER BY timestamp_history desc`).
-    WithArgs(1).
-    WillReturnRows(sqlmock.NewRows([]string{"id","order_id","order_status","timestamp_history"}).
-        AddRow(1,1,"PROCESSING",time.Now()))
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders?order_by=newest", nil))
 
-    req := httptest.NewRequest(http.MethodGet, "/orders?order_by=newest", nil)
-    w := performRequest(r, req)
-    assert.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct{ Orders []models.Orders }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Orders, 2)
+	assert.Equal(t, uint(2), response.Orders[0].Id)
 }
 
 func TestGetAllOrders_Error(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.GET("/orders", h.GetAllOrders)
+	store := repository.NewMemory()
+	store.Fail("Orders.List", errors.New("db fail"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders", h.GetAllOrders)
 
-    mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnError(errors.New("db fail"))
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders", nil))
 
-    req := httptest.NewRequest(http.MethodGet, "/orders", nil)
-    w := performRequest(r, req)
-    assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
-
 func TestAddOrder_BadInput(t *testing.T) {
-	db, _ := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/add", h.AddOrder)
 
@@ -237,14 +257,35 @@ func TestAddOrder_BadInput(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := performRequest(r, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected 400, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAddOrder_ValidationDetails(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
+
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:       1,
+		SellerId:         2,
+		SellerAddress:    "Seller",
+		DeliveryAddress:  "Addr",
+		DeliveryLatitude: 123,
+		Products:         []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 0}},
+	})
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, apierror.CodeValidation, response.Code)
+	assert.ElementsMatch(t, []apierror.FieldError{
+		{Field: "delivery_latitude", Message: "must be a latitude between -90 and 90"},
+		{Field: "products[0].quantity", Message: "must be greater than 0"},
+	}, response.Details)
 }
 
 func TestUpdateOrder_BadInput(t *testing.T) {
-	db, _ := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/update", h.UpdateOrder)
 
@@ -252,87 +293,72 @@ func TestUpdateOrder_BadInput(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := performRequest(r, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected 400, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-func TestUpdateOrder_NotFound(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+func TestUpdateOrder_ValidationDetails(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/update", h.UpdateOrder)
 
-	// Expect SELECT returning no rows
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs(999, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryLatitude: 123})
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, apierror.CodeValidation, response.Code)
+	assert.ElementsMatch(t, []apierror.FieldError{
+		{Field: "delivery_address", Message: "is required"},
+		{Field: "delivery_latitude", Message: "must be a latitude between -90 and 90"},
+	}, response.Details)
+}
+
+func TestUpdateOrder_NotFound(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-	payload := requestModels.UpdateOrderRequest{
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{
 		OrderID:           999,
 		DeliveryAddress:   "New Address",
 		DeliveryLatitude:  41.15,
 		DeliveryLongitude: -8.61,
-	}
-	body, _ := json.Marshal(payload)
-
-	req := httptest.NewRequest(http.MethodPost, "/order/update", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := performRequest(r, req)
+	})
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestUpdateOrder_Success(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	h := &OrderHandler{Store: store}
 	r := gin.Default()
 	r.POST("/order/update", h.UpdateOrder)
 
-	// Expect SELECT for order
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "delivery_address", "delivery_latitude", "delivery_longitude", "seller_latitude", "seller_longitude"}).
-			AddRow(1, "Old Address", 41.1, -8.6, 41.2, -8.5))
-
-	// Expect SELECT for order_status_history
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status", "timestamp_history"}).
-			AddRow(1, 1, "PROCESSING", time.Now()))
-
-		// Expect transaction begin
-	mock.ExpectBegin()
-
-	// Expect UPDATE statement
-	mock.ExpectExec(`UPDATE "orders"`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// Expect commit
-	mock.ExpectCommit()
-
-	payload := requestModels.UpdateOrderRequest{
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{
 		OrderID:           1,
 		DeliveryAddress:   "Updated Address",
 		DeliveryLatitude:  41.1496,
 		DeliveryLongitude: -8.6109,
-	}
-	body, _ := json.Marshal(payload)
-
-	req := httptest.NewRequest(http.MethodPost, "/order/update", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := performRequest(r, req)
-
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
-	}
+	})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Updated Address", order.Delivery_Address)
+	assert.Equal(t, 41.1496, order.Delivery_Latitude)
+	assert.False(t, order.Delivery_Estimate.IsZero())
+
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditUpdate, events[0].Action)
+	assert.Equal(t, "Rua Nova", events[0].Before["Delivery_Address"])
+	assert.Equal(t, "Updated Address", events[0].After["Delivery_Address"])
 }
 
 func TestCancelOrder_BadInput(t *testing.T) {
-	db, _ := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/cancel", h.CancelOrder)
 
@@ -340,242 +366,409 @@ func TestCancelOrder_BadInput(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 	w := performRequest(r, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected 400, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
 func TestCancelOrder_NotFound(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	h := &OrderHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/cancel", h.CancelOrder)
 
-	// Expect SELECT returning no rows
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs(999, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	payload := requestModels.CancelOrderRequest{
-		OrderID: 999,
-		Reason:  "Customer request",
-	}
-	body, _ := json.Marshal(payload)
-
-	req := httptest.NewRequest(http.MethodPost, "/order/cancel", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := performRequest(r, req)
+	w := postJSON(r, "/order/cancel", requestModels.CancelOrderRequest{OrderID: 999, Reason: "Customer request"})
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected 404, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestCancelOrder_NotProcessing(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "SHIPPED")
+	h := &OrderHandler{Store: store}
 	r := gin.Default()
 	r.POST("/order/cancel", h.CancelOrder)
 
-	// Expect SELECT for order
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "tracking_code", "delivery_address", "delivery_latitude", "delivery_longitude", "seller_latitude", "seller_longitude", "price"}).
-			AddRow(1, "TRACK123", "Rua Nova", 41.1, -8.6, 41.2, -8.5, 100.00))
-
-	// Expect SELECT for order_status_history (SHIPPED status)
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status", "timestamp_history"}).
-			AddRow(1, 1, "SHIPPED", time.Now()))
-
-	payload := requestModels.CancelOrderRequest{
-		OrderID: 1,
-		Reason:  "Customer request",
-	}
-	body, _ := json.Marshal(payload)
-
-	req := httptest.NewRequest(http.MethodPost, "/order/cancel", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := performRequest(r, req)
-
-	if w.Code != http.StatusForbidden {
-		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
-	}
+	w := postJSON(r, "/order/cancel", requestModels.CancelOrderRequest{OrderID: 1, Reason: "Customer request"})
 
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
 	var response map[string]string
-	json.Unmarshal(w.Body.Bytes(), &response)
-	if response["error"] != "Cannot cancel order with status: SHIPPED" {
-		t.Fatalf("expected error message about SHIPPED status, got: %s", response["error"])
-	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Cannot cancel order with status: SHIPPED", response["error"])
 }
 
 func TestUpdateOrder_DBErrorOnLookup(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order/update", h.UpdateOrder)
-
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnError(errors.New("db fail"))
-
-    payload := requestModels.UpdateOrderRequest{OrderID: 1}
-    body,_ := json.Marshal(payload)
+	store := repository.NewMemory()
+	store.Fail("Orders.Get", errors.New("db fail"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-    req := httptest.NewRequest(http.MethodPost,"/order/update",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New", DeliveryLatitude: 41.15, DeliveryLongitude: -8.61})
 
-    assert.Equal(t,http.StatusInternalServerError,w.Code)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
 func TestUpdateOrder_StatusHistoryError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order/update", h.UpdateOrder)
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	store.Fail("History.Latest", errors.New("db fail"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-    // Order exists
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","delivery_address","delivery_latitude","delivery_longitude","seller_latitude","seller_longitude"}).
-            AddRow(1,"Addr",41.1,-8.6,41.2,-8.5))
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New", DeliveryLatitude: 41.15, DeliveryLongitude: -8.61})
 
-    // Status history query fails
-    mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnError(errors.New("db fail"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
 
-    payload := requestModels.UpdateOrderRequest{OrderID: 1}
-    body,_ := json.Marshal(payload)
+func TestUpdateOrder_AlreadyShipped(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "SHIPPED")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-    req := httptest.NewRequest(http.MethodPost,"/order/update",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New", DeliveryLatitude: 41.15, DeliveryLongitude: -8.61})
 
-    assert.Equal(t,http.StatusInternalServerError,w.Code)
+	assert.Equal(t, http.StatusForbidden, w.Code)
 }
 
-func TestUpdateOrder_AlreadyShipped(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order/update", h.UpdateOrder)
+func TestUpdateOrder_SaveError(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	store.Fail("Orders.Update", errors.New("update fail"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","delivery_address","delivery_latitude","delivery_longitude","seller_latitude","seller_longitude"}).
-            AddRow(1,"Addr",41.1,-8.6,41.2,-8.5))
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New", DeliveryLatitude: 41.15, DeliveryLongitude: -8.61})
 
-    mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","order_status","timestamp_history"}).
-            AddRow(1,1,"SHIPPED",time.Now()))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Rua Nova", order.Delivery_Address)
+}
 
-    payload := requestModels.UpdateOrderRequest{OrderID: 1}
-    body,_ := json.Marshal(payload)
+func TestUpdateOrder_AuditFailureRollsBack(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	store.Fail("AuditEvents.Create", errors.New("insert fail"))
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-    req := httptest.NewRequest(http.MethodPost,"/order/update",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New", DeliveryLatitude: 41.15, DeliveryLongitude: -8.61})
 
-    assert.Equal(t,http.StatusForbidden,w.Code)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Rua Nova", order.Delivery_Address, "the update is not kept without its audit event")
 }
 
-func TestUpdateOrder_SaveError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order/update", h.UpdateOrder)
+func TestCancelOrder_Success(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/cancel", h.CancelOrder)
 
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","delivery_address","delivery_latitude","delivery_longitude","seller_latitude","seller_longitude"}).
-            AddRow(1,"Addr",41.1,-8.6,41.2,-8.5))
+	w := postJSON(r, "/order/cancel", requestModels.CancelOrderRequest{OrderID: 1, Reason: "Customer requested cancellation"})
 
-    mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","order_status","timestamp_history"}).
-            AddRow(1,1,"PROCESSING",time.Now()))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Order cancelled successfully", response["message"])
+	assert.Equal(t, float64(1), response["order_id"])
+	assert.Equal(t, "CANCELLED", response["status"])
+
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "CANCELLED", latest.Order_Status)
+	assert.Equal(t, "Customer requested cancellation", latest.Note)
+	assert.Equal(t, "SYSTEM", latest.Order_Location)
+}
 
-    mock.ExpectExec(`UPDATE "orders"`).WillReturnError(errors.New("update fail"))
+func TestAddOrder_OutsideServiceArea(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
 
-    payload := requestModels.UpdateOrderRequest{OrderID: 1, DeliveryAddress: "New"}
-    body,_ := json.Marshal(payload)
+	// Zero delivery coordinates and no geocoder to fill them in
+	payload := requestModels.AddOrderRequest{
+		CustomerId:      1,
+		SellerId:        2,
+		SellerAddress:   "Seller",
+		SellerLatitude:  41.1,
+		SellerLongitude: -8.6,
+		DeliveryAddress: "Addr",
+	}
+	body, _ := json.Marshal(payload)
 
-    req := httptest.NewRequest(http.MethodPost,"/order/update",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	req := httptest.NewRequest(http.MethodPost, "/order/add", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    assert.Equal(t,http.StatusInternalServerError,w.Code)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
-
-
-func TestCancelOrder_Success(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderHandler{DB: db}
+func TestAddOrder_AddressMismatch(t *testing.T) {
+	geocoder, err := geocoding.NewOfflineGeocoder()
+	assert.NoError(t, err)
+	h := &OrderHandler{Store: repository.NewMemory(), Geocoder: geocoder}
 	r := gin.Default()
-	r.POST("/order/cancel", h.CancelOrder)
+	r.POST("/order/add", h.AddOrder)
 
-	// Expect SELECT for order
-	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "tracking_code", "delivery_address", "delivery_latitude", "delivery_longitude", "seller_latitude", "seller_longitude", "price"}).
-			AddRow(1, "TRACK123", "Rua Nova", 41.1, -8.6, 41.2, -8.5, 100.00))
-
-	// Expect SELECT for order_status_history (PROCESSING status)
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE order_id = \$1 ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$2`).
-		WithArgs(1, 1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status", "timestamp_history"}).
-			AddRow(1, 1, "PROCESSING", time.Now()))
-
-	// Expect transaction begin
-	mock.ExpectBegin()
-
-	// Expect INSERT for order_status_history with RETURNING clause
-	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-		WithArgs(
-			sqlmock.AnyArg(),                  // order_id
-			sqlmock.AnyArg(),                  // timestamp_history
-			"CANCELLED",                       // order_status
-			"Customer requested cancellation", // note
-			"",                                // blockchain_transaction
-			"SYSTEM",                          // order_location
-		).
-		WillReturnRows(sqlmock.NewRows([]string{"storage_id", "id"}).
-			AddRow(nil, 2))
-
-	// Expect commit
-	mock.ExpectCommit()
-
-	payload := requestModels.CancelOrderRequest{
-		OrderID: 1,
-		Reason:  "Customer requested cancellation",
+	// Porto address with Faro coordinates
+	payload := requestModels.AddOrderRequest{
+		CustomerId:        1,
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryAddress:   "Rua de Santa Catarina, 4000-442 Porto, Portugal",
+		DeliveryLatitude:  37.0194,
+		DeliveryLongitude: -7.9304,
 	}
 	body, _ := json.Marshal(payload)
 
-	req := httptest.NewRequest(http.MethodPost, "/order/cancel", bytes.NewBuffer(body))
+	req := httptest.NewRequest(http.MethodPost, "/order/add", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := performRequest(r, req)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
-	}
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
 
-	var response map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &response)
+func TestUpdateOrder_GeocodesMissingCoordinates(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, "PROCESSING")
+	geocoder, err := geocoding.NewOfflineGeocoder()
+	assert.NoError(t, err)
+	h := &OrderHandler{Store: store, Geocoder: geocoder}
+	r := gin.Default()
+	r.POST("/order/update", h.UpdateOrder)
 
-	if response["message"] != "Order cancelled successfully" {
-		t.Fatalf("expected success message, got: %v", response["message"])
-	}
+	w := postJSON(r, "/order/update", requestModels.UpdateOrderRequest{
+		OrderID:         1,
+		DeliveryAddress: "Praça da República, 3000-343 Coimbra, Portugal",
+	})
 
-	if response["order_id"] != float64(1) {
-		t.Fatalf("expected order_id 1, got: %v", response["order_id"])
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 40.2, order.Delivery_Latitude, 0.2, "the coordinates come from the geocoder")
+}
+
+func TestAddOrder_WithProducts(t *testing.T) {
+	server := catalog.NewFakeServer(
+		requestModels.Product{ID: 10, Name: "Cork Wallet", Price: 19.9, Stock: 5},
+		requestModels.Product{ID: 11, Name: "Sardines", Price: 4.5, StockUnlimited: true},
+	)
+	defer server.Close()
+	store := repository.NewMemory()
+	h := &OrderHandler{Store: store, Catalog: catalog.NewClient(server.Config())}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
+
+	// the products are fetched before the transaction starts (not synced yet, so from Jumpseller)
+
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products: []requestModels.OrderProductRequest{
+			{ProductID: 10, Quantity: 2},
+			{ProductID: 11, Quantity: 1},
+		},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 2, server.Requests())
+
+	order, err := store.Orders().GetWithProducts(context.Background(), 1)
+	require.NoError(t, err)
+
+	// the response has the created order and its tracking code
+	var response struct {
+		Message       string
+		Order         models.Orders
+		Tracking_Code string
 	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "Update stored successfully", response.Message)
+	assert.Equal(t, order.Id, response.Order.Id)
+	assert.Equal(t, order.Tracking_Code, response.Tracking_Code)
+	assert.Equal(t, order.Tracking_Code, response.Order.Tracking_Code)
+	assert.Len(t, response.Order.Products, 2)
+	assert.InDelta(t, 2*19.9+4.5, response.Order.Price, 1e-9, "the price is the one computed from the products")
+
+	require.Len(t, order.Products, 2)
+	assert.Equal(t, "Cork Wallet", order.Products[0].Product_Name_At_Purchase)
+	assert.Equal(t, uint(2), order.Products[0].Quantity)
+	assert.Equal(t, 4.5, order.Products[1].Product_Price_At_Purchase)
+
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "PROCESSING", latest.Order_Status)
+
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "orders", events[0].Table_Name)
+	assert.Equal(t, "order_products", events[2].Table_Name)
+}
+
+func TestAddOrder_UnknownProduct(t *testing.T) {
+	server := catalog.NewFakeServer(requestModels.Product{ID: 10, Name: "Cork Wallet", Price: 19.9})
+	defer server.Close()
+	store := repository.NewMemory()
+	h := &OrderHandler{Store: store, Catalog: catalog.NewClient(server.Config())}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
+
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 1}, {ProductID: 99, Quantity: 1}},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_id":99`)
+	orders, err := store.Orders().List(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, orders, "no order is created for invalid products")
+}
+
+func TestAddOrder_JumpsellerDown(t *testing.T) {
+	server := catalog.NewFakeServer()
+	defer server.Close()
+	server.FailNext(100, http.StatusServiceUnavailable)
+	store := repository.NewMemory()
+	h := &OrderHandler{Store: store, Catalog: catalog.NewClient(server.Config())}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
+
+	// the product is in the local catalogue, Jumpseller is not needed
+	addCatalogProduct(t, store, 3)
+
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 1}},
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 0, server.Requests())
 
-	if response["status"] != "CANCELLED" {
-		t.Fatalf("expected status CANCELLED, got: %v", response["status"])
+	products, err := store.OrderProducts().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, "Cork Wallet", products[0].Product_Name_At_Purchase)
+}
+
+func TestCreateOrder_ReturnsCreatedOrder(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/orders", h.CreateOrder)
+
+	addCatalogProduct(t, store, 5)
+
+	w := postJSON(r, "/orders", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryAddress:   "Addr",
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 2}},
+	})
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var response struct {
+		Order         models.Orders
+		Tracking_Code string
 	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, uint(1), response.Order.Id)
+	assert.NotEmpty(t, response.Tracking_Code)
+	assert.Equal(t, response.Tracking_Code, response.Order.Tracking_Code)
+	assert.False(t, response.Order.Delivery_Estimate.IsZero())
+	require.Len(t, response.Order.Products, 1)
+	assert.Equal(t, "Cork Wallet", response.Order.Products[0].Product_Name_At_Purchase)
+	assert.Equal(t, "/api/v2/orders/1", w.Header().Get("Location"))
+
+	stored, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, response.Tracking_Code, stored.Tracking_Code)
+	assert.InDelta(t, 2*19.9, stored.Price, 1e-9)
+	assert.Equal(t, stored.Price, response.Order.Price, "the response has the price computed from the products")
+}
+
+func TestUpdateDeliveryAddress_OrderFromPath(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, models.StatusProcessing)
+	seedOrder(t, store, 2, models.StatusProcessing)
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.PATCH("/orders/:order_id", h.UpdateDeliveryAddress)
+
+	// the order of the body is ignored
+	body, _ := json.Marshal(requestModels.UpdateOrderRequest{OrderID: 2, DeliveryAddress: "Updated Address", DeliveryLatitude: 41.1496, DeliveryLongitude: -8.6109})
+	w := performRequest(r, httptest.NewRequest(http.MethodPatch, "/orders/1", bytes.NewBuffer(body)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct{ Order models.Orders }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, uint(1), response.Order.Id)
+	assert.Equal(t, "Updated Address", response.Order.Delivery_Address)
+
+	untouched, err := store.Orders().Get(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Rua Nova", untouched.Delivery_Address)
+}
+
+func TestUpdateDeliveryAddress_ValidatesBody(t *testing.T) {
+	h := &OrderHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.PATCH("/orders/:order_id", h.UpdateDeliveryAddress)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodPatch, "/orders/1", bytes.NewBufferString(`{}`)))
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, apierror.CodeValidation, response.Code)
+	assert.Equal(t, []apierror.FieldError{{Field: "delivery_address", Message: "is required"}}, response.Details)
+}
+
+func TestCancelOrder_OrderFromPathWithoutBody(t *testing.T) {
+	store := repository.NewMemory()
+	seedOrder(t, store, 1, models.StatusProcessing)
+	h := &OrderHandler{Store: store}
+	r := gin.Default()
+	r.POST("/orders/:order_id/cancellation", h.CancelOrder)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodPost, "/orders/1/cancellation", nil))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, latest.Order_Status)
 }
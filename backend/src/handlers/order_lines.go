@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/models"
+	"app/requestModels"
+	"app/shipping"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CodeInvalidOrderLines is the error code of the orders with invalid lines (unknown or unavailable products, variants
+// or stock)
+const CodeInvalidOrderLines = "INVALID_ORDER_LINES"
+
+// respondLineErrors writes the errors of the invalid lines, as field details and in full in "lines"
+func respondLineErrors(c *gin.Context, message string, lineErrors []requestModels.OrderLineError) {
+	details := make([]apierror.FieldError, 0, len(lineErrors))
+	for _, lineError := range lineErrors {
+		details = append(details, apierror.FieldError{Field: fmt.Sprintf("products[%d]", lineError.Line), Message: lineError.Error})
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, struct {
+		apierror.Response
+		Lines []requestModels.OrderLineError `json:"lines"`
+	}{apierror.New(c, CodeInvalidOrderLines, message, details...), lineErrors})
+}
+
+// BuildOrderProducts validates the lines of an order against the catalogue and snapshots the product (and variant)
+// name, SKU, options and price into the order products. Returns the errors of each invalid line
+func BuildOrderProducts(orderID uint, lines []requestModels.OrderProductRequest, products map[uint]*models.Product) ([]models.OrderProduct, []requestModels.OrderLineError) {
+	var orderProducts []models.OrderProduct
+	var lineErrors []requestModels.OrderLineError
+
+	// quantity requested of each product / variant (the same one can be in several lines)
+	requested := map[string]uint{}
+	for _, line := range lines {
+		requested[stockKey(line.ProductID, line.VariantID)] += line.Quantity
+	}
+
+	for i, line := range lines {
+		lineError := func(message string) {
+			lineErrors = append(lineErrors, requestModels.OrderLineError{Line: i, ProductID: line.ProductID, VariantID: line.VariantID, Error: message})
+		}
+
+		if line.Quantity == 0 {
+			lineError("Quantity must be greater than 0")
+			continue
+		}
+
+		product, ok := products[line.ProductID]
+		if !ok {
+			lineError("Product not found")
+			continue
+		}
+
+		// products removed from the catalogue (disabled by the sync) or not for sale, their variants included
+		if product.Status == models.ProductDisabled || product.Status == models.ProductNotAvailable {
+			lineError(fmt.Sprintf("Product is not available for sale (%s)", product.Status))
+			continue
+		}
+
+		orderProduct := models.OrderProduct{
+			Order_ID:                  orderID,
+			Product_ID:                product.ID,
+			Quantity:                  line.Quantity,
+			Product_Name_At_Purchase:  product.Name,
+			Product_Price_At_Purchase: product.Price,
+		}
+		stock, unlimited := product.Stock, product.Stock_Unlimited
+
+		if line.VariantID != nil {
+			variant := findVariant(product, *line.VariantID)
+			if variant == nil {
+				lineError(fmt.Sprintf("Variant %d does not belong to the product", *line.VariantID))
+				continue
+			}
+
+			sku := variant.SKU
+			orderProduct.Variant_ID = &variant.ID
+			orderProduct.Variant_SKU = &sku
+			orderProduct.Variant_Options = variant.Options
+			if variant.Price > 0 {
+				orderProduct.Product_Price_At_Purchase = variant.Price
+			}
+			stock, unlimited = variant.Stock, variant.Stock_Unlimited
+		} else if len(product.Variants) > 0 {
+			lineError("The product has variants, variant_id is required")
+			continue
+		}
+
+		if !unlimited && requested[stockKey(line.ProductID, line.VariantID)] > uint(max(stock, 0)) {
+			lineError(fmt.Sprintf("Insufficient stock: %d requested, %d available", requested[stockKey(line.ProductID, line.VariantID)], max(stock, 0)))
+			continue
+		}
+
+		orderProducts = append(orderProducts, orderProduct)
+	}
+
+	return orderProducts, lineErrors
+}
+
+func findVariant(product *models.Product, variantID uint) *models.ProductVariant {
+	for i := range product.Variants {
+		if product.Variants[i].ID == variantID {
+			return &product.Variants[i]
+		}
+	}
+	return nil
+}
+
+func stockKey(productID uint, variantID *uint) string {
+	if variantID == nil {
+		return fmt.Sprintf("%d", productID)
+	}
+	return fmt.Sprintf("%d/%d", productID, *variantID)
+}
+
+// OrderParcel computes the shipping weight of the order products from the weight and dimensions in the catalogue
+func OrderParcel(orderProducts []models.OrderProduct, products map[uint]*models.Product) shipping.Parcel {
+	items := make([]shipping.Item, 0, len(orderProducts))
+	for _, orderProduct := range orderProducts {
+		product, ok := products[orderProduct.Product_ID]
+		if !ok {
+			continue
+		}
+		items = append(items, shipping.Item{
+			Weight:   product.Weight,
+			Length:   product.Length,
+			Width:    product.Width,
+			Height:   product.Height,
+			Quantity: orderProduct.Quantity,
+		})
+	}
+	return shipping.NewParcel(items)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"app/catalog"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+func testCatalogue() map[uint]*models.Product {
+	return map[uint]*models.Product{
+		1: {ID: 1, Name: "Cork Wallet", Price: 19.9, Stock: 3},
+		2: {ID: 2, Name: "Sardines", Price: 4.5, Stock_Unlimited: true},
+		3: {ID: 3, Name: "T-Shirt", Price: 15, Variants: []models.ProductVariant{
+			{ID: 31, Product_ID: 3, SKU: "TS-M", Price: 17, Stock: 2, Options: []models.VariantOption{{Name: "Size", Value: "M"}}},
+			{ID: 32, Product_ID: 3, SKU: "TS-L", Stock_Unlimited: true},
+		}},
+		4: {ID: 4, Name: "Old Mug", Price: 8, Stock_Unlimited: true, Status: models.ProductDisabled},
+		5: {ID: 5, Name: "Cork Hat", Price: 25, Status: models.ProductNotAvailable, Variants: []models.ProductVariant{
+			{ID: 51, Product_ID: 5, SKU: "CH-M", Stock: 5},
+		}},
+	}
+}
+
+func TestBuildOrderProducts_Success(t *testing.T) {
+	orderProducts, lineErrors := BuildOrderProducts(7, []requestModels.OrderProductRequest{
+		{ProductID: 1, Quantity: 3},
+		{ProductID: 2, Quantity: 100},
+		{ProductID: 3, VariantID: uintPtr(31), Quantity: 2},
+		{ProductID: 3, VariantID: uintPtr(32), Quantity: 1},
+	}, testCatalogue())
+
+	assert.Empty(t, lineErrors)
+	require.Len(t, orderProducts, 4)
+
+	assert.Equal(t, uint(7), orderProducts[0].Order_ID)
+	assert.Nil(t, orderProducts[0].Variant_ID)
+
+	variantLine := orderProducts[2]
+	assert.Equal(t, uint(31), *variantLine.Variant_ID)
+	assert.Equal(t, "TS-M", *variantLine.Variant_SKU)
+	assert.Equal(t, "M", variantLine.Variant_Options[0].Value)
+	assert.Equal(t, 17.0, variantLine.Product_Price_At_Purchase, "the variant price is used")
+	assert.Equal(t, "T-Shirt", variantLine.Product_Name_At_Purchase)
+
+	// variants without their own price keep the product price
+	assert.Equal(t, 15.0, orderProducts[3].Product_Price_At_Purchase)
+}
+
+func TestBuildOrderProducts_LineErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []requestModels.OrderProductRequest
+		error string
+	}{
+		{"zero quantity", []requestModels.OrderProductRequest{{ProductID: 1, Quantity: 0}}, "Quantity must be greater than 0"},
+		{"unknown product", []requestModels.OrderProductRequest{{ProductID: 9, Quantity: 1}}, "Product not found"},
+		{"insufficient stock", []requestModels.OrderProductRequest{{ProductID: 1, Quantity: 4}}, "Insufficient stock: 4 requested, 3 available"},
+		{"stock across lines", []requestModels.OrderProductRequest{{ProductID: 3, VariantID: uintPtr(31), Quantity: 1}, {ProductID: 3, VariantID: uintPtr(31), Quantity: 2}}, "Insufficient stock: 3 requested, 2 available"},
+		{"variant of another product", []requestModels.OrderProductRequest{{ProductID: 1, VariantID: uintPtr(31), Quantity: 1}}, "Variant 31 does not belong to the product"},
+		{"missing variant", []requestModels.OrderProductRequest{{ProductID: 3, Quantity: 1}}, "variant_id is required"},
+		{"disabled product", []requestModels.OrderProductRequest{{ProductID: 4, Quantity: 1}}, "Product is not available for sale (disabled)"},
+		{"variant of a product not available", []requestModels.OrderProductRequest{{ProductID: 5, VariantID: uintPtr(51), Quantity: 1}}, "Product is not available for sale (not-available)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, lineErrors := BuildOrderProducts(1, tt.lines, testCatalogue())
+
+			require.NotEmpty(t, lineErrors)
+			assert.Contains(t, lineErrors[0].Error, tt.error)
+			assert.Equal(t, tt.lines[lineErrors[0].Line].ProductID, lineErrors[0].ProductID)
+		})
+	}
+}
+
+func TestAddOrder_InsufficientStock(t *testing.T) {
+	server := catalog.NewFakeServer(requestModels.Product{
+		ID:   3,
+		Name: "T-Shirt",
+		Variants: []requestModels.Variant{
+			{ID: 31, SKU: "TS-M", Stock: 1},
+		},
+	})
+	defer server.Close()
+	store := repository.NewMemory()
+	h := &OrderHandler{Store: store, Catalog: catalog.NewClient(server.Config())}
+	r := gin.Default()
+	r.POST("/order/add", h.AddOrder)
+
+	w := postJSON(r, "/order/add", requestModels.AddOrderRequest{
+		CustomerId:        1,
+		DeliveryAddress:   "Addr",
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []requestModels.OrderProductRequest{{ProductID: 3, VariantID: uintPtr(31), Quantity: 2}},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response struct {
+		Lines []requestModels.OrderLineError `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Lines, 1)
+	assert.Equal(t, uint(31), *response.Lines[0].VariantID)
+	assert.Contains(t, response.Lines[0].Error, "Insufficient stock")
+	orders, err := store.Orders().List(context.Background(), false)
+	require.NoError(t, err)
+	assert.Empty(t, orders, "the order is not created")
+}
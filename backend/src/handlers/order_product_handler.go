@@ -1,32 +1,50 @@
 package handlers
 
 import (
+	"app/apierror"
+	"app/audit"
+	"app/catalog"
 	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type OrderProductHandler struct {
-	DB *gorm.DB
+	Store    repository.Store
+	Catalog  *catalog.Client
+	Notifier Notifier
+	Audit    *audit.Logger
 }
 
-// GetOrderProducts retrieves all products for a specific order
+// GetOrderProducts retrieves all products for a specific order (given in the path of the v2 routes, in the order_id
+// query parameter otherwise)
 func (h *OrderProductHandler) GetOrderProducts(c *gin.Context) {
-	orderID := c.Query("order_id")
-
+	orderID := c.Param("order_id")
+	if orderID == "" {
+		orderID = c.Query("order_id")
+	}
 	if orderID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id query parameter is required"})
+		apierror.Respond(c, http.StatusBadRequest, "order_id query parameter is required")
+		return
+	}
+	id, err := strconv.ParseUint(orderID, 10, 64)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, "Invalid order id")
 		return
 	}
 
-	var orderProducts []models.OrderProduct
-	result := h.DB.Where("order_id = ?", orderID).Find(&orderProducts)
+	orderProducts, err := h.Store.OrderProducts().ListByOrder(c, uint(id))
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
@@ -35,16 +53,21 @@ func (h *OrderProductHandler) GetOrderProducts(c *gin.Context) {
 
 // GetOrderProductByID retrieves a specific order product by ID
 func (h *OrderProductHandler) GetOrderProductByID(c *gin.Context) {
-	id := c.Param("id")
+	id, ok := parseID(c, orderProductParam(c))
+	if !ok {
+		return
+	}
 
-	var orderProduct models.OrderProduct
-	result := h.DB.First(&orderProduct, id)
+	orderProduct, err := h.Store.OrderProducts().Get(c, id)
+	if err == nil && !inPathOrder(c, orderProduct) {
+		err = repository.ErrNotFound
+	}
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order product not found"})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Order product not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		}
 		return
 	}
@@ -52,103 +75,390 @@ func (h *OrderProductHandler) GetOrderProductByID(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"order_product": orderProduct})
 }
 
-// AddOrderProduct adds a new product to an order
-func (h *OrderProductHandler) AddOrderProduct(c *gin.Context) {
-	var orderProduct models.OrderProduct
-
-	if err := c.ShouldBindJSON(&orderProduct); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+// GetOrderChanges retrieves the changes made to the products of an order after it was placed, oldest first. They are
+// derived from the audit trail of the order
+func (h *OrderProductHandler) GetOrderChanges(c *gin.Context) {
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
 		return
 	}
 
-	// Check if quantity is valid
-	if orderProduct.Quantity <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than 0"})
+	events, err := h.Store.AuditEvents().ListByOrder(c, orderID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	changes, err := orderChanges(events)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
-	// Check if order exists
-	var order models.Orders
-	if err := h.DB.First(&order, orderProduct.Order_ID).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	c.JSON(http.StatusOK, gin.H{"changes": changes})
+}
+
+// orderChanges derives the changes of the products of an order from its audit events (oldest first): the products
+// created after the order was placed, the quantities updated and the products deleted. The other updates of the
+// products (e.g. their shipment) are not changes of the order
+func orderChanges(events []models.AuditEvent) ([]models.OrderChange, error) {
+	//the products created with the order are in the request that created it
+	var placement string
+	for _, event := range events {
+		if event.Table_Name == audit.TableOrders && event.Action == models.AuditCreate {
+			placement = event.Request_ID
+		}
+	}
+
+	changes := []models.OrderChange{}
+	for _, event := range events {
+		if event.Table_Name != audit.TableOrderProducts {
+			continue
+		}
+		before, err := orderProductSnapshot(event.Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := orderProductSnapshot(event.After)
+		if err != nil {
+			return nil, err
 		}
+
+		change := models.OrderChange{Id: event.Id, Order_ID: event.Order_ID, Order_Product_ID: event.Record_ID, Changed_At: event.Created_At}
+		product := after
+		switch {
+		case event.Action == models.AuditCreate && event.Request_ID != placement:
+			change.Action, change.Quantity_After = models.ChangeAdded, after.Quantity
+		case event.Action == models.AuditUpdate && before.Quantity != after.Quantity:
+			change.Action, change.Quantity_Before, change.Quantity_After = models.ChangeUpdated, before.Quantity, after.Quantity
+		case event.Action == models.AuditDelete:
+			change.Action, change.Quantity_Before = models.ChangeRemoved, before.Quantity
+			product = before
+		default:
+			continue
+		}
+		change.Product_ID = product.Product_ID
+		change.Variant_ID = product.Variant_ID
+		change.Product_Name = product.Product_Name_At_Purchase
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// orderProductSnapshot decodes the snapshot of an order product in an audit event (the zero value when there is none)
+func orderProductSnapshot(snapshot map[string]interface{}) (models.OrderProduct, error) {
+	var orderProduct models.OrderProduct
+	if snapshot == nil {
+		return orderProduct, nil
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return orderProduct, err
+	}
+	err = json.Unmarshal(data, &orderProduct)
+	return orderProduct, err
+}
+
+// AddOrderProduct adds a new product to an order. The name and price are taken from the catalogue
+func (h *OrderProductHandler) AddOrderProduct(c *gin.Context) {
+	var input requestModels.AddOrderProductRequest
+	if !bindOrderRequest(c, &input, &input.OrderID) {
 		return
 	}
 
-	// Create the order product
-	result := h.DB.Create(&orderProduct)
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order product"})
+	order, ok := h.editableOrder(c, input.OrderID)
+	if !ok {
 		return
 	}
 
-	// Load the product relation for the response
-	h.DB.First(&orderProduct, orderProduct.ID)
+	// Validate the product, variant and stock against the catalogue
+	line := requestModels.OrderProductRequest{ProductID: input.ProductID, VariantID: input.VariantID, Quantity: input.Quantity}
+	products, err := catalog.LookupProducts(c, h.Store.Products(), h.Catalog, []uint{input.ProductID})
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, "Error while processing the products")
+		return
+	}
+	orderProducts, lineErrors := BuildOrderProducts(order.Id, []requestModels.OrderProductRequest{line}, products)
+	if len(lineErrors) > 0 {
+		respondLineErrors(c, lineErrors[0].Error, lineErrors)
+		return
+	}
+	orderProduct := orderProducts[0]
+
+	change := models.OrderChange{Action: models.ChangeAdded, Quantity_After: orderProduct.Quantity}
+	ok = h.commitChange(c, order, &orderProduct, nil, &change, func(tx repository.Store) error {
+		return tx.OrderProducts().Create(c, &orderProduct)
+	})
+	if !ok {
+		return
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"order_product": orderProduct})
 }
 
 // UpdateOrderProduct updates the quantity of an order product
 func (h *OrderProductHandler) UpdateOrderProduct(c *gin.Context) {
-	id := c.Param("id")
-
-	var orderProduct models.OrderProduct
-	if err := h.DB.First(&orderProduct, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order product not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		}
+	orderProduct, ok := h.findOrderProduct(c)
+	if !ok {
 		return
 	}
 
-	var updateData struct {
-		Quantity uint `json:"quantity" binding:"required"`
-	}
-
+	var updateData requestModels.UpdateOrderProductRequest
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		apierror.Validation(c, err)
 		return
 	}
 
-	if updateData.Quantity <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be greater than 0"})
+	order, ok := h.editableOrder(c, orderProduct.Order_ID)
+	if !ok {
 		return
 	}
 
+	// Only the extra units need to be in stock
+	if updateData.Quantity > orderProduct.Quantity {
+		line := requestModels.OrderProductRequest{ProductID: orderProduct.Product_ID, VariantID: orderProduct.Variant_ID, Quantity: updateData.Quantity - orderProduct.Quantity}
+		products, err := catalog.LookupProducts(c, h.Store.Products(), h.Catalog, []uint{orderProduct.Product_ID})
+		if err != nil {
+			apierror.Respond(c, http.StatusBadGateway, "Error while processing the products")
+			return
+		}
+		if _, lineErrors := BuildOrderProducts(order.Id, []requestModels.OrderProductRequest{line}, products); len(lineErrors) > 0 {
+			respondLineErrors(c, lineErrors[0].Error, lineErrors)
+			return
+		}
+	}
+
+	change := models.OrderChange{Action: models.ChangeUpdated, Quantity_Before: orderProduct.Quantity, Quantity_After: updateData.Quantity}
+	before := *orderProduct
 	orderProduct.Quantity = updateData.Quantity
-	if err := h.DB.Save(&orderProduct).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order product"})
+	ok = h.commitChange(c, order, orderProduct, &before, &change, func(tx repository.Store) error {
+		return tx.OrderProducts().Save(c, orderProduct)
+	})
+	if !ok {
 		return
 	}
 
-	// Load the product relation for the response
-	h.DB.First(&orderProduct, orderProduct.ID)
-
 	c.JSON(http.StatusOK, gin.H{"order_product": orderProduct})
 }
 
 // DeleteOrderProduct removes a product from an order
 func (h *OrderProductHandler) DeleteOrderProduct(c *gin.Context) {
-	id := c.Param("id")
+	if !h.deleteOrderProduct(c) {
+		return
+	}
 
-	var orderProduct models.OrderProduct
-	if err := h.DB.First(&orderProduct, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order product not found"})
+	c.JSON(http.StatusOK, gin.H{"message": "Order product deleted successfully"})
+}
+
+// DeleteOrderItem removes a product from the order of the path (v2), responding with no content
+func (h *OrderProductHandler) DeleteOrderItem(c *gin.Context) {
+	if !h.deleteOrderProduct(c) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteOrderProduct removes the order product of the path. Writes the error response and returns false if it was
+// not removed
+func (h *OrderProductHandler) deleteOrderProduct(c *gin.Context) bool {
+	orderProduct, ok := h.findOrderProduct(c)
+	if !ok {
+		return false
+	}
+
+	order, ok := h.editableOrder(c, orderProduct.Order_ID)
+	if !ok {
+		return false
+	}
+
+	change := models.OrderChange{Action: models.ChangeRemoved, Quantity_Before: orderProduct.Quantity}
+	before := *orderProduct
+	return h.commitChange(c, order, orderProduct, &before, &change, func(tx repository.Store) error {
+		return tx.OrderProducts().Delete(c, orderProduct)
+	})
+}
+
+// orderProductParam is the path parameter of the order product: item_id on the v2 routes (where id is the order),
+// id otherwise
+func orderProductParam(c *gin.Context) string {
+	if c.Param("item_id") != "" {
+		return "item_id"
+	}
+	return "id"
+}
+
+// inPathOrder reports whether the order product belongs to the order of the path, when the path has one
+func inPathOrder(c *gin.Context, orderProduct *models.OrderProduct) bool {
+	orderID := c.Param("order_id")
+	return orderID == "" || orderID == strconv.FormatUint(uint64(orderProduct.Order_ID), 10)
+}
+
+// findOrderProduct gets the order product of the path. Writes the error response and returns false when it does not
+// exist (or belongs to another order than the one of the path)
+func (h *OrderProductHandler) findOrderProduct(c *gin.Context) (*models.OrderProduct, bool) {
+	id, ok := parseID(c, orderProductParam(c))
+	if !ok {
+		return nil, false
+	}
+
+	orderProduct, err := h.Store.OrderProducts().Get(c, id)
+	if err == nil && !inPathOrder(c, orderProduct) {
+		err = repository.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Order product not found")
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		}
-		return
+		return nil, false
 	}
+	return orderProduct, true
+}
 
-	if err := h.DB.Delete(&orderProduct).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error":err.Error()})
-		return
+// editableOrder gets the order and checks its products can still be changed (only while it is being processed).
+// Writes the error response and returns false otherwise
+func (h *OrderProductHandler) editableOrder(c *gin.Context, orderID uint) (*models.Orders, bool) {
+	order, err := h.Store.Orders().Get(c, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Order not found")
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return nil, false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Order product deleted successfully"})
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return nil, false
+	}
+	if latestStatus != nil && latestStatus.Order_Status != models.StatusProcessing {
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot change the products of an order with status: %s", latestStatus.Order_Status))
+		return nil, false
+	}
+
+	return order, true
+}
+
+// commitChange applies a change to the products of an order, records it in the audit log and updates the shipping
+// weight of the order in a single transaction. The customer is notified of the change once it is committed.
+// before is the order product before the change (nil when it is added).
+// Writes the error response and returns false if the change failed
+func (h *OrderProductHandler) commitChange(c *gin.Context, order *models.Orders, orderProduct *models.OrderProduct, before *models.OrderProduct, change *models.OrderChange, apply func(tx repository.Store) error) bool {
+	// the catalogue may be fetched from Jumpseller, it is read before the transaction so no lock waits for it
+	products, err := h.parcelProducts(c, order.Id, orderProduct, change.Action == models.ChangeRemoved)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, "Error while processing the products")
+		return false
+	}
+
+	audited := h.Audit.Batch()
+	err = h.Store.Transaction(c, func(tx repository.Store) error {
+		if err := apply(tx); err != nil {
+			return err
+		}
+		describeChange(change, order, orderProduct)
+		if _, err := audited.Record(tx.AuditEvents(), c, auditChange(change, orderProduct, before)); err != nil {
+			return err
+		}
+		return h.updateOrderParcel(c, tx, audited, order, products)
+	})
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to change the order products")
+		return false
+	}
+	audited.Anchor(c, h.Store.AuditEvents())
+
+	notifyCustomer(c, h.Notifier, CustomerNotification{
+		CustomerID: order.Customer_ID,
+		OrderID:    order.Id,
+		Title:      "Order Changed",
+		Message:    describeOrderChange(change),
+	})
+	return true
+}
+
+// describeChange fills in the order product a change is about, once it is applied
+func describeChange(change *models.OrderChange, order *models.Orders, orderProduct *models.OrderProduct) {
+	change.Order_ID = order.Id
+	change.Order_Product_ID = orderProduct.ID
+	change.Product_ID = orderProduct.Product_ID
+	change.Variant_ID = orderProduct.Variant_ID
+	change.Product_Name = orderProduct.Product_Name_At_Purchase
+	change.Changed_At = time.Now()
+}
+
+// auditChange is the audit change of the order product of a change, before is the order product before it
+func auditChange(change *models.OrderChange, orderProduct, before *models.OrderProduct) audit.Change {
+	auditChange := audit.Change{OrderID: change.Order_ID, Table: audit.TableOrderProducts, RecordID: orderProduct.ID}
+	switch change.Action {
+	case models.ChangeAdded:
+		auditChange.Action, auditChange.After = models.AuditCreate, orderProduct
+	case models.ChangeRemoved:
+		auditChange.Action, auditChange.Before = models.AuditDelete, before
+	default:
+		auditChange.Action, auditChange.Before, auditChange.After = models.AuditUpdate, before, orderProduct
+	}
+	return auditChange
+}
+
+// parcelProducts returns the catalogue products of the order once the order product is changed (or removed): those
+// of the products it keeps
+func (h *OrderProductHandler) parcelProducts(c *gin.Context, orderID uint, changed *models.OrderProduct, removed bool) (map[uint]*models.Product, error) {
+	orderProducts, err := h.Store.OrderProducts().ListByOrder(c, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := []uint{}
+	if !removed {
+		productIDs = append(productIDs, changed.Product_ID)
+	}
+	for _, orderProduct := range orderProducts {
+		if orderProduct.ID != changed.ID {
+			productIDs = append(productIDs, orderProduct.Product_ID)
+		}
+	}
+	return catalog.LookupProducts(c, h.Store.Products(), h.Catalog, productIDs)
+}
+
+// updateOrderParcel recomputes the shipping weight of the order from its current products (with the catalogue
+// products read before the transaction), the change is audited in the batch of the transaction. The order is set to
+// the one stored, with its new price
+func (h *OrderProductHandler) updateOrderParcel(c *gin.Context, tx repository.Store, audited *audit.Batch, order *models.Orders, products map[uint]*models.Product) error {
+	orderProducts, err := tx.OrderProducts().ListByOrder(c, order.Id)
+	if err != nil {
+		return err
+	}
+
+	//the order is read again, its price was just recomputed by the database from its products
+	current, err := tx.Orders().Get(c, order.Id)
+	if err != nil {
+		return err
+	}
+	*order = *current
+	before := *order
+	parcel := OrderParcel(orderProducts, products)
+	order.Shipping_Weight = parcel.Weight
+	order.Volumetric_Weight = parcel.VolumetricWeight
+	if err := tx.Orders().Update(c, order, "shipping_weight", "volumetric_weight"); err != nil {
+		return err
+	}
+
+	_, err = audited.Record(tx.AuditEvents(), c, audit.Change{OrderID: order.Id, Table: audit.TableOrders, RecordID: order.Id, Action: models.AuditUpdate, Before: before, After: order})
+	return err
+}
+
+// describeOrderChange is the message sent to the customer about a change
+func describeOrderChange(change *models.OrderChange) string {
+	switch change.Action {
+	case models.ChangeAdded:
+		return fmt.Sprintf("%d x %s was added to your order %d.", change.Quantity_After, change.Product_Name, change.Order_ID)
+	case models.ChangeRemoved:
+		return fmt.Sprintf("%s was removed from your order %d.", change.Product_Name, change.Order_ID)
+	default:
+		return fmt.Sprintf("The quantity of %s in your order %d changed from %d to %d.", change.Product_Name, change.Order_ID, change.Quantity_Before, change.Quantity_After)
+	}
 }
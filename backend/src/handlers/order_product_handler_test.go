@@ -1,23 +1,26 @@
 package handlers
 
 import (
+	"app/audit"
 	"app/models"
+	"app/repository"
+	"app/requestModels"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
-	"regexp"
 	"testing"
+	"time"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetOrderProducts_MissingParam(t *testing.T) {
-    db, _ := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+    h := &OrderProductHandler{Store: repository.NewMemory()}
     r := gin.Default()
     r.GET("/order_products", h.GetOrderProducts)
 
@@ -30,33 +33,42 @@ func TestGetOrderProducts_MissingParam(t *testing.T) {
 }
 
 func TestGetOrderProducts_Success(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+    store := repository.NewMemory()
+    require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 2}))
+    require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{Order_ID: 2, Product_ID: 11, Quantity: 1}))
+    h := &OrderProductHandler{Store: store}
     r := gin.Default()
     r.GET("/order_products", h.GetOrderProducts)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE order_id = \$1`).
-        WithArgs("1").
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
-
     req := httptest.NewRequest(http.MethodGet, "/order_products?order_id=1", nil)
     w := performRequest(r, req)
 
-    if w.Code != http.StatusOK {
-        t.Fatalf("expected 200, got %d", w.Code)
+    require.Equal(t, http.StatusOK, w.Code)
+    var response struct {
+        OrderProducts []models.OrderProduct `json:"order_products"`
     }
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+    require.Len(t, response.OrderProducts, 1)
+    assert.Equal(t, uint(10), response.OrderProducts[0].Product_ID)
 }
 
-func TestGetOrderProducts_DBError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+func TestGetOrderProducts_InvalidOrderID(t *testing.T) {
+    h := &OrderProductHandler{Store: repository.NewMemory()}
     r := gin.Default()
     r.GET("/order_products", h.GetOrderProducts)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE order_id = \$1`).
-        WithArgs("1").
-        WillReturnError(errors.New("db fail"))
+    req := httptest.NewRequest(http.MethodGet, "/order_products?order_id=abc", nil)
+    w := performRequest(r, req)
+
+    assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetOrderProducts_DBError(t *testing.T) {
+    store := repository.NewMemory()
+    store.Fail("OrderProducts.ListByOrder", errors.New("db fail"))
+    h := &OrderProductHandler{Store: store}
+    r := gin.Default()
+    r.GET("/order_products", h.GetOrderProducts)
 
     req := httptest.NewRequest(http.MethodGet, "/order_products?order_id=1", nil)
     w := performRequest(r, req)
@@ -68,15 +80,10 @@ func TestGetOrderProducts_DBError(t *testing.T) {
 
 
 func TestGetOrderProductByID_NotFound(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+    h := &OrderProductHandler{Store: repository.NewMemory()}
     r := gin.Default()
     r.GET("/order_product/:id", h.GetOrderProductByID)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("999",1).
-        WillReturnError(gorm.ErrRecordNotFound)
-
     req := httptest.NewRequest(http.MethodGet, "/order_product/999", nil)
     w := performRequest(r, req)
 
@@ -86,34 +93,26 @@ func TestGetOrderProductByID_NotFound(t *testing.T) {
 }
 
 func TestGetOrderProductByID_Success(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+    store := repository.NewMemory()
+    require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{ID: 1, Order_ID: 1, Product_ID: 10, Quantity: 2}))
+    h := &OrderProductHandler{Store: store}
     r := gin.Default()
     r.GET("/order_product/:id", h.GetOrderProductByID)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
-
     req := httptest.NewRequest(http.MethodGet, "/order_product/1", nil)
     w := performRequest(r, req)
 
-    if w.Code != http.StatusOK {
-        t.Fatalf("expected 200, got %d", w.Code)
-    }
+    require.Equal(t, http.StatusOK, w.Code)
+    assert.Contains(t, w.Body.String(), `"Product_ID":10`)
 }
 
 func TestGetOrderProductByID_DBError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
+    store := repository.NewMemory()
+    store.Fail("OrderProducts.Get", errors.New("db fail"))
+    h := &OrderProductHandler{Store: store}
     r := gin.Default()
     r.GET("/order_product/:id", h.GetOrderProductByID)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnError(errors.New("db fail"))
-
     req := httptest.NewRequest(http.MethodGet, "/order_product/1", nil)
     w := performRequest(r, req)
 
@@ -123,365 +122,545 @@ func TestGetOrderProductByID_DBError(t *testing.T) {
 }
 
 
-func TestAddOrderProduct_BadJSON(t *testing.T) {
-    db, _ := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+type fakeNotifier struct {
+	notifications []CustomerNotification
+	err           error
+}
 
-    req := httptest.NewRequest(http.MethodPost, "/order_product/add", bytes.NewBufferString("not-json"))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r, req)
+func (n *fakeNotifier) Notify(ctx context.Context, notification CustomerNotification) error {
+	n.notifications = append(n.notifications, notification)
+	return n.err
+}
 
-    if w.Code != http.StatusBadRequest {
-        t.Fatalf("expected 400, got %d", w.Code)
-    }
+// setupOrderProductHandler creates the handler of order 1 (still being processed), its products and the local
+// catalogue are in the store
+func setupOrderProductHandler(t *testing.T) (*OrderProductHandler, *repository.Memory, *fakeNotifier) {
+	store := repository.NewMemory()
+	notifier := &fakeNotifier{}
+	seedOrder(t, store, 1, models.StatusProcessing)
+	return &OrderProductHandler{Store: store, Notifier: notifier}, store, notifier
 }
 
-func TestAddOrderProduct_InvalidQuantity(t *testing.T) {
-    db, _ := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+// seedWallet stores the order product 1: product 10 of order 1
+func seedWallet(t *testing.T, store *repository.Memory, quantity uint) {
+	t.Helper()
+	require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{
+		ID: 1, Order_ID: 1, Product_ID: 10, Quantity: quantity, Product_Name_At_Purchase: "Cork Wallet", Product_Price_At_Purchase: 19.9,
+	}))
+}
 
-    payload := models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 0}
-    body,_ := json.Marshal(payload)
+// addCatalogProduct stores product 10 in the local catalogue: 0.5 kg, 20x10x5 cm (0.2 kg volumetric)
+func addCatalogProduct(t *testing.T, store *repository.Memory, stock int) {
+	t.Helper()
+	require.NoError(t, store.Products().Create(context.Background(), &models.Product{
+		ID: 10, Name: "Cork Wallet", Price: 19.9, Stock: stock, Weight: 0.5, Length: 20, Width: 10, Height: 5,
+	}))
+}
 
-    req := httptest.NewRequest(http.MethodPost,"/order_product/add",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+// assertOrderChange checks the change was audited and the shipping weight of the order recomputed with the quantity
+// left of product 10, without overwriting the price kept by the database
+func assertOrderChange(t *testing.T, store *repository.Memory, action string, after uint) {
+	t.Helper()
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5*float64(after), order.Shipping_Weight, 1e-9)
+	assert.InDelta(t, 0.2*float64(after), order.Volumetric_Weight, 1e-9)
+	assert.InDelta(t, 19.9*float64(after), order.Price, 1e-9)
+
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, []string{audit.TableOrderProducts, auditActions[action]}, []string{events[0].Table_Name, events[0].Action})
+	assert.Equal(t, []string{audit.TableOrders, models.AuditUpdate}, []string{events[1].Table_Name, events[1].Action})
+	assert.InDelta(t, order.Price, events[1].After["Price"], 1e-9, "the audited order has its new price")
+}
 
-    if w.Code != http.StatusBadRequest {
-        t.Fatalf("expected 400, got %d", w.Code)
-    }
+// assertNoOrderChange checks the products and the audit trail of order 1 are unchanged
+func assertNoOrderChange(t *testing.T, store *repository.Memory, products ...models.OrderProduct) {
+	t.Helper()
+	stored, err := store.OrderProducts().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, len(products), len(stored))
+	for i := range products {
+		assert.Equal(t, products[i].Quantity, stored[i].Quantity)
+	}
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
 }
 
-func TestAddOrderProduct_OrderNotFound(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+// audit action of each kind of order change
+var auditActions = map[string]string{
+	models.ChangeAdded:   models.AuditCreate,
+	models.ChangeUpdated: models.AuditUpdate,
+	models.ChangeRemoved: models.AuditDelete,
+}
 
-    payload := models.OrderProduct{Order_ID: 999, Product_ID: 10, Quantity: 2}
-    body,_ := json.Marshal(payload)
+func TestAddOrderProduct_InvalidQuantity(t *testing.T) {
+	h, _, _ := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
 
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(999,1).
-        WillReturnError(gorm.ErrRecordNotFound)
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 0})
 
-    req := httptest.NewRequest(http.MethodPost,"/order_product/add",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
 
-    if w.Code != http.StatusNotFound {
-        t.Fatalf("expected 404, got %d", w.Code)
-    }
+func TestAddOrderProduct_OrderNotFound(t *testing.T) {
+	h, _, _ := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
+
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 999, ProductID: 10, Quantity: 2})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestAddOrderProduct_DBErrorOnOrderLookup(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+	h, store, _ := setupOrderProductHandler(t)
+	store.Fail("Orders.Get", errors.New("db fail"))
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
+
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 2})
 
-    payload := models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 2}
-    body,_ := json.Marshal(payload)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
 
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnError(errors.New("db fail"))
+func TestAddOrderProduct_OrderAlreadyShipped(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedUpdate(t, store, 1, models.StatusShipped, time.Now().Add(time.Minute))
+	store.Fail("Products.ListByIDs", errors.New("the catalogue is not queried"))
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
 
-    req := httptest.NewRequest(http.MethodPost,"/order_product/add",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 2})
 
-    if w.Code != http.StatusInternalServerError {
-        t.Fatalf("expected 500, got %d", w.Code)
-    }
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assertNoOrderChange(t, store)
 }
 
-func TestAddOrderProduct_DBErrorOnCreate(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+func TestAddOrderProduct_NotInCatalogue(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
 
-    payload := models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 2}
-    body,_ := json.Marshal(payload)
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 2})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Product not found")
+	assertNoOrderChange(t, store)
+}
 
-    // Order exists
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+func TestAddOrderProduct_DBErrorOnCreate(t *testing.T) {
+	h, store, notifier := setupOrderProductHandler(t)
+	store.Fail("OrderProducts.Create", errors.New("insert fail"))
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
 
-    // Fail on create
-    mock.ExpectBegin()
-    mock.ExpectExec(`INSERT INTO "order_products"`).WillReturnError(errors.New("insert fail"))
-    mock.ExpectRollback()
+	addCatalogProduct(t, store, 10)
 
-    req := httptest.NewRequest(http.MethodPost,"/order_product/add",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 2})
 
-    if w.Code != http.StatusInternalServerError {
-        t.Fatalf("expected 500, got %d", w.Code)
-    }
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, notifier.notifications)
 }
 
 func TestAddOrderProduct_Success(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.POST("/order_product/add", h.AddOrderProduct)
+	h, store, notifier := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
+
+	// the product is validated against the catalogue, then the parcel of the order is recomputed
+	addCatalogProduct(t, store, 10)
+
+	w := postJSON(r, "/order_product/add", map[string]interface{}{
+		"order_id":                  1,
+		"product_id":                10,
+		"quantity":                  2,
+		"Product_Name_At_Purchase":  "Free stuff", // ignored, the catalogue is used
+		"Product_Price_At_Purchase": 0.01,
+	})
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Created with the name and price of the catalogue
+	stored, err := store.OrderProducts().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "Cork Wallet", stored[0].Product_Name_At_Purchase)
+	assert.Equal(t, 19.9, stored[0].Product_Price_At_Purchase)
+	assertOrderChange(t, store, models.ChangeAdded, 2)
+
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, uint(42), notifier.notifications[0].CustomerID)
+	assert.Equal(t, "2 x Cork Wallet was added to your order 1.", notifier.notifications[0].Message)
+}
+
+func TestUpdateOrderProduct_NotFound(t *testing.T) {
+	h, _, _ := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
-    payload := models.OrderProduct{Order_ID: 1, Product_ID: 10, Quantity: 2}
-    body,_ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPut, "/order_product/999", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    // Order exists
-    mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
 
-    // Create succeeds
-    mock.ExpectBegin()
-	mock.ExpectQuery(regexp.QuoteMeta(
-		`INSERT INTO "order_products" ("order_id","product_id","quantity","product_name_at_purchase","product_price_at_purchase") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`)).
-		WithArgs(1, 10, 2, "", float64(0)). 
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
+func TestUpdateOrderProduct_InvalidQuantity(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
 
-    // Reload product
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
+func TestUpdateOrderProduct_BadJSON(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
-    req := httptest.NewRequest(http.MethodPost,"/order_product/add",bytes.NewBuffer(body))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString("not-json"))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    if w.Code != http.StatusCreated {
-        t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
-    }
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestUpdateOrderProduct_OrderAlreadyShipped(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	seedUpdate(t, store, 1, models.StatusInTransit, time.Now().Add(time.Minute))
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-func TestUpdateOrderProduct_NotFound(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.PUT("/order_product/:id", h.UpdateOrderProduct)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
+}
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("999",1).
-        WillReturnError(gorm.ErrRecordNotFound)
+func TestUpdateOrderProduct_InsufficientStock(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
-    req := httptest.NewRequest(http.MethodPut,"/order_product/999",bytes.NewBufferString(`{"quantity":5}`))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	addCatalogProduct(t, store, 2)
 
-    if w.Code != http.StatusNotFound {
-        t.Fatalf("expected 404, got %d", w.Code)
-    }
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Insufficient stock: 3 requested, 2 available")
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
 }
 
-func TestUpdateOrderProduct_InvalidQuantity(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.PUT("/order_product/:id", h.UpdateOrderProduct)
+func TestUpdateOrderProduct_SaveError(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	store.Fail("OrderProducts.Save", errors.New("update fail"))
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
+	addCatalogProduct(t, store, 10)
 
-    req := httptest.NewRequest(http.MethodPut,"/order_product/1",bytes.NewBufferString(`{"quantity":0}`))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    if w.Code != http.StatusBadRequest {
-        t.Fatalf("expected 400, got %d", w.Code)
-    }
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
 }
 
-func TestUpdateOrderProduct_BadJSON(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.PUT("/order_product/:id", h.UpdateOrderProduct)
+func TestUpdateOrderProduct_RollsBackWhenTheParcelFails(t *testing.T) {
+	h, store, notifier := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	store.Fail("Orders.Update", errors.New("update fail"))
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
+	addCatalogProduct(t, store, 10)
 
-    req := httptest.NewRequest(http.MethodPut,"/order_product/1",bytes.NewBufferString("not-json"))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    if w.Code != http.StatusBadRequest {
-        t.Fatalf("expected 400, got %d", w.Code)
-    }
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
+	assert.Empty(t, notifier.notifications, "the customer is not notified of failed changes")
 }
 
-func TestUpdateOrderProduct_SaveError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.PUT("/order_product/:id", h.UpdateOrderProduct)
+// outsideTransactions is a store whose catalogue fails when it is read while a transaction is open
+type outsideTransactions struct {
+	*repository.Memory
+	open bool
+}
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
+func (s *outsideTransactions) Transaction(ctx context.Context, fn func(tx repository.Store) error) error {
+	return s.Memory.Transaction(ctx, func(repository.Store) error {
+		s.open = true
+		defer func() { s.open = false }()
+		return fn(s)
+	})
+}
 
-    mock.ExpectBegin()
-    mock.ExpectExec(`UPDATE "order_products"`).WillReturnError(errors.New("update fail"))
-    mock.ExpectRollback()
+func (s *outsideTransactions) Products() repository.Products {
+	return catalogueOutsideTransactions{s.Memory.Products(), s}
+}
 
-    req := httptest.NewRequest(http.MethodPut,"/order_product/1",bytes.NewBufferString(`{"quantity":5}`))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
+type catalogueOutsideTransactions struct {
+	repository.Products
+	s *outsideTransactions
+}
 
-    if w.Code != http.StatusInternalServerError {
-        t.Fatalf("expected 500, got %d", w.Code)
-    }
+func (p catalogueOutsideTransactions) ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	if p.s.open {
+		return nil, errors.New("the catalogue is read inside the transaction")
+	}
+	return p.Products.ListByIDs(ctx, ids)
+}
+
+func TestAddOrderProduct_ReadsTheCatalogueBeforeTheTransaction(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 1)
+	addCatalogProduct(t, store, 10)
+	h.Store = &outsideTransactions{Memory: store}
+	r := gin.Default()
+	r.POST("/order_product/add", h.AddOrderProduct)
+
+	w := postJSON(r, "/order_product/add", requestModels.AddOrderProductRequest{OrderID: 1, ProductID: 10, Quantity: 2})
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5*3, order.Shipping_Weight, 1e-9, "the parcel has the products of the order and the added one")
 }
 
 func TestUpdateOrderProduct_Success(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.PUT("/order_product/:id", h.UpdateOrderProduct)
-
-    // Existing product
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
-
-    // Update succeeds
-    mock.ExpectBegin()
-    mock.ExpectExec(`UPDATE "order_products"`).
-        WillReturnResult(sqlmock.NewResult(1,1))
-    mock.ExpectCommit()
-
-    // Reload product
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs(1,1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,5))
-
-    req := httptest.NewRequest(http.MethodPut,"/order_product/1",bytes.NewBufferString(`{"quantity":5}`))
-    req.Header.Set("Content-Type","application/json")
-    w := performRequest(r,req)
-
-    if w.Code != http.StatusOK {
-        t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
-    }
+	h, store, notifier := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
+
+	addCatalogProduct(t, store, 10)
+
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"Quantity":5`)
+	assertOrderChange(t, store, models.ChangeUpdated, 5)
+	require.Len(t, notifier.notifications, 1)
+	assert.Contains(t, notifier.notifications[0].Message, "changed from 2 to 5")
 }
 
+func TestUpdateOrderProduct_LowerQuantitySkipsStockCheck(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.PUT("/order_product/:id", h.UpdateOrderProduct)
 
+	// only the parcel of the order is recomputed
+	addCatalogProduct(t, store, 100)
 
-func TestDeleteOrderProduct_NotFound(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.DELETE("/order_product/:id", h.DeleteOrderProduct)
+	req := httptest.NewRequest(http.MethodPut, "/order_product/1", bytes.NewBufferString(`{"quantity":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := performRequest(r, req)
 
-    // Simulate record not found
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("999", 1).
-        WillReturnError(gorm.ErrRecordNotFound)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assertOrderChange(t, store, models.ChangeUpdated, 1)
+}
 
-    req := httptest.NewRequest(http.MethodDelete, "/order_product/999", nil)
-    w := performRequest(r, req)
+func TestDeleteOrderProduct_NotFound(t *testing.T) {
+	h, _, _ := setupOrderProductHandler(t)
+	r := gin.Default()
+	r.DELETE("/order_product/:id", h.DeleteOrderProduct)
 
-    if w.Code != http.StatusNotFound {
-        t.Fatalf("expected 404, got %d", w.Code)
-    }
+	req := httptest.NewRequest(http.MethodDelete, "/order_product/999", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
 }
 
 func TestDeleteOrderProduct_Success(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.DELETE("/order_product/:id", h.DeleteOrderProduct)
-
-    // Record exists
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1", 1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1, 1, 10, 2))
-
-    // Transaction + delete
-    mock.ExpectBegin()
-    mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "order_products" WHERE "order_products"."id" = $1`)).
-        WithArgs(1).
-        WillReturnResult(sqlmock.NewResult(1, 1))
-    mock.ExpectCommit()
-
-    req := httptest.NewRequest(http.MethodDelete, "/order_product/1", nil)
-    w := performRequest(r, req)
+	h, store, notifier := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	store.Fail("Products.ListByIDs", errors.New("an order without products needs no catalogue"))
+	r := gin.Default()
+	r.DELETE("/order_product/:id", h.DeleteOrderProduct)
+
+	req := httptest.NewRequest(http.MethodDelete, "/order_product/1", nil)
+	w := performRequest(r, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err := store.OrderProducts().Get(context.Background(), 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assertOrderChange(t, store, models.ChangeRemoved, 0)
+	require.Len(t, notifier.notifications, 1)
+	assert.Equal(t, "Cork Wallet was removed from your order 1.", notifier.notifications[0].Message)
+}
 
-    if w.Code != http.StatusOK {
-        t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
-    }
+func TestDeleteOrderProduct_OrderAlreadyDelivered(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	seedUpdate(t, store, 1, models.StatusDelivered, time.Now().Add(time.Minute))
+	r := gin.Default()
+	r.DELETE("/order_product/:id", h.DeleteOrderProduct)
 
-    if err := mock.ExpectationsWereMet(); err != nil {
-        t.Fatalf("unmet expectations: %v", err)
-    }
+	req := httptest.NewRequest(http.MethodDelete, "/order_product/1", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
 }
 
 func TestDeleteOrderProduct_DBErrorOnLookup(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.DELETE("/order_product/:id", h.DeleteOrderProduct)
+	h, store, _ := setupOrderProductHandler(t)
+	store.Fail("OrderProducts.Get", errors.New("db fail"))
+	r := gin.Default()
+	r.DELETE("/order_product/:id", h.DeleteOrderProduct)
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnError(errors.New("db fail"))
+	req := httptest.NewRequest(http.MethodDelete, "/order_product/1", nil)
+	w := performRequest(r, req)
 
-    req := httptest.NewRequest(http.MethodDelete,"/order_product/1",nil)
-    w := performRequest(r,req)
-
-    if w.Code != http.StatusInternalServerError {
-        t.Fatalf("expected 500, got %d", w.Code)
-    }
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
 func TestDeleteOrderProduct_DeleteError(t *testing.T) {
-    db, mock := setupMockDB(t)
-    h := &OrderProductHandler{DB: db}
-    r := gin.Default()
-    r.DELETE("/order_product/:id", h.DeleteOrderProduct)
+	h, store, notifier := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	store.Fail("OrderProducts.Delete", errors.New("delete fail"))
+	r := gin.Default()
+	r.DELETE("/order_product/:id", h.DeleteOrderProduct)
+
+	req := httptest.NewRequest(http.MethodDelete, "/order_product/1", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
+	assert.Empty(t, notifier.notifications, "the customer is not notified of failed changes")
+}
 
-    mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."id" = \$1 ORDER BY "order_products"."id" LIMIT \$2`).
-        WithArgs("1",1).
-        WillReturnRows(sqlmock.NewRows([]string{"id","order_id","product_id","quantity"}).
-            AddRow(1,1,10,2))
+func TestGetOrderChanges(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderProductHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/changes/:order_id", h.GetOrderChanges)
+
+	// the audit trail of order 1: placed with product 10, product 11 added, the quantity of product 10 changed, product
+	// 10 shipped and product 11 removed
+	wallet := models.OrderProduct{ID: 1, Order_ID: 1, Product_ID: 10, Quantity: 2, Product_Name_At_Purchase: "Cork Wallet"}
+	variant := uint(3)
+	bag := models.OrderProduct{ID: 2, Order_ID: 1, Product_ID: 11, Variant_ID: &variant, Quantity: 1, Product_Name_At_Purchase: "Cork Bag"}
+	more := wallet
+	more.Quantity = 5
+	shipped := more
+	shipment := uint(4)
+	shipped.Shipment_ID = &shipment
+	record := func(requestID string, change audit.Change) {
+		change.OrderID = 1
+		ctx := audit.NewContext(context.Background(), audit.Metadata{Source: audit.SourceHTTP, RequestID: requestID})
+		_, err := (&audit.Logger{}).Record(store.AuditEvents(), ctx, change)
+		require.NoError(t, err)
+	}
+	record("req-1", audit.Change{Table: audit.TableOrders, RecordID: 1, Action: models.AuditCreate, After: models.Orders{Id: 1}})
+	record("req-1", audit.Change{Table: audit.TableOrderProducts, RecordID: 1, Action: models.AuditCreate, After: wallet})
+	record("req-2", audit.Change{Table: audit.TableOrderProducts, RecordID: 2, Action: models.AuditCreate, After: bag})
+	record("req-3", audit.Change{Table: audit.TableOrderProducts, RecordID: 1, Action: models.AuditUpdate, Before: wallet, After: more})
+	record("req-4", audit.Change{Table: audit.TableOrderProducts, RecordID: 1, Action: models.AuditUpdate, Before: more, After: shipped})
+	record("req-5", audit.Change{Table: audit.TableOrderProducts, RecordID: 2, Action: models.AuditDelete, Before: bag})
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/changes/1", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Changes []models.OrderChange
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Changes, 3)
+	assert.Equal(t, models.OrderChange{Id: 3, Order_ID: 1, Order_Product_ID: 2, Action: models.ChangeAdded, Product_ID: 11, Variant_ID: &variant, Product_Name: "Cork Bag", Quantity_After: 1, Changed_At: response.Changes[0].Changed_At}, response.Changes[0])
+	assert.Equal(t, models.ChangeUpdated, response.Changes[1].Action)
+	assert.Equal(t, "Cork Wallet", response.Changes[1].Product_Name)
+	assert.Equal(t, []uint{2, 5}, []uint{response.Changes[1].Quantity_Before, response.Changes[1].Quantity_After})
+	assert.Equal(t, models.ChangeRemoved, response.Changes[2].Action)
+	assert.Equal(t, uint(11), response.Changes[2].Product_ID)
+	assert.Equal(t, uint(1), response.Changes[2].Quantity_Before)
+}
 
-    mock.ExpectBegin()
-    mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "order_products" WHERE "order_products"."id" = $1`)).
-        WithArgs(1).
-        WillReturnError(errors.New("delete fail"))
-    mock.ExpectRollback()
+func TestGetOrderChanges_Errors(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderProductHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/changes/:order_id", h.GetOrderChanges)
 
-    req := httptest.NewRequest(http.MethodDelete,"/order_product/1",nil)
-    w := performRequest(r,req)
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/order/changes/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-    if w.Code != http.StatusInternalServerError {
-        t.Fatalf("expected 500, got %d", w.Code)
-    }
+	store.Fail("AuditEvents.ListByOrder", assert.AnError)
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/order/changes/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestNotifyCustomer_FailureIsOnlyLogged(t *testing.T) {
+	notifier := &fakeNotifier{err: errors.New("pubsub down")}
 
+	notifyCustomer(context.Background(), notifier, CustomerNotification{CustomerID: 1, OrderID: 2})
+	notifyCustomer(context.Background(), nil, CustomerNotification{CustomerID: 1, OrderID: 2})
 
+	assert.Len(t, notifier.notifications, 1)
+}
+
+func TestDeleteOrderItem_NoContent(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.DELETE("/orders/:order_id/items/:item_id", h.DeleteOrderItem)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodDelete, "/orders/1/items/1", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Empty(t, w.Body.String())
+	assertOrderChange(t, store, models.ChangeRemoved, 0)
+}
+
+func TestDeleteOrderItem_OfAnotherOrder(t *testing.T) {
+	h, store, _ := setupOrderProductHandler(t)
+	seedWallet(t, store, 2)
+	r := gin.Default()
+	r.DELETE("/orders/:order_id/items/:item_id", h.DeleteOrderItem)
 
+	w := performRequest(r, httptest.NewRequest(http.MethodDelete, "/orders/2/items/1", nil))
 
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assertNoOrderChange(t, store, models.OrderProduct{Quantity: 2})
+}
 
+func TestGetOrderProducts_OrderFromPath(t *testing.T) {
+	store := repository.NewMemory()
+	require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{Order_ID: 3, Product_ID: 10, Quantity: 1}))
+	require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{Order_ID: 4, Product_ID: 10, Quantity: 1}))
+	h := &OrderProductHandler{Store: store}
+	r := gin.Default()
+	r.GET("/orders/:order_id/items", h.GetOrderProducts)
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/orders/3/items", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct{ Order_Products []models.OrderProduct }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Order_Products, 1)
+	assert.Equal(t, uint(3), response.Order_Products[0].Order_ID)
+}
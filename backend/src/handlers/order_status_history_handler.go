@@ -1,96 +1,207 @@
 package handlers
 
 import (
+	"app/apierror"
+	"app/blobstore"
 	"app/blockchain"
 	"app/models"
-	"crypto/sha256"
+	"app/repository"
+	"app/requestModels"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type OrderStatusHistoryHandler struct {
-	DB     *gorm.DB
+	Store  repository.Store
 	Client *blockchain.Client
+	Blobs  blobstore.BlobStore
 }
 
 func (h *OrderStatusHistoryHandler) GetOrderStatusByOrderID(c *gin.Context) {
-	id := c.Param("order_id")
-	var orderStatus []models.OrderStatusHistory
-	result := h.DB.Preload("Storage").Where("Order_ID = ?", id).Order("Timestamp_History desc").Find(&orderStatus)
+	id, ok := parseID(c, "order_id")
+	if !ok {
+		return
+	}
 
-	//check if there was an error with the database request
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Order Status not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-		}
+	orderStatus, err := h.Store.History().ListByOrder(c, id)
 
-	} else {
-		c.JSON(http.StatusOK, gin.H{"order_status_history": orderStatus})
+	//check if there was an error with the database request
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
 	}
+
+	c.JSON(http.StatusOK, gin.H{"order_status_history": orderStatus})
 }
 
 func (h *OrderStatusHistoryHandler) AddOrderUpdate(c *gin.Context) {
 
 	//get the order status from the post request
-	var input models.OrderStatusHistory
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad Input"})
+	var request requestModels.AddOrderUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	_, review, err := h.AppendUpdate(c, request)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if review != nil {
+		c.JSON(http.StatusAccepted, gin.H{"message": "Update stored and flagged for review", "location_review": review})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Update stored successfully"})
+}
+
+// CreateOrderEvent adds a status update to the order of the path (v2), responding with the stored update. Updates
+// flagged by the geofence check are accepted (202) with their location review
+func (h *OrderStatusHistoryHandler) CreateOrderEvent(c *gin.Context) {
+	var request requestModels.AddOrderUpdateRequest
+	if !bindOrderRequest(c, &request, &request.Order_ID) {
+		return
+	}
+
+	update, review, err := h.AppendUpdate(c, request)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if review != nil {
+		c.JSON(http.StatusAccepted, gin.H{"event": update, "location_review": review})
 		return
 	}
 
+	c.Header("Location", fmt.Sprintf("/api/v2/orders/%d/events", update.Order_ID))
+	c.JSON(http.StatusCreated, gin.H{"event": update})
+}
+
+// AppendUpdate validates and stores a status update, with its proof of delivery and its location review when it has
+// them. The location review is returned when the update was flagged by the geofence check
+func (h *OrderStatusHistoryHandler) AppendUpdate(ctx context.Context, request requestModels.AddOrderUpdateRequest) (*models.OrderStatusHistory, *models.LocationReview, error) {
+	input := request.OrderStatusHistory
+
+	//the proof hash can only come from a proof of delivery
+	input.Proof_Hash = nil
+	var proof *models.DeliveryProof
+	var proofMedia map[string][]byte
+	if request.ProofOfDelivery != nil {
+		if input.Order_Status != models.StatusDelivered {
+			return nil, nil, serviceError(http.StatusBadRequest, "Proof of delivery is only allowed for DELIVERED updates")
+		}
+
+		var err error
+		proof, proofMedia, err = BuildDeliveryProof(input.Order_ID, request.ProofOfDelivery)
+		if err != nil {
+			return nil, nil, serviceError(http.StatusBadRequest, fmt.Sprintf("Invalid proof of delivery: %v", err))
+		}
+		input.Proof_Hash = &proof.Proof_Hash
+	}
+
 	//assign a value to timestamp if there is none
 	if input.Timestamp_History.IsZero() {
 		input.Timestamp_History = time.Now()
 	}
 
-	if h.Client != nil {
-		//get an instance of the contract
-		auth := h.Client.Auth
-		ethClient := h.Client.EthClient
-		addr := os.Getenv("BLOCKCHAIN_CONTRACT_ADDRESS")
-		contract, err := blockchain.GetContractInstance(ethClient, addr)
+	//shipment updates must refer to a shipment of the same order
+	if input.Shipment_ID != nil {
+		shipment, err := h.Store.Shipments().Get(ctx, *input.Shipment_ID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
-			return
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, nil, serviceError(http.StatusNotFound, "Shipment not found")
+			}
+			return nil, nil, err
+		}
+		if shipment.Order_ID != input.Order_ID {
+			return nil, nil, serviceError(http.StatusBadRequest, "Shipment does not belong to the order")
 		}
+	}
 
-		//hash the main components of the request
-		data := fmt.Sprintf("%d|%s|%s|%s",
-			input.Order_ID,
-			input.Order_Status,
-			input.Timestamp_History.Format(time.RFC3339),
-			input.Order_Location,
-		)
+	//the courier position is only checked when the update is sent with one
+	if (input.Latitude == nil) != (input.Longitude == nil) {
+		return nil, nil, serviceError(http.StatusBadRequest, "Both latitude and longitude are required")
+	}
+	if input.Latitude == nil && proof != nil && proof.Latitude != nil {
+		input.Latitude, input.Longitude = proof.Latitude, proof.Longitude
+	}
+	if input.Latitude != nil && (*input.Latitude < -90 || *input.Latitude > 90 || *input.Longitude < -180 || *input.Longitude > 180) {
+		return nil, nil, serviceError(http.StatusBadRequest, "Invalid coordinates")
+	}
 
-		hash := sha256.Sum256([]byte(data))
+	//updates sent away from where they claim to be are stored but flagged for review
+	review, err := CheckUpdateLocation(ctx, h.Store, &input)
+	if err != nil {
+		if errors.Is(err, ErrStorageNotFound) {
+			return nil, nil, serviceError(http.StatusNotFound, "Storage not found")
+		}
+		return nil, nil, err
+	}
 
-		//store the hash in the blockchain
-		txHash, err := StoreUpdateHash(auth, contract, uint64(input.Order_ID), hash)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save update"})
-			return
+	//store the hash of the update in the blockchain
+	if err := NotarizeUpdate(ctx, h.Client, &input); err != nil {
+		return nil, nil, serviceError(http.StatusInternalServerError, "Failed to save update")
+	}
+
+	if proof == nil && review == nil {
+		//store the update into the database
+		if err := h.Store.History().Create(ctx, &input); err != nil {
+			return nil, nil, serviceError(http.StatusInternalServerError, "Failed to save update")
 		}
-		// Log the transaction hash for debugging
-		if txHash != "" {
-			fmt.Printf("Blockchain transaction hash: %s\n", txHash)
+	} else if err := h.storeUpdateWithRecords(ctx, &input, proof, proofMedia, review); err != nil {
+		return nil, nil, err
+	}
+
+	//the order status follows the status of its shipments
+	if input.Shipment_ID != nil {
+		if err := SyncOrderStatus(ctx, h.Store, h.Client, input.Order_ID, input.Order_Location); err != nil {
+			slog.WarnContext(ctx, "Failed to update the order status from its shipments", "order_id", input.Order_ID, "error", err)
 		}
+	}
 
-		input.Blockchain_Transaction = txHash
+	return &input, review, nil
+}
+
+// stores the update together with its proof of delivery (and media) and its location review, when there are any
+func (h *OrderStatusHistoryHandler) storeUpdateWithRecords(ctx context.Context, update *models.OrderStatusHistory, proof *models.DeliveryProof, media map[string][]byte, review *models.LocationReview) error {
+	if len(media) > 0 && h.Blobs == nil {
+		return serviceError(http.StatusInternalServerError, "Proof of delivery storage not configured")
 	}
 
-	//store the update into the database
-	if err := h.DB.Create(&input).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save update"})
-		return
+	for key, data := range media {
+		if err := h.Blobs.Put(key, data); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to store the proof of delivery")
+		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Update stored successfully"})
+	//the next operations are made inside a transaction to ensure atomicity
+	return h.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.History().Create(ctx, update); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to save update")
+		}
+
+		if proof != nil {
+			proof.Order_Status_History_ID = update.Id
+			if err := tx.DeliveryProofs().Create(ctx, proof); err != nil {
+				return serviceError(http.StatusInternalServerError, "Failed to store the proof of delivery")
+			}
+		}
+
+		if review != nil {
+			review.Order_Status_History_ID = update.Id
+			if err := tx.LocationReviews().Create(ctx, review); err != nil {
+				return serviceError(http.StatusInternalServerError, "Failed to flag the update for review")
+			}
+		}
+		return nil
+	})
 }
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"app/apierror"
 	"app/models"
+	"app/repository"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -10,71 +13,59 @@ import (
 	"testing"
 	"time"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/gorm"
+	"github.com/stretchr/testify/require"
 )
 
 // --- GetOrderStatusByOrderID Tests ---
 
 func TestGetOrderStatusByOrderID_Success(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db}
+	store := repository.NewMemory()
+	storageID := uint(3)
+	require.NoError(t, store.Storages().Create(context.Background(), &models.Storage{Id: storageID, Name: "Warehouse A"}))
+	ts := time.Now()
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{Order_ID: 1, Timestamp_History: ts.Add(-time.Hour), Order_Status: "PROCESSING", Note: "Order received", Order_Location: "Origin"}))
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{Order_ID: 1, Timestamp_History: ts, Order_Status: "SHIPPED", Note: "Shipped to customer", Order_Location: "Warehouse A", Storage_ID: &storageID}))
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{Order_ID: 2, Timestamp_History: ts, Order_Status: "PROCESSING", Order_Location: "Origin"}))
+	h := &OrderStatusHistoryHandler{Store: store}
 	r := gin.Default()
 	r.GET("/order/:order_id/status", h.GetOrderStatusByOrderID)
 
-	ts := time.Now()
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE Order_ID = \$1 ORDER BY Timestamp_History desc`).
-		WithArgs("1").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "timestamp_history", "order_status", "note", "order_location"}).
-			AddRow(2, 1, ts, "SHIPPED", "Shipped to customer", "Warehouse A").
-			AddRow(1, 1, ts.Add(-time.Hour), "PROCESSING", "Order received", "Origin"))
-
-	// Expect preload for Storage
-	mock.ExpectQuery(`SELECT \* FROM "storage" WHERE "storage"."id" IN \(\$1,\$2\)`).
-		WithArgs(nil, nil).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
-
 	req := httptest.NewRequest(http.MethodGet, "/order/1/status", nil)
 	w := performRequest(r, req)
 
-	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "order_status_history")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		History []models.OrderStatusHistory `json:"order_status_history"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.History, 2)
+	assert.Equal(t, "SHIPPED", response.History[0].Order_Status, "newest update first")
+	require.NotNil(t, response.History[0].Storage)
+	assert.Equal(t, "Warehouse A", response.History[0].Storage.Name)
 }
 
 func TestGetOrderStatusByOrderID_EmptyHistory(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db}
+	h := &OrderStatusHistoryHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.GET("/order/:order_id/status", h.GetOrderStatusByOrderID)
 
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE Order_ID = \$1 ORDER BY Timestamp_History desc`).
-		WithArgs("999").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "timestamp_history", "order_status", "note", "order_location"}))
-
-	// Empty preload
-	mock.ExpectQuery(`SELECT \* FROM "storage"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
-
 	req := httptest.NewRequest(http.MethodGet, "/order/999/status", nil)
 	w := performRequest(r, req)
 
 	// Should return 200 with empty array
 	assert.Equal(t, http.StatusOK, w.Code)
-	assert.Contains(t, w.Body.String(), "order_status_history")
+	assert.JSONEq(t, `{"order_status_history": []}`, w.Body.String())
 }
 
 func TestGetOrderStatusByOrderID_DBError(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db}
+	store := repository.NewMemory()
+	store.Fail("History.ListByOrder", errors.New("db failure"))
+	h := &OrderStatusHistoryHandler{Store: store}
 	r := gin.Default()
 	r.GET("/order/:order_id/status", h.GetOrderStatusByOrderID)
 
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE Order_ID = \$1`).
-		WithArgs("1").
-		WillReturnError(errors.New("db failure"))
-
 	req := httptest.NewRequest(http.MethodGet, "/order/1/status", nil)
 	w := performRequest(r, req)
 
@@ -84,8 +75,7 @@ func TestGetOrderStatusByOrderID_DBError(t *testing.T) {
 // --- AddOrderUpdate Tests ---
 
 func TestAddOrderUpdate_BadInput(t *testing.T) {
-	db, _ := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db}
+	h := &OrderStatusHistoryHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.POST("/order/update", h.AddOrderUpdate)
 
@@ -96,16 +86,28 @@ func TestAddOrderUpdate_BadInput(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
 
+func TestAddOrderUpdate_UnknownStatus(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/update", h.AddOrderUpdate)
+
+	w := postJSON(r, "/order/update", models.OrderStatusHistory{Order_ID: 1, Order_Status: "IN_TRANSIT", Order_Location: "Porto"})
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response apierror.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, apierror.CodeValidation, response.Code)
+	assert.Equal(t, []apierror.FieldError{{Field: "Order_Status", Message: "must be a known order status"}}, response.Details)
+	assert.Empty(t, orderStatuses(t, store, 1), "nothing is stored")
+}
+
 func TestAddOrderUpdate_Success_NoBlockchain(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db, Client: nil} // No blockchain
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store, Client: nil} // No blockchain
 	r := gin.Default()
 	r.POST("/order/update", h.AddOrderUpdate)
 
-	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	payload := models.OrderStatusHistory{
 		Order_ID:       1,
@@ -121,18 +123,19 @@ func TestAddOrderUpdate_Success_NoBlockchain(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Update stored successfully")
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Package shipped", latest.Note)
+	assert.Equal(t, "Warehouse B", latest.Order_Location)
 }
 
 func TestAddOrderUpdate_DBCreateError(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db, Client: nil}
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store, Client: nil}
 	r := gin.Default()
 	r.POST("/order/update", h.AddOrderUpdate)
 
-	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-		WillReturnError(errors.New("db insert failed"))
-	mock.ExpectRollback()
+	store.Fail("History.Create", errors.New("db insert failed"))
 
 	payload := models.OrderStatusHistory{
 		Order_ID:       1,
@@ -147,23 +150,21 @@ func TestAddOrderUpdate_DBCreateError(t *testing.T) {
 	w := performRequest(r, req)
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	store.Fail("History.Create", nil)
+	assert.Empty(t, orderStatuses(t, store, 1))
 }
 
 func TestAddOrderUpdate_TimestampDefault(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db, Client: nil}
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store, Client: nil}
 	r := gin.Default()
 	r.POST("/order/update", h.AddOrderUpdate)
 
-	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	// Payload without timestamp - should use current time
 	payload := models.OrderStatusHistory{
 		Order_ID:       1,
-		Order_Status:   "IN_TRANSIT",
+		Order_Status:   models.StatusInTransit,
 		Note:           "Package in transit",
 		Order_Location: "Hub C",
 	}
@@ -174,24 +175,23 @@ func TestAddOrderUpdate_TimestampDefault(t *testing.T) {
 	w := performRequest(r, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), latest.Timestamp_History, time.Minute)
 }
 
 // --- Tracking State Tests ---
 
 func TestAddOrderUpdate_AllTrackingStates(t *testing.T) {
-	trackingStates := []string{"PROCESSING", "SHIPPED", "IN_TRANSIT", "DELIVERED"}
+	trackingStates := []string{"PROCESSING", "SHIPPED", models.StatusInTransit, "DELIVERED"}
 
 	for _, state := range trackingStates {
 		t.Run("State_"+state, func(t *testing.T) {
-			db, mock := setupMockDB(t)
-			h := &OrderStatusHistoryHandler{DB: db, Client: nil}
+			store := repository.NewMemory()
+			h := &OrderStatusHistoryHandler{Store: store, Client: nil}
 			r := gin.Default()
 			r.POST("/order/update", h.AddOrderUpdate)
 
-			mock.ExpectBegin()
-			mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-			mock.ExpectCommit()
 
 			payload := models.OrderStatusHistory{
 				Order_ID:       1,
@@ -207,20 +207,17 @@ func TestAddOrderUpdate_AllTrackingStates(t *testing.T) {
 
 			assert.Equal(t, http.StatusOK, w.Code, "Failed for state: %s", state)
 			assert.Contains(t, w.Body.String(), "Update stored successfully")
+			assert.Equal(t, []string{state}, orderStatuses(t, store, 1))
 		})
 	}
 }
 
 func TestAddOrderUpdate_WithStorageID(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db, Client: nil}
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store, Client: nil}
 	r := gin.Default()
 	r.POST("/order/update", h.AddOrderUpdate)
 
-	mock.ExpectBegin()
-	mock.ExpectQuery(`INSERT INTO "order_status_history"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
-	mock.ExpectCommit()
 
 	storageID := uint(5)
 	payload := models.OrderStatusHistory{
@@ -237,22 +234,55 @@ func TestAddOrderUpdate_WithStorageID(t *testing.T) {
 	w := performRequest(r, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, &storageID, latest.Storage_ID)
 }
 
 // --- Edge Case Tests ---
 
-func TestGetOrderStatusByOrderID_RecordNotFoundError(t *testing.T) {
-	db, mock := setupMockDB(t)
-	h := &OrderStatusHistoryHandler{DB: db}
+func TestGetOrderStatusByOrderID_InvalidID(t *testing.T) {
+	h := &OrderStatusHistoryHandler{Store: repository.NewMemory()}
 	r := gin.Default()
 	r.GET("/order/:order_id/status", h.GetOrderStatusByOrderID)
 
-	mock.ExpectQuery(`SELECT \* FROM "order_status_history" WHERE Order_ID = \$1`).
-		WithArgs("1").
-		WillReturnError(gorm.ErrRecordNotFound)
+	req := httptest.NewRequest(http.MethodGet, "/order/abc/status", nil)
+	w := performRequest(r, req)
 
-	req := httptest.NewRequest(http.MethodGet, "/order/1/status", nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateOrderEvent_OrderFromPath(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/orders/:order_id/events", h.CreateOrderEvent)
+
+
+	// the order of the path is used, the body does not need one
+	body, _ := json.Marshal(gin.H{"Order_Status": models.StatusShipped, "Order_Location": "Warehouse B"})
+	req := httptest.NewRequest(http.MethodPost, "/orders/7/events", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
 	w := performRequest(r, req)
 
-	assert.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var response struct{ Event models.OrderStatusHistory }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, uint(1), response.Event.Id)
+	assert.Equal(t, uint(7), response.Event.Order_ID)
+	assert.Equal(t, "/api/v2/orders/7/events", w.Header().Get("Location"))
+	assert.Equal(t, []string{models.StatusShipped}, orderStatuses(t, store, 7))
+}
+
+func TestCreateOrderEvent_InvalidOrder(t *testing.T) {
+	h := &OrderStatusHistoryHandler{}
+	r := gin.Default()
+	r.POST("/orders/:order_id/events", h.CreateOrderEvent)
+
+	body, _ := json.Marshal(gin.H{"Order_Status": models.StatusShipped})
+	req := httptest.NewRequest(http.MethodPost, "/orders/abc/events", bytes.NewBuffer(body))
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid order id")
 }
@@ -1,92 +1,74 @@
 package handlers
 
 import (
-	"app/models"
-	"app/requestModels"
-	"encoding/json"
-	"fmt"
+	"app/apierror"
+	"app/catalog"
+	"app/repository"
 	"net/http"
-	"os"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+)
+
+// Pagination of the product list
+const (
+	DefaultProductPageSize = 20
+	MaxProductPageSize     = 100
 )
 
 type ProductHandler struct {
-	DB *gorm.DB
+	Store   repository.Store
+	Catalog *catalog.Client
 }
 
-var GetProductByIDAPIFunc = GetProductByIDAPI
-
-// GetAllProducts retrieves all available products from the jumpseller API
+// GetAllProducts retrieves the products of the local catalogue.
+// Query params: q (searches the name and SKU), page (starting at 1) and limit
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	client := &http.Client{}
-	url := fmt.Sprintf("%s/products.json", os.Getenv("JUMPSELLER_BASE_URL"))
-	req, err := http.NewRequest("GET",url, nil)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		apierror.Respond(c, http.StatusBadRequest, "Invalid page")
 		return
 	}
-
-	req.SetBasicAuth(os.Getenv("LOGIN_JUMPSELLER_API"), os.Getenv("TOKEN_JUMPSELLER_API"))
-
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err})
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultProductPageSize)))
+	if err != nil || limit < 1 || limit > MaxProductPageSize {
+		apierror.Respond(c, http.StatusBadRequest, "Invalid limit")
 		return
 	}
 
-	var result []requestModels.ProductResponse
-	json.NewDecoder(resp.Body).Decode(&result)
-	err = resp.Body.Close()
-
+	products, total, err := h.Store.Products().List(c, strings.TrimSpace(c.Query("q")), (page-1)*limit, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error":  err})
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"products": result})
-}
 
-// GetProductByID retrieves a specific product by ID from the jump seller API
-func (h *ProductHandler) GetProductByID(c *gin.Context) {
-	id := c.Param("id")
-
-	var product *models.Product
-	product, err := GetProductByIDAPIFunc(id)
-	if err != nil{
-		c.JSON(http.StatusBadRequest, gin.H{"error": err})
-	}
-
-	c.JSON(http.StatusOK, gin.H{"product": &product})
+	c.JSON(http.StatusOK, gin.H{
+		"products": products,
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+	})
 }
 
-//gets a product from the Jumpseller API
-func GetProductByIDAPI(id string) (*models.Product, error) {
-	client := &http.Client{}
-	url := fmt.Sprintf("%s/products/%s.json", os.Getenv("JUMPSELLER_BASE_URL"), id)
-	req, err := http.NewRequest("GET",url, nil)
+// GetProductByID retrieves a product from the local catalogue (or from Jumpseller when it was not synced yet)
+func (h *ProductHandler) GetProductByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		return nil, err
+		apierror.Respond(c, http.StatusBadRequest, "Invalid product id")
+		return
 	}
 
-	req.SetBasicAuth(os.Getenv("LOGIN_JUMPSELLER_API"), os.Getenv("TOKEN_JUMPSELLER_API"))
-
-	resp, err := client.Do(req)
+	products, err := catalog.LookupProducts(c, h.Store.Products(), h.Catalog, []uint{uint(id)})
 	if err != nil {
-		return nil, err
+		apierror.Respond(c, http.StatusBadGateway, "Failed to retrieve the product")
+		return
 	}
 
-	var result requestModels.ProductResponse
-	json.NewDecoder(resp.Body).Decode(&result)
-	err = resp.Body.Close()
-
-	if err != nil {
-		return nil, err
+	product, ok := products[uint(id)]
+	if !ok {
+		apierror.Respond(c, http.StatusNotFound, "Product not found")
+		return
 	}
-	
-	var product models.Product
-	product.ID = uint(result.Product.ID)
-	product.Name = result.Product.Name
-	product.Price = result.Product.Price
 
-	return &product, nil
+	c.JSON(http.StatusOK, gin.H{"product": product})
 }
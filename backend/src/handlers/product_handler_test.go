@@ -1,140 +1,135 @@
 package handlers
 
 import (
+	"app/catalog"
 	"app/models"
+	"app/repository"
 	"app/requestModels"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 )
 
-
-
-func setupTestEnv() {
-	os.Setenv("JUMPSELLER_BASE_URL", "http://mock-api")
-	os.Setenv("LOGIN_JUMPSELLER_API", "user")
-	os.Setenv("TOKEN_JUMPSELLER_API", "token")
-}
-
 func init() {
 	gin.SetMode(gin.TestMode)
 }
 
-func TestGetAllProducts(t *testing.T) {
-	setupTestEnv()
-
-	// Mock external Jumpseller API
-	mockProducts := []requestModels.ProductResponse{
-		{Product: requestModels.Product{
-			ID:    1,
-			Name:  "Test Product",
-			Price: 10.5,
-		}},
-	}
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockProducts)
-	}))
-	defer server.Close()
-
-	// Override env to point to mock server
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
-
-	// Prepare Gin context
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
+// creates a product handler backed by a fake Jumpseller API
+func setupProductHandler(t *testing.T, products ...requestModels.Product) (*ProductHandler, sqlmock.Sqlmock, *catalog.FakeServer) {
+	db, mock := setupMockDB(t)
+	server := catalog.NewFakeServer(products...)
+	t.Cleanup(server.Close)
+	return &ProductHandler{Store: repository.NewPostgres(db), Catalog: catalog.NewClient(server.Config())}, mock, server
+}
 
-	handler := ProductHandler{}
-	handler.GetAllProducts(c)
+func TestGetAllProducts(t *testing.T) {
+	h, mock, server := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products", h.GetAllProducts)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE status IS NULL OR status <> \$1`).
+		WithArgs(models.ProductDisabled).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE status IS NULL OR status <> \$1 ORDER BY name asc LIMIT \$2`).
+		WithArgs(models.ProductDisabled, DefaultProductPageSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "weight"}).AddRow(1, "Test Product", 10.5, 0.4))
+	mock.ExpectQuery(`SELECT \* FROM "product_variants" WHERE "product_variants"."product_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id", "sku", "options"}).AddRow(7, 1, "TP-M", `[{"Name":"Size","Value":"M"}]`))
+
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	w := performRequest(r, req)
 
 	if w.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", w.Code)
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
 	}
 
-	var response map[string][]requestModels.ProductResponse
+	var response struct {
+		Products []models.Product
+		Total    int
+	}
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	if len(response["products"]) != 1 {
-		t.Fatalf("expected 1 product, got %d", len(response["products"]))
+	if len(response.Products) != 1 || response.Total != 1 {
+		t.Fatalf("expected 1 product, got %d", len(response.Products))
 	}
 
-	if response["products"][0].Product.Name != "Test Product" {
-		t.Fatalf("unexpected product name")
+	if response.Products[0].Name != "Test Product" || response.Products[0].Variants[0].Options[0].Value != "M" {
+		t.Fatalf("unexpected product: %+v", response.Products[0])
 	}
-}
-
-func TestGetAllProducts_Success(t *testing.T) {
-	setupTestEnv()
 
-	mockProducts := []requestModels.ProductResponse{
-		{Product: requestModels.Product{ID: 1, Name: "Test", Price: 10}},
+	if server.Requests() != 0 {
+		t.Fatalf("products should be served from the local catalogue")
 	}
+}
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockProducts)
-	}))
-	defer server.Close()
-
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
+func TestGetAllProducts_SearchAndPagination(t *testing.T) {
+	h, mock, _ := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products", h.GetAllProducts)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products" WHERE \(status IS NULL OR status <> \$1\) AND \(lower\(name\) LIKE \$2 OR lower\(sku\) LIKE \$3\)`).
+		WithArgs(models.ProductDisabled, "%cork%", "%cork%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE .* ORDER BY name asc LIMIT \$4 OFFSET \$5`).
+		WithArgs(models.ProductDisabled, "%cork%", "%cork%", 5, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
 
-	handler := ProductHandler{}
-	handler.GetAllProducts(c)
+	req := httptest.NewRequest(http.MethodGet, "/products?q=Cork&page=3&limit=5", nil)
+	w := performRequest(r, req)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d", w.Code)
-	}
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":12`)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestGetAllProducts_HTTPClientError(t *testing.T) {
-	setupTestEnv()
+func TestGetAllProducts_InvalidPagination(t *testing.T) {
+	h, _, _ := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products", h.GetAllProducts)
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		hj, _ := w.(http.Hijacker)
-		conn, _, _ := hj.Hijack()
-		conn.Close()
-	}))
-	defer server.Close()
+	for _, query := range []string{"page=0", "page=abc", "limit=0", "limit=1000"} {
+		req := httptest.NewRequest(http.MethodGet, "/products?"+query, nil)
+		w := performRequest(r, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
 
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
+func TestGetAllProducts_DBError(t *testing.T) {
+	h, mock, _ := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products", h.GetAllProducts)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "products"`).WillReturnError(errors.New("db failure"))
 
-	handler := ProductHandler{}
-	handler.GetAllProducts(c)
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	w := performRequest(r, req)
 
 	if w.Code != http.StatusInternalServerError {
 		t.Fatalf("expected 500, got %d", w.Code)
 	}
 }
 
-
 func TestGetProductByID(t *testing.T) {
-	setupTestEnv()
-
-	// Mock the API function
-	GetProductByIDAPIFunc = func(id string) (*models.Product, error) {
-		return &models.Product{
-			ID:    1,
-			Name:  "Mocked Product",
-			Price: 20.0,
-		}, nil
-	}
+	h, mock, server := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products/:id", h.GetProductByID)
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE id IN \(\$1\)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price"}).AddRow(1, "Local Product", 20.0))
+	mock.ExpectQuery(`SELECT \* FROM "product_variants"`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "product_id"}))
 
-	handler := ProductHandler{}
-	handler.GetProductByID(c)
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	w := performRequest(r, req)
 
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", w.Code)
@@ -143,119 +138,89 @@ func TestGetProductByID(t *testing.T) {
 	var response map[string]*models.Product
 	json.Unmarshal(w.Body.Bytes(), &response)
 
-	if response["product"].Name != "Mocked Product" {
-		t.Fatalf("unexpected product name")
+	if response["product"].Name != "Local Product" || response["product"].Price != 20.0 {
+		t.Fatalf("unexpected product: %+v", response["product"])
 	}
-}
 
-func TestGetProductByID_Success(t *testing.T) {
-	GetProductByIDAPIFunc = func(id string) (*models.Product, error) {
-		return &models.Product{ID: 1, Name: "OK"}, nil
+	if server.Requests() != 0 {
+		t.Fatalf("the API should not be called")
 	}
+}
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Params = []gin.Param{{Key: "id", Value: "1"}}
+func TestGetProductByID_NotSyncedYet(t *testing.T) {
+	h, mock, _ := setupProductHandler(t, requestModels.Product{ID: 5, Name: "API Product", Price: 99.99, Weight: 1.2})
+	r := gin.Default()
+	r.GET("/products/:id", h.GetProductByID)
 
-	handler := ProductHandler{}
-	handler.GetProductByID(c)
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE id IN \(\$1\)`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d", w.Code)
-	}
-}
+	req := httptest.NewRequest(http.MethodGet, "/products/5", nil)
+	w := performRequest(r, req)
 
-func TestGetProductByID_Error(t *testing.T) {
-	GetProductByIDAPIFunc = func(id string) (*models.Product, error) {
-		return nil, fmt.Errorf("not found")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 
-	w := httptest.NewRecorder()
-	c, _ := gin.CreateTestContext(w)
-	c.Params = []gin.Param{{Key: "id", Value: "1"}}
-
-	handler := ProductHandler{}
-	handler.GetProductByID(c)
+	var response map[string]*models.Product
+	json.Unmarshal(w.Body.Bytes(), &response)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected 400, got %d", w.Code)
+	if response["product"].Name != "API Product" || response["product"].Weight != 1.2 {
+		t.Fatalf("unexpected product: %+v", response["product"])
 	}
 }
 
+func TestGetProductByID_NotFound(t *testing.T) {
+	h, mock, _ := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products/:id", h.GetProductByID)
 
-func TestGetProductByIDAPI(t *testing.T) {
-	setupTestEnv()
+	mock.ExpectQuery(`SELECT \* FROM "products"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	mockResponse := requestModels.ProductResponse{
-		Product: requestModels.Product{
-			ID:    5,
-			Name:  "API Product",
-			Price: 99.99,
-		},
-	}
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	w := performRequest(r, req)
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(mockResponse)
-	}))
-	defer server.Close()
-
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
-
-	product, err := GetProductByIDAPI("5")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
 	}
+}
 
-	if product.Name != "API Product" {
-		t.Fatalf("expected API Product, got %s", product.Name)
-	}
+func TestGetProductByID_InvalidID(t *testing.T) {
+	h, _, server := setupProductHandler(t)
+	r := gin.Default()
+	r.GET("/products/:id", h.GetProductByID)
 
-	if product.Price != 99.99 {
-		t.Fatalf("unexpected price")
-	}
-}
+	req := httptest.NewRequest(http.MethodGet, "/products/abc", nil)
+	w := performRequest(r, req)
 
-func TestGetProductByIDAPI_Success(t *testing.T) {
-	setupTestEnv()
-
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(requestModels.ProductResponse{
-			Product: requestModels.Product{
-				ID:    10,
-				Name:  "Test",
-				Price: 99.9,
-			},
-		})
-	}))
-	defer server.Close()
-
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
-
-	product, err := GetProductByIDAPI("10")
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
 	}
 
-	if product.Name != "Test" {
-		t.Fatalf("wrong product name")
+	if server.Requests() != 0 {
+		t.Fatalf("the API should not be called")
 	}
 }
 
-func TestGetProductByIDAPI_HTTPError(t *testing.T) {
-	setupTestEnv()
+func TestGetProductByID_APIError(t *testing.T) {
+	h, mock, server := setupProductHandler(t, requestModels.Product{ID: 1, Name: "OK"})
+	server.FailNext(10, http.StatusInternalServerError)
+	r := gin.Default()
+	r.GET("/products/:id", h.GetProductByID)
 
-	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		hj, _ := w.(http.Hijacker)
-		conn, _, _ := hj.Hijack()
-		conn.Close()
-	}))
-	defer server.Close()
+	mock.ExpectQuery(`SELECT \* FROM "products"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	os.Setenv("JUMPSELLER_BASE_URL", server.URL)
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	w := performRequest(r, req)
 
-	_, err := GetProductByIDAPI("10")
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("expected 502, got %d", w.Code)
 	}
-}
 
+	if !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("expected a single JSON error, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/audit"
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Time after delivery in which the customer can still return the order
+const ReturnWindow = 14 * 24 * time.Hour
+
+// Number of failed delivery attempts before the order is returned to the seller
+const MaxDeliveryAttempts = 3
+
+// Reason codes accepted for failed delivery attempts
+var FailedDeliveryReasons = map[string]string{
+	"RECIPIENT_ABSENT": "Recipient not available",
+	"WRONG_ADDRESS":    "Address incorrect or incomplete",
+	"REFUSED":          "Delivery refused by the recipient",
+	"ACCESS_DENIED":    "No access to the delivery location",
+	"DAMAGED":          "Parcel damaged",
+	"OTHER":            "Other reason",
+}
+
+type ReturnHandler struct {
+	Store  repository.Store
+	Client *blockchain.Client
+	Audit  *audit.Logger
+}
+
+// RequestReturn registers the return of a delivered order, requested by the customer. The order is RETURNED once the
+// return is completed (CompleteReturn)
+func (h *ReturnHandler) RequestReturn(c *gin.Context) {
+	var input requestModels.ReturnOrderRequest
+	if !bindOrderRequest(c, &input, &input.OrderID) {
+		return
+	}
+
+	order, ok := h.findOrder(c, input.OrderID)
+	if !ok {
+		return
+	}
+
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Only delivered orders can be returned and only inside the return window
+	if latestStatus == nil || latestStatus.Order_Status != models.StatusDelivered {
+		status := "NONE"
+		if latestStatus != nil {
+			status = latestStatus.Order_Status
+		}
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot return order with status: %s", status))
+		return
+	}
+
+	if time.Since(latestStatus.Timestamp_History) > ReturnWindow {
+		apierror.Respond(c, http.StatusForbidden, "The return window for this order has expired")
+		return
+	}
+
+	requestedStatus := models.OrderStatusHistory{
+		Order_ID:          order.Id,
+		Order_Status:      models.StatusReturnRequested,
+		Timestamp_History: time.Now(),
+		Order_Location:    order.Delivery_Address,
+		Note:              fmt.Sprintf("Return requested by the customer: %s", input.Reason),
+	}
+
+	if err := NotarizeUpdate(c, h.Client, &requestedStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+		return
+	}
+
+	if err := h.Store.History().Create(c, &requestedStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Return registered successfully",
+		"order_id": order.Id,
+		"status":   models.StatusReturnRequested,
+	})
+}
+
+// CompleteReturn registers that a requested return is back with the seller, the order is then RETURNED
+func (h *ReturnHandler) CompleteReturn(c *gin.Context) {
+	var input requestModels.CompleteReturnRequest
+	if !bindOrderRequest(c, &input, &input.OrderID) {
+		return
+	}
+
+	order, ok := h.findOrder(c, input.OrderID)
+	if !ok {
+		return
+	}
+
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// Only a requested return can be completed
+	if latestStatus == nil || latestStatus.Order_Status != models.StatusReturnRequested {
+		status := "NONE"
+		if latestStatus != nil {
+			status = latestStatus.Order_Status
+		}
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot complete the return of order with status: %s", status))
+		return
+	}
+
+	location := input.OrderLocation
+	if location == "" {
+		location = order.Seller_Address
+	}
+	note := "Return received by the seller"
+	if input.Note != "" {
+		note = fmt.Sprintf("%s. %s", note, input.Note)
+	}
+
+	returnedStatus := models.OrderStatusHistory{
+		Order_ID:          order.Id,
+		Order_Status:      models.StatusReturned,
+		Timestamp_History: time.Now(),
+		Order_Location:    location,
+		Note:              note,
+	}
+
+	if err := NotarizeUpdate(c, h.Client, &returnedStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+		return
+	}
+
+	if err := h.Store.History().Create(c, &returnedStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Return completed successfully",
+		"order_id": order.Id,
+		"status":   models.StatusReturned,
+	})
+}
+
+// RecordFailedDelivery registers a failed delivery attempt and schedules a new one.
+// After MaxDeliveryAttempts failed attempts the order is returned to the seller
+func (h *ReturnHandler) RecordFailedDelivery(c *gin.Context) {
+	var input requestModels.FailedDeliveryRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	reason, ok := FailedDeliveryReasons[input.ReasonCode]
+	if !ok {
+		apierror.Respond(c, http.StatusBadRequest, fmt.Sprintf("Unknown reason code: %s", input.ReasonCode))
+		return
+	}
+
+	redeliveryDate := time.Now().AddDate(0, 0, 1)
+	if input.RedeliveryDate != "" {
+		date, err := time.Parse("2006-01-02", input.RedeliveryDate)
+		if err != nil || date.Before(time.Now().Truncate(24*time.Hour)) {
+			apierror.Respond(c, http.StatusBadRequest, "Invalid redelivery date")
+			return
+		}
+		redeliveryDate = date
+	}
+
+	order, ok := h.findOrder(c, input.OrderID)
+	if !ok {
+		return
+	}
+
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// A delivery can only fail while the order is out for delivery
+	if latestStatus == nil || latestStatus.Order_Status != models.StatusOutForDelivery {
+		status := "NONE"
+		if latestStatus != nil {
+			status = latestStatus.Order_Status
+		}
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot record a failed delivery for order with status: %s", status))
+		return
+	}
+
+	updates, err := h.Store.History().ListByOrder(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	attempt := 1
+	for _, update := range updates {
+		if update.Order_Status == models.StatusFailedDelivery {
+			attempt++
+		}
+	}
+
+	location := input.OrderLocation
+	if location == "" {
+		location = order.Delivery_Address
+	}
+
+	note := fmt.Sprintf("Delivery attempt %d of %d failed (%s): %s", attempt, MaxDeliveryAttempts, input.ReasonCode, reason)
+	if input.Note != "" {
+		note = fmt.Sprintf("%s. %s", note, input.Note)
+	}
+
+	failedStatus := models.OrderStatusHistory{
+		Order_ID:          order.Id,
+		Order_Status:      models.StatusFailedDelivery,
+		Timestamp_History: time.Now(),
+		Order_Location:    location,
+		Note:              note,
+	}
+
+	// No attempts left, the order goes back to the seller
+	var returnedStatus *models.OrderStatusHistory
+	if attempt >= MaxDeliveryAttempts {
+		returnedStatus = &models.OrderStatusHistory{
+			Order_ID:          order.Id,
+			Order_Status:      models.StatusReturned,
+			Timestamp_History: failedStatus.Timestamp_History.Add(time.Second),
+			Order_Location:    location,
+			Note:              fmt.Sprintf("Returned to the seller after %d failed delivery attempts", attempt),
+		}
+	}
+
+	//the updates are notarized before the transaction is opened, it is not held during the chain calls
+	if err := NotarizeUpdate(c, h.Client, &failedStatus); err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+		return
+	}
+	if returnedStatus != nil {
+		if err := NotarizeUpdate(c, h.Client, returnedStatus); err != nil {
+			apierror.Respond(c, http.StatusInternalServerError, "Failed to save update")
+			return
+		}
+	}
+
+	//the next operations are made inside a transaction to ensure atomicity
+	audited := h.Audit.Batch()
+	err = h.Store.Transaction(c, func(tx repository.Store) error {
+		if err := tx.History().Create(c, &failedStatus); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to save update")
+		}
+
+		if returnedStatus != nil {
+			if err := tx.History().Create(c, returnedStatus); err != nil {
+				return serviceError(http.StatusInternalServerError, "Failed to save update")
+			}
+			return nil
+		}
+
+		// Schedule the next delivery attempt
+		before := *order
+		order.Delivery_Estimate = redeliveryDate
+		if err := tx.Orders().Update(c, order, "delivery_estimate"); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to schedule redelivery")
+		}
+		if _, err := audited.Record(tx.AuditEvents(), c, audit.Change{OrderID: order.Id, Table: audit.TableOrders, RecordID: order.Id, Action: models.AuditUpdate, Before: before, After: order}); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to schedule redelivery")
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if returnedStatus != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Maximum delivery attempts reached, order returned to the seller",
+			"order_id":     order.Id,
+			"attempt":      attempt,
+			"max_attempts": MaxDeliveryAttempts,
+			"status":       models.StatusReturned,
+		})
+		return
+	}
+	audited.Anchor(c, h.Store.AuditEvents())
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Failed delivery recorded, redelivery scheduled",
+		"order_id":        order.Id,
+		"attempt":         attempt,
+		"max_attempts":    MaxDeliveryAttempts,
+		"status":          models.StatusFailedDelivery,
+		"redelivery_date": redeliveryDate.Format("2006-01-02"),
+	})
+}
+
+// fetches the order and writes the error response if it does not exist
+func (h *ReturnHandler) findOrder(c *gin.Context, orderID uint) (*models.Orders, bool) {
+	order, err := h.Store.Orders().Get(c, orderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("Order with id %d not found", orderID))
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return nil, false
+	}
+
+	return order, true
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedUpdate stores a status update of an order
+func seedUpdate(t *testing.T, store *repository.Memory, orderID uint, status string, timestamp time.Time) {
+	t.Helper()
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{
+		Order_ID: orderID, Order_Status: status, Timestamp_History: timestamp, Order_Location: "Porto",
+	}))
+}
+
+// orderStatuses returns the statuses of the updates of an order, newest first
+func orderStatuses(t *testing.T, store *repository.Memory, orderID uint) []string {
+	t.Helper()
+	updates, err := store.History().ListByOrder(context.Background(), orderID)
+	require.NoError(t, err)
+	statuses := make([]string, 0, len(updates))
+	for _, update := range updates {
+		statuses = append(statuses, update.Order_Status)
+	}
+	return statuses
+}
+
+func postJSON(r *gin.Engine, path string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	return performRequest(r, req)
+}
+
+func TestRequestReturn_Success(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/return", h.RequestReturn)
+
+	seedOrder(t, store, 1, "")
+	seedUpdate(t, store, 1, models.StatusDelivered, time.Now().Add(-48*time.Hour))
+
+	w := postJSON(r, "/order/return", requestModels.ReturnOrderRequest{OrderID: 1, Reason: "Wrong size"})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), models.StatusReturnRequested)
+
+	// the order is not returned yet, only once the return is completed
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusReturnRequested, latest.Order_Status)
+	assert.Equal(t, "Rua Nova", latest.Order_Location)
+	assert.Contains(t, latest.Note, "Wrong size")
+
+	w = postJSON(r, "/order/return", requestModels.ReturnOrderRequest{OrderID: 1})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Cannot return order with status: RETURN REQUESTED")
+}
+
+func TestCompleteReturn(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/orders/:order_id/return/completion", h.CompleteReturn)
+
+	seedOrder(t, store, 1, "")
+	seedUpdate(t, store, 1, models.StatusDelivered, time.Now().Add(-48*time.Hour))
+	seedUpdate(t, store, 1, models.StatusReturnRequested, time.Now().Add(-time.Hour))
+
+	w := postJSON(r, "/orders/1/return/completion", requestModels.CompleteReturnRequest{Note: "Parcel intact"})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusReturned, latest.Order_Status)
+	assert.Equal(t, "Seller", latest.Order_Location, "the return is back with the seller")
+	assert.Equal(t, "Return received by the seller. Parcel intact", latest.Note)
+}
+
+func TestCompleteReturn_NotRequested(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/orders/:order_id/return/completion", h.CompleteReturn)
+
+	seedOrder(t, store, 1, models.StatusDelivered)
+
+	w := postJSON(r, "/orders/1/return/completion", requestModels.CompleteReturnRequest{})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Cannot complete the return of order with status: DELIVERED")
+	assert.Equal(t, []string{models.StatusDelivered}, orderStatuses(t, store, 1))
+}
+
+func TestRequestReturn_NotDelivered(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/return", h.RequestReturn)
+
+	seedOrder(t, store, 1, models.StatusInTransit)
+
+	w := postJSON(r, "/order/return", requestModels.ReturnOrderRequest{OrderID: 1})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Cannot return order with status: IN TRANSIT")
+	assert.Equal(t, []string{models.StatusInTransit}, orderStatuses(t, store, 1))
+}
+
+func TestRequestReturn_WindowExpired(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/return", h.RequestReturn)
+
+	seedOrder(t, store, 1, "")
+	seedUpdate(t, store, 1, models.StatusDelivered, time.Now().Add(-ReturnWindow-time.Hour))
+
+	w := postJSON(r, "/order/return", requestModels.ReturnOrderRequest{OrderID: 1})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "return window")
+}
+
+func TestRequestReturn_OrderNotFound(t *testing.T) {
+	h := &ReturnHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/return", h.RequestReturn)
+
+	w := postJSON(r, "/order/return", requestModels.ReturnOrderRequest{OrderID: 999})
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRecordFailedDelivery_UnknownReason(t *testing.T) {
+	h := &ReturnHandler{Store: repository.NewMemory()}
+	r := gin.Default()
+	r.POST("/order/failed-delivery", h.RecordFailedDelivery)
+
+	w := postJSON(r, "/order/failed-delivery", requestModels.FailedDeliveryRequest{OrderID: 1, ReasonCode: "ALIENS"})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRecordFailedDelivery_NotOutForDelivery(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/failed-delivery", h.RecordFailedDelivery)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+
+	w := postJSON(r, "/order/failed-delivery", requestModels.FailedDeliveryRequest{OrderID: 1, ReasonCode: "RECIPIENT_ABSENT"})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestRecordFailedDelivery_SchedulesRedelivery(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/failed-delivery", h.RecordFailedDelivery)
+
+	seedOrder(t, store, 1, models.StatusOutForDelivery)
+	redelivery := time.Now().AddDate(0, 0, 2).Format("2006-01-02")
+
+	w := postJSON(r, "/order/failed-delivery", requestModels.FailedDeliveryRequest{
+		OrderID:        1,
+		ReasonCode:     "RECIPIENT_ABSENT",
+		RedeliveryDate: redelivery,
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "FAILED DELIVERY", response["status"])
+	assert.Equal(t, float64(1), response["attempt"])
+
+	assert.Equal(t, []string{models.StatusFailedDelivery, models.StatusOutForDelivery}, orderStatuses(t, store, 1))
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, redelivery, order.Delivery_Estimate.Format("2006-01-02"))
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditUpdate, events[0].Action)
+}
+
+func TestRecordFailedDelivery_AutoReturn(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/failed-delivery", h.RecordFailedDelivery)
+
+	seedOrder(t, store, 1, "")
+	for i := 0; i < MaxDeliveryAttempts-1; i++ {
+		seedUpdate(t, store, 1, models.StatusFailedDelivery, time.Now().Add(-time.Duration(2*MaxDeliveryAttempts-2*i)*time.Hour))
+		seedUpdate(t, store, 1, models.StatusOutForDelivery, time.Now().Add(-time.Duration(2*MaxDeliveryAttempts-2*i-1)*time.Hour))
+	}
+
+	w := postJSON(r, "/order/failed-delivery", requestModels.FailedDeliveryRequest{OrderID: 1, ReasonCode: "REFUSED"})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "RETURNED")
+	assert.Equal(t, []string{models.StatusReturned, models.StatusFailedDelivery}, orderStatuses(t, store, 1)[:2])
+}
+
+func TestRecordFailedDelivery_RollsBackWhenTheRedeliveryFails(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ReturnHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/failed-delivery", h.RecordFailedDelivery)
+
+	seedOrder(t, store, 1, models.StatusOutForDelivery)
+	store.Fail("Orders.Update", assert.AnError)
+
+	w := postJSON(r, "/order/failed-delivery", requestModels.FailedDeliveryRequest{OrderID: 1, ReasonCode: "RECIPIENT_ABSENT"})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to schedule redelivery")
+	assert.Equal(t, []string{models.StatusOutForDelivery}, orderStatuses(t, store, 1))
+}
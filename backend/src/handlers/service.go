@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/requestModels"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The tracking operations (PlaceOrder, AppendUpdate and Verify) do not depend on gin, they are shared by the REST
+// handlers and the gRPC server. Their errors for the client are ServiceErrors, any other error is an internal error
+
+// ServiceError is an error of a tracking operation that is reported to the client
+type ServiceError struct {
+	Status  int // HTTP status of the error
+	Message string
+	// Lines are the invalid lines of an order
+	Lines []requestModels.OrderLineError
+}
+
+func (e *ServiceError) Error() string {
+	return e.Message
+}
+
+func serviceError(status int, message string) *ServiceError {
+	return &ServiceError{Status: status, Message: message}
+}
+
+// respondError writes the error response of an error returned by a tracking operation
+func respondError(c *gin.Context, err error) {
+	var serviceErr *ServiceError
+	switch {
+	case !errors.As(err, &serviceErr):
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+	case len(serviceErr.Lines) > 0:
+		respondLineErrors(c, serviceErr.Message, serviceErr.Lines)
+	default:
+		apierror.Respond(c, serviceErr.Status, serviceErr.Message)
+	}
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/audit"
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"app/utils"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ShipmentHandler struct {
+	Store  repository.Store
+	Client *blockchain.Client
+	Audit  *audit.Logger
+}
+
+// CreateShipment splits some of the products of an order into a new parcel
+func (h *ShipmentHandler) CreateShipment(c *gin.Context) {
+	var input requestModels.CreateShipmentRequest
+	if !bindOrderRequest(c, &input, &input.OrderID) {
+		return
+	}
+
+	order, err := h.Store.Orders().Get(c, input.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("Order with id %d not found", input.OrderID))
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	// Orders can only be split before they leave the seller
+	latestStatus, err := h.Store.History().Latest(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	if latestStatus != nil && latestStatus.Order_Status != models.StatusProcessing {
+		apierror.Respond(c, http.StatusForbidden, fmt.Sprintf("Cannot split order with status: %s", latestStatus.Order_Status))
+		return
+	}
+
+	// The products must belong to the order and not be in another shipment
+	products, err := h.Store.OrderProducts().ListByOrder(c, order.Id)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	requested := make(map[uint]bool, len(input.OrderProductIDs))
+	for _, id := range input.OrderProductIDs {
+		requested[id] = true
+	}
+	var orderProducts []models.OrderProduct
+	for _, orderProduct := range products {
+		if requested[orderProduct.ID] {
+			orderProducts = append(orderProducts, orderProduct)
+		}
+	}
+	if len(orderProducts) != len(input.OrderProductIDs) {
+		apierror.Respond(c, http.StatusBadRequest, "Some products do not belong to the order")
+		return
+	}
+	for _, orderProduct := range orderProducts {
+		if orderProduct.Shipment_ID != nil {
+			apierror.Respond(c, http.StatusConflict, fmt.Sprintf("Order product %d is already in shipment %d", orderProduct.ID, *orderProduct.Shipment_ID))
+			return
+		}
+	}
+
+	// The parcel leaves from the storage if there is one, otherwise from the seller
+	originLatitude, originLongitude := order.Seller_Latitude, order.Seller_Longitude
+	location := order.Seller_Address
+	if input.StorageID != nil {
+		storage, err := h.Store.Storages().Get(c, *input.StorageID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("Storage with id %d not found", *input.StorageID))
+			} else {
+				apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+			}
+			return
+		}
+		originLatitude, originLongitude = storage.Latitude, storage.Longitude
+		location = storage.Name
+	}
+
+	estimate_time := utils.EstimateDeliveryTime(originLatitude, originLongitude, order.Delivery_Latitude, order.Delivery_Longitude, 30)
+
+	shipment := models.Shipment{
+		Order_ID:          order.Id,
+		Tracking_Code:     uuid.New().String(),
+		Storage_ID:        input.StorageID,
+		Delivery_Estimate: time.Now().Add(time.Duration(estimate_time * float64(time.Hour))),
+		Created_At:        time.Now(),
+	}
+
+	//insert a first update (processing)
+	statusHistory := models.OrderStatusHistory{
+		Order_ID:          order.Id,
+		Order_Status:      models.StatusProcessing,
+		Timestamp_History: time.Now(),
+		Order_Location:    location,
+		Note:              "Processing the Shipment",
+	}
+
+	//the next operations are made inside a transaction to ensure atomicity
+	audited := h.Audit.Batch()
+	err = h.Store.Transaction(c, func(tx repository.Store) error {
+		if err := tx.Shipments().Create(c, &shipment); err != nil {
+			return err
+		}
+
+		for i := range orderProducts {
+			before := orderProducts[i]
+			orderProducts[i].Shipment_ID = &shipment.Id
+			if err := tx.OrderProducts().Save(c, &orderProducts[i]); err != nil {
+				return err
+			}
+			if _, err := audited.Record(tx.AuditEvents(), c, audit.Change{OrderID: order.Id, Table: audit.TableOrderProducts, RecordID: before.ID, Action: models.AuditUpdate, Before: before, After: orderProducts[i]}); err != nil {
+				return err
+			}
+		}
+
+		//the order is delivered when its last parcel arrives
+		if shipment.Delivery_Estimate.After(order.Delivery_Estimate) {
+			before := *order
+			order.Delivery_Estimate = shipment.Delivery_Estimate
+			if err := tx.Orders().Update(c, order, "delivery_estimate"); err != nil {
+				return err
+			}
+			if _, err := audited.Record(tx.AuditEvents(), c, audit.Change{OrderID: order.Id, Table: audit.TableOrders, RecordID: order.Id, Action: models.AuditUpdate, Before: before, After: order}); err != nil {
+				return err
+			}
+		}
+
+		statusHistory.Shipment_ID = &shipment.Id
+		if err := tx.History().Create(c, &statusHistory); err != nil {
+			return serviceError(http.StatusInternalServerError, "Failed to save update")
+		}
+		return nil
+	})
+	if err != nil {
+		var serviceErr *ServiceError
+		if !errors.As(err, &serviceErr) {
+			err = serviceError(http.StatusInternalServerError, "Failed to create shipment")
+		}
+		respondError(c, err)
+		return
+	}
+	audited.Anchor(c, h.Store.AuditEvents())
+	notarizeStoredUpdate(c, h.Client, h.Store.History(), &statusHistory)
+
+	shipment.Products = orderProducts
+	shipment.Status = models.StatusProcessing
+
+	c.JSON(http.StatusCreated, gin.H{"shipment": shipment})
+}
+
+// GetShipmentsByOrderID retrieves the shipments of an order and the order status derived from them
+func (h *ShipmentHandler) GetShipmentsByOrderID(c *gin.Context) {
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
+		return
+	}
+
+	shipments, err := h.Store.Shipments().ListByOrder(c, orderID)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	statuses := make([]string, 0, len(shipments))
+	for i := range shipments {
+		if len(shipments[i].Updates) > 0 {
+			shipments[i].Status = shipments[i].Updates[0].Order_Status
+		}
+		statuses = append(statuses, shipments[i].Status)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shipments":    shipments,
+		"order_status": utils.DeriveOrderStatus(statuses),
+	})
+}
+
+// GetShipmentByTrackingCode retrieves a shipment using its tracking code
+func (h *ShipmentHandler) GetShipmentByTrackingCode(c *gin.Context) {
+	shipment, err := h.Store.Shipments().GetByTrackingCode(c, c.Param("tracking_code"))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, "Shipment not found")
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	if len(shipment.Updates) > 0 {
+		shipment.Status = shipment.Updates[0].Order_Status
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shipment": shipment})
+}
+
+// SyncOrderStatus derives the order status from its shipments and stores a new order level update when it changes
+func SyncOrderStatus(ctx context.Context, store repository.Store, client *blockchain.Client, orderID uint, location string) error {
+	updates, err := store.History().ListByOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+
+	// latest status of each shipment, and of the order itself (the updates are listed newest first)
+	latestByShipment := map[uint]string{}
+	var latestOrderUpdate *models.OrderStatusHistory
+	for i := range updates {
+		update := &updates[i]
+		if update.Shipment_ID == nil {
+			if latestOrderUpdate == nil {
+				latestOrderUpdate = update
+			}
+			continue
+		}
+		if _, seen := latestByShipment[*update.Shipment_ID]; !seen {
+			latestByShipment[*update.Shipment_ID] = update.Order_Status
+		}
+	}
+
+	statuses := make([]string, 0, len(latestByShipment))
+	for _, status := range latestByShipment {
+		statuses = append(statuses, status)
+	}
+
+	derived := utils.DeriveOrderStatus(statuses)
+	if derived == "" {
+		return nil
+	}
+	if latestOrderUpdate != nil && latestOrderUpdate.Order_Status == derived {
+		return nil
+	}
+
+	orderUpdate := models.OrderStatusHistory{
+		Order_ID:          orderID,
+		Order_Status:      derived,
+		Timestamp_History: time.Now(),
+		Order_Location:    location,
+		Note:              "Order status updated from its shipments",
+	}
+
+	if err := NotarizeUpdate(ctx, client, &orderUpdate); err != nil {
+		return err
+	}
+
+	return store.History().Create(ctx, &orderUpdate)
+}
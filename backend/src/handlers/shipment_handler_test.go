@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"app/blockchain"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedOrderProducts stores products of an order, shipped in a shipment when it is not nil
+func seedOrderProducts(t *testing.T, store *repository.Memory, orderID uint, shipmentID *uint, ids ...uint) {
+	t.Helper()
+	for _, id := range ids {
+		require.NoError(t, store.OrderProducts().Create(context.Background(), &models.OrderProduct{
+			ID: id, Order_ID: orderID, Product_ID: 100 + id, Quantity: 1, Product_Name_At_Purchase: "Mug", Shipment_ID: shipmentID,
+		}))
+	}
+}
+
+// seedShipmentUpdate stores a status update of a shipment of an order
+func seedShipmentUpdate(t *testing.T, store *repository.Memory, orderID, shipmentID uint, status string, timestamp time.Time) {
+	t.Helper()
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{
+		Order_ID: orderID, Shipment_ID: &shipmentID, Order_Status: status, Timestamp_History: timestamp, Order_Location: "Warehouse",
+	}))
+}
+
+func TestCreateShipment_Success(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/shipments/add", h.CreateShipment)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	seedOrderProducts(t, store, 1, nil, 10, 11, 12)
+	require.NoError(t, store.Storages().Create(context.Background(), &models.Storage{Id: 2, Name: "Distribution Center Porto", Latitude: 41.1496, Longitude: -8.6109}))
+
+	storageID := uint(2)
+	w := postJSON(r, "/order/shipments/add", requestModels.CreateShipmentRequest{
+		OrderID:         1,
+		StorageID:       &storageID,
+		OrderProductIDs: []uint{10, 11},
+	})
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"Tracking_Code"`)
+
+	shipments, err := store.Shipments().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	shipment := shipments[0]
+	assert.Equal(t, &storageID, shipment.Storage_ID)
+	require.Len(t, shipment.Products, 2)
+	assert.Equal(t, []uint{10, 11}, []uint{shipment.Products[0].ID, shipment.Products[1].ID})
+	require.Len(t, shipment.Updates, 1)
+	assert.Equal(t, models.StatusProcessing, shipment.Updates[0].Order_Status)
+	assert.Equal(t, "Distribution Center Porto", shipment.Updates[0].Order_Location)
+
+	order, err := store.Orders().Get(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, shipment.Delivery_Estimate.Unix(), order.Delivery_Estimate.Unix())
+
+	// the products moved to the shipment and the new delivery estimate are audited
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, []string{"order_products", "order_products", "orders"}, []string{events[0].Table_Name, events[1].Table_Name, events[2].Table_Name})
+}
+
+func TestCreateShipment_RollsBackWhenTheUpdateFails(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/shipments/add", h.CreateShipment)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	seedOrderProducts(t, store, 1, nil, 10)
+	store.Fail("History.Create", assert.AnError)
+
+	w := postJSON(r, "/order/shipments/add", requestModels.CreateShipmentRequest{OrderID: 1, OrderProductIDs: []uint{10}})
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to save update")
+	shipments, err := store.Shipments().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, shipments)
+	orderProduct, err := store.OrderProducts().Get(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Nil(t, orderProduct.Shipment_ID)
+}
+
+func TestCreateShipment_ProductsFromAnotherOrder(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/shipments/add", h.CreateShipment)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	seedOrder(t, store, 2, models.StatusProcessing)
+	seedOrderProducts(t, store, 1, nil, 10)
+	seedOrderProducts(t, store, 2, nil, 99)
+
+	w := postJSON(r, "/order/shipments/add", requestModels.CreateShipmentRequest{
+		OrderID:         1,
+		OrderProductIDs: []uint{10, 99},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateShipment_ProductAlreadyShipped(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/shipments/add", h.CreateShipment)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	shipmentID := uint(3)
+	seedOrderProducts(t, store, 1, &shipmentID, 10)
+
+	w := postJSON(r, "/order/shipments/add", requestModels.CreateShipmentRequest{
+		OrderID:         1,
+		OrderProductIDs: []uint{10},
+	})
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCreateShipment_OrderAlreadyShipped(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/shipments/add", h.CreateShipment)
+
+	seedOrder(t, store, 1, models.StatusShipped)
+
+	w := postJSON(r, "/order/shipments/add", requestModels.CreateShipmentRequest{
+		OrderID:         1,
+		OrderProductIDs: []uint{10},
+	})
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetShipmentsByOrderID_DerivesOrderStatus(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.GET("/order/shipments/:order_id", h.GetShipmentsByOrderID)
+
+	seedOrder(t, store, 1, models.StatusProcessing)
+	for _, shipment := range []models.Shipment{{Id: 1, Order_ID: 1, Tracking_Code: "TRACK-A"}, {Id: 2, Order_ID: 1, Tracking_Code: "TRACK-B"}} {
+		require.NoError(t, store.Shipments().Create(context.Background(), &shipment))
+		seedOrderProducts(t, store, 1, &shipment.Id, 9+shipment.Id)
+	}
+	seedShipmentUpdate(t, store, 1, 1, models.StatusDelivered, time.Now())
+	seedShipmentUpdate(t, store, 1, 2, models.StatusInTransit, time.Now())
+
+	req := httptest.NewRequest(http.MethodGet, "/order/shipments/1", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		Shipments   []models.Shipment `json:"shipments"`
+		OrderStatus string            `json:"order_status"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "IN TRANSIT", response.OrderStatus)
+	require.Len(t, response.Shipments, 2)
+	assert.Equal(t, models.StatusDelivered, response.Shipments[0].Status)
+	assert.Len(t, response.Shipments[0].Products, 1)
+}
+
+func TestGetShipmentByTrackingCode(t *testing.T) {
+	store := repository.NewMemory()
+	h := &ShipmentHandler{Store: store}
+	r := gin.Default()
+	r.GET("/shipment/:tracking_code", h.GetShipmentByTrackingCode)
+
+	require.NoError(t, store.Shipments().Create(context.Background(), &models.Shipment{Id: 1, Order_ID: 1, Tracking_Code: "TRACK-A"}))
+	seedShipmentUpdate(t, store, 1, 1, models.StatusProcessing, time.Now().Add(-time.Hour))
+	seedShipmentUpdate(t, store, 1, 1, models.StatusShipped, time.Now())
+
+	w := performRequest(r, httptest.NewRequest(http.MethodGet, "/shipment/TRACK-A", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var response struct {
+		Shipment models.Shipment `json:"shipment"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, models.StatusShipped, response.Shipment.Status)
+
+	w = performRequest(r, httptest.NewRequest(http.MethodGet, "/shipment/missing", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAddOrderUpdate_ShipmentFromAnotherOrder(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	require.NoError(t, store.Shipments().Create(context.Background(), &models.Shipment{Id: 5, Order_ID: 2, Tracking_Code: "TRACK-B"}))
+
+	shipmentID := uint(5)
+	w := postJSON(r, "/order/history/add", map[string]interface{}{
+		"order_id":       1,
+		"shipment_id":    shipmentID,
+		"order_status":   "SHIPPED",
+		"order_location": "Warehouse",
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAddOrderUpdate_ShipmentUpdatesOrderStatus(t *testing.T) {
+	store := repository.NewMemory()
+	h := &OrderStatusHistoryHandler{Store: store}
+	r := gin.Default()
+	r.POST("/order/history/add", h.AddOrderUpdate)
+
+	// the first shipment already left the seller, the second one leaves now
+	seedUpdate(t, store, 1, models.StatusProcessing, time.Now().Add(-3*time.Hour))
+	for _, id := range []uint{4, 5} {
+		require.NoError(t, store.Shipments().Create(context.Background(), &models.Shipment{Id: id, Order_ID: 1}))
+		seedShipmentUpdate(t, store, 1, id, models.StatusProcessing, time.Now().Add(-2*time.Hour))
+	}
+	seedShipmentUpdate(t, store, 1, 4, models.StatusShipped, time.Now().Add(-time.Hour))
+
+	w := postJSON(r, "/order/history/add", map[string]interface{}{
+		"order_id":       1,
+		"shipment_id":    5,
+		"order_status":   "SHIPPED",
+		"order_location": "Warehouse",
+	})
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Both shipments left the seller, the order is now shipped
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Nil(t, latest.Shipment_ID)
+	assert.Equal(t, models.StatusShipped, latest.Order_Status)
+	assert.Equal(t, "Order status updated from its shipments", latest.Note)
+
+	// the order status is only stored again when it changes
+	w = postJSON(r, "/order/history/add", map[string]interface{}{
+		"order_id":       1,
+		"shipment_id":    4,
+		"order_status":   "SHIPPED",
+		"order_location": "Warehouse",
+	})
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	latest, err = store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.NotNil(t, latest.Shipment_ID)
+}
+
+func TestVerifyOrder_PerShipment(t *testing.T) {
+	db, mock := setupMockDB(t)
+	h := &VerificationHandler{Store: repository.NewPostgres(db), Client: &blockchain.Client{EthClient: &ethclient.Client{}}}
+	r := gin.Default()
+	r.GET("/order/verify/:order_id", h.VerifyOrder)
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	shipmentA, shipmentB := uint(1), uint(2)
+	rows := sqlmock.NewRows([]string{"id", "order_id", "shipment_id", "timestamp_history", "order_status", "order_location"}).
+		AddRow(1, 1, nil, ts, "PROCESSING", "Seller").
+		AddRow(2, 1, shipmentA, ts, "PROCESSING", "Warehouse A").
+		AddRow(3, 1, shipmentB, ts, "PROCESSING", "Warehouse B")
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).WillReturnRows(rows)
+
+	// The update of shipment B was never stored on the chain
+	h.GetContractInstanceFunc = func(client *ethclient.Client, contractAddress string) (*blockchain.Blockchain, error) {
+		return &blockchain.Blockchain{}, nil
+	}
+	h.GetUpdateHashesFunc = func(contract *blockchain.Blockchain, orderID *big.Int) ([][32]byte, error) {
+		return [][32]byte{
+			HashOrderUpdate(&models.OrderStatusHistory{Order_ID: 1, Order_Status: "PROCESSING", Timestamp_History: ts, Order_Location: "Seller"}),
+			HashOrderUpdate(&models.OrderStatusHistory{Order_ID: 1, Shipment_ID: &shipmentA, Order_Status: "PROCESSING", Timestamp_History: ts, Order_Location: "Warehouse A"}),
+		}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/order/verify/1", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp requestModels.VerificationResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "PARTIALLY_VERIFIED", resp.Status)
+	assert.Len(t, resp.Shipments, 2)
+	assert.True(t, resp.Shipments[0].Verified)
+	assert.False(t, resp.Shipments[1].Verified)
+	assert.Len(t, resp.Shipments[1].Mismatches, 1)
+}
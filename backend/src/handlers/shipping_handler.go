@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"app/apierror"
+	"app/catalog"
+	"app/repository"
+	"app/requestModels"
+	"app/shipping"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShippingHandler struct {
+	Store   repository.Store
+	Catalog *catalog.Client
+	Rates   shipping.Rates
+}
+
+// QuoteShipping returns the shipping cost of a list of products to a destination (before the order is placed)
+func (h *ShippingHandler) QuoteShipping(c *gin.Context) {
+	var input requestModels.ShippingQuoteRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		apierror.Validation(c, err)
+		return
+	}
+
+	zone, err := shipping.ZoneFor(input.DeliveryLatitude, input.DeliveryLongitude)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, fmt.Sprintf("Invalid delivery location: %v", err))
+		return
+	}
+
+	productIDs := make([]uint, 0, len(input.Products))
+	for _, productRequest := range input.Products {
+		productIDs = append(productIDs, productRequest.ProductID)
+	}
+	products, err := catalog.LookupProducts(c, h.Store.Products(), h.Catalog, productIDs)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadGateway, "Error while processing the products")
+		return
+	}
+
+	orderProducts, lineErrors := BuildOrderProducts(0, input.Products, products)
+	if len(lineErrors) > 0 {
+		respondLineErrors(c, "Some products of the order are invalid", lineErrors)
+		return
+	}
+
+	h.respondQuote(c, 0, zone, OrderParcel(orderProducts, products))
+}
+
+// GetOrderShipping returns the parcel weight and the shipping cost of an order (used by the couriers)
+func (h *ShippingHandler) GetOrderShipping(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("order_id"), 10, 64)
+	if err != nil {
+		apierror.Respond(c, http.StatusBadRequest, "Invalid order ID")
+		return
+	}
+
+	order, err := h.Store.Orders().Get(c, uint(orderID))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Respond(c, http.StatusNotFound, fmt.Sprintf("Order with id %d not found", orderID))
+		} else {
+			apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		}
+		return
+	}
+
+	zone, err := shipping.ZoneFor(order.Delivery_Latitude, order.Delivery_Longitude)
+	if err != nil {
+		apierror.Respond(c, http.StatusUnprocessableEntity, fmt.Sprintf("Invalid delivery location: %v", err))
+		return
+	}
+
+	h.respondQuote(c, order.Id, zone, shipping.Parcel{Weight: order.Shipping_Weight, VolumetricWeight: order.Volumetric_Weight})
+}
+
+func (h *ShippingHandler) respondQuote(c *gin.Context, orderID uint, zone shipping.Zone, parcel shipping.Parcel) {
+	price, err := h.Rates.Quote(zone, parcel)
+	if err != nil {
+		apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": requestModels.ShippingQuoteResponse{
+		OrderID:          orderID,
+		Zone:             string(zone),
+		Weight:           parcel.Weight,
+		VolumetricWeight: parcel.VolumetricWeight,
+		ChargeableWeight: parcel.ChargeableWeight(),
+		Price:            price,
+	}})
+}
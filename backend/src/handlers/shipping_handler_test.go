@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"app/catalog"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"app/shipping"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupShippingHandler(t *testing.T, products ...requestModels.Product) (*gin.Engine, sqlmock.Sqlmock) {
+	db, mock := setupMockDB(t)
+	server := catalog.NewFakeServer(products...)
+	t.Cleanup(server.Close)
+	h := &ShippingHandler{Store: repository.NewPostgres(db), Catalog: catalog.NewClient(server.Config()), Rates: shipping.DefaultRates()}
+
+	r := gin.Default()
+	r.POST("/shipping/quote", h.QuoteShipping)
+	r.GET("/order/shipping/:order_id", h.GetOrderShipping)
+	return r, mock
+}
+
+func decodeQuote(t *testing.T, body []byte) requestModels.ShippingQuoteResponse {
+	var response struct {
+		Quote requestModels.ShippingQuoteResponse `json:"quote"`
+	}
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response.Quote
+}
+
+func TestOrderParcel(t *testing.T) {
+	products := testCatalogue()
+	products[1].Weight = 0.5
+	products[3].Weight, products[3].Length, products[3].Width, products[3].Height = 0.2, 30, 20, 5
+
+	parcel := OrderParcel([]models.OrderProduct{
+		{Product_ID: 1, Quantity: 3},
+		{Product_ID: 3, Quantity: 2},
+		{Product_ID: 9, Quantity: 1}, // not in the catalogue
+	}, products)
+
+	assert.InDelta(t, 1.9, parcel.Weight, 1e-9)
+	assert.InDelta(t, 1.2, parcel.VolumetricWeight, 1e-9)
+}
+
+func TestQuoteShipping(t *testing.T) {
+	r, mock := setupShippingHandler(t, requestModels.Product{ID: 10, Name: "Cork Backpack", Price: 49.9, Weight: 0.8, Length: 40, Width: 30, Height: 12, StockUnlimited: true})
+
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE id IN \(\$1\)`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w := postJSON(r, "/shipping/quote", requestModels.ShippingQuoteRequest{
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 2}},
+		DeliveryLatitude:  32.6669, // Funchal
+		DeliveryLongitude: -16.9241,
+	})
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	quote := decodeQuote(t, w.Body.Bytes())
+	assert.Equal(t, string(shipping.ZoneIslands), quote.Zone)
+	assert.InDelta(t, 1.6, quote.Weight, 1e-9)
+	assert.InDelta(t, 5.76, quote.ChargeableWeight, 1e-9)
+	assert.Equal(t, 14.90, quote.Price)
+}
+
+func TestQuoteShipping_OutsideZones(t *testing.T) {
+	r, mock := setupShippingHandler(t)
+
+	w := postJSON(r, "/shipping/quote", requestModels.ShippingQuoteRequest{
+		Products:          []requestModels.OrderProductRequest{{ProductID: 10, Quantity: 1}},
+		DeliveryLatitude:  40.4168, // Madrid
+		DeliveryLongitude: -3.7038,
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuoteShipping_InvalidProducts(t *testing.T) {
+	r, mock := setupShippingHandler(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "products" WHERE id IN \(\$1\)`).
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w := postJSON(r, "/shipping/quote", requestModels.ShippingQuoteRequest{
+		Products:          []requestModels.OrderProductRequest{{ProductID: 99, Quantity: 1}},
+		DeliveryLatitude:  41.14961,
+		DeliveryLongitude: -8.61099,
+	})
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"product_id":99`)
+}
+
+func TestGetOrderShipping(t *testing.T) {
+	r, mock := setupShippingHandler(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1`).
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "delivery_latitude", "delivery_longitude", "shipping_weight", "volumetric_weight"}).
+			AddRow(5, 41.14961, -8.61099, 35.5, 12))
+
+	req := httptest.NewRequest(http.MethodGet, "/order/shipping/5", nil)
+	w := performRequest(r, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	quote := decodeQuote(t, w.Body.Bytes())
+	assert.Equal(t, uint(5), quote.OrderID)
+	assert.Equal(t, string(shipping.ZoneMainland), quote.Zone)
+	assert.Equal(t, 35.5, quote.ChargeableWeight)
+	assert.Equal(t, 18.90, quote.Price, "6 kg above the last bracket")
+}
+
+func TestGetOrderShipping_NotFound(t *testing.T) {
+	r, mock := setupShippingHandler(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	req := httptest.NewRequest(http.MethodGet, "/order/shipping/5", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetOrderShipping_InvalidID(t *testing.T) {
+	r, _ := setupShippingHandler(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/order/shipping/abc", nil)
+	w := performRequest(r, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
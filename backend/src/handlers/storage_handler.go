@@ -1,22 +1,21 @@
 package handlers
 
 import (
-    "app/models"
+	"app/apierror"
+    "app/repository"
     "net/http"
     "github.com/gin-gonic/gin"
-    "gorm.io/gorm"
 )
 
 type StorageHandler struct {
-    DB *gorm.DB
+    Store repository.Store
 }
 
 func (h *StorageHandler) GetAllStorages(c *gin.Context) {
-    var storages []models.Storage
-    result := h.DB.Order("id ASC").Find(&storages)
+    storages, err := h.Store.Storages().List(c)
 
-    if result.Error != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+    if err != nil {
+        apierror.Respond(c, http.StatusInternalServerError, "Internal server error")
         return
     }
 
@@ -1,72 +1,49 @@
 package handlers
 
 import (
+	"app/models"
+	"app/repository"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
-	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
-	db, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("failed to create sqlmock: %v", err)
-	}
-
-	gormDB, err := gorm.Open(postgres.New(postgres.Config{
-		Conn: db,
-	}), &gorm.Config{})
-
-	if err != nil {
-		t.Fatalf("failed to open gorm db: %v", err)
-	}
-
-	return gormDB, mock
-}
-
 func TestGetAllStorages_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	gormDB, mock := newMockDB(t)
+	store := repository.NewMemory()
+	require.NoError(t, store.Storages().Create(context.Background(), &models.Storage{Id: 2, Name: "Storage B"}))
+	require.NoError(t, store.Storages().Create(context.Background(), &models.Storage{Id: 1, Name: "Storage A"}))
 
-	// Expected rows
-	rows := sqlmock.NewRows([]string{"id", "name"}).
-		AddRow(1, "Storage A").
-		AddRow(2, "Storage B")
-
-	// Expect the SQL query GORM will generate
-	mock.ExpectQuery(`SELECT \* FROM "storages" ORDER BY id ASC`).
-		WillReturnRows(rows)
-
-	handler := StorageHandler{DB: gormDB}
+	handler := StorageHandler{Store: store}
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 
 	handler.GetAllStorages(c)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected 200, got %d", w.Code)
-	}
-
-	mock.ExpectationsWereMet()
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct{ Storages []models.Storage }
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Storages, 2)
+	assert.Equal(t, "Storage A", response.Storages[0].Name, "sorted by id")
 }
 
 func TestGetAllStorages_DBError(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	gormDB, mock := newMockDB(t)
-
+	store := repository.NewMemory()
 	// Force DB error
-	mock.ExpectQuery(`SELECT \* FROM "storages" ORDER BY id ASC`).
-		WillReturnError(sql.ErrConnDone)
+	store.Fail("Storages.List", sql.ErrConnDone)
 
-	handler := StorageHandler{DB: gormDB}
+	handler := StorageHandler{Store: store}
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -76,6 +53,4 @@ func TestGetAllStorages_DBError(t *testing.T) {
 	if w.Code != http.StatusInternalServerError {
 		t.Fatalf("expected 500, got %d", w.Code)
 	}
-
-	mock.ExpectationsWereMet()
-}
\ No newline at end of file
+}
@@ -1,24 +1,30 @@
 package handlers
 
 import (
+	"app/blobstore"
 	"app/blockchain"
+	"app/metrics"
 	"app/models"
+	"app/repository"
 	"app/requestModels"
-	"crypto/sha256"
+	"app/telemetry"
+	"context"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
-	"os"
-	"time"
+	"sort"
 
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type VerificationHandler struct {
-	DB     *gorm.DB
+	Store  repository.Store
 	Client *blockchain.Client
+	Blobs  blobstore.BlobStore
 	// Test hooks - if set, used instead of fetching data directly from blockchain
 	GetContractInstanceFunc func(client *ethclient.Client, contractAddress string) (*blockchain.Blockchain, error)
 	GetUpdateHashesFunc     func(contract *blockchain.Blockchain, orderID *big.Int) ([][32]byte, error)
@@ -27,64 +33,79 @@ type VerificationHandler struct {
 
 // VerifyOrder verifies all updates for an order against blockchain
 func (h *VerificationHandler) VerifyOrder(c *gin.Context) {
-	if h.Client == nil {
-		c.JSON(http.StatusOK, requestModels.VerificationResponse{
-			Status:   "BLOCKCHAIN_NOT_AVAILABLE",
-			Message:  "Blockchain not configured",
-			Verified: false,
-		})
+	orderID, ok := parseID(c, "order_id")
+	if !ok {
 		return
 	}
-	orderID := c.Param("order_id")
 
-	// Fetch all order status updates from database
-	var orderHistory []models.OrderStatusHistory
-	if err := h.DB.Where("Order_ID = ?", orderID).Order("Timestamp_History asc").Find(&orderHistory).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch order history"})
+	response, err := h.Verify(c, orderID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if len(orderHistory) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No order history found"})
-		return
-	}
+	c.JSON(http.StatusOK, response)
+}
 
-	// Get blockchain contract instance
-	ethClient := h.Client.EthClient
-	addr := os.Getenv("BLOCKCHAIN_CONTRACT_ADDRESS")
-	var contract *blockchain.Blockchain
-	var err error
-	if h.GetContractInstanceFunc != nil {
-		contract, err = h.GetContractInstanceFunc(ethClient, addr)
-	} else {
-		contract, err = blockchain.GetContractInstance(ethClient, addr)
+// Verify checks the updates of an order (and their shipments and proofs of delivery) against the hashes stored in
+// the blockchain
+func (h *VerificationHandler) Verify(ctx context.Context, orderID uint) (*requestModels.VerificationResponse, error) {
+	if h.Client == nil {
+		metrics.ObserveVerification("BLOCKCHAIN_NOT_AVAILABLE")
+		return &requestModels.VerificationResponse{
+			Status:   "BLOCKCHAIN_NOT_AVAILABLE",
+			Message:  "Blockchain not configured",
+			Verified: false,
+		}, nil
 	}
+
+	// Fetch all order status updates from database, with whether their location was rejected on review
+	reviewedHistory, err := h.Store.History().ListReviewed(ctx, orderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to connect to blockchain"})
-		return
+		return nil, serviceError(http.StatusInternalServerError, "Failed to fetch order history")
 	}
 
-	// Get all hashes from blockchain for this order
-	orderIDBigInt := new(big.Int)
-	orderIDBigInt.SetString(orderID, 10)
-	var blockchainHashes [][32]byte
-	if h.GetUpdateHashesFunc != nil {
-		blockchainHashes, err = h.GetUpdateHashesFunc(contract, orderIDBigInt)
-	} else {
-		blockchainHashes, err = contract.GetUpdateHash(nil, orderIDBigInt)
+	// the rejected updates are left out, they do not count for the status of the order
+	allUpdates := make([]models.OrderStatusHistory, 0, len(reviewedHistory))
+	var orderHistory, rejected []models.OrderStatusHistory
+	for _, update := range reviewedHistory {
+		allUpdates = append(allUpdates, update.OrderStatusHistory)
+		if update.Rejected {
+			rejected = append(rejected, update.OrderStatusHistory)
+		} else {
+			orderHistory = append(orderHistory, update.OrderStatusHistory)
+		}
+	}
+
+	if len(orderHistory) == 0 {
+		return nil, serviceError(http.StatusNotFound, "No order history found")
 	}
+
+	// the hashes of the contracts the updates (the rejected ones included) were notarized in
+	legacy := h.Client.LegacyDeployment()
+	contracts, err := h.contractsOf(ctx, orderID, allUpdates, legacy)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blockchain hashes"})
-		return
+		return nil, err
 	}
 
 	response := requestModels.VerificationResponse{
 		TotalUpdates:      len(orderHistory),
-		BlockchainHashes:  len(blockchainHashes),
 		Mismatches:        []string{},
 		TransactionHashes: []string{},
-		ContractAddress:   addr,
+		ContractAddress:   contracts[contractKey(&orderHistory[len(orderHistory)-1], legacy)].verification.ContractAddress,
 	}
+	blockchainHashes := 0
+	for _, contract := range contracts {
+		blockchainHashes += len(contract.hashes)
+	}
+	// the hashes of the rejected updates stay in the blockchain, they are not extra hashes
+	for i := range rejected {
+		if contracts[contractKey(&rejected[i], legacy)].has(HashOrderUpdate(&rejected[i])) {
+			blockchainHashes--
+		}
+	}
+	response.BlockchainHashes = blockchainHashes
+	response.ExcludedUpdates = len(rejected)
 
 
 	//Insert the transaction hashed in the response
@@ -92,37 +113,76 @@ func (h *VerificationHandler) VerifyOrder(c *gin.Context) {
 		response.TransactionHashes = append(response.TransactionHashes, update.Blockchain_Transaction)
 	}
 
+	// Verification of each shipment (in the order they first appear)
+	shipments := map[uint]*requestModels.ShipmentVerification{}
+	shipmentOrder := []uint{}
+
 	// Verify each update
 	verifiedCount := 0
+	verifiedUpdates := map[uint]bool{}
 	for i, update := range orderHistory {
-		// Compute hash for this update (same logic as when storing)
-		data := fmt.Sprintf("%d|%s|%s|%s",
-			update.Order_ID,
-			update.Order_Status,
-			update.Timestamp_History.Format(time.RFC3339),
-			update.Order_Location,
-		)
-		computedHash := sha256.Sum256([]byte(data))
-
-		// Check if this hash exists in blockchain
-		found := false
-		for j, blockchainHash := range blockchainHashes {
-			if computedHash == blockchainHash {
-				found = true
-				verifiedCount++
-				break
+		var shipment *requestModels.ShipmentVerification
+		if update.Shipment_ID != nil {
+			shipment = shipments[*update.Shipment_ID]
+			if shipment == nil {
+				shipment = &requestModels.ShipmentVerification{ShipmentID: *update.Shipment_ID}
+				shipments[*update.Shipment_ID] = shipment
+				shipmentOrder = append(shipmentOrder, *update.Shipment_ID)
 			}
-			// For debugging - check if it's just in wrong order
-			_ = j
+			shipment.TotalUpdates++
+		}
+
+		// Compute hash for this update (same logic as when storing)
+		computedHash := HashOrderUpdate(&update)
+
+		// Check if this hash exists in the contract the update was notarized in
+		contract := contracts[contractKey(&update, legacy)]
+		contract.verification.TotalUpdates++
+		found := contract.has(computedHash)
+		if found {
+			verifiedCount++
+			contract.verification.VerifiedUpdates++
 		}
 
+		verifiedUpdates[update.Id] = found
 		if !found {
 			response.Mismatches = append(response.Mismatches, fmt.Sprintf("Update #%d (%s) not found in blockchain", i+1, update.Order_Status))
 		}
+
+		if shipment != nil {
+			if found {
+				shipment.VerifiedUpdates++
+			} else {
+				shipment.Mismatches = append(shipment.Mismatches, fmt.Sprintf("Update #%d (%s) not found in blockchain", shipment.TotalUpdates, update.Order_Status))
+			}
+		}
 	}
 
+	for _, shipmentID := range shipmentOrder {
+		shipment := shipments[shipmentID]
+		shipment.Verified = shipment.VerifiedUpdates == shipment.TotalUpdates
+		response.Shipments = append(response.Shipments, *shipment)
+	}
+
+	// Proofs of delivery (only loaded when some update has one)
+	for _, update := range orderHistory {
+		if update.Proof_Hash != nil {
+			proofs, err := h.verifyDeliveryProofs(ctx, orderID, orderHistory, verifiedUpdates)
+			if err != nil {
+				return nil, serviceError(http.StatusInternalServerError, "Failed to fetch the proofs of delivery")
+			}
+			response.DeliveryProofs = proofs
+			break
+		}
+	}
+
+	for _, contract := range contracts {
+		response.Contracts = append(response.Contracts, contract.verification)
+	}
+	sort.Slice(response.Contracts, func(i, j int) bool { return response.Contracts[i].DeploymentID < response.Contracts[j].DeploymentID })
+
 	response.VerifiedUpdates = verifiedCount
-	response.Verified = (verifiedCount == len(orderHistory)) && (len(orderHistory) == len(blockchainHashes))
+	response.Verified = (verifiedCount == len(orderHistory)) && (len(orderHistory) == blockchainHashes)
 
 	// Determine status message
 	if response.Verified {
@@ -134,7 +194,7 @@ func (h *VerificationHandler) VerifyOrder(c *gin.Context) {
 	} else if verifiedCount < len(orderHistory) {
 		response.Status = "PARTIALLY_VERIFIED"
 		response.Message = fmt.Sprintf("Only %d out of %d updates are verified", verifiedCount, len(orderHistory))
-	} else if len(blockchainHashes) > len(orderHistory) {
+	} else if blockchainHashes > len(orderHistory) {
 		response.Status = "EXTRA_HASHES"
 		response.Message = "More hashes on blockchain than in database"
 	} else {
@@ -142,5 +202,164 @@ func (h *VerificationHandler) VerifyOrder(c *gin.Context) {
 		response.Message = "Database and blockchain data mismatch"
 	}
 
-	c.JSON(http.StatusOK, response)
+	metrics.ObserveVerification(response.Status)
+	return &response, nil
+}
+
+
+// notarizedIn is a contract the updates of an order were notarized in, with the hashes it has for the order
+type notarizedIn struct {
+	hashes       [][32]byte
+	verification requestModels.ContractVerification
+}
+
+// has reports whether the contract has a hash for the order
+func (n *notarizedIn) has(hash [32]byte) bool {
+	for _, stored := range n.hashes {
+		if stored == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// contractKey is the deployment an update is pinned to. The updates notarized before the registry are in the legacy
+// contract (0 when it is not registered)
+func contractKey(update *models.OrderStatusHistory, legacy models.ContractDeployment) uint {
+	if update.Contract_Deployment_ID == nil {
+		return legacy.Id
+	}
+	return *update.Contract_Deployment_ID
+}
+
+// contractsOf reads the hashes of the order from each contract its updates were notarized in, by deployment. The
+// contracts on another chain are read through a node of that chain
+func (h *VerificationHandler) contractsOf(ctx context.Context, orderID uint, orderHistory []models.OrderStatusHistory, legacy models.ContractDeployment) (map[uint]*notarizedIn, error) {
+	deployments := map[uint]models.ContractDeployment{}
+	var pinned []uint
+	for i := range orderHistory {
+		if orderHistory[i].Contract_Deployment_ID == nil {
+			deployments[legacy.Id] = legacy
+		}
+	}
+	for i := range orderHistory {
+		key := contractKey(&orderHistory[i], legacy)
+		if _, ok := deployments[key]; !ok {
+			deployments[key] = models.ContractDeployment{}
+			pinned = append(pinned, key)
+		}
+	}
+	if len(pinned) > 0 {
+		registered, err := h.Store.Deployments().ListByIDs(ctx, pinned)
+		if err != nil {
+			return nil, serviceError(http.StatusInternalServerError, "Failed to fetch the contract deployments")
+		}
+		for _, deployment := range registered {
+			deployments[deployment.Id] = deployment
+		}
+	}
+
+	orderIDBigInt := new(big.Int).SetUint64(uint64(orderID))
+	contracts := map[uint]*notarizedIn{}
+	for key, deployment := range deployments {
+		if key != 0 && deployment.Id == 0 {
+			return nil, serviceError(http.StatusInternalServerError, fmt.Sprintf("The contract deployment %d is not in the registry", key))
+		}
+
+		ethClient, err := h.Client.Reader(ctx, deployment.Chain_ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to connect to the chain of a contract", "chain_id", deployment.Chain_ID, "contract", deployment.Contract_Address, "error", err)
+			return nil, serviceError(http.StatusInternalServerError, fmt.Sprintf("Failed to connect to the chain %d of the contract %s", deployment.Chain_ID, deployment.Contract_Address))
+		}
+		var contract *blockchain.Blockchain
+		if h.GetContractInstanceFunc != nil {
+			contract, err = h.GetContractInstanceFunc(ethClient, deployment.Contract_Address)
+		} else {
+			contract, err = blockchain.GetContractInstance(ethClient, deployment.Contract_Address)
+		}
+		if err != nil {
+			return nil, serviceError(http.StatusInternalServerError, "Failed to connect to blockchain")
+		}
+
+		var hashes [][32]byte
+		chainCtx, span := telemetry.Start(ctx, "chain getUpdateHash", trace.SpanKindClient, trace.WithAttributes(telemetry.OrderID(orderID)))
+		if h.GetUpdateHashesFunc != nil {
+			hashes, err = h.GetUpdateHashesFunc(contract, orderIDBigInt)
+		} else {
+			hashes, err = contract.GetUpdateHash(&bind.CallOpts{Context: chainCtx}, orderIDBigInt)
+		}
+		telemetry.End(span, err)
+		if err != nil {
+			return nil, serviceError(http.StatusInternalServerError, "Failed to retrieve blockchain hashes")
+		}
+
+		contracts[key] = &notarizedIn{
+			hashes: hashes,
+			verification: requestModels.ContractVerification{
+				ChainID:          deployment.Chain_ID,
+				ContractAddress:  deployment.Contract_Address,
+				DeploymentID:     deployment.Id,
+				BlockchainHashes: len(hashes),
+			},
+		}
+	}
+	return contracts, nil
+}
+
+// checks each proof of delivery against the proof hash of its update (which is part of the notarized hash)
+func (h *VerificationHandler) verifyDeliveryProofs(ctx context.Context, orderID uint, orderHistory []models.OrderStatusHistory, verifiedUpdates map[uint]bool) ([]requestModels.DeliveryProofVerification, error) {
+	proofs, err := h.Store.DeliveryProofs().ListByOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	proofsByUpdate := map[uint]*models.DeliveryProof{}
+	for i := range proofs {
+		proofsByUpdate[proofs[i].Order_Status_History_ID] = &proofs[i]
+	}
+
+	results := []requestModels.DeliveryProofVerification{}
+	for _, update := range orderHistory {
+		if update.Proof_Hash == nil {
+			continue
+		}
+
+		result := requestModels.DeliveryProofVerification{
+			UpdateID:  update.Id,
+			ProofHash: *update.Proof_Hash,
+		}
+
+		proof := proofsByUpdate[update.Id]
+		switch {
+		case proof == nil:
+			result.Message = "Proof of delivery is missing"
+		case HashDeliveryProof(proof) != *update.Proof_Hash:
+			result.Message = "Proof of delivery does not match the notarized hash"
+		case !verifiedUpdates[update.Id]:
+			result.Message = "The update of the proof of delivery is not in the blockchain"
+		default:
+			result.Verified = true
+			result.Message = "Proof of delivery matches the blockchain"
+		}
+
+		// the stored media must still match the proof
+		if result.Verified && h.Blobs != nil {
+			if err := verifyDeliveryProofMedia(h.Blobs, proof); err != nil {
+				result.Verified = false
+				result.Message = fmt.Sprintf("Proof of delivery media was changed or is missing: %v", err)
+			}
+		}
+
+		if proof != nil {
+			result.RecipientName = proof.Recipient_Name
+			result.HasSignature = proof.Signature_Key != ""
+			result.HasPhoto = proof.Photo_Key != ""
+			result.Latitude = proof.Latitude
+			result.Longitude = proof.Longitude
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
 }
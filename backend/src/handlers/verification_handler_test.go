@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"app/models"
+	"app/repository"
 	"app/requestModels"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
@@ -17,6 +20,7 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // setupMockDB creates a gorm DB backed by sqlmock
@@ -25,7 +29,7 @@ import (
 // Tests
 func TestVerifyOrder_NoBlockchain(t *testing.T) {
 	db, _ := setupMockDB(t)
-	h := &VerificationHandler{DB: db}
+	h := &VerificationHandler{Store: repository.NewPostgres(db)}
 	r := gin.Default()
 	r.GET("/order/verify/:order_id", h.VerifyOrder)
 
@@ -44,7 +48,7 @@ func TestVerifyOrder_NoBlockchain(t *testing.T) {
 
 func TestVerifyOrder_ReturnsTransactionHashes(t *testing.T) {
 	db, mock := setupMockDB(t)
-	h := &VerificationHandler{DB: db}
+	h := &VerificationHandler{Store: repository.NewPostgres(db)}
 	r := gin.Default()
 	r.GET("/order/verify/:order_id", h.VerifyOrder)
 
@@ -58,8 +62,8 @@ func TestVerifyOrder_ReturnsTransactionHashes(t *testing.T) {
 		"order_status",
 		"order_location",
 	}).AddRow(1, orderID, ts, "DELIVERED", "POINT(1 1)")
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).WillReturnRows(rows)
 
 	// Compute expected hash for the single update
 	data := fmt.Sprintf(
@@ -103,14 +107,14 @@ func TestVerifyOrder_ReturnsTransactionHashes(t *testing.T) {
 func TestVerifyOrder_DBError(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
 	r.GET("/order/verify/:order_id", h.VerifyOrder)
 
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
 		WillReturnError(errors.New("db failure"))
 
 	req := httptest.NewRequest(http.MethodGet, "/order/verify/1", nil)
@@ -122,15 +126,15 @@ func TestVerifyOrder_DBError(t *testing.T) {
 func TestVerifyOrder_NotFound(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
 	r.GET("/order/verify/:order_id", h.VerifyOrder)
 
 	// Return empty result set
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("999").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 999).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "timestamp_history", "order_status", "order_location"}))
 
 	req := httptest.NewRequest(http.MethodGet, "/order/verify/999", nil)
@@ -142,7 +146,7 @@ func TestVerifyOrder_NotFound(t *testing.T) {
 func TestVerifyOrder_BlockchainHashError(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
@@ -152,8 +156,8 @@ func TestVerifyOrder_BlockchainHashError(t *testing.T) {
 	rows := sqlmock.NewRows([]string{
 		"id", "order_id", "timestamp_history", "order_status", "order_location",
 	}).AddRow(1, 1, ts, "PROCESSING", "Origin")
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
 		WillReturnRows(rows)
 
 	// Inject GetUpdateHashesFunc to return error
@@ -173,7 +177,7 @@ func TestVerifyOrder_BlockchainHashError(t *testing.T) {
 func TestVerifyOrder_PartiallyVerified(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
@@ -185,8 +189,8 @@ func TestVerifyOrder_PartiallyVerified(t *testing.T) {
 		"id", "order_id", "timestamp_history", "order_status", "order_location",
 	}).AddRow(1, 1, ts, "PROCESSING", "Origin").
 		AddRow(2, 1, ts.Add(time.Hour), "SHIPPED", "Warehouse")
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
 		WillReturnRows(rows)
 
 	// Only first hash matches
@@ -214,7 +218,7 @@ func TestVerifyOrder_PartiallyVerified(t *testing.T) {
 func TestVerifyOrder_NotVerified(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
@@ -224,8 +228,8 @@ func TestVerifyOrder_NotVerified(t *testing.T) {
 	rows := sqlmock.NewRows([]string{
 		"id", "order_id", "timestamp_history", "order_status", "order_location",
 	}).AddRow(1, 1, ts, "PROCESSING", "Origin")
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
 		WillReturnRows(rows)
 
 	// Return empty hashes - nothing verified
@@ -249,7 +253,7 @@ func TestVerifyOrder_NotVerified(t *testing.T) {
 func TestVerifyOrder_ExtraHashes(t *testing.T) {
 	db, mock := setupMockDB(t)
 	h := &VerificationHandler{
-		DB:     db,
+		Store:  repository.NewPostgres(db),
 		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
 	}
 	r := gin.Default()
@@ -259,8 +263,8 @@ func TestVerifyOrder_ExtraHashes(t *testing.T) {
 	rows := sqlmock.NewRows([]string{
 		"id", "order_id", "timestamp_history", "order_status", "order_location",
 	}).AddRow(1, 1, ts, "PROCESSING", "Origin")
-	mock.ExpectQuery(`SELECT .* FROM "order_status_history" WHERE .*`).
-		WithArgs("1").
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
 		WillReturnRows(rows)
 
 	// Compute matching hash plus an extra one
@@ -284,3 +288,121 @@ func TestVerifyOrder_ExtraHashes(t *testing.T) {
 	assert.Equal(t, "EXTRA_HASHES", resp.Status)
 }
 
+
+func TestVerifyOrder_AcrossAContractRotation(t *testing.T) {
+	db, mock := setupMockDB(t)
+	ctx := context.Background()
+
+	// the legacy contract, then a contract rotated in (the updates notarized since are pinned to it)
+	registry := repository.NewMemory().Deployments()
+	legacy := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: "0x00000000000000000000000000000000000000A1", Legacy: true, Active: true}
+	require.NoError(t, registry.Create(ctx, &legacy))
+	client := &blockchain.Client{EthClient: &ethclient.Client{}, ChainID: big.NewInt(11155111)}
+	require.NoError(t, blockchain.LoadDeployments(ctx, registry, client, ""))
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	rows := sqlmock.NewRows([]string{
+		"id", "order_id", "timestamp_history", "order_status", "order_location", "contract_deployment_id",
+	}).AddRow(1, 1, ts, "PROCESSING", "Origin", nil). // before the registry
+		AddRow(2, 1, ts.Add(time.Hour), "SHIPPED", "Warehouse", 1).
+		AddRow(3, 1, ts.Add(2*time.Hour), "DELIVERED", "Customer", 2)
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "contract_deployments" WHERE id IN \(\$1\)`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chain_id", "contract_address"}).
+			AddRow(2, 11155111, "0x00000000000000000000000000000000000000B2"))
+
+	hash := func(status string, at time.Time, location string) [32]byte {
+		return sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%s|%s", 1, status, at.Format(time.RFC3339), location)))
+	}
+	hashes := map[string][][32]byte{
+		"0x00000000000000000000000000000000000000A1": {hash("PROCESSING", ts, "Origin"), hash("SHIPPED", ts.Add(time.Hour), "Warehouse")},
+		"0x00000000000000000000000000000000000000B2": {hash("DELIVERED", ts.Add(2*time.Hour), "Customer")},
+	}
+	contracts := map[*blockchain.Blockchain]string{}
+	h := &VerificationHandler{
+		Store:  repository.NewPostgres(db),
+		Client: client,
+		GetContractInstanceFunc: func(ethClient *ethclient.Client, address string) (*blockchain.Blockchain, error) {
+			contract := &blockchain.Blockchain{}
+			contracts[contract] = address
+			return contract, nil
+		},
+		GetUpdateHashesFunc: func(contract *blockchain.Blockchain, orderID *big.Int) ([][32]byte, error) {
+			return hashes[contracts[contract]], nil
+		},
+	}
+
+	response, err := h.Verify(ctx, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "VERIFIED", response.Status, response.Mismatches)
+	assert.Equal(t, 3, response.BlockchainHashes, "the legacy contract is read once")
+	assert.Equal(t, "0x00000000000000000000000000000000000000B2", response.ContractAddress)
+	require.Len(t, response.Contracts, 2)
+	assert.Equal(t, requestModels.ContractVerification{
+		ChainID: 11155111, ContractAddress: "0x00000000000000000000000000000000000000A1", DeploymentID: 1,
+		TotalUpdates: 2, VerifiedUpdates: 2, BlockchainHashes: 2,
+	}, response.Contracts[0])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerifyOrder_ContractOnAnotherChain(t *testing.T) {
+	db, mock := setupMockDB(t)
+	h := &VerificationHandler{
+		Store:  repository.NewPostgres(db),
+		Client: &blockchain.Client{EthClient: &ethclient.Client{}, ChainID: big.NewInt(1)},
+	}
+
+	mock.ExpectQuery(`SELECT .* FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE .*`).
+		WithArgs(models.ReviewRejected, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status", "contract_deployment_id"}).AddRow(1, 1, "PROCESSING", 1))
+	mock.ExpectQuery(`SELECT \* FROM "contract_deployments" WHERE id IN \(\$1\)`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chain_id", "contract_address"}).
+			AddRow(1, 11155111, "0x00000000000000000000000000000000000000A1"))
+
+	_, err := h.Verify(context.Background(), 1)
+
+	// no node of the chain of the contract is configured
+	var serviceErr *ServiceError
+	require.ErrorAs(t, err, &serviceErr)
+	assert.Equal(t, http.StatusInternalServerError, serviceErr.Status)
+	assert.Contains(t, serviceErr.Message, "chain 11155111")
+}
+
+func TestVerifyOrder_RejectedLocationIsLeftOut(t *testing.T) {
+	db, mock := setupMockDB(t)
+	h := &VerificationHandler{
+		Store:  repository.NewPostgres(db),
+		Client: &blockchain.Client{EthClient: &ethclient.Client{}},
+	}
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	processing := models.OrderStatusHistory{Id: 1, Order_ID: 1, Timestamp_History: ts, Order_Status: models.StatusProcessing, Order_Location: "Origin"}
+	delivered := models.OrderStatusHistory{Id: 2, Order_ID: 1, Timestamp_History: ts.Add(time.Hour), Order_Status: models.StatusDelivered, Order_Location: "Lisbon"}
+	mock.ExpectQuery(`SELECT order_status_history.\*, .*EXISTS \(SELECT 1 FROM location_reviews .*\) AS rejected FROM "order_status_history" LEFT JOIN update_notarizations .* WHERE order_id = \$2`).
+		WithArgs(models.ReviewRejected, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "timestamp_history", "order_status", "order_location", "rejected"}).
+			AddRow(1, 1, ts, models.StatusProcessing, "Origin", false).
+			AddRow(2, 1, ts.Add(time.Hour), models.StatusDelivered, "Lisbon", true))
+
+	// the rejected update was notarized, its hash stays in the contract
+	h.GetContractInstanceFunc = func(client *ethclient.Client, contractAddress string) (*blockchain.Blockchain, error) {
+		return &blockchain.Blockchain{}, nil
+	}
+	h.GetUpdateHashesFunc = func(contract *blockchain.Blockchain, orderID *big.Int) ([][32]byte, error) {
+		return [][32]byte{HashOrderUpdate(&processing), HashOrderUpdate(&delivered)}, nil
+	}
+
+	response, err := h.Verify(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "VERIFIED", response.Status, response.Mismatches)
+	assert.Equal(t, 1, response.TotalUpdates)
+	assert.Equal(t, 1, response.BlockchainHashes, "the hash of the rejected update is not an extra hash")
+	assert.Equal(t, 1, response.ExcludedUpdates)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
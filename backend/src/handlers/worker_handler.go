@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"app/supervisor"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkerHandler reports the workers of the supervisor (nil when the service runs without one)
+type WorkerHandler struct {
+	Supervisor *supervisor.Supervisor
+}
+
+// GetWorkers returns the state of every worker: running, restarting after a failure, failed or stopped
+func (h *WorkerHandler) GetWorkers(c *gin.Context) {
+	workers := []supervisor.Status{}
+	if h.Supervisor != nil {
+		workers = h.Supervisor.Statuses()
+	}
+	c.JSON(http.StatusOK, gin.H{"workers": workers})
+}
@@ -0,0 +1,162 @@
+// Package health serves the probes of the service: /healthz reports that the process is up and /readyz checks each
+// dependency (the database, the broker and the ledger) separately
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DefaultTimeout bounds each check of the readiness probe
+const DefaultTimeout = 2 * time.Second
+
+// Status of a check
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDisabled = "disabled" // the dependency is not configured
+)
+
+// Status of the service
+const (
+	Ready    = "ready"
+	Degraded = "degraded" // an optional dependency is down, the service still takes requests
+	NotReady = "not_ready"
+)
+
+// Check is a dependency checked by the readiness probe
+type Check struct {
+	Name string
+	// Probe returns nil when the dependency is available. A nil Probe means the dependency is not configured
+	Probe func(ctx context.Context) error
+	// Required checks make the service not ready when they fail, the other ones only degrade it
+	Required bool
+}
+
+// Result is the result of a check
+type Result struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the body of the readiness probe
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Checker runs the checks of the readiness probe
+type Checker struct {
+	Checks []Check
+	// Timeout bounds each check (DefaultTimeout when it is not set)
+	Timeout time.Duration
+}
+
+// NewChecker creates a checker of the checks
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{Checks: checks}
+}
+
+// Run runs the checks concurrently
+func (c *Checker) Run(ctx context.Context) Report {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	results := make([]Result, len(c.Checks))
+	var wg sync.WaitGroup
+	for i, check := range c.Checks {
+		if check.Probe == nil {
+			results[i] = Result{Status: StatusDisabled}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Probe(checkCtx)
+			results[i] = Result{Status: StatusUp, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Status = StatusDown
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: Ready, Checks: make(map[string]Result, len(c.Checks))}
+	for i, check := range c.Checks {
+		report.Checks[check.Name] = results[i]
+		if results[i].Status != StatusDown {
+			continue
+		}
+		if check.Required {
+			report.Status = NotReady
+		} else if report.Status == Ready {
+			report.Status = Degraded
+		}
+	}
+	return report
+}
+
+// Liveness answers the liveness probe: the process is up, the dependencies are not checked
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness answers the readiness probe with the result of each check, 503 when a required check fails
+func (c *Checker) Readiness(ctx *gin.Context) {
+	report := c.Run(ctx.Request.Context())
+	status := http.StatusOK
+	if report.Status == NotReady {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, report)
+}
+
+// Database checks the connection to the database
+func Database(db *gorm.DB) Check {
+	return Check{Name: "database", Required: true, Probe: func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}}
+}
+
+// Pinger is a client of a dependency that can be checked
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Broker checks the message broker (nil when it is not configured)
+func Broker(broker Pinger) Check {
+	check := Check{Name: "broker"}
+	if broker != nil {
+		check.Probe = broker.Ping
+	}
+	return check
+}
+
+// Ledger checks the blockchain node with the block number function of its client (nil when it is not configured)
+func Ledger(blockNumber func(ctx context.Context) (uint64, error)) Check {
+	check := Check{Name: "ledger"}
+	if blockNumber != nil {
+		check.Probe = func(ctx context.Context) error {
+			_, err := blockNumber(ctx)
+			return err
+		}
+	}
+	return check
+}
@@ -0,0 +1,116 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	mock.ExpectPing() // gorm pings when it opens the connection
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gdb, mock
+}
+
+type pinger struct{ err error }
+
+func (p pinger) Ping(ctx context.Context) error { return p.err }
+
+func readiness(t *testing.T, checker *Checker) (int, Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/readyz", checker.Readiness)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestReadiness_Ready(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectPing()
+
+	ledger := func(ctx context.Context) (uint64, error) { return 42, nil }
+	code, report := readiness(t, NewChecker(Database(db), Broker(pinger{}), Ledger(ledger)))
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Ready, report.Status)
+	for _, name := range []string{"database", "broker", "ledger"} {
+		assert.Equal(t, StatusUp, report.Checks[name].Status, name)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReadiness_DatabaseDown(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	code, report := readiness(t, NewChecker(Database(db), Broker(nil), Ledger(nil)))
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, NotReady, report.Status)
+	assert.Equal(t, StatusDown, report.Checks["database"].Status)
+	assert.Equal(t, "connection refused", report.Checks["database"].Error)
+	// the dependencies that are not configured are reported, not checked
+	assert.Equal(t, StatusDisabled, report.Checks["broker"].Status)
+	assert.Equal(t, StatusDisabled, report.Checks["ledger"].Status)
+}
+
+func TestReadiness_OptionalDependencyDown(t *testing.T) {
+	db, mock := setupMockDB(t)
+	mock.ExpectPing()
+
+	ledger := func(ctx context.Context) (uint64, error) { return 0, errors.New("node unreachable") }
+	code, report := readiness(t, NewChecker(Database(db), Broker(pinger{err: errors.New("topic missing")}), Ledger(ledger)))
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Degraded, report.Status)
+	assert.Equal(t, StatusUp, report.Checks["database"].Status)
+	assert.Equal(t, "topic missing", report.Checks["broker"].Error)
+	assert.Equal(t, "node unreachable", report.Checks["ledger"].Error)
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	slow := Check{Name: "slow", Required: true, Probe: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	checker := NewChecker(slow)
+	checker.Timeout = 10 * time.Millisecond
+
+	report := checker.Run(context.Background())
+
+	assert.Equal(t, NotReady, report.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+}
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", Liveness)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
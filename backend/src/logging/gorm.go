@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold is the duration above which the queries are logged as warnings
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// explainedPlaceholder matches the placeholders of PostgreSQL as left by GORM when it explains a query without its
+// parameters ($1 becomes $1$)
+var explainedPlaceholder = regexp.MustCompile(`\$(\d+)\$`)
+
+// GormLogger logs the queries of GORM through slog. The SQL is logged with its placeholders, never with the values
+// of the parameters (they carry the personal data of the customers). The queries are logged at debug level, the slow
+// ones as warnings and the failed ones as errors (a record not found is not a failure)
+type GormLogger struct {
+	logger        *slog.Logger
+	slowThreshold time.Duration
+	silent        bool
+}
+
+// NewGormLogger creates the logger of the queries
+func NewGormLogger(logger *slog.Logger) *GormLogger {
+	return &GormLogger{logger: logger, slowThreshold: SlowQueryThreshold}
+}
+
+// LogMode only tells apart the silent mode (used by GORM for its own queries), the levels are the ones of slog
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.silent = level == gormlogger.Silent
+	return &copied
+}
+
+func (l *GormLogger) Info(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelInfo, message, data)
+}
+
+func (l *GormLogger) Warn(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelWarn, message, data)
+}
+
+func (l *GormLogger) Error(ctx context.Context, message string, data ...interface{}) {
+	l.log(ctx, slog.LevelError, message, data)
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, message string, data []interface{}) {
+	if l.silent {
+		return
+	}
+	l.logger.Log(ctx, level, fmt.Sprintf(message, data...))
+}
+
+// Trace logs a query once it is run
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	level, message := slog.LevelDebug, "Database query"
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level, message = slog.LevelError, "Database query failed"
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold:
+		level, message = slog.LevelWarn, "Slow database query"
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	sql = explainedPlaceholder.ReplaceAllString(sql, "$$$1")
+	attrs := []any{"sql", sql, "rows", rows, "duration", elapsed}
+	if level == slog.LevelError {
+		attrs = append(attrs, "error", err)
+	}
+	l.logger.Log(ctx, level, message, attrs...)
+}
+
+// ParamsFilter leaves the parameters out of the logged SQL
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
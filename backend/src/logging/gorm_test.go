@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type testOrder struct {
+	Id               uint
+	Delivery_Address string
+}
+
+// openWithLogger opens a mocked database whose queries are logged (as JSON) in output
+func openWithLogger(t *testing.T, level slog.Level, output *bytes.Buffer) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: NewGormLogger(New(Config{Level: level, Output: output}))})
+	require.NoError(t, err)
+	return db, mock
+}
+
+// entries decodes the logged records
+func entries(t *testing.T, output *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		records = append(records, entry)
+	}
+	return records
+}
+
+func TestGormLogger_ParameterizedSQL(t *testing.T) {
+	var output bytes.Buffer
+	db, mock := openWithLogger(t, slog.LevelDebug, &output)
+
+	mock.ExpectQuery(`SELECT \* FROM "test_orders" WHERE delivery_address = \$1`).
+		WithArgs("Rua Augusta 1, Lisboa").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	var orders []testOrder
+	require.NoError(t, db.Where("delivery_address = ?", "Rua Augusta 1, Lisboa").Find(&orders).Error)
+
+	records := entries(t, &output)
+	require.Len(t, records, 1)
+	assert.Equal(t, "DEBUG", records[0]["level"])
+	assert.Equal(t, `SELECT * FROM "test_orders" WHERE delivery_address = $1`, records[0]["sql"])
+	assert.Equal(t, float64(1), records[0]["rows"])
+	assert.NotContains(t, output.String(), "Rua Augusta", "the values of the parameters are not logged")
+}
+
+func TestGormLogger_Levels(t *testing.T) {
+	var output bytes.Buffer
+	db, mock := openWithLogger(t, slog.LevelInfo, &output)
+
+	mock.ExpectQuery(`SELECT \* FROM "test_orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "test_orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "test_orders"`).WithArgs(7, "Rua Augusta 1, Lisboa", 1).WillReturnError(errors.New("connection reset"))
+
+	var orders []testOrder
+	require.NoError(t, db.Find(&orders).Error)
+	var order testOrder
+	assert.ErrorIs(t, db.First(&order).Error, gorm.ErrRecordNotFound)
+	assert.Error(t, db.Where("id = ? AND delivery_address = ?", 7, "Rua Augusta 1, Lisboa").First(&order).Error)
+
+	// the queries are logged at debug level, and a record not found is not a failure
+	records := entries(t, &output)
+	require.Len(t, records, 1)
+	assert.Equal(t, "ERROR", records[0]["level"])
+	assert.Equal(t, "Database query failed", records[0]["msg"])
+	assert.Equal(t, "connection reset", records[0]["error"])
+	assert.Contains(t, records[0]["sql"], "id = $1 AND delivery_address = $2")
+	assert.NotContains(t, output.String(), "Rua Augusta")
+}
@@ -0,0 +1,126 @@
+// Package logging configures the structured logs (log/slog) of the service. The records logged with a context
+// carry its correlation IDs (the request ID of the HTTP and gRPC requests, the ID of the Pub/Sub messages) and its
+// trace. In production the personal data of the customers (delivery addresses, coordinates and customer IDs) is
+// redacted: it must only be logged as an attribute under one of the SensitiveKeys, never in the message.
+//
+// The logs are configured by the Log section of the config (LOG_LEVEL, LOG_FORMAT and LOG_REDACT).
+package logging
+
+import (
+	"app/config"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Correlation attributes
+const (
+	RequestIDKey = "request_id"
+	MessageIDKey = "message_id"
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+)
+
+// Redacted replaces the values of the sensitive attributes
+const Redacted = "[REDACTED]"
+
+// SensitiveKeys are the attributes with personal data, redacted in production
+var SensitiveKeys = map[string]bool{
+	"address":          true,
+	"delivery_address": true,
+	"seller_address":   true,
+	"location":         true,
+	"latitude":         true,
+	"longitude":        true,
+	"coordinates":      true,
+	"customer_id":      true,
+	"user_id":          true,
+	"payload":          true, // raw bodies of the messages and requests
+}
+
+// Config of the logs
+type Config struct {
+	Level  slog.Level
+	Format string // json or text
+	Redact bool
+	Output io.Writer // stderr when it is not set
+}
+
+// ConfigOf returns the config of the logs of the service config
+func ConfigOf(log config.Log) Config {
+	return Config{Level: log.Level, Format: log.Format, Redact: log.Redacts()}
+}
+
+// New creates a logger of the config
+func New(config Config) *slog.Logger {
+	output := config.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	options := &slog.HandlerOptions{Level: config.Level}
+	if config.Redact {
+		options.ReplaceAttr = redact
+	}
+
+	var handler slog.Handler
+	if config.Format == "text" {
+		handler = slog.NewTextHandler(output, options)
+	} else {
+		handler = slog.NewJSONHandler(output, options)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+// Setup configures the default logger (also used by the log package)
+func Setup(config Config) *slog.Logger {
+	logger := New(config)
+	slog.SetDefault(logger)
+	return logger
+}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if SensitiveKeys[attr.Key] {
+		return slog.String(attr.Key, Redacted)
+	}
+	return attr
+}
+
+type attrsKey struct{}
+
+// NewContext returns a context whose records carry the attributes (in addition to the ones of ctx)
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	previous, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(previous)+len(attrs))
+	combined = append(combined, previous...)
+	combined = append(combined, attrs...)
+	return context.WithValue(ctx, attrsKey{}, combined)
+}
+
+// contextHandler adds the correlation attributes and the trace of the context to the records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+			record.AddAttrs(attrs...)
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String(TraceIDKey, span.TraceID().String()), slog.String(SpanIDKey, span.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
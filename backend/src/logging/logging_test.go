@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"app/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// record logs with a JSON logger of the config and returns the decoded record
+func record(t *testing.T, config Config, log func(logger *slog.Logger)) map[string]any {
+	t.Helper()
+	var output bytes.Buffer
+	config.Output = &output
+	log(New(config))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry), output.String())
+	return entry
+}
+
+func TestNew_RedactsThePersonalData(t *testing.T) {
+	log := func(logger *slog.Logger) {
+		logger.Info("Order placed", "order_id", 7, "delivery_address", "Rua Augusta 1, Lisboa", "customer_id", "c-42")
+	}
+
+	entry := record(t, Config{Redact: true}, log)
+	assert.Equal(t, Redacted, entry["delivery_address"])
+	assert.Equal(t, Redacted, entry["customer_id"])
+	assert.Equal(t, float64(7), entry["order_id"])
+
+	entry = record(t, Config{}, log)
+	assert.Equal(t, "Rua Augusta 1, Lisboa", entry["delivery_address"])
+	assert.Equal(t, "c-42", entry["customer_id"])
+}
+
+func TestNew_Level(t *testing.T) {
+	var output bytes.Buffer
+	logger := New(Config{Level: slog.LevelWarn, Output: &output})
+	logger.Info("not logged")
+	assert.Empty(t, output.String())
+}
+
+func TestNewContext_CorrelationIDs(t *testing.T) {
+	ctx := NewContext(context.Background(), slog.String(RequestIDKey, "req-1"))
+	ctx = NewContext(ctx, slog.String(MessageIDKey, "msg-1"))
+
+	entry := record(t, Config{}, func(logger *slog.Logger) { logger.InfoContext(ctx, "Message handled") })
+	assert.Equal(t, "req-1", entry[RequestIDKey])
+	assert.Equal(t, "msg-1", entry[MessageIDKey])
+	assert.NotContains(t, entry, TraceIDKey)
+}
+
+func TestNewContext_Trace(t *testing.T) {
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "handler")
+	defer span.End()
+
+	entry := record(t, Config{}, func(logger *slog.Logger) { logger.With("component", "test").InfoContext(ctx, "Update notarized") })
+	assert.Equal(t, span.SpanContext().TraceID().String(), entry[TraceIDKey])
+	assert.Equal(t, span.SpanContext().SpanID().String(), entry[SpanIDKey])
+	assert.Equal(t, "test", entry["component"])
+}
+
+func TestConfigOf(t *testing.T) {
+	redact := true
+	logs := ConfigOf(config.Log{Level: slog.LevelDebug, Format: config.LogText, Redact: &redact})
+	assert.Equal(t, Config{Level: slog.LevelDebug, Format: "text", Redact: true}, logs)
+
+	assert.False(t, ConfigOf(config.Log{}).Redact)
+}
+
+func TestMiddleware(t *testing.T) {
+	var output bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(New(Config{Output: &output}))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(), func(c *gin.Context) {
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), slog.String(RequestIDKey, "req-1")))
+	})
+	r.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/7?address=secret", nil))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry), output.String())
+	assert.Equal(t, "HTTP request", entry["msg"])
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "/orders/:id", entry["route"])
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	assert.Equal(t, "req-1", entry[RequestIDKey])
+	assert.NotContains(t, output.String(), "secret")
+}
@@ -3,33 +3,113 @@ package main
 
 import (
 	"app/blockchain"
+	"app/catalog"
+	"app/config"
+	"app/handlers"
+	"app/logging"
+	"app/metrics"
+	"app/migrations"
+	"app/repository"
 	"app/routes"
+	"app/supervisor"
     "app/pubsub"
+	"app/telemetry"
+	"app/tracking"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 
     googlepubsub "cloud.google.com/go/pubsub"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 // configure the database connection using gorm
-func configDB() (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s", os.Getenv("DB_HOST"), os.Getenv("DB_USER"), os.Getenv("DB_PASS"), os.Getenv("DB_NAME"), os.Getenv("DB_PORT"))
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	return db, err
+func configDB(cfg config.Database) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{Logger: logging.NewGormLogger(slog.Default())})
+	if err != nil {
+		return nil, err
+	}
+	// the queries of the requests and messages are traced
+	if err := db.Use(telemetry.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+	return db, nil
 }
 
-func configPubSubClient(db *gorm.DB, blockChainClient *blockchain.Client, topicID []string, subscriptionID []string) (*googlepubsub.Client, []*googlepubsub.Subscription, error) {
+// migrate runs the migrate subcommand: up (the default), status or baseline <version>
+func migrate(db *gorm.DB, args []string) error {
+	runner, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := runner.Up(ctx)
+		for _, migration := range applied {
+			slog.Info("Applied migration", "version", migration.Version, "name", migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			slog.Info("The database schema is up to date")
+		}
+		return err
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied != nil {
+				state = "applied " + status.Applied.Applied_At.Format(time.RFC3339)
+				if status.Applied.Baseline {
+					state += " (baseline)"
+				}
+			}
+			fmt.Printf("%03d_%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	case "baseline":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate baseline <version>")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := runner.Baseline(ctx, version); err != nil {
+			return err
+		}
+		slog.Info("Database baselined", "version", version)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, status or baseline <version>", command)
+	}
+}
+
+func configPubSubClient(cfg config.PubSub, topicID []string, subscriptionID []string) (*googlepubsub.Client, []*googlepubsub.Subscription, error) {
     ctx := context.Background()
-    pubsubClient, err := pubsub.StartPubSubClient(ctx, db, blockChainClient)
+    pubsubClient, err := pubsub.StartPubSubClient(ctx, cfg)
 
     if err != nil {
         return nil, nil, err
@@ -46,39 +126,61 @@ func configPubSubClient(db *gorm.DB, blockChainClient *blockchain.Client, topicI
 }
 
 
-func configBlockChainClient() (*blockchain.Client, error) {
-	rpcURL := os.Getenv("BLOCKCHAIN_RPC_URL")
-	if rpcURL == "" {
+// grpcWorker is the worker serving the gRPC tracking service on the gRPC port of the config
+func grpcWorker(cfg *config.Config, db *gorm.DB, blockChainClient *blockchain.Client, sup *supervisor.Supervisor) (supervisor.Worker, error) {
+	listener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		return supervisor.Worker{}, err
+	}
+
+	var options []grpc.ServerOption
+	if cfg.GRPC.TLS() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.GRPC.TLSCertFile, cfg.GRPC.TLSKeyFile)
+		if err != nil {
+			listener.Close()
+			return supervisor.Worker{}, fmt.Errorf("failed to load the gRPC TLS certificate: %w", err)
+		}
+		options = append(options, grpc.Creds(creds))
+	} else {
+		slog.Warn("Serving the gRPC calls without TLS, keep the gRPC port on an internal network")
+	}
+
+	// the watches of the orders are cut when the server drains, the callers watch again on another instance
+	streams := supervisor.NewStreams()
+	options = append(options, grpc.ChainStreamInterceptor(streams.Interceptor()))
+
+	server := tracking.NewGRPCServer(tracking.NewServer(db, blockChainClient, cfg, sup.Tasks), options...)
+	return supervisor.GRPCServer("grpc", server, listener, streams), nil
+}
+
+
+// configBlockChainClient connects to the chain, the updates are notarized in the active contract of the registry
+func configBlockChainClient(cfg config.Blockchain, db *gorm.DB) (*blockchain.Client, error) {
+	if !cfg.Enabled() {
 		return nil, nil
 	}
 	//connect to the block chain client
-	client, err := blockchain.NewClient()
+	client, err := blockchain.NewClient(context.Background(), cfg)
 	if err != nil {
 
 		return nil, err
 	}
+	if err := blockchain.LoadDeployments(context.Background(), repository.NewPostgres(db).Deployments(), client, cfg.ContractAddress); err != nil {
+		client.Close()
+		return nil, err
+	}
 	return client, nil
 }
 
-// Configure the router that will be used for the API
-func configRouter(db *gorm.DB) (*gin.Engine, *blockchain.Client, error) {
-	router := gin.Default()
-
-	// Configure CORS middleware (Allow frontend and localhost)
+// Configure the router that will be used for the API (the notifier is nil when Pub/Sub is not available)
+func configRouter(cfg *config.Config, db *gorm.DB, notifier handlers.Notifier, sup *supervisor.Supervisor) (*gin.Engine, *blockchain.Client, error) {
+	// gin.Default without its logger: the requests are logged by the structured logs
+	router := gin.New()
+	router.Use(gin.Recovery(), logging.Middleware())
 
+	// Configure CORS middleware (the origins of the config: the frontend, Cloud Run and localhost by default)
 	router.Use(cors.New(cors.Config{
-		AllowOriginFunc: func(origin string) bool {
-			// Allow localhost for development
-
-			if strings.HasPrefix(origin, "http://localhost") {
-				return true
-			}
-			// Allow any .run.app domain (Cloud Run)
-			if strings.HasSuffix(origin, ".run.app") || strings.HasPrefix(origin, "https://frontend.madeinportugal.store") {
-				return true
-			}
-			return false
-		},
+		AllowOriginFunc:  cfg.HTTP.AllowsOrigin,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		ExposeHeaders:    []string{"Content-Length"},
@@ -86,75 +188,147 @@ func configRouter(db *gorm.DB) (*gin.Engine, *blockchain.Client, error) {
 		MaxAge:           12 * time.Hour,
 	}))
 
-	blockChainClient, err := configBlockChainClient()
+	blockChainClient, err := configBlockChainClient(cfg.Blockchain, db)
 
 	if err != nil {
 		return nil,nil, err
 	}
 
 	//registers the routes
-	routes.RegisterRoutes(router, db, blockChainClient)
+	routes.RegisterRoutes(router, db, blockChainClient, notifier, cfg, sup)
 	return router, blockChainClient, nil
 }
 
 
 func main() {
-	db, err := configDB()
+	// The config is validated before anything is started, every invalid setting is reported (by the default logger,
+	// the logs are configured by the config)
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Invalid config", "error", err)
+		os.Exit(1)
+	}
+
+	// Structured logs (the Log section of the config), configured first so every component logs through them
+	logging.Setup(logging.ConfigOf(cfg.Log))
+
+	// SIGTERM (a deploy or a scale down) and Ctrl+C drain the service
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, cfg, os.Args[1:]); err != nil {
+		slog.Error("The service stopped with an error", "error", err)
+		stop()
+		os.Exit(1)
+	}
+}
 
+// run configures the service and runs its workers until ctx is done (or runs the migrate subcommand)
+func run(ctx context.Context, cfg *config.Config, args []string) error {
+	// Export the traces (the Tracing section of the config), the pending spans are flushed when run returns
+	shutdownTracing, err := telemetry.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Printf("Error while conecting to the database: %v", err)
-		return
+		return fmt.Errorf("configuring the tracing: %w", err)
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Error while flushing the traces", "error", err)
+		}
+	}()
 
-	router, blockChainClient, err := configRouter(db)
+	// The product catalogue is shared by the REST API, the gRPC service, the listeners and the syncer
+	catalog.SetDefault(catalog.NewClient(catalog.ConfigOf(cfg.Jumpseller)))
 
+	db, err := configDB(cfg.Database)
 	if err != nil {
-		log.Printf("Error while configuring the routing: %v", err)
-		return
+		return fmt.Errorf("connecting to the database: %w", err)
 	}
 
-	// Create and start the Pub/Sub client
-    ctx := context.Background()
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrate(db, args[1:]); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		return nil
+	}
 
-	
-	// Configure Pub/Sub client and subscriptions 
-    client, subs, err := configPubSubClient(db, blockChainClient, []string{}, []string{}) // Empty topic and subscription IDs since they are not created yet by the checkout team
+	// Bring the schema up to date before serving (MIGRATE_ON_START=false leaves it to the migrate subcommand)
+	if cfg.Database.MigrateOnStart {
+		if err := migrate(db, nil); err != nil {
+			return fmt.Errorf("migrating the database: %w", err)
+		}
+	}
+
+	// Every long-running component runs under the supervisor, which drains them on SIGTERM
+	sup := supervisor.New(time.Duration(cfg.ShutdownTimeout))
+	sup.PreStopDelay = time.Duration(cfg.PreStopDelay)
 
+	// Configure Pub/Sub client and subscriptions (before the router, the handlers use it to notify the customers)
+	client, subs, err := configPubSubClient(cfg.PubSub, []string{}, []string{}) // Empty topic and subscription IDs since they are not created yet by the checkout team
+
+	var notifier handlers.Notifier
 	if err != nil {
-        log.Printf("Error configuring PubSub: %v", err)
-        // Continue without PubSub
-        log.Printf("Continuing without PubSub functionality")
-    } else if client != nil {
+		// Continue without PubSub
+		slog.Warn("Error configuring PubSub, continuing without PubSub functionality", "error", err)
+	} else if client != nil {
+		// Closed after the listeners are drained
+		defer client.Close()
+		notifier = pubsub.NewNotifier(client)
+	}
+
+	router, blockChainClient, err := configRouter(cfg, db, notifier, sup)
+	if err != nil {
+		return fmt.Errorf("configuring the routing: %w", err)
+	}
+	if blockChainClient != nil {
+		defer blockChainClient.Close()
+
+		// The wallet paying for the transactions is reported in the metrics
+		metrics.WatchWallet(func(ctx context.Context) (*big.Int, error) {
+			return blockChainClient.EthClient.BalanceAt(ctx, blockChainClient.WalletAddress, nil)
+		})
+	}
+
+	sup.Add(supervisor.HTTPServer("http", &http.Server{Addr: ":" + cfg.HTTP.Port, Handler: router}))
+
+	// The gRPC tracking service runs the same operations as the REST API, for the services of the other teams
+	grpcServer, err := grpcWorker(cfg, db, blockChainClient, sup)
+	if err != nil {
+		return fmt.Errorf("starting the gRPC server: %w", err)
+	}
+	sup.Add(grpcServer)
+
+	// Keep the local product catalogue in sync with Jumpseller
+	if cfg.Jumpseller.Enabled() {
+		sup.Add(supervisor.Worker{Name: "catalog-sync", Run: catalog.NewSyncer(db, catalog.Default()).Run})
+	} else {
+		slog.Warn("JUMPSELLER_BASE_URL not set, the product catalogue will not be synced")
+	}
+
+	if client != nil {
 		// List all topics and subscriptions (for debugging)
 		pubsub.ListAllTopics(ctx, client)
 		pubsub.ListAllSubscriptions(ctx, client)
 
 		// Create notifications topic to send notifications whenever status updates occur
 		//_, err = pubsub.CreateTopicWithID(ctx, client, "tracking-notifications")  -  Notifications are already created
-        defer client.Close() // Close client when main exits
-        
-        // Only start listeners if we have subscriptions
-        if len(subs) > 0 {
-            err = pubsub.StartListener(ctx, client, subs[0], db, blockChainClient)
-            if err != nil {
-                log.Printf("Error starting PubSub listener for order status: %v", err)
-            }
-        } else {
-            log.Printf("No subscriptions configured, skipping listeners")
-        }
-        
-        if len(subs) > 1 {
-            err = pubsub.StartListenerOrders(ctx, client, subs[1], db, blockChainClient)
-            if err != nil {
-                log.Printf("Error starting PubSub listener for checkout orders: %v", err)
-            }
-        }
-
-        //pubsub.TestOrdersPubSub()  // Uncomment to test order publishing
-    }
-	
 
+		// Only start listeners if we have subscriptions
+		if len(subs) > 0 {
+			sup.Add(supervisor.Worker{Name: "pubsub-" + subs[0].ID(), Run: func(ctx context.Context) error {
+				return pubsub.Listen(ctx, client, subs[0], db, blockChainClient, cfg)
+			}})
+		} else {
+			slog.Info("No subscriptions configured, skipping listeners")
+		}
 
-	router.Run(":8080") // listens on 0.0.0.0:8080 by default
-}
+		if len(subs) > 1 {
+			sup.Add(supervisor.Worker{Name: "pubsub-" + subs[1].ID(), Run: func(ctx context.Context) error {
+				return pubsub.ListenOrders(ctx, client, subs[1], db, blockChainClient, cfg, sup.Tasks)
+			}})
+		}
 
+		//pubsub.TestOrdersPubSub()  // Uncomment to test order publishing
+	}
+
+	return sup.Run(ctx)
+}
@@ -2,19 +2,18 @@ package main
 
 import (
     "app/blockchain"
+    "app/config"
     "app/routes"
     "github.com/gin-gonic/gin"
     "gorm.io/gorm"
     "net/http"
     "net/http/httptest"
-    "os"
     "testing"
 )
 
 
 func TestConfigBlockChainClient_NoRPCURL(t *testing.T) {
-    os.Setenv("BLOCKCHAIN_RPC_URL", "")
-    client, err := configBlockChainClient()
+    client, err := configBlockChainClient(config.Blockchain{}, &gorm.DB{})
     if err != nil {
         t.Fatalf("expected no error, got %v", err)
     }
@@ -26,7 +25,7 @@ func TestConfigBlockChainClient_NoRPCURL(t *testing.T) {
 func TestConfigRouter_PingRoute(t *testing.T) {
     r := gin.Default()
     // Use nil DB and dummy blockchain client
-    routes.RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{})
+    routes.RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
 
     req := httptest.NewRequest(http.MethodGet, "/ping", nil)
     w := httptest.NewRecorder()
@@ -41,7 +40,7 @@ func TestConfigRouter_PingRoute(t *testing.T) {
 }
 
 func TestConfigRouter_CORS(t *testing.T) {
-    r, _, err := configRouter(&gorm.DB{})
+    r, _, err := configRouter(config.Default(), &gorm.DB{}, nil, nil)
     if err != nil {
         t.Fatalf("unexpected error: %v", err)
     }
@@ -0,0 +1,181 @@
+// Package metrics keeps the Prometheus metrics of the service and serves them on /metrics. The packages record
+// their metrics with the Observe functions, which do not fail and do not block
+package metrics
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tracking"
+
+// Outcomes of the Pub/Sub messages
+const (
+	MessageProcessed = "processed"
+	MessageFailed    = "failed"
+)
+
+// Registry has the metrics of the service and the Go runtime and process metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of the HTTP requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	pubsubMessages = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pubsub_messages_total",
+		Help:      "Pub/Sub messages received by subscription and outcome (processed or failed).",
+	}, []string{"subscription", "outcome"})
+
+	chainSubmitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chain_submit_duration_seconds",
+		Help:      "Latency of the transactions submitted to the blockchain by operation.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"operation"})
+
+	chainSubmitFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chain_submit_failures_total",
+		Help:      "Transactions that could not be submitted to the blockchain by operation.",
+	}, []string{"operation"})
+
+	verifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "verifications_total",
+		Help:      "Verifications of orders against the blockchain by status.",
+	}, []string{"status"})
+
+	jumpsellerRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "jumpseller_request_duration_seconds",
+		Help:      "Latency of the requests to the Jumpseller API by response status (error when there is no response).",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"status"})
+
+	wallet = &walletCollector{desc: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "wallet_balance_eth"),
+		"Balance of the wallet paying for the blockchain transactions, read when the metrics are collected.",
+		nil, nil,
+	)}
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		pubsubMessages,
+		chainSubmitDuration,
+		chainSubmitFailures,
+		verifications,
+		jumpsellerRequestDuration,
+		wallet,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	return gin.WrapH(handler)
+}
+
+// Middleware records the latency of the requests by the route that matched them ("unmatched" for the unknown
+// routes, so the paths of the requests do not become labels)
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObservePubSubMessage counts a message of a subscription
+func ObservePubSubMessage(subscription string, processed bool) {
+	outcome := MessageProcessed
+	if !processed {
+		outcome = MessageFailed
+	}
+	pubsubMessages.WithLabelValues(subscription, outcome).Inc()
+}
+
+// ObserveChainSubmit records the latency of a transaction submitted to the blockchain, and counts it as failed when
+// it could not be submitted
+func ObserveChainSubmit(operation string, duration time.Duration, err error) {
+	chainSubmitDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		chainSubmitFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveVerification counts a verification of an order by its status
+func ObserveVerification(status string) {
+	verifications.WithLabelValues(status).Inc()
+}
+
+// ObserveJumpsellerRequest records the latency of a request to Jumpseller by its response status (0 when the
+// request got no response)
+func ObserveJumpsellerRequest(status int, duration time.Duration) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	jumpsellerRequestDuration.WithLabelValues(label).Observe(duration.Seconds())
+}
+
+// WatchWallet reports the wallet balance (in wei) returned by balance. It is read on each collection, with a timeout,
+// and the metric is left out when it fails. A nil balance stops reporting it
+func WatchWallet(balance func(ctx context.Context) (*big.Int, error)) {
+	wallet.mu.Lock()
+	defer wallet.mu.Unlock()
+	wallet.balance = balance
+}
+
+// walletTimeout bounds the balance request, a slow node must not hold the scrape
+const walletTimeout = 3 * time.Second
+
+type walletCollector struct {
+	desc    *prometheus.Desc
+	mu      sync.Mutex
+	balance func(ctx context.Context) (*big.Int, error)
+}
+
+func (w *walletCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- w.desc
+}
+
+func (w *walletCollector) Collect(ch chan<- prometheus.Metric) {
+	w.mu.Lock()
+	balance := w.balance
+	w.mu.Unlock()
+	if balance == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), walletTimeout)
+	defer cancel()
+	wei, err := balance(ctx)
+	if err != nil || wei == nil {
+		return
+	}
+	eth, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	ch <- prometheus.MustNewConstMetric(w.desc, prometheus.GaugeValue, eth)
+}
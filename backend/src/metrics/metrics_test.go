@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_RecordsTheRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/api/v2/orders/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	before := testutil.CollectAndCount(httpRequestDuration)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/orders/7", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v2/orders/8", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown/path", nil))
+
+	// the requests are grouped by the route, not by the path
+	assert.Equal(t, before+2, testutil.CollectAndCount(httpRequestDuration))
+	assert.Equal(t, uint64(2), sampleCount(t, "GET", "/api/v2/orders/:id", "200"))
+	assert.Equal(t, uint64(1), sampleCount(t, "GET", "unmatched", "404"))
+}
+
+func sampleCount(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "tracking_http_request_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			values := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				values[label.GetName()] = label.GetValue()
+			}
+			if values["method"] == labels[0] && values["route"] == labels[1] && values["status"] == labels[2] {
+				return metric.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestObserveChainSubmit_CountsFailures(t *testing.T) {
+	ObserveChainSubmit("test_submit", 300*time.Millisecond, nil)
+	ObserveChainSubmit("test_submit", time.Second, errors.New("nonce too low"))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(chainSubmitFailures.WithLabelValues("test_submit")))
+}
+
+func TestObservePubSubMessage(t *testing.T) {
+	ObservePubSubMessage("test-sub", true)
+	ObservePubSubMessage("test-sub", true)
+	ObservePubSubMessage("test-sub", false)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(pubsubMessages.WithLabelValues("test-sub", MessageProcessed)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(pubsubMessages.WithLabelValues("test-sub", MessageFailed)))
+}
+
+func TestWatchWallet(t *testing.T) {
+	defer WatchWallet(nil)
+
+	// not reported until there is a wallet
+	assert.Equal(t, 0, testutil.CollectAndCount(wallet))
+
+	balance, _ := new(big.Int).SetString("1500000000000000000", 10)
+	WatchWallet(func(ctx context.Context) (*big.Int, error) { return balance, nil })
+	assert.Equal(t, 1.5, testutil.ToFloat64(wallet))
+
+	// a failed read leaves the metric out instead of reporting 0
+	WatchWallet(func(ctx context.Context) (*big.Int, error) { return nil, errors.New("node unreachable") })
+	assert.Equal(t, 0, testutil.CollectAndCount(wallet))
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/metrics", Handler())
+	ObserveVerification("VERIFIED")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `tracking_verifications_total{status="VERIFIED"}`))
+	assert.True(t, strings.Contains(w.Body.String(), "go_goroutines"))
+}
@@ -1,10 +1,3 @@
---Remove any content that already exists in the db
-DROP TABLE IF EXISTS orders CASCADE;
-DROP TABLE IF EXISTS order_status_history CASCADE;
-DROP TABLE IF EXISTS order_products CASCADE;
-DROP TABLE IF EXISTS storages CASCADE;
-DROP TYPE IF EXISTS order_state CASCADE;
-
 --Create an enum with all the possible order states
 CREATE TYPE order_state AS ENUM ('PROCESSING', 'SHIPPED', 'IN TRANSIT', 'OUT FOR DELIVERY', 'DELIVERED', 'CANCELLED', 'RETURNED', 'FAILED DELIVERY');
 
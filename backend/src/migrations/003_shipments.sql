@@ -0,0 +1,18 @@
+-- Shipments: an order can be split in several parcels, each with its own tracking code, products and status history
+CREATE TABLE shipments (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tracking_code TEXT UNIQUE NOT NULL,
+    storage_id INTEGER REFERENCES storages(id) ON DELETE SET NULL, -- warehouse the parcel ships from
+    delivery_estimate TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Products and status updates can belong to a shipment (NULL for order level data)
+ALTER TABLE order_products ADD COLUMN shipment_id INTEGER REFERENCES shipments(id) ON DELETE SET NULL;
+ALTER TABLE order_status_history ADD COLUMN shipment_id INTEGER REFERENCES shipments(id);
+
+-- Index for faster lookups
+CREATE INDEX idx_shipment_order_id ON shipments(order_id);
+CREATE INDEX idx_order_product_shipment_id ON order_products(shipment_id);
+CREATE INDEX idx_status_shipment_time ON order_status_history(shipment_id, timestamp_history);
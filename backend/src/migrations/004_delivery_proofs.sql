@@ -0,0 +1,26 @@
+-- Hash of the proof of delivery bundle, part of the hash stored in the blockchain
+ALTER TABLE order_status_history ADD COLUMN proof_hash TEXT;
+
+-- Proof of delivery attached to DELIVERED updates (the media is kept in the blob storage)
+CREATE TABLE delivery_proofs (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_status_history_id INTEGER NOT NULL REFERENCES order_status_history(id),
+    recipient_name TEXT,
+    signature_key TEXT,
+    signature_hash TEXT,
+    photo_key TEXT,
+    photo_hash TEXT,
+    latitude DECIMAL(10, 8),
+    longitude DECIMAL(11, 8),
+    proof_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_delivery_proof_order_id ON delivery_proofs(order_id);
+
+-- Proofs are evidence for disputes, they can not be changed
+CREATE TRIGGER update_delivery_proofs
+BEFORE UPDATE OR DELETE ON delivery_proofs
+FOR EACH ROW
+EXECUTE FUNCTION update_history_violation();
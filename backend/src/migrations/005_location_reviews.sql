@@ -0,0 +1,22 @@
+-- Where the courier was when sending the update (optional)
+ALTER TABLE order_status_history ADD COLUMN latitude DECIMAL(10, 8);
+ALTER TABLE order_status_history ADD COLUMN longitude DECIMAL(11, 8);
+
+CREATE TYPE review_state AS ENUM ('PENDING', 'APPROVED', 'REJECTED');
+
+-- Updates sent too far from the storage or delivery address, kept until someone reviews them
+CREATE TABLE location_reviews (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_status_history_id INTEGER NOT NULL REFERENCES order_status_history(id),
+    reason TEXT NOT NULL,
+    distance_km DOUBLE PRECISION NOT NULL,
+    tolerance_km DOUBLE PRECISION NOT NULL,
+    review_status review_state NOT NULL DEFAULT 'PENDING',
+    reviewer_note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP
+);
+
+CREATE INDEX idx_location_review_order_id ON location_reviews(order_id);
+CREATE INDEX idx_location_review_status ON location_reviews(review_status);
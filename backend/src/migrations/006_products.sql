@@ -0,0 +1,32 @@
+-- Local copy of the Jumpseller catalogue (filled by the catalogue sync)
+CREATE TABLE products (
+    id INTEGER PRIMARY KEY, -- same id as in Jumpseller
+    name TEXT NOT NULL,
+    sku TEXT,
+    brand TEXT,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    status TEXT,
+    stock INTEGER NOT NULL DEFAULT 0,
+    stock_unlimited BOOLEAN NOT NULL DEFAULT FALSE,
+    weight DOUBLE PRECISION NOT NULL DEFAULT 0,
+    length DOUBLE PRECISION NOT NULL DEFAULT 0,
+    width DOUBLE PRECISION NOT NULL DEFAULT 0,
+    height DOUBLE PRECISION NOT NULL DEFAULT 0,
+    package_format TEXT,
+    synced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE product_variants (
+    id INTEGER PRIMARY KEY, -- same id as in Jumpseller
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    sku TEXT,
+    price DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    stock INTEGER NOT NULL DEFAULT 0,
+    stock_unlimited BOOLEAN NOT NULL DEFAULT FALSE,
+    options JSONB,
+    synced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_product_name ON products(lower(name));
+CREATE INDEX idx_product_sku ON products(sku);
+CREATE INDEX idx_product_variant_product_id ON product_variants(product_id);
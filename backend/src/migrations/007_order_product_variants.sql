@@ -0,0 +1,4 @@
+-- Variant of the product that was ordered (snapshot taken when the order was placed)
+ALTER TABLE order_products ADD COLUMN variant_id INTEGER;
+ALTER TABLE order_products ADD COLUMN variant_sku TEXT;
+ALTER TABLE order_products ADD COLUMN variant_options JSONB;
@@ -0,0 +1,3 @@
+-- Shipping weight of the order, computed from the weight and dimensions of its products (kg)
+ALTER TABLE orders ADD COLUMN shipping_weight DECIMAL(10,3) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN volumetric_weight DECIMAL(10,3) NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+CREATE TYPE order_change_action AS ENUM ('ADDED', 'UPDATED', 'REMOVED');
+
+-- Changes made to the products of an order after it was placed (append only)
+CREATE TABLE order_changes (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    order_product_id INTEGER NOT NULL, -- no foreign key, removed products are deleted from order_products
+    action order_change_action NOT NULL,
+    product_id INTEGER NOT NULL,
+    variant_id INTEGER,
+    product_name TEXT NOT NULL,
+    quantity_before INTEGER NOT NULL,
+    quantity_after INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_order_change_order_id ON order_changes(order_id);
@@ -0,0 +1,28 @@
+CREATE TYPE audit_action AS ENUM ('CREATE', 'UPDATE', 'DELETE');
+CREATE TYPE audit_source AS ENUM ('HTTP', 'PUBSUB', 'ADMIN');
+
+-- Changes to orders and order_products (those tables are updated in place)
+CREATE TABLE audit_events (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL, -- no foreign key, the events outlive the rows
+    table_name TEXT NOT NULL,
+    record_id INTEGER NOT NULL,
+    action audit_action NOT NULL,
+    before JSONB,
+    after JSONB,
+    actor TEXT NOT NULL,
+    source audit_source NOT NULL,
+    request_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    hash TEXT NOT NULL,
+    blockchain_transaction TEXT
+);
+
+CREATE INDEX idx_audit_event_order_id ON audit_events(order_id);
+CREATE INDEX idx_audit_event_request_id ON audit_events(request_id);
+
+-- Audit events are append only, the same as order_status_history
+CREATE TRIGGER update_audit_events
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW
+EXECUTE FUNCTION update_history_violation();
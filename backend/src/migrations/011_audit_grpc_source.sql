@@ -0,0 +1,2 @@
+-- Changes made through the gRPC tracking service
+ALTER TYPE audit_source ADD VALUE IF NOT EXISTS 'GRPC';
@@ -0,0 +1,21 @@
+-- Registry of the deployments of the tracking contract, on every chain the service used
+CREATE TABLE contract_deployments (
+    id SERIAL PRIMARY KEY,
+    chain_id BIGINT NOT NULL,
+    contract_address TEXT NOT NULL,
+    deploy_transaction TEXT, -- null for the contracts registered from the config, deployed before the registry
+    abi_version TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    legacy BOOLEAN NOT NULL DEFAULT FALSE, -- the contract of the updates notarized before the registry
+    deployed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (chain_id, contract_address)
+);
+
+-- The updates are notarized in the active contract of the chain of the service
+CREATE UNIQUE INDEX idx_contract_deployment_active ON contract_deployments(chain_id) WHERE active;
+CREATE UNIQUE INDEX idx_contract_deployment_legacy ON contract_deployments(legacy) WHERE legacy;
+
+-- The updates and audit events are pinned to the contract their hash was stored in, so they are verified against it
+-- after the contract is rotated or the service moves to another chain (null before the registry)
+ALTER TABLE order_status_history ADD COLUMN contract_deployment_id INTEGER REFERENCES contract_deployments(id);
+ALTER TABLE audit_events ADD COLUMN contract_deployment_id INTEGER REFERENCES contract_deployments(id);
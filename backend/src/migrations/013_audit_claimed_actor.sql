@@ -0,0 +1,3 @@
+-- The actor named by the caller (the X-Actor header, the x-actor metadata), which is not verified. The actor of the
+-- events is the authenticated caller from now on, the actors recorded before were claimed
+ALTER TABLE audit_events ADD COLUMN claimed_actor TEXT;
@@ -0,0 +1,3 @@
+-- The changes of the order products are derived from their audit events (010), which record every one of them. The
+-- table is kept for the changes recorded before, it is no longer written
+COMMENT ON TABLE order_changes IS 'Superseded by audit_events, no longer written';
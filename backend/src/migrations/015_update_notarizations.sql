@@ -0,0 +1,14 @@
+-- The chain transaction of the updates notarized once they were stored (their hash depends on ids only known then).
+-- The updates are append only (update_history trigger), so the notarization is recorded in its own append only table
+-- instead of being written to the update
+CREATE TABLE update_notarizations (
+    order_status_history_id INTEGER PRIMARY KEY REFERENCES order_status_history(id),
+    blockchain_transaction TEXT NOT NULL,
+    contract_deployment_id INTEGER REFERENCES contract_deployments(id),
+    notarized_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_notarizations_history
+BEFORE UPDATE OR DELETE ON update_notarizations
+FOR EACH ROW
+EXECUTE FUNCTION update_history_violation();
@@ -0,0 +1,14 @@
+-- The chain transaction the audit events were anchored in, once their transaction was committed. The audit events are
+-- append only (update_audit_events trigger), so the anchor is recorded in its own append only table instead of being
+-- written to the event
+CREATE TABLE audit_anchors (
+    audit_event_id INTEGER PRIMARY KEY REFERENCES audit_events(id),
+    blockchain_transaction TEXT NOT NULL,
+    contract_deployment_id INTEGER REFERENCES contract_deployments(id),
+    anchored_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_audit_anchors
+BEFORE UPDATE OR DELETE ON audit_anchors
+FOR EACH ROW
+EXECUTE FUNCTION update_history_violation();
@@ -0,0 +1,2 @@
+-- A return requested by the customer, the order is RETURNED once the return is completed
+ALTER TYPE order_state ADD VALUE IF NOT EXISTS 'RETURN REQUESTED' BEFORE 'RETURNED';
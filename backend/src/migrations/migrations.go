@@ -0,0 +1,288 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The schema migrations, named <version>_<name>.sql. Migrations are forward only: once released a file is never
+// edited, schema changes ship as a new file with the next version
+//
+//go:embed *.sql
+var files embed.FS
+
+// Key of the Postgres advisory lock held while migrating (several instances may start at the same time)
+const lockKey = 7_305_901
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+var (
+	ErrNotBaselined = errors.New("the database has a schema but no migration state, baseline it with `migrate baseline <version>`")
+	ErrBaselined    = errors.New("the database already has a migration state")
+)
+
+// Migration is a versioned SQL file
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// SchemaMigration is a row of the migration state table
+type SchemaMigration struct {
+	Version    int `gorm:"primaryKey;autoIncrement:false"`
+	Name       string
+	Checksum   string
+	Baseline   bool // marked as applied by the baseline, the SQL was not run
+	Applied_At time.Time
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+const createStateTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    baseline BOOLEAN NOT NULL DEFAULT FALSE,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Status of a migration in the database (Applied is nil while it is pending)
+type Status struct {
+	Migration
+	Applied *SchemaMigration
+}
+
+// Runner applies the migrations to a database
+type Runner struct {
+	DB         *gorm.DB
+	Migrations []Migration
+}
+
+// New creates a runner with the migrations embedded in the binary
+func New(db *gorm.DB) (*Runner, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{DB: db, Migrations: migrations}, nil
+}
+
+// Load reads the migrations in the root of fsys, sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	versions := map[int]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected <version>_<name>.sql", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, entry.Name())
+		}
+		versions[version] = entry.Name()
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(data)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     match[2],
+			SQL:      string(data),
+			Checksum: hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies the pending migrations, each one in its own transaction. Returns the migrations applied
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.locked(ctx, func(conn *gorm.DB) error {
+		state, err := r.state(conn)
+		if err != nil {
+			return err
+		}
+
+		if len(state) == 0 {
+			existing, err := hasSchema(conn)
+			if err != nil {
+				return err
+			}
+			if existing {
+				return ErrNotBaselined
+			}
+		}
+
+		pending, err := r.pending(state)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.SQL).Error; err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{
+					Version:    migration.Version,
+					Name:       migration.Name,
+					Checksum:   migration.Checksum,
+					Applied_At: time.Now(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Baseline marks the migrations up to version as applied without running them. It is used once on databases that
+// were created before the runner existed (by the Postgres init scripts), version being the last migration they have
+func (r *Runner) Baseline(ctx context.Context, version int) error {
+	found := false
+	for _, migration := range r.Migrations {
+		found = found || migration.Version == version
+	}
+	if !found {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return r.locked(ctx, func(conn *gorm.DB) error {
+		state, err := r.state(conn)
+		if err != nil {
+			return err
+		}
+		if len(state) > 0 {
+			return ErrBaselined
+		}
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			for _, migration := range r.Migrations {
+				if migration.Version > version {
+					break
+				}
+				row := SchemaMigration{Version: migration.Version, Name: migration.Name, Checksum: migration.Checksum, Baseline: true, Applied_At: now}
+				if err := tx.Create(&row).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// Status lists the migrations and whether they were applied
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := r.locked(ctx, func(conn *gorm.DB) error {
+		state, err := r.state(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range r.Migrations {
+			status := Status{Migration: migration}
+			if row, ok := state[migration.Version]; ok {
+				status.Applied = &row
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// pending returns the migrations that were not applied yet. Fails when an applied migration was edited or when a new
+// migration has a version lower than the last applied one (it would run out of order). Applied migrations this
+// binary does not know are ignored, they come from a newer release (during a rollout both versions run)
+func (r *Runner) pending(state map[int]SchemaMigration) ([]Migration, error) {
+	latest := 0
+	for _, migration := range r.Migrations {
+		if row, ok := state[migration.Version]; ok {
+			if row.Checksum != migration.Checksum {
+				return nil, fmt.Errorf("migration %03d_%s was changed after it was applied", migration.Version, migration.Name)
+			}
+			if migration.Version > latest {
+				latest = migration.Version
+			}
+		}
+	}
+	var pending []Migration
+	for _, migration := range r.Migrations {
+		if _, ok := state[migration.Version]; ok {
+			continue
+		}
+		if migration.Version < latest {
+			return nil, fmt.Errorf("migration %03d_%s is older than the last applied migration %03d", migration.Version, migration.Name, latest)
+		}
+		pending = append(pending, migration)
+	}
+	return pending, nil
+}
+
+// state creates the state table if needed and returns the applied migrations by version
+func (r *Runner) state(conn *gorm.DB) (map[int]SchemaMigration, error) {
+	if err := conn.Exec(createStateTable).Error; err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	if err := conn.Order("version asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	state := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		state[row.Version] = row
+	}
+	return state, nil
+}
+
+// locked runs fn on a single connection holding the migration lock
+func (r *Runner) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return r.DB.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+		return fn(conn)
+	})
+}
+
+// hasSchema reports whether the tables of the service already exist
+func hasSchema(conn *gorm.DB) (bool, error) {
+	var exists bool
+	err := conn.Raw("SELECT to_regclass('public.orders') IS NOT NULL").Scan(&exists).Error
+	return exists, err
+}
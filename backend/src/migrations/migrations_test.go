@@ -0,0 +1,215 @@
+package migrations
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+var testFiles = fstest.MapFS{
+	"001_init.sql":   {Data: []byte("CREATE TABLE orders (id SERIAL PRIMARY KEY);")},
+	"002_status.sql": {Data: []byte("ALTER TABLE orders ADD COLUMN status TEXT;")},
+	"README.md":      {Data: []byte("not a migration")},
+}
+
+func setupRunner(t *testing.T) (*Runner, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+
+	migrations, err := Load(testFiles)
+	require.NoError(t, err)
+	return &Runner{DB: gdb, Migrations: migrations}, mock
+}
+
+// expectState expects the lock and the read of the state table, returning the given applied migrations
+func expectState(mock sqlmock.Sqlmock, applied ...Migration) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "baseline", "applied_at"})
+	for _, migration := range applied {
+		rows.AddRow(migration.Version, migration.Name, migration.Checksum, false, time.Now())
+	}
+	mock.ExpectQuery(`SELECT \* FROM "schema_migrations" ORDER BY version asc`).WillReturnRows(rows)
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFiles)
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "init", migrations[0].Name)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Len(t, migrations[1].Checksum, 64)
+}
+
+func TestLoad_InvalidFiles(t *testing.T) {
+	_, err := Load(fstest.MapFS{"add_status.sql": {Data: []byte("")}})
+	assert.ErrorContains(t, err, "invalid migration file name")
+
+	_, err = Load(fstest.MapFS{"1_a.sql": {Data: []byte("")}, "001_b.sql": {Data: []byte("")}})
+	assert.ErrorContains(t, err, "have the same version")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	runner, err := New(nil)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, runner.Migrations)
+	for i, migration := range runner.Migrations {
+		assert.Equal(t, i+1, migration.Version, "the versions have no gaps")
+		assert.NotContains(t, migration.SQL, "DROP TABLE", "migrations are forward only")
+	}
+}
+
+func TestUp_FreshDatabase(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock)
+	mock.ExpectQuery(`SELECT to_regclass\('public.orders'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	for _, migration := range runner.Migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.SQL)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`INSERT INTO "schema_migrations"`).
+			WithArgs(migration.Version, migration.Name, migration.Checksum, false, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+
+	require.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_AppliesOnlyPending(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock, runner.Migrations[0])
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE orders ADD COLUMN status TEXT`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO "schema_migrations"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, 2, applied[0].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_FailedMigrationIsRolledBack(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock, runner.Migrations[0])
+	mock.ExpectBegin()
+	mock.ExpectExec(`ALTER TABLE orders`).WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+
+	assert.ErrorContains(t, err, "migration 002_status failed")
+	assert.Empty(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUp_ExistingSchemaNeedsBaseline(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock)
+	mock.ExpectQuery(`SELECT to_regclass`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	expectUnlock(mock)
+
+	_, err := runner.Up(context.Background())
+
+	assert.ErrorIs(t, err, ErrNotBaselined)
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing is run on the existing schema")
+}
+
+func TestUp_ChangedMigration(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	changed := runner.Migrations[0]
+	changed.Checksum = "edited"
+	expectState(mock, changed)
+	expectUnlock(mock)
+
+	_, err := runner.Up(context.Background())
+
+	assert.ErrorContains(t, err, "was changed after it was applied")
+}
+
+func TestUp_OutOfOrderMigration(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock, runner.Migrations[1])
+	expectUnlock(mock)
+
+	_, err := runner.Up(context.Background())
+
+	assert.ErrorContains(t, err, "older than the last applied migration 002")
+}
+
+func TestUp_IgnoresMigrationsOfNewerReleases(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock, runner.Migrations[0], runner.Migrations[1], Migration{Version: 3, Name: "newer", Checksum: "abc"})
+	expectUnlock(mock)
+
+	applied, err := runner.Up(context.Background())
+
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+}
+
+func TestBaseline(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "schema_migrations"`).
+		WithArgs(1, "init", runner.Migrations[0].Checksum, true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectUnlock(mock)
+
+	require.NoError(t, runner.Baseline(context.Background(), 1))
+	assert.NoError(t, mock.ExpectationsWereMet(), "only the migrations up to the version are marked")
+}
+
+func TestBaseline_AlreadyMigrated(t *testing.T) {
+	runner, mock := setupRunner(t)
+
+	expectState(mock, runner.Migrations[0])
+	expectUnlock(mock)
+
+	assert.ErrorIs(t, runner.Baseline(context.Background(), 2), ErrBaselined)
+}
+
+func TestBaseline_UnknownVersion(t *testing.T) {
+	runner, _ := setupRunner(t)
+
+	assert.ErrorContains(t, runner.Baseline(context.Background(), 7), "unknown migration version 7")
+}
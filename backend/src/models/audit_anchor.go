@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// AuditAnchor is the chain transaction an audit event was anchored in (append only, the event itself is never changed)
+type AuditAnchor struct {
+    Audit_Event_ID         uint      `gorm:"primaryKey;autoIncrement:false"`
+    Blockchain_Transaction string    `gorm:"not null"`
+    Contract_Deployment_ID *uint     // the contract the hash was anchored in
+    Anchored_At            time.Time `gorm:"not null"`
+}
+
+func (AuditAnchor) TableName() string {
+    return "audit_anchors"
+}
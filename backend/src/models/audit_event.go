@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// AuditEvent is a change to a row of orders or order_products, with the row before and after the change
+type AuditEvent struct {
+    Id                     uint                   `gorm:"primaryKey"`
+    Order_ID               uint                   `gorm:"not null"`
+    Table_Name             string                 `gorm:"not null"`
+    Record_ID              uint                   `gorm:"not null"`
+    Action                 string                 `gorm:"not null"`
+    Before                 map[string]interface{} `gorm:"type:jsonb;serializer:json"` // null when the row was created
+    After                  map[string]interface{} `gorm:"type:jsonb;serializer:json"` // null when the row was deleted
+    Actor                  string                 `gorm:"not null"`              // the authenticated caller, anonymous when it was not
+    Claimed_Actor          *string                `gorm:"default:null"`          // the actor named by the caller, not verified
+    Source                 string                 `gorm:"not null"`
+    Request_ID             string                 `gorm:"not null"`
+    Created_At             time.Time              `gorm:"not null"`
+    Hash                   string                 `gorm:"not null"`
+    Blockchain_Transaction *string                `gorm:"default:null"` // the transaction of the anchor of the event, once it was anchored
+    Contract_Deployment_ID *uint                  `gorm:"default:null"` // the contract the hash was anchored in
+}
+
+func (AuditEvent) TableName() string {
+    return "audit_events"
+}
+
+// Actions of the audit events
+const (
+    AuditCreate = "CREATE"
+    AuditUpdate = "UPDATE"
+    AuditDelete = "DELETE"
+)
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ContractDeployment is a deployment of the tracking contract in the registry. The updates are pinned to the
+// deployment they were notarized in
+type ContractDeployment struct {
+    Id                 uint      `gorm:"primaryKey"`
+    Chain_ID           int64     `gorm:"not null"`
+    Contract_Address   string    `gorm:"not null"`
+    Deploy_Transaction *string   `gorm:"default:null"` // nil when registered from the config
+    ABI_Version        string    `gorm:"column:abi_version;not null"`
+    Active             bool      `gorm:"not null"` // the contract the updates of its chain are notarized in
+    Legacy             bool      `gorm:"not null"` // the contract of the updates notarized before the registry
+    Deployed_At        time.Time `gorm:"not null"`
+}
+
+func (ContractDeployment) TableName() string {
+    return "contract_deployments"
+}
+
+// Ref is the reference the updates notarized in the deployment are pinned with, nil when it is not registered
+func (d ContractDeployment) Ref() *uint {
+    if d.Id == 0 {
+        return nil
+    }
+    id := d.Id
+    return &id
+}
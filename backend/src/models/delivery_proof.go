@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DeliveryProof is the proof of delivery attached to a DELIVERED update
+type DeliveryProof struct {
+    Id                      uint      `gorm:"primaryKey"`
+    Order_ID                uint      `gorm:"not null"`
+    Order_Status_History_ID uint      `gorm:"not null"`
+    Recipient_Name          string
+    Signature_Key           string    // key of the signature image in the blob storage
+    Signature_Hash          string
+    Photo_Key               string    // key of the photo in the blob storage
+    Photo_Hash              string
+    Latitude                *float64  `gorm:"type:decimal(10,8)"`
+    Longitude               *float64  `gorm:"type:decimal(11,8)"`
+    Proof_Hash              string    `gorm:"not null"`
+    Created_At              time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (DeliveryProof) TableName() string {
+    return "delivery_proofs"
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// LocationReview flags an update sent from outside the expected geofence
+type LocationReview struct {
+    Id                      uint       `gorm:"primaryKey"`
+    Order_ID                uint       `gorm:"not null"`
+    Order_Status_History_ID uint       `gorm:"not null"`
+    Reason                  string     `gorm:"type:text;not null"`
+    Distance_Km             float64    `gorm:"not null"`
+    Tolerance_Km            float64    `gorm:"not null"`
+    Review_Status           string     `gorm:"not null"`
+    Reviewer_Note           string
+    Created_At              time.Time  `gorm:"not null;default:CURRENT_TIMESTAMP"`
+    Reviewed_At             *time.Time `gorm:"default:null"`
+}
+
+func (LocationReview) TableName() string {
+    return "location_reviews"
+}
+
+// Possible review states (review_state enum in the database)
+const (
+    ReviewPending  = "PENDING"
+    ReviewApproved = "APPROVED"
+    ReviewRejected = "REJECTED"
+)
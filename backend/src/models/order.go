@@ -16,7 +16,10 @@ type Orders struct{
     Delivery_Latitude   float64  `gorm:"type:decimal(10,8)"`
     Delivery_Longitude  float64  `gorm:"type:decimal(11,8)"`
     Price               float64   `gorm:"not null;default:0"`
+    Shipping_Weight     float64   `gorm:"type:decimal(10,3);not null;default:0"` // kg
+    Volumetric_Weight   float64   `gorm:"type:decimal(10,3);not null;default:0"` // kg
 
     Products []OrderProduct        `gorm:"foreignKey:Order_ID"`
     Updates  []OrderStatusHistory  `gorm:"foreignKey:Order_ID"`
+    Shipments []Shipment           `gorm:"foreignKey:Order_ID"`
 }
\ No newline at end of file
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OrderChange is a change made to the products of an order after it was placed. It is not stored, the changes are
+// derived from the audit events of the order products (Id is the ID of the event)
+type OrderChange struct {
+    Id               uint
+    Order_ID         uint
+    Order_Product_ID uint
+    Action           string
+    Product_ID       uint
+    Variant_ID       *uint
+    Product_Name     string
+    Quantity_Before  uint // 0 when the product was added
+    Quantity_After   uint // 0 when the product was removed
+    Changed_At       time.Time
+}
+
+// Actions of the order changes
+const (
+    ChangeAdded   = "ADDED"
+    ChangeUpdated = "UPDATED"
+    ChangeRemoved = "REMOVED"
+)
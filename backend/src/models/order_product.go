@@ -7,4 +7,8 @@ type OrderProduct struct {
     Quantity  uint     `gorm:"not null"`
     Product_Name_At_Purchase string `gorm:"not null"`
     Product_Price_At_Purchase float64 `gorm:"not null"`
+    Shipment_ID *uint `gorm:"default:null"`
+    Variant_ID  *uint `gorm:"default:null"`
+    Variant_SKU *string `gorm:"default:null"` // snapshot of the variant when the order was placed
+    Variant_Options []VariantOption `gorm:"type:jsonb;serializer:json;default:null"`
 }
\ No newline at end of file
@@ -6,17 +6,58 @@ import (
 
 type OrderStatusHistory struct{
     Id                uint      `gorm:"primaryKey"`
-    Order_ID          uint      `gorm:"not null"`
+    Order_ID          uint      `gorm:"not null" binding:"required"`
     Timestamp_History time.Time `gorm:"not null"`
-    Order_Status      string    `gorm:"not null"`
+    Order_Status      string    `gorm:"not null" binding:"required,order_status"`
     Note              string
     Blockchain_Transaction string  `gorm:"not null"`   
     Order_Location    string    `gorm:"not null"`
     Storage_ID        *uint     `gorm:"default:null"`
+    Shipment_ID       *uint     `gorm:"default:null"`
+    Proof_Hash        *string   `gorm:"default:null"` // hash of the proof of delivery bundle (DELIVERED updates)
+    Latitude          *float64  `gorm:"type:decimal(10,8);default:null" binding:"omitempty,latitude"` // where the courier was when sending the update
+    Longitude         *float64  `gorm:"type:decimal(11,8);default:null" binding:"omitempty,longitude"`
+    Contract_Deployment_ID *uint `gorm:"default:null"` // the contract the hash was stored in (nil before the registry)
     Order             *Orders   `gorm:"foreignKey:Order_ID;references:Id"`
     Storage           *Storage  `gorm:"foreignKey:Storage_ID;references:Id"`
 }
 
 func (OrderStatusHistory) TableName() string {
     return "order_status_history" 
-}
\ No newline at end of file
+}
+
+// Possible order states (order_state enum in the database)
+const (
+    StatusProcessing      = "PROCESSING"
+    StatusShipped         = "SHIPPED"
+    StatusInTransit       = "IN TRANSIT"
+    StatusOutForDelivery  = "OUT FOR DELIVERY"
+    StatusDelivered       = "DELIVERED"
+    StatusCancelled       = "CANCELLED"
+    StatusReturnRequested = "RETURN REQUESTED" // the customer asked to return the delivered order, it is not back yet
+    StatusReturned        = "RETURNED"
+    StatusFailedDelivery  = "FAILED DELIVERY"
+)
+
+// OrderStatuses lists the order states, in the order they usually happen
+var OrderStatuses = []string{
+    StatusProcessing,
+    StatusShipped,
+    StatusInTransit,
+    StatusOutForDelivery,
+    StatusDelivered,
+    StatusCancelled,
+    StatusReturnRequested,
+    StatusReturned,
+    StatusFailedDelivery,
+}
+
+// IsOrderStatus reports whether status is one of the order states
+func IsOrderStatus(status string) bool {
+    for _, known := range OrderStatuses {
+        if status == known {
+            return true
+        }
+    }
+    return false
+}
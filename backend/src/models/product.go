@@ -1,7 +1,56 @@
 package models
 
+import "time"
+
+// Product is the local copy of a Jumpseller product (kept up to date by the catalogue sync)
 type Product struct {
-    ID    uint    
-    Name  string  
-    Price float64 
-}
\ No newline at end of file
+    ID              uint      `gorm:"primaryKey;autoIncrement:false"` // same id as in Jumpseller
+    Name            string    `gorm:"not null"`
+    SKU             string
+    Brand           string
+    Price           float64   `gorm:"not null;default:0"`
+    Status          string
+    Stock           int
+    Stock_Unlimited bool
+    Weight          float64   // kg
+    Length          float64   // cm
+    Width           float64   // cm
+    Height          float64   // cm
+    Package_Format  string
+    Synced_At       time.Time
+
+    Variants []ProductVariant `gorm:"foreignKey:Product_ID"`
+}
+
+func (Product) TableName() string {
+    return "products"
+}
+
+// ProductVariant is a variant of a product (e.g. size or colour) with its own SKU, price and stock
+type ProductVariant struct {
+    ID              uint            `gorm:"primaryKey;autoIncrement:false"` // same id as in Jumpseller
+    Product_ID      uint            `gorm:"not null"`
+    SKU             string
+    Price           float64         `gorm:"not null;default:0"`
+    Stock           int
+    Stock_Unlimited bool
+    Options         []VariantOption `gorm:"type:jsonb;serializer:json"`
+    Synced_At       time.Time
+}
+
+func (ProductVariant) TableName() string {
+    return "product_variants"
+}
+
+// VariantOption is one of the options that define a variant (e.g. Size: M)
+type VariantOption struct {
+    Name  string
+    Value string
+}
+
+// Product statuses (as used by Jumpseller)
+const (
+    ProductAvailable    = "available"
+    ProductNotAvailable = "not-available"
+    ProductDisabled     = "disabled"
+)
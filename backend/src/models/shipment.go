@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Shipment is a parcel of an order (a subset of the order products shipped together)
+type Shipment struct {
+    Id                uint      `gorm:"primaryKey"`
+    Order_ID          uint      `gorm:"not null"`
+    Tracking_Code     string    `gorm:"unique;not null"`
+    Storage_ID        *uint     `gorm:"default:null"`
+    Delivery_Estimate time.Time
+    Created_At        time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+    Status            string    `gorm:"-"` // latest status of the shipment (not stored)
+
+    Products []OrderProduct       `gorm:"foreignKey:Shipment_ID"`
+    Updates  []OrderStatusHistory `gorm:"foreignKey:Shipment_ID"`
+}
+
+func (Shipment) TableName() string {
+    return "shipments"
+}
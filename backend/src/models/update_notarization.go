@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UpdateNotarization is the chain transaction an update was notarized in once it was stored (append only, the
+// update itself is never changed)
+type UpdateNotarization struct {
+    Order_Status_History_ID uint      `gorm:"primaryKey;autoIncrement:false"`
+    Blockchain_Transaction  string    `gorm:"not null"`
+    Contract_Deployment_ID  *uint     // the contract the hash was stored in
+    Notarized_At            time.Time `gorm:"not null"`
+}
+
+func (UpdateNotarization) TableName() string {
+    return "update_notarizations"
+}
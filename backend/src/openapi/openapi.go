@@ -0,0 +1,260 @@
+// Package openapi builds the OpenAPI 3 document of the API. The paths come from the routes registered in gin, so a
+// route cannot be renamed without the document following it, and the schemas are generated from the request and
+// response types of the handlers (their json and binding tags). Only the summary of each operation, its query
+// parameters and its body types are written by hand, in an Operations table.
+package openapi
+
+import (
+	"app/apierror"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version of the OpenAPI specification the documents follow
+const Version = "3.0.3"
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operations documents the routes, keyed by method and gin path (e.g. "GET /api/order/:id")
+type Operations map[string]Operation
+
+// Operation documents a route. The path parameters are taken from the route itself
+type Operation struct {
+	Summary string
+	Tag     string
+	Query   []Parameter
+	// Deprecated marks the operations replaced by another version of the API
+	Deprecated bool
+	// Request is a value of the type of the JSON body, nil when the operation has no body
+	Request interface{}
+	// PathFields are the fields of the Request taken from the path, they are left out of the body
+	PathFields []string
+	// Status is the status of a successful response, 200 when it is not set
+	Status int
+	// Response is a value of the type of the JSON body of a successful response (Fields for gin.H bodies), nil when
+	// the response has no JSON body
+	Response interface{}
+	// ContentType is the type of a successful response that is not JSON (e.g. an image)
+	ContentType string
+	// Role is the role the caller must have (in its bearer token), empty for the operations open to anyone
+	Role string
+}
+
+// Parameter is a query parameter
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string // string when it is not set
+	Required    bool
+}
+
+// Fields is the type of a response built with gin.H: the fields of the body with a value of their type
+type Fields map[string]interface{}
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// bearerAuth is the security scheme of the operations that require a role
+const bearerAuth = "bearerAuth"
+
+type operation struct {
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Tags        []string              `json:"tags,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Build generates the document of the registered routes. Routes without an operation are left out of the document
+// and returned as undocumented
+func Build(info Info, routes gin.RoutesInfo, operations Operations) (*Document, []string) {
+	gen := newGenerator()
+	gen.names[reflect.TypeOf(apierror.Response{})] = "Error"
+	errorSchema := gen.schema(reflect.TypeOf(apierror.Response{}))
+
+	document := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]operation{},
+		Components: components{
+			Schemas: gen.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "A token of the identity provider, its roles claim has the roles of the caller"},
+			},
+		},
+	}
+
+	// sorted so that the names of the schemas do not depend on the order the routes were registered in
+	sorted := append(gin.RoutesInfo{}, routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return Key(sorted[i].Method, sorted[i].Path) < Key(sorted[j].Method, sorted[j].Path)
+	})
+
+	var undocumented []string
+	for _, route := range sorted {
+		key := Key(route.Method, route.Path)
+		op, ok := operations[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+
+		path, params := convertPath(route.Path)
+		built := operation{
+			Summary:     op.Summary,
+			OperationID: operationID(route.Method, route.Path),
+			Deprecated:  op.Deprecated,
+			Parameters:  params,
+			Responses: map[string]response{
+				"default": {Description: "Error", Content: jsonContent(errorSchema)},
+			},
+		}
+		if op.Tag != "" {
+			built.Tags = []string{op.Tag}
+		}
+		if op.Role != "" {
+			built.Summary += fmt.Sprintf(" (requires the %s role)", op.Role)
+			built.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		for _, query := range op.Query {
+			built.Parameters = append(built.Parameters, parameter{
+				Name:        query.Name,
+				In:          "query",
+				Description: query.Description,
+				Required:    query.Required,
+				Schema:      &Schema{Type: firstNonEmpty(query.Type, "string")},
+			})
+		}
+		if op.Request != nil {
+			built.RequestBody = &requestBody{Required: true, Content: jsonContent(gen.body(typeOf(op.Request), op.PathFields))}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := response{Description: http.StatusText(status)}
+		switch {
+		case op.Response != nil:
+			success.Content = jsonContent(gen.schema(typeOf(op.Response)))
+		case op.ContentType != "":
+			success.Content = map[string]mediaType{op.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+		}
+		built.Responses[fmt.Sprint(status)] = success
+
+		if document.Paths[path] == nil {
+			document.Paths[path] = map[string]operation{}
+		}
+		document.Paths[path][strings.ToLower(route.Method)] = built
+	}
+	return document, undocumented
+}
+
+// Key is the key of a route in the Operations
+func Key(method, path string) string {
+	return method + " " + path
+}
+
+// Has reports whether the document has the operation of a route (method and gin path)
+func (d *Document) Has(method, path string) bool {
+	converted, _ := convertPath(path)
+	_, ok := d.Paths[converted][strings.ToLower(method)]
+	return ok
+}
+
+// convertPath converts the parameters of a gin path (:id, *file) to the OpenAPI syntax ({id})
+func convertPath(path string) (string, []parameter) {
+	var params []parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		name := segment[1:]
+		segments[i] = "{" + name + "}"
+
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer", Minimum: float(1)}
+		}
+		params = append(params, parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationID names an operation after its route, e.g. GET /api/order/:id is getApiOrderId
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func jsonContent(schema *Schema) map[string]mediaType {
+	return map[string]mediaType{"application/json": {Schema: schema}}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package openapi
+
+import (
+	"app/models"
+	"app/requestModels"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noop(c *gin.Context) {}
+
+func testRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/order/:id", noop)
+	r.POST("/api/order/add", noop)
+	r.GET("/api/proof/:id/:media", noop)
+	r.GET("/api/undocumented", noop)
+	return r
+}
+
+var testOperations = Operations{
+	"GET /api/order/:id": {
+		Summary: "Get an order", Tag: "orders",
+		Response: Fields{"order": models.Orders{}},
+	},
+	"POST /api/order/add": {
+		Summary: "Create an order", Tag: "orders",
+		Request: requestModels.AddOrderRequest{}, Status: http.StatusCreated,
+	},
+	"GET /api/proof/:id/:media": {Summary: "Download a proof", ContentType: "application/octet-stream", Role: "admin"},
+}
+
+func TestBuild_Paths(t *testing.T) {
+	document, undocumented := Build(Info{Title: "Test", Version: "1"}, testRouter().Routes(), testOperations)
+
+	assert.Equal(t, []string{"GET /api/undocumented"}, undocumented)
+	assert.Len(t, document.Paths, 3)
+	assert.True(t, document.Has(http.MethodGet, "/api/order/:id"))
+	assert.False(t, document.Has(http.MethodGet, "/api/undocumented"))
+
+	proof := document.Paths["/api/proof/{id}/{media}"]["get"]
+	require.Len(t, proof.Parameters, 2)
+	assert.Equal(t, "integer", proof.Parameters[0].Schema.Type)
+	assert.Equal(t, "media", proof.Parameters[1].Name)
+	assert.Equal(t, "string", proof.Parameters[1].Schema.Type)
+	assert.Contains(t, proof.Responses["200"].Content, "application/octet-stream")
+	assert.Equal(t, "getApiProofIdMedia", proof.OperationID)
+	assert.Equal(t, []map[string][]string{{bearerAuth: {}}}, proof.Security, "an operation with a role requires a token")
+	assert.Equal(t, "Download a proof (requires the admin role)", proof.Summary)
+	assert.Equal(t, "bearer", document.Components.SecuritySchemes[bearerAuth].Scheme)
+
+	add := document.Paths["/api/order/add"]["post"]
+	require.NotNil(t, add.RequestBody)
+	assert.Empty(t, add.Security)
+	assert.Contains(t, add.Responses, "201")
+	assert.Equal(t, "#/components/schemas/Error", add.Responses["default"].Content["application/json"].Schema.Ref)
+}
+
+func TestBuild_SchemaFromBindingTags(t *testing.T) {
+	document, _ := Build(Info{}, testRouter().Routes(), testOperations)
+	schemas := document.Components.Schemas
+
+	request := schemas["AddOrderRequest"]
+	require.NotNil(t, request)
+	assert.Equal(t, []string{"customer_id", "delivery_address", "seller_address", "seller_id"}, request.Required)
+	assert.Equal(t, 1, *request.Properties["products"].MinItems)
+	assert.Equal(t, "#/components/schemas/OrderProductRequest", request.Properties["products"].Items.Ref)
+	assert.Equal(t, -90.0, *request.Properties["delivery_latitude"].Minimum)
+	assert.Equal(t, 180.0, *request.Properties["delivery_longitude"].Maximum)
+
+	line := schemas["OrderProductRequest"]
+	require.NotNil(t, line)
+	assert.True(t, line.Properties["quantity"].ExclusiveMinimum)
+	assert.True(t, line.Properties["variant_id"].Nullable)
+
+	// the models have no json tags, their fields keep the Go names
+	order := schemas["Orders"]
+	require.NotNil(t, order)
+	assert.Equal(t, "date-time", order.Properties["Created_At"].Format)
+	assert.Equal(t, "#/components/schemas/OrderStatusHistory", order.Properties["Updates"].Items.Ref)
+}
+
+func TestBuild_EmbeddedStructsAndEnums(t *testing.T) {
+	r := gin.New()
+	r.POST("/update", noop)
+	r.POST("/resolve", noop)
+	document, _ := Build(Info{}, r.Routes(), Operations{
+		"POST /update":  {Request: requestModels.AddOrderUpdateRequest{}},
+		"POST /resolve": {Request: requestModels.ResolveLocationReviewRequest{}},
+	})
+	schemas := document.Components.Schemas
+
+	update := schemas["AddOrderUpdateRequest"]
+	require.NotNil(t, update)
+	assert.Contains(t, update.Properties, "Order_ID", "the fields of the embedded model are promoted")
+	assert.Contains(t, update.Properties, "proof_of_delivery")
+	assert.Equal(t, []string{"Order_ID", "Order_Status"}, update.Required)
+	assert.Equal(t, models.OrderStatuses, update.Properties["Order_Status"].Enum)
+
+	assert.Equal(t, []string{"APPROVED", "REJECTED"}, schemas["ResolveLocationReviewRequest"].Properties["decision"].Enum)
+}
+
+func TestBuild_SameTypeNameInTwoPackages(t *testing.T) {
+	r := gin.New()
+	r.GET("/a", noop)
+	r.GET("/b", noop)
+	document, _ := Build(Info{}, r.Routes(), Operations{
+		"GET /a": {Response: models.VariantOption{}},
+		"GET /b": {Response: requestModels.VariantOption{}},
+	})
+
+	assert.Contains(t, document.Components.Schemas, "VariantOption")
+	assert.Contains(t, document.Components.Schemas, "requestModels.VariantOption")
+}
+
+func TestHandler(t *testing.T) {
+	r := testRouter()
+	r.GET("/api/openapi.json", Handler(r, Info{Title: "Test", Version: "1"}, testOperations))
+	r.GET("/api/docs", Docs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &document))
+	assert.Equal(t, Version, document["openapi"])
+	assert.Equal(t, "Test", document["info"].(map[string]interface{})["title"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+}
+
+func TestBuild_PathFieldsAndDeprecated(t *testing.T) {
+	r := gin.New()
+	r.POST("/api/order/cancel", noop)
+	r.POST("/api/v2/orders/:id/cancellation", noop)
+	document, _ := Build(Info{}, r.Routes(), Operations{
+		"POST /api/order/cancel":               {Request: requestModels.CancelOrderRequest{}, Deprecated: true},
+		"POST /api/v2/orders/:id/cancellation": {Request: requestModels.CancelOrderRequest{}, PathFields: []string{"order_id"}},
+	})
+
+	v1 := document.Paths["/api/order/cancel"]["post"]
+	assert.True(t, v1.Deprecated)
+	assert.Equal(t, "#/components/schemas/CancelOrderRequest", v1.RequestBody.Content["application/json"].Schema.Ref)
+
+	v2 := document.Paths["/api/v2/orders/{id}/cancellation"]["post"]
+	assert.False(t, v2.Deprecated)
+	body := v2.RequestBody.Content["application/json"].Schema
+	assert.NotContains(t, body.Properties, "order_id")
+	assert.Contains(t, body.Properties, "reason")
+	assert.Empty(t, body.Required)
+	assert.Contains(t, document.Components.Schemas["CancelOrderRequest"].Required, "order_id", "the component keeps the field")
+}
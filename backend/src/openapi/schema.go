@@ -0,0 +1,289 @@
+package openapi
+
+import (
+	"app/models"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// enums are the values of the custom validation rules of the request models (see requestModels.RegisterValidations)
+var enums = map[string][]string{
+	"order_status": models.OrderStatuses,
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	fieldsType    = reflect.TypeOf(Fields{}) // a Fields nested in a type, its keys are not known
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// generator generates the schemas of Go types. Structs are added to the components and referenced, named after
+// their type (qualified by their package when two packages have a type with the same name)
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// typeOf returns the type of a value. Each Fields value gets its own type, an anonymous struct with a field per key
+func typeOf(value interface{}) reflect.Type {
+	fields, ok := value.(Fields)
+	if !ok {
+		return reflect.TypeOf(value)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	structFields := make([]reflect.StructField, 0, len(keys))
+	for i, key := range keys {
+		structFields = append(structFields, reflect.StructField{
+			Name: "F" + strconv.Itoa(i),
+			Type: typeOf(fields[key]),
+			Tag:  reflect.StructTag(`json:"` + key + `"`),
+		})
+	}
+	return reflect.StructOf(structFields)
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == nil:
+		return &Schema{}
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == fieldsType:
+		return &Schema{Type: "object"}
+	case t.Kind() != reflect.Struct && t.Kind() != reflect.Ptr && t.Implements(marshalerType):
+		// the scalars written by their own MarshalJSON (durations, redacted secrets) are written as strings
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := g.schema(t.Elem())
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.component(t)
+	}
+	// interfaces accept any value
+	return &Schema{}
+}
+
+// body generates the schema of a request body without the fields taken from the path. The schema is inlined when it
+// leaves fields out, the component of the type keeps them all
+func (g *generator) body(t reflect.Type, pathFields []string) *Schema {
+	if len(pathFields) == 0 || t.Kind() != reflect.Struct {
+		return g.schema(t)
+	}
+
+	schema := g.object(t)
+	for _, field := range pathFields {
+		delete(schema.Properties, field)
+		for i, required := range schema.Required {
+			if required == field {
+				schema.Required = append(schema.Required[:i:i], schema.Required[i+1:]...)
+				break
+			}
+		}
+	}
+	return schema
+}
+
+// component adds a named struct to the components and returns a reference to it
+func (g *generator) component(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			name = packageName(t) + "." + t.Name()
+		}
+		g.names[t] = name
+	}
+	if _, ok := g.schemas[name]; !ok {
+		// reserved before the fields are generated, for the types that reference themselves
+		g.schemas[name] = &Schema{}
+		*g.schemas[name] = *g.object(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object generates the schema of a struct. The fields of embedded structs are promoted, as encoding/json does
+func (g *generator) object(t reflect.Type) *Schema {
+	object := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			embedded := g.object(field.Type)
+			for property, schema := range embedded.Properties {
+				object.Properties[property] = schema
+			}
+			object.Required = append(object.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		schema := g.schema(field.Type)
+		if applyRules(schema, field.Tag.Get("binding")) {
+			object.Required = append(object.Required, name)
+		}
+		object.Properties[name] = schema
+	}
+	sort.Strings(object.Required)
+	return object
+}
+
+// applyRules adds the constraints of the binding rules of a field to its schema and reports whether the field is
+// required. The rules after dive apply to the elements of a slice, they are not documented
+func applyRules(schema *Schema, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "dive" {
+			break
+		}
+		if name == "required" {
+			required = true
+			continue
+		}
+		// the constraints of a referenced struct are in its own schema
+		if schema.Ref != "" {
+			continue
+		}
+
+		switch name {
+		case "min", "max":
+			limit, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				continue
+			}
+			setLimit(schema, name == "min", limit)
+		case "gt":
+			if limit, err := strconv.ParseFloat(param, 64); err == nil {
+				schema.Minimum, schema.ExclusiveMinimum = float(limit), true
+			}
+		case "latitude":
+			schema.Minimum, schema.Maximum = float(-90), float(90)
+		case "longitude":
+			schema.Minimum, schema.Maximum = float(-180), float(180)
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "datetime":
+			if param == "2006-01-02" {
+				schema.Format = "date"
+			} else {
+				schema.Description = "Layout " + param
+			}
+		default:
+			if values, ok := enums[name]; ok {
+				schema.Enum = values
+			}
+		}
+	}
+	return required
+}
+
+// setLimit sets the minimum (or maximum) of a schema: a number of items, a length or a value depending on its type
+func setLimit(schema *Schema, minimum bool, limit float64) {
+	switch schema.Type {
+	case "array":
+		if minimum {
+			schema.MinItems = count(limit)
+		} else {
+			schema.MaxItems = count(limit)
+		}
+	case "string":
+		if minimum {
+			schema.MinLength = count(limit)
+		} else {
+			schema.MaxLength = count(limit)
+		}
+	default:
+		if minimum {
+			schema.Minimum = float(limit)
+		} else {
+			schema.Maximum = float(limit)
+		}
+	}
+}
+
+// packageName returns the last element of the import path of a type
+func packageName(t reflect.Type) string {
+	path := t.PkgPath()
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+func float(value float64) *float64 { return &value }
+
+func count(value float64) *int {
+	n := int(value)
+	return &n
+}
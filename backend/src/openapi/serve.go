@@ -0,0 +1,38 @@
+package openapi
+
+import (
+	_ "embed"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Handler serves the document of the routes of the router. It is built on the first request, once every route is
+// registered, and the undocumented routes are logged
+func Handler(router *gin.Engine, info Info, operations Operations) gin.HandlerFunc {
+	var (
+		once     sync.Once
+		document *Document
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			var undocumented []string
+			document, undocumented = Build(info, router.Routes(), operations)
+			for _, route := range undocumented {
+				slog.Warn("Route is not documented in the OpenAPI document", "route", route)
+			}
+		})
+		c.JSON(http.StatusOK, document)
+	}
+}
+
+// Docs serves the documentation page, it renders the document served next to it (openapi.json) with Swagger UI,
+// loaded from the jsDelivr CDN
+func Docs(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
@@ -1,15 +1,24 @@
 package pubsub
 
 import (
+	"app/audit"
+	"app/blobstore"
 	"app/blockchain"
+	"app/catalog"
+	"app/config"
+	"app/geocoding"
 	"app/handlers"
+	"app/logging"
+	"app/metrics"
 	"app/models"
+	"app/repository"
+	"app/supervisor"
+	"app/telemetry"
 	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"time"
     "encoding/json"
@@ -17,10 +26,18 @@ import (
     "google.golang.org/protobuf/proto"
 	"cloud.google.com/go/pubsub"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"google.golang.org/api/option"
 	"gorm.io/gorm"
 )
 
+// Page of an order in the tracking frontend
+const orderPageURL = "https://tracking-status-frontend-edneicy3ca-ew.a.run.app/order/%d"
+
+// Topic of the notifications service
+const notificationsTopic = "tracking-notifications"
+
 // Topic interface for Pub/Sub operations
 type Topic interface {
 	Publish(ctx context.Context, msg *pubsub.Message) *pubsub.PublishResult
@@ -44,18 +61,13 @@ type PubSubClient interface {
 	Close() error
 }
 
-// Initializes pubsub client
-func StartPubSubClient(ctx context.Context, db *gorm.DB, blockChainClient *blockchain.Client) (*pubsub.Client, error) {
-
-    projectID := os.Getenv("PUBSUB_PROJECT") // Use MIPS project if set
-    credentialsPath := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
-
-    log.Printf("Initializing Pub/Sub client for project: %s", projectID)
-    log.Printf("Using credentials path: %s", credentialsPath)
+// Initializes pubsub client, with the credentials file of the config or the default credentials
+func StartPubSubClient(ctx context.Context, cfg config.PubSub) (*pubsub.Client, error) {
+    projectID := cfg.Project
+    credentialsPath := cfg.CredentialsFile
 
     if projectID == "" {
-        projectID = "madeinportugal" // Fallback to default project
-        log.Printf("PUBSUB_PROJECT not set, falling back to default project: %s", projectID)
+        return nil, fmt.Errorf("the Pub/Sub project is not set (PUBSUB_PROJECT)")
     }
 
     var client *pubsub.Client
@@ -63,15 +75,15 @@ func StartPubSubClient(ctx context.Context, db *gorm.DB, blockChainClient *block
 
     // If credentials path is provided, use it
     if credentialsPath != "" {
-        log.Printf("Creating Pub/Sub client with credentials from: %s", credentialsPath)
+        slog.Info("Creating the Pub/Sub client with the credentials file", "project", projectID)
         client, err = pubsub.NewClient(ctx, projectID, option.WithCredentialsFile(credentialsPath))
     } else {
-        log.Printf("Creating Pub/Sub client with default credentials")
+        slog.Info("Creating the Pub/Sub client with the default credentials", "project", projectID)
         client, err = pubsub.NewClient(ctx, projectID)
     }
 
     if err != nil {
-        log.Printf("Failed to create Pub/Sub client: %v", err)
+        slog.Error("Failed to create the Pub/Sub client", "error", err)
         return nil, err
     }
 
@@ -81,12 +93,10 @@ func StartPubSubClient(ctx context.Context, db *gorm.DB, blockChainClient *block
 func CreateTopicWithID(ctx context.Context, client *pubsub.Client, topicID string) (*pubsub.Topic, error) {
     
     if client == nil {
-        log.Printf("Failed to create topic: client is nil")
         return nil, fmt.Errorf("pubsub client is nil")
     }
     
     if topicID == "" {
-        log.Printf("Failed to create topic: topicID is empty")
         return nil, fmt.Errorf("topicID is empty")
     }
 
@@ -94,20 +104,20 @@ func CreateTopicWithID(ctx context.Context, client *pubsub.Client, topicID strin
     topic := client.Topic(topicID)
     exists, err := topic.Exists(ctx)
     if err != nil {
-        log.Printf("Failed to check if topic exists: %v", err)
+        slog.WarnContext(ctx, "Failed to check if the topic exists", "topic", topicID, "error", err)
         // Continue anyway
     }
     
     if !exists || err != nil {
-        log.Printf("Creating topic: %s", topicID)
+        slog.InfoContext(ctx, "Creating the topic", "topic", topicID)
         topic, err = client.CreateTopic(ctx, topicID)
         if err != nil {
             // If topic already exists, just use the existing one
             if strings.Contains(err.Error(), "AlreadyExists") {
-                log.Printf("Topic already exists, using existing topic: %s", topicID)
+                slog.InfoContext(ctx, "The topic already exists, using it", "topic", topicID)
                 topic = client.Topic(topicID)
             } else {
-                log.Printf("Failed to create topic: %v", err)
+                slog.ErrorContext(ctx, "Failed to create the topic", "topic", topicID, "error", err)
                 return nil, err
             }
         }
@@ -119,17 +129,14 @@ func CreateTopicWithID(ctx context.Context, client *pubsub.Client, topicID strin
 func SubscribeClient(ctx context.Context, client *pubsub.Client, topicID string, subscriptionID string) (*pubsub.Subscription, error) {
 
     if client == nil {
-        log.Printf("Failed to create subscription: client is nil")
         return nil, fmt.Errorf("pubsub client is nil")
     }
     
     if topicID == "" {
-        log.Printf("Failed to create subscription: topicID is empty")
         return nil, fmt.Errorf("topicID is empty")
     }
     
     if subscriptionID == "" {
-        log.Printf("Failed to create subscription: subscriptionID is empty")
         return nil, fmt.Errorf("subscriptionID is empty")
     }
 
@@ -143,12 +150,12 @@ func SubscribeClient(ctx context.Context, client *pubsub.Client, topicID string,
     sub := client.Subscription(subscriptionID)
     exists, err := sub.Exists(ctx)
     if err != nil {
-        log.Printf("Failed to check if subscription exists: %v", err)
+        slog.WarnContext(ctx, "Failed to check if the subscription exists", "subscription", subscriptionID, "error", err)
         // Continue anyway
     }
 
     if !exists || err != nil {
-        log.Printf("Creating subscription: %s", subscriptionID)
+        slog.InfoContext(ctx, "Creating the subscription", "subscription", subscriptionID)
         sub, err = client.CreateSubscription(ctx, subscriptionID, pubsub.SubscriptionConfig{
             Topic:       topic,
             AckDeadline: 20 * time.Second,
@@ -156,10 +163,10 @@ func SubscribeClient(ctx context.Context, client *pubsub.Client, topicID string,
         if err != nil {
             // If subscription already exists, just use the existing one
             if strings.Contains(err.Error(), "AlreadyExists") {
-                log.Printf("Subscription already exists, using existing subscription: %s", subscriptionID)
+                slog.InfoContext(ctx, "The subscription already exists, using it", "subscription", subscriptionID)
                 sub = client.Subscription(subscriptionID)
             } else {
-                log.Printf("Failed to create subscription: %v", err)
+                slog.ErrorContext(ctx, "Failed to create the subscription", "subscription", subscriptionID, "error", err)
                 return nil, err
             }
         }
@@ -168,60 +175,127 @@ func SubscribeClient(ctx context.Context, client *pubsub.Client, topicID string,
     return sub, nil
 }
 
-func PublishNotification(ctx context.Context, client *pubsub.Client, notification []byte) error {
-    topic := client.Topic("tracking-notifications")
+// PublishNotification publishes a notification, with the trace context of ctx in its attributes
+func PublishNotification(ctx context.Context, client *pubsub.Client, notification []byte) (err error) {
+    if client == nil {
+        return fmt.Errorf("pubsub client is nil")
+    }
+
+    if len(notification) == 0 {
+        return fmt.Errorf("notification is empty")
+    }
+
+    attributes := map[string]string{}
+    ctx, span := telemetry.StartProducer(ctx, notificationsTopic, attributes)
+    defer func() { telemetry.End(span, err) }()
+
+    topic := client.Topic(notificationsTopic)
 
     result := topic.Publish(ctx, &pubsub.Message{
-        Data: notification,
+        Data:       notification,
+        Attributes: attributes,
     })
 
     id, err := result.Get(ctx)
     if err != nil {
-        log.Printf("Failed to publish notification: %v", err)
+        slog.ErrorContext(ctx, "Failed to publish the notification", "error", err)
         return err
     }
+    span.SetAttributes(semconv.MessagingMessageID(id))
 
-    log.Printf("Published notification with message ID: %s", id)
+    slog.InfoContext(ctx, "Notification published", "notification_id", id)
 
     messageNotification := &NotificationRequest{}
     if err := proto.Unmarshal(notification, messageNotification); err != nil {
-        log.Printf("Failed to unmarshal notification for logging: %v", err)
+        slog.WarnContext(ctx, "Failed to read the published notification", "error", err)
         return nil
     }
-    log.Printf("Notification payload: %v", messageNotification)
+    slog.DebugContext(ctx, "Notification payload", "user_id", messageNotification.UserId, "title", messageNotification.Title, "payload", messageNotification.Payload)
     return nil
 }
 
-func buildNotificationPayloadOrder(messageData []byte, db *gorm.DB, blockChainClient *blockchain.Client) []byte {
+// Notifier publishes the notifications of the handlers to the notifications service
+type Notifier struct {
+    Client *pubsub.Client
+}
+
+func NewNotifier(client *pubsub.Client) *Notifier {
+    return &Notifier{Client: client}
+}
 
-    if messageData == nil || len(messageData) == 0 {
-        log.Printf("Failed to build notification: messageData is empty")
-        return nil
+func (n *Notifier) Notify(ctx context.Context, notification handlers.CustomerNotification) error {
+    payload, err := buildCustomerNotification(notification)
+    if err != nil {
+        return err
     }
+    return PublishNotification(ctx, n.Client, payload)
+}
 
-    // Parse the update to the order status model
-    var order models.Orders
-    err := json.Unmarshal(messageData, &order)
+// Ping checks the broker for the readiness probe: the notifications topic must exist
+func (n *Notifier) Ping(ctx context.Context) error {
+    if n.Client == nil {
+        return fmt.Errorf("pubsub client is nil")
+    }
+    exists, err := n.Client.Topic(notificationsTopic).Exists(ctx)
     if err != nil {
-        log.Printf("Failed to unmarshal order update for notification: %v", err)
+        return err
+    }
+    if !exists {
+        return fmt.Errorf("topic %s does not exist", notificationsTopic)
+    }
+    return nil
+}
+
+func buildCustomerNotification(notification handlers.CustomerNotification) ([]byte, error) {
+    message := &NotificationRequest{
+        UserId:    fmt.Sprintf("%d", notification.CustomerID),
+        Type:      "sms",
+        Title:     notification.Title,
+        Payload:   notification.Message,
+        Hyperlink: fmt.Sprintf(orderPageURL, notification.OrderID),
+        CreatedAt: time.Now().Format(time.RFC3339),
+    }
+    return proto.Marshal(message)
+}
+
+// createdOrder reads the order created by the order handler from its response
+func createdOrder(response []byte) (*models.Orders, error) {
+    var body struct {
+        Order *models.Orders `json:"order"`
+    }
+    if err := json.Unmarshal(response, &body); err != nil {
+        return nil, err
+    }
+    if body.Order == nil || body.Order.Id == 0 {
+        return nil, fmt.Errorf("the response has no created order")
+    }
+    return body.Order, nil
+}
+
+// buildNotificationPayloadOrder builds the notification of a created order (as it was stored, with its id and
+// tracking code)
+func buildNotificationPayloadOrder(order *models.Orders) []byte {
+
+    if order == nil || order.Id == 0 {
+        slog.Warn("Failed to build the notification: the order was not stored")
         return nil
     }
-    
-    log.Printf("Order with id %d created for customer %d", order.Id, order.Customer_ID)
+
+    slog.Info("Order created", "order_id", order.Id, "customer_id", order.Customer_ID)
 
     notification := &NotificationRequest{ 
         UserId:     fmt.Sprintf("%d", order.Customer_ID), 
         Type:       "sms",
         Title:      "New Order Created",
-        Payload:    fmt.Sprintf("Order with ID %d has been created.", order.Id),
-        Hyperlink:  fmt.Sprintf("https://tracking-status-frontend-edneicy3ca-ew.a.run.app/order/%d", order.Id),
+        Payload:    fmt.Sprintf("Order with ID %d has been created. Tracking code: %s", order.Id, order.Tracking_Code),
+        Hyperlink:  fmt.Sprintf(orderPageURL, order.Id),
         CreatedAt:  time.Now().Format(time.RFC3339),
     }
 
     // Encrypt to protobuf
     protoData, err := proto.Marshal(notification)
     if err != nil {
-        log.Printf("Failed to marshal notification to protobuf: %v", err)
+        slog.Error("Failed to encode the notification", "error", err)
         return nil
     }
 
@@ -231,7 +305,7 @@ func buildNotificationPayloadOrder(messageData []byte, db *gorm.DB, blockChainCl
 func buildNotificationPayloadStatus(messageData []byte, db *gorm.DB, blockChainClient *blockchain.Client) []byte {
 
     if messageData == nil || len(messageData) == 0 {
-        log.Printf("Failed to build notification: messageData is empty")
+        slog.Warn("Failed to build the notification: the message is empty")
         return nil
     }
 
@@ -239,15 +313,13 @@ func buildNotificationPayloadStatus(messageData []byte, db *gorm.DB, blockChainC
     var order_update models.OrderStatusHistory
     err := json.Unmarshal(messageData, &order_update)
     if err != nil {
-        log.Printf("Failed to unmarshal order update for notification: %v", err)
+        slog.Warn("Failed to read the order update of the notification", "error", err)
         return nil
     }
 
-    // Create handler to get user ID
-    handler := handlers.OrderHandler{DB: db, Client: blockChainClient}
-    userID, err := handlers.GetUserIDByOrderID(handler.DB, order_update.Order_ID)
+    userID, err := handlers.GetUserIDByOrderID(db, order_update.Order_ID)
     if err != nil {
-        log.Printf("Failed to get user ID for order ID %d: %v", order_update.Order_ID, err)
+        slog.Warn("Failed to get the customer of the order", "order_id", order_update.Order_ID, "error", err)
         return nil
     }
 
@@ -256,21 +328,23 @@ func buildNotificationPayloadStatus(messageData []byte, db *gorm.DB, blockChainC
         Type: "sms", 
         Title: "Order Status Update", 
         Payload: fmt.Sprintf("Your order status has changed to: %s", order_update.Order_Status), 
-        Hyperlink: fmt.Sprintf("https://tracking-status-frontend-edneicy3ca-ew.a.run.app/order/%d", order_update.Order_ID),
+        Hyperlink: fmt.Sprintf(orderPageURL, order_update.Order_ID),
         CreatedAt: time.Now().Format(time.RFC3339),
     }
 
     // Encrypt to protobuf
     protoData, err := proto.Marshal(notification)
     if err != nil {
-        log.Printf("Failed to marshal notification to protobuf: %v", err)
+        slog.Error("Failed to encode the notification", "error", err)
         return nil
     }
 
     return protoData
 }
 
-func StartListener(ctx context.Context, client *pubsub.Client, sub *pubsub.Subscription, db *gorm.DB, blockChainClient *blockchain.Client) error {
+// Listen handles the messages of the subscription with the components of the config. It blocks until ctx is done and
+// the messages in flight are handled
+func Listen(ctx context.Context, client *pubsub.Client, sub *pubsub.Subscription, db *gorm.DB, blockChainClient *blockchain.Client, cfg *config.Config) error {
     
     if client == nil {
         return fmt.Errorf("pubsub client is nil")
@@ -281,50 +355,68 @@ func StartListener(ctx context.Context, client *pubsub.Client, sub *pubsub.Subsc
     }
     
 	// Handler
-    orderStatusHistory := handlers.OrderStatusHistoryHandler{DB: db, Client: blockChainClient}
-	fmt.Println("Listening for order status update messages...")
-	go func() {
-		err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
-			fmt.Printf("RAW MESSAGE %s\n", string(m.Data))
-			m.Ack()
-
-			// Create a mock Gin context
-			w := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(w)
-			
-			// Create a request with the PubSub data as JSON body
-			c.Request = httptest.NewRequest("POST", "/order/history/add", bytes.NewReader(m.Data))
-			c.Request.Header.Set("Content-Type", "application/json")
-
-			// Call the existing handler
-			orderStatusHistory.AddOrderUpdate(c)
-
-			// Check the response
-			fmt.Printf("Response Status: %d, Body: %s\n", w.Code, w.Body.String())
-			
-			if w.Code >= 400 {
-				fmt.Printf("Failed to save update: Status %d, Response: %s\n", w.Code, w.Body.String())
-				m.Nack()
-			} else {
-				fmt.Printf("Successfully saved update via HTTP handler\n")
-
-                // Send notification for status update
-                notificationPayload := buildNotificationPayloadStatus(m.Data, db, blockChainClient)
-                if err:= PublishNotification(ctx, client, notificationPayload); err != nil {
-                    log.Printf("Failed to publish notification: %v", err)
-                }
-			}
-    	})
-
-		if err != nil {
-			log.Printf("PubSub listener stopped: %v", err)
+    orderStatusHistory := handlers.OrderStatusHistoryHandler{Store: repository.NewPostgres(db), Client: blockChainClient, Blobs: blobstore.NewFileSystemStore(cfg.Storage.BlobPath)}
+	slog.InfoContext(ctx, "Listening for order status update messages", "subscription", sub.ID())
+	err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// a message in flight when the listener stops is handled to the end (the supervisor waits for it)
+		ctx = context.WithoutCancel(ctx)
+
+		// the handling of the message continues the trace of its publisher, its logs carry the message ID
+		ctx, span := telemetry.StartConsumer(ctx, sub.ID(), m.ID, m.Attributes)
+		defer span.End()
+		ctx = logging.NewContext(ctx, slog.String(logging.MessageIDKey, m.ID), slog.String("subscription", sub.ID()))
+
+		slog.DebugContext(ctx, "Message received", "payload", string(m.Data))
+		m.Ack()
+
+		// Create a mock Gin context (the handlers read the trace from the context of the request)
+		w := httptest.NewRecorder()
+		c, engine := gin.CreateTestContext(w)
+		engine.ContextWithFallback = true
+		
+		// Create a request with the PubSub data as JSON body
+		c.Request = httptest.NewRequestWithContext(ctx, "POST", "/order/history/add", bytes.NewReader(m.Data))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		// Call the existing handler
+		orderStatusHistory.AddOrderUpdate(c)
+
+		// Check the response (the error responses have no personal data, the created resources are not logged)
+		metrics.ObservePubSubMessage(sub.ID(), w.Code < 400)
+		if w.Code >= 400 {
+			span.SetStatus(codes.Error, fmt.Sprintf("handler answered %d", w.Code))
+			slog.WarnContext(ctx, "Failed to handle the message", "status", w.Code, "response", w.Body.String())
+			m.Nack()
+		} else {
+			slog.InfoContext(ctx, "Message handled", "status", w.Code)
+
+            // Send notification for status update
+            notificationPayload := buildNotificationPayloadStatus(m.Data, db, blockChainClient)
+            if err:= PublishNotification(ctx, client, notificationPayload); err != nil {
+                slog.ErrorContext(ctx, "Failed to publish the notification", "error", err)
+            }
 		}
-
-	}()
+	})
+	if err != nil {
+		return fmt.Errorf("receiving from %s: %w", sub.ID(), err)
+	}
 	return nil
 }
 
-func StartListenerOrders(ctx context.Context, client *pubsub.Client, sub *pubsub.Subscription, db *gorm.DB, blockChainClient *blockchain.Client) error {
+// auditMetadata identifies the changes made by a message in the audit trail (the publisher is the actor, the message ID
+// is the request ID)
+func auditMetadata(m *pubsub.Message) audit.Metadata {
+    actor := m.Attributes["source"]
+    if actor == "" {
+        actor = "pubsub"
+    }
+    return audit.Metadata{Actor: actor, Source: audit.SourcePubSub, RequestID: m.ID}
+}
+
+// ListenOrders handles the messages of the subscription with the components of the config, the chain transactions
+// submitted after the orders are created are drained with the tasks. It blocks until ctx is
+// done and the messages in flight are handled
+func ListenOrders(ctx context.Context, client *pubsub.Client, sub *pubsub.Subscription, db *gorm.DB, blockChainClient *blockchain.Client, cfg *config.Config, tasks *supervisor.Tasks) error {
     
     if client == nil {
         return fmt.Errorf("pubsub client is nil")
@@ -335,99 +427,94 @@ func StartListenerOrders(ctx context.Context, client *pubsub.Client, sub *pubsub
     }
     
 	// Handler
-    orderHandler := handlers.OrderHandler{DB: db, Client: blockChainClient}
-	fmt.Println("Listening for new order messages...")
-	go func() {
-		err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
-			fmt.Printf("RAW MESSAGE %s\n", string(m.Data))
-			m.Ack()
-
-			// Create a mock Gin context
-			w := httptest.NewRecorder()
-			c, _ := gin.CreateTestContext(w)
-			
-			// Create a request with the PubSub data as JSON body
-			c.Request = httptest.NewRequest("POST", "/order/add", bytes.NewReader(m.Data))
-			c.Request.Header.Set("Content-Type", "application/json")
-
-			// Call the existing handler
-			orderHandler.AddOrder(c)
-
-			// Check the response
-			fmt.Printf("Response Status: %d, Body: %s\n", w.Code, w.Body.String())
-			
-			if w.Code >= 400 {
-				fmt.Printf("Failed to save update: Status %d, Response: %s\n", w.Code, w.Body.String())
-				m.Nack()
-			} else {
-				fmt.Printf("Successfully saved update via HTTP handler\n")
-
-                // Send notification for status update
-                notificationPayload := buildNotificationPayloadOrder(m.Data, db, blockChainClient)
-                if err:= PublishNotification(ctx, client, notificationPayload); err != nil {
-                    log.Printf("Failed to publish notification: %v", err)
-                }
-			}
-    	})
-
-		if err != nil {
-			log.Printf("PubSub listener stopped: %v", err)
+    orderHandler := handlers.OrderHandler{Store: repository.NewPostgres(db), Client: blockChainClient, Geocoder: geocoding.Default(), Catalog: catalog.Default(), Audit: audit.NewLogger(blockChainClient, cfg.Audit.ChainAnchoring, tasks), Tasks: tasks}
+	slog.InfoContext(ctx, "Listening for new order messages", "subscription", sub.ID())
+	err := sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		// a message in flight when the listener stops is handled to the end (the supervisor waits for it)
+		ctx = context.WithoutCancel(ctx)
+
+		// the handling of the message continues the trace of its publisher, its logs carry the message ID
+		ctx, span := telemetry.StartConsumer(ctx, sub.ID(), m.ID, m.Attributes)
+		defer span.End()
+		ctx = logging.NewContext(ctx, slog.String(logging.MessageIDKey, m.ID), slog.String("subscription", sub.ID()))
+
+		slog.DebugContext(ctx, "Message received", "payload", string(m.Data))
+		m.Ack()
+
+		// Create a mock Gin context (the handlers read the trace from the context of the request)
+		w := httptest.NewRecorder()
+		c, engine := gin.CreateTestContext(w)
+		engine.ContextWithFallback = true
+		
+		// Create a request with the PubSub data as JSON body
+		c.Request = httptest.NewRequestWithContext(ctx, "POST", "/order/add", bytes.NewReader(m.Data))
+		c.Request.Header.Set("Content-Type", "application/json")
+		audit.SetMetadata(c, auditMetadata(m))
+
+		// Call the existing handler
+		orderHandler.AddOrder(c)
+
+		// Check the response (the error responses have no personal data, the created resources are not logged)
+		metrics.ObservePubSubMessage(sub.ID(), w.Code < 400)
+		if w.Code >= 400 {
+			span.SetStatus(codes.Error, fmt.Sprintf("handler answered %d", w.Code))
+			slog.WarnContext(ctx, "Failed to handle the message", "status", w.Code, "response", w.Body.String())
+			m.Nack()
+		} else {
+			slog.InfoContext(ctx, "Message handled", "status", w.Code)
+
+            // Send the notification of the created order (the one stored, not the request)
+            order, err := createdOrder(w.Body.Bytes())
+            if err != nil {
+                slog.ErrorContext(ctx, "Failed to read the created order", "error", err)
+                return
+            }
+            notificationPayload := buildNotificationPayloadOrder(order)
+            if err:= PublishNotification(ctx, client, notificationPayload); err != nil {
+                slog.ErrorContext(ctx, "Failed to publish the notification", "error", err)
+            }
 		}
-
-	}()
+	})
+	if err != nil {
+		return fmt.Errorf("receiving from %s: %w", sub.ID(), err)
+	}
 	return nil
 }
 
-// ListAllTopics prints all available topics in the Pub/Sub project
+// ListAllTopics logs the topics of the Pub/Sub project
 func ListAllTopics(ctx context.Context, client *pubsub.Client) error {
 	if client == nil {
 		return fmt.Errorf("pubsub client is nil")
 	}
 
+	topics := []string{}
 	it := client.Topics(ctx)
-
-	fmt.Println("\n========== Available Topics ==========")
-	count := 0
 	for {
 		topic, err := it.Next()
 		if err != nil {
 			break
 		}
-		count++
-		fmt.Printf("%d. %s\n", count, topic.ID())
+		topics = append(topics, topic.ID())
 	}
-
-	if count == 0 {
-		fmt.Println("No topics found in the project")
-	}
-	fmt.Println("=========================================\n")
-
+	slog.InfoContext(ctx, "Available Pub/Sub topics", "topics", topics)
 	return nil
 }
 
-// ListAllSubscriptions prints all available subscriptions in the Pub/Sub project
+// ListAllSubscriptions logs the subscriptions of the Pub/Sub project
 func ListAllSubscriptions(ctx context.Context, client *pubsub.Client) error {
 	if client == nil {
 		return fmt.Errorf("pubsub client is nil")
 	}
 
+	subscriptions := []string{}
 	it := client.Subscriptions(ctx)
-
-	fmt.Println("\n========== Available Subscriptions ==========")
-	count := 0
 	for {
 		sub, err := it.Next()
 		if err != nil {
 			break
 		}
-		count++
-		fmt.Printf("%d. %s\n", count, sub.ID())
+		subscriptions = append(subscriptions, sub.ID())
 	}
-
-	if count == 0 {
-		fmt.Println("No subscriptions found in the project")
-	}
-	fmt.Println("=============================================\n")
-
+	slog.InfoContext(ctx, "Available Pub/Sub subscriptions", "subscriptions", subscriptions)
 	return nil
-}
\ No newline at end of file
+}
@@ -1,6 +1,8 @@
 package pubsub
 
 import (
+	"app/config"
+	"app/handlers"
 	"app/models"
 	"bytes"
 	"cloud.google.com/go/pubsub"
@@ -8,7 +10,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 
@@ -169,16 +170,11 @@ func TestStorageIDLogic(t *testing.T) {
 
 // TestBuildNotificationPayloadOrder tests the order notification payload builder
 func TestBuildNotificationPayloadOrder(t *testing.T) {
-	// Create test order data
-	orderJSON := `{
-		"id": 1,
-		"customer_id": 101,
-		"seller_id": 501,
-		"tracking_code": "TRACK001"
-	}`
+	// The order as it was stored
+	order := &models.Orders{Id: 1, Customer_ID: 101, Seller_ID: 501, Tracking_Code: "TRACK001"}
 	
 	// Build the notification
-	payload := buildNotificationPayloadOrder([]byte(orderJSON), nil, nil)
+	payload := buildNotificationPayloadOrder(order)
 	
 	// Verify payload is not nil and can be unmarshaled
 	assert.NotNil(t, payload, "Notification payload should not be nil")
@@ -193,13 +189,29 @@ func TestBuildNotificationPayloadOrder(t *testing.T) {
 	assert.Equal(t, "sms", notification.Type)
 	assert.Equal(t, "New Order Created", notification.Title)
 	assert.Contains(t, notification.Payload, "Order with ID 1 has been created")
+	assert.Contains(t, notification.Payload, "TRACK001")
+	assert.Contains(t, notification.Hyperlink, "1")
 }
 
-// TestBuildNotificationPayloadOrderInvalidJSON tests error handling for invalid JSON
-func TestBuildNotificationPayloadOrderInvalidJSON(t *testing.T) {
-	// Invalid JSON should return nil
-	payload := buildNotificationPayloadOrder([]byte(`invalid json`), nil, nil)
-	assert.Nil(t, payload, "Should return nil for invalid JSON")
+// TestCreatedOrder tests reading the created order from the response of the order handler
+func TestCreatedOrder(t *testing.T) {
+	response := `{"message": "Update stored successfully", "order": {"Id": 7, "Customer_ID": 101, "Tracking_Code": "abc"}, "tracking_code": "abc"}`
+
+	order, err := createdOrder([]byte(response))
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), order.Id)
+	assert.Equal(t, "abc", order.Tracking_Code)
+}
+
+// TestCreatedOrderInvalidResponse tests that the request is never used as the created order
+func TestCreatedOrderInvalidResponse(t *testing.T) {
+	_, err := createdOrder([]byte(`invalid json`))
+	assert.Error(t, err)
+
+	// a response without the stored order (e.g. the message of the request)
+	_, err = createdOrder([]byte(`{"customer_id": 101, "seller_id": 501}`))
+	assert.Error(t, err)
 }
 
 // TestBuildNotificationPayloadStatus tests the status update notification payload builder
@@ -316,17 +328,10 @@ func TestNotificationRequestProtobufMarshaling(t *testing.T) {
 
 // TestStartPubSubClientMissingProjectID tests client creation with missing project ID
 func TestStartPubSubClientMissingProjectID(t *testing.T) {
-	// Save current env var
-	originalProjectID := os.Getenv("PUBSUB_PROJECT")
-	defer os.Setenv("PUBSUB_PROJECT", originalProjectID)
-	
-	// Clear the env var to trigger error
-	os.Unsetenv("PUBSUB_PROJECT")
-	
 	ctx := context.Background()
 	
 	// With empty project ID, client creation should fail
-	client, err := StartPubSubClient(ctx, nil, nil)
+	client, err := StartPubSubClient(ctx, config.PubSub{})
 	assert.Nil(t, client, "Client should be nil with empty project ID")
 	assert.Error(t, err, "Should return error with empty project ID")
 }
@@ -441,8 +446,9 @@ func TestMultipleNotificationMarshalings(t *testing.T) {
 		},
 	}
 	
-	for i, notif := range notifications {
-		data, err := proto.Marshal(&notif)
+	for i := range notifications {
+		notif := &notifications[i]
+		data, err := proto.Marshal(notif)
 		assert.NoError(t, err, "Should marshal notification %d", i)
 		
 		result := &NotificationRequest{}
@@ -532,39 +538,39 @@ func TestPublishNotificationWithEmptyPayload(t *testing.T) {
 	assert.Error(t, err, "Should return error with empty payload")
 }
 
-// TestStartListenerWithNilClient tests StartListener with nil client
-func TestStartListenerWithNilClient(t *testing.T) {
+// TestListenWithNilClient tests Listen with nil client
+func TestListenWithNilClient(t *testing.T) {
 	ctx := context.Background()
 	
-	// StartListener should return error with nil client
-	err := StartListener(ctx, nil, nil, nil, nil)
+	// Listen should return error with nil client
+	err := Listen(ctx, nil, nil, nil, nil, nil)
 	assert.Error(t, err, "Should return error with nil client")
 }
 
-// TestStartListenerWithNilSubscription tests StartListener with nil subscription
-func TestStartListenerWithNilSubscription(t *testing.T) {
+// TestListenWithNilSubscription tests Listen with nil subscription
+func TestListenWithNilSubscription(t *testing.T) {
 	ctx := context.Background()
 	
-	// StartListener should return error with nil subscription
-	err := StartListener(ctx, &pubsub.Client{}, nil, nil, nil)
+	// Listen should return error with nil subscription
+	err := Listen(ctx, &pubsub.Client{}, nil, nil, nil, nil)
 	assert.Error(t, err, "Should return error with nil subscription")
 }
 
-// TestStartListenerOrdersWithNilClient tests StartListenerOrders with nil client
-func TestStartListenerOrdersWithNilClient(t *testing.T) {
+// TestListenOrdersWithNilClient tests ListenOrders with nil client
+func TestListenOrdersWithNilClient(t *testing.T) {
 	ctx := context.Background()
 	
-	// StartListenerOrders should return error with nil client
-	err := StartListenerOrders(ctx, nil, nil, nil, nil)
+	// ListenOrders should return error with nil client
+	err := ListenOrders(ctx, nil, nil, nil, nil, nil, nil)
 	assert.Error(t, err, "Should return error with nil client")
 }
 
-// TestStartListenerOrdersWithNilSubscription tests StartListenerOrders with nil subscription
-func TestStartListenerOrdersWithNilSubscription(t *testing.T) {
+// TestListenOrdersWithNilSubscription tests ListenOrders with nil subscription
+func TestListenOrdersWithNilSubscription(t *testing.T) {
 	ctx := context.Background()
 	
-	// StartListenerOrders should return error with nil subscription
-	err := StartListenerOrders(ctx, &pubsub.Client{}, nil, nil, nil)
+	// ListenOrders should return error with nil subscription
+	err := ListenOrders(ctx, &pubsub.Client{}, nil, nil, nil, nil, nil)
 	assert.Error(t, err, "Should return error with nil subscription")
 }
 
@@ -618,15 +624,15 @@ func TestSubscribeClientWithEmptySubscriptionID(t *testing.T) {
 	assert.Equal(t, "subscriptionID is empty", err.Error())
 }
 
-// TestBuildNotificationPayloadOrderEmptyPayload tests with nil/empty payload
-func TestBuildNotificationPayloadOrderEmptyPayload(t *testing.T) {
-	// Test with nil payload
-	result := buildNotificationPayloadOrder(nil, nil, nil)
-	assert.Nil(t, result, "Should return nil for nil payload")
+// TestBuildNotificationPayloadOrderNotStored tests with an order that was not stored
+func TestBuildNotificationPayloadOrderNotStored(t *testing.T) {
+	// Test with nil order
+	result := buildNotificationPayloadOrder(nil)
+	assert.Nil(t, result, "Should return nil for nil order")
 	
-	// Test with empty payload
-	result = buildNotificationPayloadOrder([]byte{}, nil, nil)
-	assert.Nil(t, result, "Should return nil for empty payload")
+	// Test with an order without id
+	result = buildNotificationPayloadOrder(&models.Orders{Customer_ID: 101})
+	assert.Nil(t, result, "Should return nil for an order without id")
 }
 
 // TestBuildNotificationPayloadStatusEmptyPayload tests with nil/empty payload
@@ -653,31 +659,31 @@ func TestCreateTopicLogic(t *testing.T) {
 func TestOrderPayloadMarshaling(t *testing.T) {
 	tests := []struct {
 		name   string
-		json   string
+		order  models.Orders
 		expUserID string
 	}{
 		{
 			name:   "Simple order",
-			json:   `{"id": 5, "customer_id": 250}`,
+			order:  models.Orders{Id: 5, Customer_ID: 250},
 			expUserID: "250",
 		},
 		{
 			name:   "Order with additional fields",
-			json:   `{"id": 10, "customer_id": 500, "total": 99.99, "status": "pending"}`,
+			order:  models.Orders{Id: 10, Customer_ID: 500, Price: 99.99, Tracking_Code: "TRACK010"},
 			expUserID: "500",
 		},
 	}
 	
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			result := buildNotificationPayloadOrder([]byte(test.json), nil, nil)
+			result := buildNotificationPayloadOrder(&test.order)
 			assert.NotNil(t, result)
 			
 			var notification NotificationRequest
 			err := proto.Unmarshal(result, &notification)
 			assert.NoError(t, err)
 			assert.Equal(t, test.expUserID, notification.UserId)
-			assert.Contains(t, notification.Payload, "Order with ID")
+			assert.Contains(t, notification.Payload, fmt.Sprintf("Order with ID %d", test.order.Id))
 		})
 	}
 }
@@ -743,4 +749,21 @@ func TestEmptyPayloadValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+// TestBuildCustomerNotification tests the payload of the notifications sent by the handlers
+func TestBuildCustomerNotification(t *testing.T) {
+	payload, err := buildCustomerNotification(handlers.CustomerNotification{
+		CustomerID: 101,
+		OrderID:    7,
+		Title:      "Order Changed",
+		Message:    "Cork Wallet was removed from your order 7.",
+	})
+	assert.NoError(t, err)
+
+	notification := &NotificationRequest{}
+	assert.NoError(t, proto.Unmarshal(payload, notification))
+	assert.Equal(t, "101", notification.UserId)
+	assert.Equal(t, "Order Changed", notification.Title)
+	assert.Equal(t, "Cork Wallet was removed from your order 7.", notification.Payload)
+	assert.True(t, strings.HasSuffix(notification.Hyperlink, "/order/7"))
+}
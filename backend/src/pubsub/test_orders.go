@@ -3,7 +3,7 @@ package pubsub
 import (
     "context"
     "fmt"
-    "log"
+    "log/slog"
     "os"
     "time"
 
@@ -15,7 +15,7 @@ func TestOrdersPubSub() {
 
     pubsubEmulatorHost := os.Getenv("PUBSUB_EMULATOR_HOST")
     if pubsubEmulatorHost != "" {
-        log.Printf("Using Pub/Sub emulator at %s", pubsubEmulatorHost)
+        slog.Info("Using the Pub/Sub emulator", "host", pubsubEmulatorHost)
     }
 
     projectID := os.Getenv("GCP_PROJECT_ID")
@@ -25,7 +25,8 @@ func TestOrdersPubSub() {
 
     client, err := pubsub.NewClient(ctx, projectID)
     if err != nil {
-        log.Fatalf("Failed to create Pub/Sub client: %v", err)
+        slog.Error("Failed to create the Pub/Sub client", "error", err)
+        return
     }
     defer client.Close()
 
@@ -33,11 +34,13 @@ func TestOrdersPubSub() {
     topic := client.Topic(topicName)
     exists, err := topic.Exists(ctx)
     if err != nil {
-        log.Fatalf("Failed to check if topic exists: %v", err)
+        slog.Error("Failed to check if the topic exists", "topic", topicName, "error", err)
+        return
     }
     if !exists {
         if _, err := client.CreateTopic(ctx, topicName); err != nil {
-            log.Fatalf("Failed to create topic: %v", err)
+            slog.Error("Failed to create the topic", "topic", topicName, "error", err)
+            return
         }
         topic = client.Topic(topicName)
     }
@@ -78,9 +81,9 @@ func TestOrdersPubSub() {
 
 	id, err := res.Get(ctx)
 	if err != nil {
-		log.Printf("Failed to publish message: %v", err)
+		slog.Error("Failed to publish the test order", "error", err)
 	} else {
-		log.Printf("Published message ID: %s, Data: %s", id, msgData)
+		slog.Info("Test order published", "message_id", id, "payload", msgData)
 	}
 }
 
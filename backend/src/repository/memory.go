@@ -0,0 +1,904 @@
+package repository
+
+import (
+	"app/models"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a store that keeps the rows in memory, used by the tests. Transactions restore the previous rows when
+// they fail, they are not isolated from concurrent writers
+type Memory struct {
+	mu       sync.Mutex
+	data     memoryData
+	failures map[string]error
+}
+
+type memoryData struct {
+	orders          map[uint]models.Orders
+	history         map[uint]models.OrderStatusHistory
+	notarizations   map[uint]models.UpdateNotarization
+	storages        map[uint]models.Storage
+	orderProducts   map[uint]models.OrderProduct
+	shipments       map[uint]models.Shipment
+	locationReviews map[uint]models.LocationReview
+	deliveryProofs  map[uint]models.DeliveryProof
+	auditEvents     map[uint]models.AuditEvent
+	auditAnchors    map[uint]models.AuditAnchor
+	deployments     map[uint]models.ContractDeployment
+	products        map[uint]models.Product
+	lastID          map[string]uint
+}
+
+// NewMemory creates an empty in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		data: memoryData{
+			orders:          map[uint]models.Orders{},
+			history:         map[uint]models.OrderStatusHistory{},
+			notarizations:   map[uint]models.UpdateNotarization{},
+			storages:        map[uint]models.Storage{},
+			orderProducts:   map[uint]models.OrderProduct{},
+			shipments:       map[uint]models.Shipment{},
+			locationReviews: map[uint]models.LocationReview{},
+			deliveryProofs:  map[uint]models.DeliveryProof{},
+			auditEvents:     map[uint]models.AuditEvent{},
+			auditAnchors:    map[uint]models.AuditAnchor{},
+			deployments:     map[uint]models.ContractDeployment{},
+			products:        map[uint]models.Product{},
+			lastID:          map[string]uint{},
+		},
+		failures: map[string]error{},
+	}
+}
+
+// Fail makes an operation (named <Repository>.<Method>, e.g. "Orders.Create") return err until it is cleared with
+// a nil error
+func (s *Memory) Fail(operation string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, operation)
+		return
+	}
+	s.failures[operation] = err
+}
+
+func (s *Memory) Orders() Orders                   { return memoryOrders{s} }
+func (s *Memory) History() History                 { return memoryHistory{s} }
+func (s *Memory) Storages() Storages               { return memoryStorages{s} }
+func (s *Memory) OrderProducts() OrderProducts     { return memoryOrderProducts{s} }
+func (s *Memory) Shipments() Shipments             { return memoryShipments{s} }
+func (s *Memory) LocationReviews() LocationReviews { return memoryLocationReviews{s} }
+func (s *Memory) DeliveryProofs() DeliveryProofs   { return memoryDeliveryProofs{s} }
+func (s *Memory) AuditEvents() AuditEvents         { return memoryAuditEvents{s} }
+func (s *Memory) Deployments() Deployments         { return memoryDeployments{s} }
+func (s *Memory) Products() Products               { return memoryProducts{s} }
+
+func (s *Memory) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// do runs an operation with the store locked, unless a failure was set for it
+func (s *Memory) do(operation string, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.failures[operation]; err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (s *Memory) nextID(table string) uint {
+	s.data.lastID[table]++
+	return s.data.lastID[table]
+}
+
+// useID reserves an id given by the caller, so the next generated ids do not collide with it
+func (s *Memory) useID(table string, id uint) {
+	if id > s.data.lastID[table] {
+		s.data.lastID[table] = id
+	}
+}
+
+func (d memoryData) clone() memoryData {
+	return memoryData{
+		orders:          cloneMap(d.orders),
+		history:         cloneMap(d.history),
+		notarizations:   cloneMap(d.notarizations),
+		storages:        cloneMap(d.storages),
+		orderProducts:   cloneMap(d.orderProducts),
+		shipments:       cloneMap(d.shipments),
+		locationReviews: cloneMap(d.locationReviews),
+		deliveryProofs:  cloneMap(d.deliveryProofs),
+		auditEvents:     cloneMap(d.auditEvents),
+		auditAnchors:    cloneMap(d.auditAnchors),
+		deployments:     cloneMap(d.deployments),
+		products:        cloneMap(d.products),
+		lastID:          cloneMap(d.lastID),
+	}
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	clone := make(map[K]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// sortedValues returns the rows that match, sorted by less
+func sortedValues[V any](m map[uint]V, match func(V) bool, less func(a, b V) bool) []V {
+	values := []V{}
+	for _, v := range m {
+		if match == nil || match(v) {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return less(values[i], values[j]) })
+	return values
+}
+
+type memoryOrders struct{ s *Memory }
+
+func (r memoryOrders) Create(ctx context.Context, order *models.Orders) error {
+	return r.s.do("Orders.Create", func() error {
+		if order.Id == 0 {
+			order.Id = r.s.nextID("orders")
+		} else {
+			r.s.useID("orders", order.Id)
+		}
+		r.s.data.orders[order.Id] = stripOrder(*order)
+		return nil
+	})
+}
+
+func (r memoryOrders) Get(ctx context.Context, id uint) (*models.Orders, error) {
+	var order models.Orders
+	err := r.s.do("Orders.Get", func() error {
+		var ok bool
+		if order, ok = r.s.data.orders[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r memoryOrders) GetWithProducts(ctx context.Context, id uint) (*models.Orders, error) {
+	var order models.Orders
+	err := r.s.do("Orders.GetWithProducts", func() error {
+		var ok bool
+		if order, ok = r.s.data.orders[id]; !ok {
+			return ErrNotFound
+		}
+		order.Products = r.s.orderProductsOf(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r memoryOrders) GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Orders, error) {
+	var order models.Orders
+	err := r.s.do("Orders.GetByTrackingCode", func() error {
+		for _, stored := range r.s.data.orders {
+			if stored.Tracking_Code == trackingCode {
+				order = stored
+				order.Products = r.s.orderProductsOf(order.Id)
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r memoryOrders) List(ctx context.Context, oldestFirst bool) ([]models.Orders, error) {
+	var orders []models.Orders
+	err := r.s.do("Orders.List", func() error {
+		orders = sortedValues(r.s.data.orders, nil, func(a, b models.Orders) bool {
+			if oldestFirst {
+				return a.Created_At.Before(b.Created_At)
+			}
+			return a.Created_At.After(b.Created_At)
+		})
+		for i := range orders {
+			orders[i].Products = r.s.orderProductsOf(orders[i].Id)
+			orders[i].Updates = r.s.historyOf(orders[i].Id)
+		}
+		return nil
+	})
+	return orders, err
+}
+
+func (r memoryOrders) Update(ctx context.Context, order *models.Orders, columns ...string) error {
+	return r.s.do("Orders.Update", func() error {
+		stored, ok := r.s.data.orders[order.Id]
+		if !ok {
+			return ErrNotFound
+		}
+		if err := copyColumns(&stored, order, columns); err != nil {
+			return err
+		}
+		r.s.data.orders[order.Id] = stored
+		return nil
+	})
+}
+
+// copyColumns copies the fields of the columns (named as in the database, e.g. delivery_estimate) from a row to another
+func copyColumns[T any](to, from *T, columns []string) error {
+	target, source := reflect.ValueOf(to).Elem(), reflect.ValueOf(from).Elem()
+	for _, column := range columns {
+		field, ok := target.Type().FieldByNameFunc(func(name string) bool { return strings.EqualFold(name, column) })
+		if !ok {
+			return fmt.Errorf("unknown column %s", column)
+		}
+		target.FieldByIndex(field.Index).Set(source.FieldByIndex(field.Index))
+	}
+	return nil
+}
+
+// updatePrice sets the price of an order from its products, as the trigger of the order products does in the database
+func (s *Memory) updatePrice(orderID uint) {
+	order, ok := s.data.orders[orderID]
+	if !ok {
+		return
+	}
+	order.Price = 0
+	for _, orderProduct := range s.orderProductsOf(orderID) {
+		order.Price += orderProduct.Product_Price_At_Purchase * float64(orderProduct.Quantity)
+	}
+	s.data.orders[orderID] = order
+}
+
+// stripOrder removes the associations, they are stored in their own tables
+func stripOrder(order models.Orders) models.Orders {
+	order.Products, order.Updates, order.Shipments = nil, nil, nil
+	return order
+}
+
+func (s *Memory) orderProductsOf(orderID uint) []models.OrderProduct {
+	return sortedValues(s.data.orderProducts, func(p models.OrderProduct) bool { return p.Order_ID == orderID },
+		func(a, b models.OrderProduct) bool { return a.ID < b.ID })
+}
+
+// historyOf returns the updates of an order, newest first, without the ones whose location was rejected
+func (s *Memory) historyOf(orderID uint) []models.OrderStatusHistory {
+	rejected := map[uint]bool{}
+	for _, review := range s.data.locationReviews {
+		if review.Review_Status == models.ReviewRejected {
+			rejected[review.Order_Status_History_ID] = true
+		}
+	}
+	updates := sortedValues(s.data.history, func(u models.OrderStatusHistory) bool { return u.Order_ID == orderID && !rejected[u.Id] },
+		func(a, b models.OrderStatusHistory) bool {
+			if a.Timestamp_History.Equal(b.Timestamp_History) {
+				return a.Id > b.Id
+			}
+			return a.Timestamp_History.After(b.Timestamp_History)
+		})
+	for i := range updates {
+		s.notarize(&updates[i])
+	}
+	return updates
+}
+
+// notarize sets the transaction and the contract of the notarization recorded once the update was stored, if any
+func (s *Memory) notarize(update *models.OrderStatusHistory) {
+	if notarization, ok := s.data.notarizations[update.Id]; ok {
+		update.Blockchain_Transaction = notarization.Blockchain_Transaction
+		if notarization.Contract_Deployment_ID != nil {
+			update.Contract_Deployment_ID = notarization.Contract_Deployment_ID
+		}
+	}
+}
+
+type memoryHistory struct{ s *Memory }
+
+func (r memoryHistory) Create(ctx context.Context, update *models.OrderStatusHistory) error {
+	return r.s.do("History.Create", func() error {
+		update.Id = r.s.nextID("order_status_history")
+		stored := *update
+		stored.Order, stored.Storage = nil, nil
+		r.s.data.history[update.Id] = stored
+		return nil
+	})
+}
+
+func (r memoryHistory) Latest(ctx context.Context, orderID uint) (*models.OrderStatusHistory, error) {
+	var latest *models.OrderStatusHistory
+	err := r.s.do("History.Latest", func() error {
+		if updates := r.s.historyOf(orderID); len(updates) > 0 {
+			latest = &updates[0]
+		}
+		return nil
+	})
+	return latest, err
+}
+
+func (r memoryHistory) ListByOrder(ctx context.Context, orderID uint) ([]models.OrderStatusHistory, error) {
+	var updates []models.OrderStatusHistory
+	err := r.s.do("History.ListByOrder", func() error {
+		updates = r.s.historyOf(orderID)
+		for i := range updates {
+			if updates[i].Storage_ID == nil {
+				continue
+			}
+			if storage, ok := r.s.data.storages[*updates[i].Storage_ID]; ok {
+				updates[i].Storage = &storage
+			}
+		}
+		return nil
+	})
+	return updates, err
+}
+
+func (r memoryHistory) ListReviewed(ctx context.Context, orderID uint) ([]ReviewedUpdate, error) {
+	var updates []ReviewedUpdate
+	err := r.s.do("History.ListReviewed", func() error {
+		rejected := map[uint]bool{}
+		for _, review := range r.s.data.locationReviews {
+			if review.Review_Status == models.ReviewRejected {
+				rejected[review.Order_Status_History_ID] = true
+			}
+		}
+		history := sortedValues(r.s.data.history, func(u models.OrderStatusHistory) bool { return u.Order_ID == orderID },
+			func(a, b models.OrderStatusHistory) bool {
+				if a.Timestamp_History.Equal(b.Timestamp_History) {
+					return a.Id < b.Id
+				}
+				return a.Timestamp_History.Before(b.Timestamp_History)
+			})
+		updates = make([]ReviewedUpdate, 0, len(history))
+		for _, update := range history {
+			r.s.notarize(&update)
+			updates = append(updates, ReviewedUpdate{OrderStatusHistory: update, Rejected: rejected[update.Id]})
+		}
+		return nil
+	})
+	return updates, err
+}
+
+func (r memoryHistory) RecordNotarization(ctx context.Context, notarization *models.UpdateNotarization) error {
+	return r.s.do("History.RecordNotarization", func() error {
+		if _, ok := r.s.data.history[notarization.Order_Status_History_ID]; !ok {
+			return ErrNotFound
+		}
+		if _, ok := r.s.data.notarizations[notarization.Order_Status_History_ID]; ok {
+			return fmt.Errorf("update %d is already notarized", notarization.Order_Status_History_ID)
+		}
+		r.s.data.notarizations[notarization.Order_Status_History_ID] = *notarization
+		return nil
+	})
+}
+
+type memoryStorages struct{ s *Memory }
+
+func (r memoryStorages) Create(ctx context.Context, storage *models.Storage) error {
+	return r.s.do("Storages.Create", func() error {
+		if storage.Id == 0 {
+			storage.Id = r.s.nextID("storages")
+		} else {
+			r.s.useID("storages", storage.Id)
+		}
+		r.s.data.storages[storage.Id] = *storage
+		return nil
+	})
+}
+
+func (r memoryStorages) Get(ctx context.Context, id uint) (*models.Storage, error) {
+	var storage models.Storage
+	err := r.s.do("Storages.Get", func() error {
+		var ok bool
+		if storage, ok = r.s.data.storages[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &storage, nil
+}
+
+func (r memoryStorages) List(ctx context.Context) ([]models.Storage, error) {
+	var storages []models.Storage
+	err := r.s.do("Storages.List", func() error {
+		storages = sortedValues(r.s.data.storages, nil, func(a, b models.Storage) bool { return a.Id < b.Id })
+		return nil
+	})
+	return storages, err
+}
+
+type memoryOrderProducts struct{ s *Memory }
+
+func (r memoryOrderProducts) Create(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.s.do("OrderProducts.Create", func() error {
+		if orderProduct.ID == 0 {
+			orderProduct.ID = r.s.nextID("order_products")
+		} else {
+			r.s.useID("order_products", orderProduct.ID)
+		}
+		r.s.data.orderProducts[orderProduct.ID] = *orderProduct
+		r.s.updatePrice(orderProduct.Order_ID)
+		return nil
+	})
+}
+
+func (r memoryOrderProducts) Get(ctx context.Context, id uint) (*models.OrderProduct, error) {
+	var orderProduct models.OrderProduct
+	err := r.s.do("OrderProducts.Get", func() error {
+		var ok bool
+		if orderProduct, ok = r.s.data.orderProducts[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &orderProduct, nil
+}
+
+func (r memoryOrderProducts) ListByOrder(ctx context.Context, orderID uint) ([]models.OrderProduct, error) {
+	var orderProducts []models.OrderProduct
+	err := r.s.do("OrderProducts.ListByOrder", func() error {
+		orderProducts = r.s.orderProductsOf(orderID)
+		return nil
+	})
+	return orderProducts, err
+}
+
+func (r memoryOrderProducts) Save(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.s.do("OrderProducts.Save", func() error {
+		if orderProduct.ID == 0 {
+			orderProduct.ID = r.s.nextID("order_products")
+		}
+		r.s.data.orderProducts[orderProduct.ID] = *orderProduct
+		r.s.updatePrice(orderProduct.Order_ID)
+		return nil
+	})
+}
+
+func (r memoryOrderProducts) Delete(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.s.do("OrderProducts.Delete", func() error {
+		delete(r.s.data.orderProducts, orderProduct.ID)
+		r.s.updatePrice(orderProduct.Order_ID)
+		return nil
+	})
+}
+
+type memoryShipments struct{ s *Memory }
+
+func (r memoryShipments) Create(ctx context.Context, shipment *models.Shipment) error {
+	return r.s.do("Shipments.Create", func() error {
+		if shipment.Id == 0 {
+			shipment.Id = r.s.nextID("shipments")
+		} else {
+			r.s.useID("shipments", shipment.Id)
+		}
+		if shipment.Created_At.IsZero() {
+			shipment.Created_At = time.Now()
+		}
+		stored := *shipment
+		stored.Products, stored.Updates, stored.Status = nil, nil, ""
+		r.s.data.shipments[shipment.Id] = stored
+		return nil
+	})
+}
+
+func (r memoryShipments) Get(ctx context.Context, id uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.s.do("Shipments.Get", func() error {
+		var ok bool
+		if shipment, ok = r.s.data.shipments[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+func (r memoryShipments) ListByOrder(ctx context.Context, orderID uint) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.s.do("Shipments.ListByOrder", func() error {
+		shipments = sortedValues(r.s.data.shipments, func(s models.Shipment) bool { return s.Order_ID == orderID },
+			func(a, b models.Shipment) bool { return a.Id < b.Id })
+		for i := range shipments {
+			r.s.withContents(&shipments[i])
+		}
+		return nil
+	})
+	return shipments, err
+}
+
+func (r memoryShipments) GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.s.do("Shipments.GetByTrackingCode", func() error {
+		for _, stored := range r.s.data.shipments {
+			if stored.Tracking_Code == trackingCode {
+				shipment = stored
+				r.s.withContents(&shipment)
+				return nil
+			}
+		}
+		return ErrNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+// withContents sets the products and the updates (newest first) of a shipment
+func (s *Memory) withContents(shipment *models.Shipment) {
+	shipment.Products = sortedValues(s.data.orderProducts, func(p models.OrderProduct) bool {
+		return p.Shipment_ID != nil && *p.Shipment_ID == shipment.Id
+	}, func(a, b models.OrderProduct) bool { return a.ID < b.ID })
+	shipment.Updates = []models.OrderStatusHistory{}
+	for _, update := range s.historyOf(shipment.Order_ID) {
+		if update.Shipment_ID != nil && *update.Shipment_ID == shipment.Id {
+			shipment.Updates = append(shipment.Updates, update)
+		}
+	}
+}
+
+type memoryLocationReviews struct{ s *Memory }
+
+func (r memoryLocationReviews) Create(ctx context.Context, review *models.LocationReview) error {
+	return r.s.do("LocationReviews.Create", func() error {
+		if review.Id == 0 {
+			review.Id = r.s.nextID("location_reviews")
+		} else {
+			r.s.useID("location_reviews", review.Id)
+		}
+		if review.Created_At.IsZero() {
+			review.Created_At = time.Now()
+		}
+		r.s.data.locationReviews[review.Id] = *review
+		return nil
+	})
+}
+
+func (r memoryLocationReviews) Get(ctx context.Context, id uint) (*models.LocationReview, error) {
+	var review models.LocationReview
+	err := r.s.do("LocationReviews.Get", func() error {
+		var ok bool
+		if review, ok = r.s.data.locationReviews[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r memoryLocationReviews) ListByStatus(ctx context.Context, status string) ([]models.LocationReview, error) {
+	return r.list("LocationReviews.ListByStatus", func(review models.LocationReview) bool { return review.Review_Status == status })
+}
+
+func (r memoryLocationReviews) ListByOrder(ctx context.Context, orderID uint) ([]models.LocationReview, error) {
+	return r.list("LocationReviews.ListByOrder", func(review models.LocationReview) bool { return review.Order_ID == orderID })
+}
+
+// list returns the reviews that match, oldest first
+func (r memoryLocationReviews) list(operation string, match func(models.LocationReview) bool) ([]models.LocationReview, error) {
+	var reviews []models.LocationReview
+	err := r.s.do(operation, func() error {
+		reviews = sortedValues(r.s.data.locationReviews, match, func(a, b models.LocationReview) bool {
+			if a.Created_At.Equal(b.Created_At) {
+				return a.Id < b.Id
+			}
+			return a.Created_At.Before(b.Created_At)
+		})
+		return nil
+	})
+	return reviews, err
+}
+
+func (r memoryLocationReviews) Save(ctx context.Context, review *models.LocationReview) error {
+	return r.s.do("LocationReviews.Save", func() error {
+		if review.Id == 0 {
+			review.Id = r.s.nextID("location_reviews")
+		}
+		r.s.data.locationReviews[review.Id] = *review
+		return nil
+	})
+}
+
+type memoryDeliveryProofs struct{ s *Memory }
+
+func (r memoryDeliveryProofs) Create(ctx context.Context, proof *models.DeliveryProof) error {
+	return r.s.do("DeliveryProofs.Create", func() error {
+		if proof.Id == 0 {
+			proof.Id = r.s.nextID("delivery_proofs")
+		} else {
+			r.s.useID("delivery_proofs", proof.Id)
+		}
+		if proof.Created_At.IsZero() {
+			proof.Created_At = time.Now()
+		}
+		r.s.data.deliveryProofs[proof.Id] = *proof
+		return nil
+	})
+}
+
+func (r memoryDeliveryProofs) Get(ctx context.Context, id uint) (*models.DeliveryProof, error) {
+	var proof models.DeliveryProof
+	err := r.s.do("DeliveryProofs.Get", func() error {
+		var ok bool
+		if proof, ok = r.s.data.deliveryProofs[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+func (r memoryDeliveryProofs) ListByOrder(ctx context.Context, orderID uint) ([]models.DeliveryProof, error) {
+	var proofs []models.DeliveryProof
+	err := r.s.do("DeliveryProofs.ListByOrder", func() error {
+		proofs = sortedValues(r.s.data.deliveryProofs, func(p models.DeliveryProof) bool { return p.Order_ID == orderID },
+			func(a, b models.DeliveryProof) bool {
+				if a.Created_At.Equal(b.Created_At) {
+					return a.Id < b.Id
+				}
+				return a.Created_At.Before(b.Created_At)
+			})
+		return nil
+	})
+	return proofs, err
+}
+
+type memoryAuditEvents struct{ s *Memory }
+
+func (r memoryAuditEvents) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.s.do("AuditEvents.Create", func() error {
+		if event.Id == 0 {
+			event.Id = r.s.nextID("audit_events")
+		} else {
+			r.s.useID("audit_events", event.Id)
+		}
+		r.s.data.auditEvents[event.Id] = *event
+		return nil
+	})
+}
+
+func (r memoryAuditEvents) RecordAnchor(ctx context.Context, ids []uint, transaction string, deploymentID *uint) error {
+	return r.s.do("AuditEvents.RecordAnchor", func() error {
+		for _, id := range ids {
+			if _, ok := r.s.data.auditEvents[id]; !ok {
+				return ErrNotFound
+			}
+			if _, ok := r.s.data.auditAnchors[id]; ok {
+				return fmt.Errorf("audit event %d is already anchored", id)
+			}
+		}
+		anchoredAt := time.Now()
+		for _, id := range ids {
+			r.s.data.auditAnchors[id] = models.AuditAnchor{Audit_Event_ID: id, Blockchain_Transaction: transaction, Contract_Deployment_ID: deploymentID, Anchored_At: anchoredAt}
+		}
+		return nil
+	})
+}
+
+func (r memoryAuditEvents) ListByOrder(ctx context.Context, orderID uint) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.s.do("AuditEvents.ListByOrder", func() error {
+		events = sortedValues(r.s.data.auditEvents, func(e models.AuditEvent) bool { return e.Order_ID == orderID },
+			func(a, b models.AuditEvent) bool {
+				if a.Created_At.Equal(b.Created_At) {
+					return a.Id < b.Id
+				}
+				return a.Created_At.Before(b.Created_At)
+			})
+		for i := range events {
+			if anchor, ok := r.s.data.auditAnchors[events[i].Id]; ok {
+				events[i].Blockchain_Transaction = &anchor.Blockchain_Transaction
+				events[i].Contract_Deployment_ID = anchor.Contract_Deployment_ID
+			}
+		}
+		return nil
+	})
+	return events, err
+}
+
+type memoryDeployments struct{ s *Memory }
+
+func (r memoryDeployments) Create(ctx context.Context, deployment *models.ContractDeployment) error {
+	return r.s.do("Deployments.Create", func() error {
+		if deployment.Id == 0 {
+			deployment.Id = r.s.nextID("contract_deployments")
+		} else {
+			r.s.useID("contract_deployments", deployment.Id)
+		}
+		r.s.data.deployments[deployment.Id] = *deployment
+		return nil
+	})
+}
+
+func (r memoryDeployments) Get(ctx context.Context, id uint) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	err := r.s.do("Deployments.Get", func() error {
+		var ok bool
+		if deployment, ok = r.s.data.deployments[id]; !ok {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r memoryDeployments) GetByAddress(ctx context.Context, chainID int64, address string) (*models.ContractDeployment, error) {
+	deployment, err := r.first("Deployments.GetByAddress", func(d models.ContractDeployment) bool {
+		return d.Chain_ID == chainID && d.Contract_Address == address
+	})
+	if err == nil && deployment == nil {
+		return nil, ErrNotFound
+	}
+	return deployment, err
+}
+
+func (r memoryDeployments) Active(ctx context.Context, chainID int64) (*models.ContractDeployment, error) {
+	return r.first("Deployments.Active", func(d models.ContractDeployment) bool { return d.Chain_ID == chainID && d.Active })
+}
+
+func (r memoryDeployments) Legacy(ctx context.Context) (*models.ContractDeployment, error) {
+	return r.first("Deployments.Legacy", func(d models.ContractDeployment) bool { return d.Legacy })
+}
+
+// first returns the deployment that matches, nil when there is none
+func (r memoryDeployments) first(operation string, match func(models.ContractDeployment) bool) (*models.ContractDeployment, error) {
+	var deployment *models.ContractDeployment
+	err := r.s.do(operation, func() error {
+		for _, stored := range r.s.data.deployments {
+			if match(stored) {
+				deployment = &stored
+				return nil
+			}
+		}
+		return nil
+	})
+	return deployment, err
+}
+
+func (r memoryDeployments) List(ctx context.Context) ([]models.ContractDeployment, error) {
+	var deployments []models.ContractDeployment
+	err := r.s.do("Deployments.List", func() error {
+		deployments = sortedValues(r.s.data.deployments, nil, func(a, b models.ContractDeployment) bool {
+			if a.Deployed_At.Equal(b.Deployed_At) {
+				return a.Id > b.Id
+			}
+			return a.Deployed_At.After(b.Deployed_At)
+		})
+		return nil
+	})
+	return deployments, err
+}
+
+func (r memoryDeployments) ListByIDs(ctx context.Context, ids []uint) ([]models.ContractDeployment, error) {
+	deployments := []models.ContractDeployment{}
+	err := r.s.do("Deployments.ListByIDs", func() error {
+		for _, id := range ids {
+			if deployment, ok := r.s.data.deployments[id]; ok {
+				deployments = append(deployments, deployment)
+			}
+		}
+		return nil
+	})
+	return deployments, err
+}
+
+func (r memoryDeployments) Activate(ctx context.Context, id uint) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	err := r.s.do("Deployments.Activate", func() error {
+		var ok bool
+		if deployment, ok = r.s.data.deployments[id]; !ok {
+			return ErrNotFound
+		}
+		for otherID, other := range r.s.data.deployments {
+			if other.Chain_ID == deployment.Chain_ID && other.Active {
+				other.Active = false
+				r.s.data.deployments[otherID] = other
+			}
+		}
+		deployment.Active = true
+		r.s.data.deployments[id] = deployment
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+type memoryProducts struct{ s *Memory }
+
+func (r memoryProducts) Create(ctx context.Context, product *models.Product) error {
+	return r.s.do("Products.Create", func() error {
+		if _, ok := r.s.data.products[product.ID]; ok {
+			return fmt.Errorf("product %d already exists", product.ID)
+		}
+		stored := *product
+		stored.Variants = append([]models.ProductVariant(nil), product.Variants...)
+		r.s.data.products[product.ID] = stored
+		return nil
+	})
+}
+
+func (r memoryProducts) List(ctx context.Context, search string, offset, limit int) ([]models.Product, int64, error) {
+	var page []models.Product
+	var total int64
+	err := r.s.do("Products.List", func() error {
+		search = strings.ToLower(search)
+		products := sortedValues(r.s.data.products, func(p models.Product) bool {
+			if p.Status == models.ProductDisabled {
+				return false
+			}
+			return search == "" || strings.Contains(strings.ToLower(p.Name), search) || strings.Contains(strings.ToLower(p.SKU), search)
+		}, func(a, b models.Product) bool {
+			if a.Name == b.Name {
+				return a.ID < b.ID
+			}
+			return a.Name < b.Name
+		})
+		total = int64(len(products))
+		if offset > len(products) {
+			offset = len(products)
+		}
+		if end := offset + limit; end < len(products) {
+			products = products[:end]
+		}
+		page = products[offset:]
+		return nil
+	})
+	return page, total, err
+}
+
+func (r memoryProducts) ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	products := []models.Product{}
+	err := r.s.do("Products.ListByIDs", func() error {
+		for _, id := range ids {
+			if product, ok := r.s.data.products[id]; ok {
+				products = append(products, product)
+			}
+		}
+		return nil
+	})
+	return products, err
+}
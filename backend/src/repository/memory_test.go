@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"app/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_Orders(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	first := models.Orders{Tracking_Code: "A", Created_At: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)}
+	second := models.Orders{Tracking_Code: "B", Created_At: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, store.Orders().Create(ctx, &first))
+	require.NoError(t, store.Orders().Create(ctx, &second))
+	assert.Equal(t, uint(1), first.Id)
+	assert.Equal(t, uint(2), second.Id)
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: 1, Product_ID: 10}))
+
+	order, err := store.Orders().GetWithProducts(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, order.Products, 1)
+
+	_, err = store.Orders().Get(ctx, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	order, err = store.Orders().GetByTrackingCode(ctx, "A")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), order.Id)
+	assert.Len(t, order.Products, 1)
+
+	_, err = store.Orders().GetByTrackingCode(ctx, "C")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	orders, err := store.Orders().List(ctx, false)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, "B", orders[0].Tracking_Code, "newest first")
+
+	orders, err = store.Orders().List(ctx, true)
+	require.NoError(t, err)
+	assert.Equal(t, "A", orders[0].Tracking_Code)
+}
+
+func TestMemory_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.Orders().Create(ctx, &models.Orders{Delivery_Address: "Rua Nova"}))
+
+	order, err := store.Orders().Get(ctx, 1)
+	require.NoError(t, err)
+	order.Delivery_Address = "changed without saving"
+
+	stored, err := store.Orders().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Rua Nova", stored.Delivery_Address)
+}
+
+func TestMemory_HistoryLatest(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	latest, err := store.History().Latest(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, latest, "no updates yet")
+
+	now := time.Now()
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "SHIPPED", Timestamp_History: now}))
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "PROCESSING", Timestamp_History: now.Add(-time.Hour)}))
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 2, Order_Status: "DELIVERED", Timestamp_History: now.Add(time.Hour)}))
+
+	latest, err = store.History().Latest(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "SHIPPED", latest.Order_Status)
+
+	updates, err := store.History().ListByOrder(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, updates, 2)
+	assert.Equal(t, "PROCESSING", updates[1].Order_Status)
+}
+
+func TestMemory_TransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.Orders().Create(ctx, &models.Orders{Delivery_Address: "Rua Nova"}))
+
+	err := store.Transaction(ctx, func(tx Store) error {
+		order, err := tx.Orders().Get(ctx, 1)
+		require.NoError(t, err)
+		order.Delivery_Address = "Rua Velha"
+		require.NoError(t, tx.Orders().Update(ctx, order, "delivery_address"))
+		require.NoError(t, tx.AuditEvents().Create(ctx, &models.AuditEvent{Order_ID: 1}))
+		return errors.New("abort")
+	})
+	assert.EqualError(t, err, "abort")
+
+	order, err := store.Orders().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Rua Nova", order.Delivery_Address)
+	events, err := store.AuditEvents().ListByOrder(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestMemory_RecordAnchor(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.AuditEvents().Create(ctx, &models.AuditEvent{Order_ID: 1}))
+	require.NoError(t, store.AuditEvents().Create(ctx, &models.AuditEvent{Order_ID: 1}))
+	deployment := uint(2)
+
+	require.NoError(t, store.AuditEvents().RecordAnchor(ctx, []uint{1, 2}, "0xabc", &deployment))
+
+	events, err := store.AuditEvents().ListByOrder(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, "0xabc", *event.Blockchain_Transaction)
+		assert.Equal(t, &deployment, event.Contract_Deployment_ID)
+	}
+	// the events are append only, the anchor is recorded apart
+	assert.Nil(t, store.data.auditEvents[1].Blockchain_Transaction)
+	assert.Nil(t, store.data.auditEvents[2].Contract_Deployment_ID)
+
+	assert.Error(t, store.AuditEvents().RecordAnchor(ctx, []uint{1}, "0xdef", nil), "an event is anchored once")
+	assert.ErrorIs(t, store.AuditEvents().RecordAnchor(ctx, []uint{9}, "0xabc", nil), ErrNotFound)
+}
+
+func TestMemory_Fail(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+
+	store.Fail("Storages.List", errors.New("connection lost"))
+	_, err := store.Storages().List(ctx)
+	assert.EqualError(t, err, "connection lost")
+
+	store.Fail("Storages.List", nil)
+	_, err = store.Storages().List(ctx)
+	assert.NoError(t, err)
+}
+
+func TestMemory_Deployments(t *testing.T) {
+	ctx := context.Background()
+	deployments := NewMemory().Deployments()
+
+	active, err := deployments.Active(ctx, 11155111)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+
+	first := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: "0xA", Legacy: true, Deployed_At: time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)}
+	second := models.ContractDeployment{Chain_ID: 11155111, Contract_Address: "0xB", Deployed_At: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	other := models.ContractDeployment{Chain_ID: 1, Contract_Address: "0xA", Active: true, Deployed_At: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)}
+	for _, deployment := range []*models.ContractDeployment{&first, &second, &other} {
+		require.NoError(t, deployments.Create(ctx, deployment))
+	}
+
+	_, err = deployments.Activate(ctx, first.Id)
+	require.NoError(t, err)
+	activated, err := deployments.Activate(ctx, second.Id)
+	require.NoError(t, err)
+	assert.True(t, activated.Active)
+
+	active, err = deployments.Active(ctx, 11155111)
+	require.NoError(t, err)
+	assert.Equal(t, second.Id, active.Id, "the previous contract of the chain is deactivated")
+	stored, err := deployments.Get(ctx, first.Id)
+	require.NoError(t, err)
+	assert.False(t, stored.Active)
+	active, err = deployments.Active(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, other.Id, active.Id, "the other chains keep their contract")
+
+	found, err := deployments.GetByAddress(ctx, 1, "0xA")
+	require.NoError(t, err)
+	assert.Equal(t, other.Id, found.Id)
+	_, err = deployments.GetByAddress(ctx, 1, "0xB")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = deployments.Activate(ctx, 9)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	legacy, err := deployments.Legacy(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, first.Id, legacy.Id)
+
+	all, err := deployments.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, other.Id, all[0].Id, "newest first")
+}
+
+func TestMemory_ShipmentContents(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.Shipments().Create(ctx, &models.Shipment{Order_ID: 1, Tracking_Code: "SHIP1"}))
+	shipmentID := uint(1)
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: 1, Product_ID: 10, Shipment_ID: &shipmentID}))
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: 1, Product_ID: 11}))
+	now := time.Now()
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "SHIPPED", Timestamp_History: now.Add(-time.Hour), Shipment_ID: &shipmentID}))
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "IN TRANSIT", Timestamp_History: now, Shipment_ID: &shipmentID}))
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "PROCESSING", Timestamp_History: now.Add(-2 * time.Hour)}))
+
+	shipment, err := store.Shipments().GetByTrackingCode(ctx, "SHIP1")
+	require.NoError(t, err)
+	require.Len(t, shipment.Products, 1)
+	assert.Equal(t, uint(10), shipment.Products[0].Product_ID)
+	require.Len(t, shipment.Updates, 2)
+	assert.Equal(t, "IN TRANSIT", shipment.Updates[0].Order_Status, "newest first")
+
+	stored, err := store.Shipments().Get(ctx, shipmentID)
+	require.NoError(t, err)
+	assert.Empty(t, stored.Products, "only the lookups by order and tracking code load the contents")
+	_, err = store.Shipments().GetByTrackingCode(ctx, "UNKNOWN")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemory_HistoryRecordNotarization(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "PROCESSING", Timestamp_History: time.Now()}))
+	stored := store.data.history[1]
+	deployment := uint(2)
+
+	require.NoError(t, store.History().RecordNotarization(ctx, &models.UpdateNotarization{Order_Status_History_ID: 1, Blockchain_Transaction: "0xabc", Contract_Deployment_ID: &deployment}))
+
+	latest, err := store.History().Latest(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "0xabc", latest.Blockchain_Transaction)
+	assert.Equal(t, &deployment, latest.Contract_Deployment_ID)
+	reviewed, err := store.History().ListReviewed(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "0xabc", reviewed[0].Blockchain_Transaction)
+
+	// the updates are append only, like the table (update_history trigger)
+	assert.Equal(t, stored, store.data.history[1], "the stored update is not changed")
+	assert.Error(t, store.History().RecordNotarization(ctx, &models.UpdateNotarization{Order_Status_History_ID: 1, Blockchain_Transaction: "0xdef"}), "a notarization is not replaced")
+	assert.ErrorIs(t, store.History().RecordNotarization(ctx, &models.UpdateNotarization{Order_Status_History_ID: 9, Blockchain_Transaction: "0xabc"}), ErrNotFound)
+}
+
+func TestMemory_HistoryWithoutRejectedUpdates(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	now := time.Now()
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "IN TRANSIT", Timestamp_History: now.Add(-time.Hour)}))
+	require.NoError(t, store.History().Create(ctx, &models.OrderStatusHistory{Order_ID: 1, Order_Status: "DELIVERED", Timestamp_History: now}))
+	review := models.LocationReview{Order_ID: 1, Order_Status_History_ID: 2, Review_Status: models.ReviewPending}
+	require.NoError(t, store.LocationReviews().Create(ctx, &review))
+
+	latest, err := store.History().Latest(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "DELIVERED", latest.Order_Status, "pending updates still count")
+
+	review.Review_Status = models.ReviewRejected
+	require.NoError(t, store.LocationReviews().Save(ctx, &review))
+
+	latest, err = store.History().Latest(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "IN TRANSIT", latest.Order_Status)
+	updates, err := store.History().ListByOrder(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, updates, 1)
+
+	// the verification reads them all, oldest first
+	reviewed, err := store.History().ListReviewed(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reviewed, 2)
+	assert.Equal(t, []string{"IN TRANSIT", "DELIVERED"}, []string{reviewed[0].Order_Status, reviewed[1].Order_Status})
+	assert.Equal(t, []bool{false, true}, []bool{reviewed[0].Rejected, reviewed[1].Rejected})
+}
+
+func TestMemory_Products(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	for _, product := range []models.Product{
+		{ID: 1, Name: "Sardines", SKU: "SAR-1"},
+		{ID: 2, Name: "Cork Wallet", SKU: "CORK-1", Variants: []models.ProductVariant{{ID: 7, Product_ID: 2}}},
+		{ID: 3, Name: "Cork Hat", SKU: "CORK-2", Status: models.ProductDisabled},
+		{ID: 4, Name: "Cork Backpack", SKU: "CORK-3", Status: models.ProductNotAvailable},
+	} {
+		require.NoError(t, store.Products().Create(ctx, &product))
+	}
+
+	products, total, err := store.Products().List(ctx, "cork", 0, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total, "the disabled products are left out")
+	require.Len(t, products, 1)
+	assert.Equal(t, "Cork Backpack", products[0].Name)
+	products, _, err = store.Products().List(ctx, "", 2, 10)
+	require.NoError(t, err)
+	require.Len(t, products, 1)
+	assert.Equal(t, "Sardines", products[0].Name)
+
+	products, err = store.Products().ListByIDs(ctx, []uint{2, 3, 9})
+	require.NoError(t, err)
+	require.Len(t, products, 2)
+	assert.Len(t, products[0].Variants, 1)
+}
+
+func TestMemory_OrderUpdateKeepsThePrice(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	require.NoError(t, store.Orders().Create(ctx, &models.Orders{Delivery_Address: "Rua Nova"}))
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: 1, Quantity: 2, Product_Price_At_Purchase: 10}))
+
+	// a copy of the order read before the products changed
+	stale, err := store.Orders().Get(ctx, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: 1, Quantity: 1, Product_Price_At_Purchase: 5}))
+
+	stale.Shipping_Weight = 3
+	stale.Delivery_Address = "Rua Velha"
+	require.NoError(t, store.Orders().Update(ctx, stale, "shipping_weight"))
+
+	order, err := store.Orders().Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, order.Price, "the price follows the products")
+	assert.Equal(t, 3.0, order.Shipping_Weight)
+	assert.Equal(t, "Rua Nova", order.Delivery_Address, "only the given columns are written")
+
+	assert.ErrorIs(t, store.Orders().Update(ctx, &models.Orders{Id: 9}, "shipping_weight"), ErrNotFound)
+}
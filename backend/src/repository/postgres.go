@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"app/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Postgres is the store backed by the service database
+type Postgres struct {
+	db *gorm.DB
+}
+
+// NewPostgres creates a store on a database connection (or on an open gorm transaction)
+func NewPostgres(db *gorm.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (s *Postgres) Orders() Orders                   { return postgresOrders{s.db} }
+func (s *Postgres) History() History                 { return postgresHistory{s.db} }
+func (s *Postgres) Storages() Storages               { return postgresStorages{s.db} }
+func (s *Postgres) OrderProducts() OrderProducts     { return postgresOrderProducts{s.db} }
+func (s *Postgres) Shipments() Shipments             { return postgresShipments{s.db} }
+func (s *Postgres) LocationReviews() LocationReviews { return postgresLocationReviews{s.db} }
+func (s *Postgres) DeliveryProofs() DeliveryProofs   { return postgresDeliveryProofs{s.db} }
+func (s *Postgres) AuditEvents() AuditEvents         { return postgresAuditEvents{s.db} }
+func (s *Postgres) Deployments() Deployments         { return postgresDeployments{s.db} }
+func (s *Postgres) Products() Products               { return postgresProducts{s.db} }
+
+func (s *Postgres) Transaction(ctx context.Context, fn func(tx Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewPostgres(tx))
+	})
+}
+
+// notFound converts the gorm error of a missing row
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+type postgresOrders struct{ db *gorm.DB }
+
+func (r postgresOrders) Create(ctx context.Context, order *models.Orders) error {
+	return r.db.WithContext(ctx).Create(order).Error
+}
+
+func (r postgresOrders) Get(ctx context.Context, id uint) (*models.Orders, error) {
+	var order models.Orders
+	if err := r.db.WithContext(ctx).First(&order, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (r postgresOrders) GetWithProducts(ctx context.Context, id uint) (*models.Orders, error) {
+	var order models.Orders
+	if err := r.db.WithContext(ctx).Preload("Products").First(&order, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (r postgresOrders) GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Orders, error) {
+	var order models.Orders
+	if err := r.db.WithContext(ctx).Preload("Products").Where("tracking_code = ?", trackingCode).First(&order).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &order, nil
+}
+
+func (r postgresOrders) List(ctx context.Context, oldestFirst bool) ([]models.Orders, error) {
+	order := "created_at desc"
+	if oldestFirst {
+		order = "created_at asc"
+	}
+
+	var orders []models.Orders
+	err := r.db.WithContext(ctx).Order(order).
+		Preload("Products").
+		Preload("Updates", func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(withNotarization, withoutRejected).Order("timestamp_history desc")
+		}).
+		Find(&orders).Error
+	return orders, err
+}
+
+func (r postgresOrders) Update(ctx context.Context, order *models.Orders, columns ...string) error {
+	result := r.db.WithContext(ctx).Model(order).Select(columns).Updates(order)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return result.Error
+}
+
+type postgresHistory struct{ db *gorm.DB }
+
+// notarizedColumns are the columns of the updates, with the notarization recorded once they were stored in place of
+// their own transaction and contract (only set when they were notarized before being stored). Read with withNotarization
+const notarizedColumns = `order_status_history.*, COALESCE(update_notarizations.blockchain_transaction, order_status_history.blockchain_transaction) AS blockchain_transaction, COALESCE(update_notarizations.contract_deployment_id, order_status_history.contract_deployment_id) AS contract_deployment_id`
+
+const notarizationJoin = "LEFT JOIN update_notarizations ON update_notarizations.order_status_history_id = order_status_history.id"
+
+// withNotarization reads the updates with their notarization (see notarizedColumns)
+func withNotarization(db *gorm.DB) *gorm.DB {
+	return db.Select(notarizedColumns).Joins(notarizationJoin)
+}
+
+// withoutRejected leaves out the updates whose location was rejected on review
+func withoutRejected(db *gorm.DB) *gorm.DB {
+	return db.Where(`NOT EXISTS (SELECT 1 FROM location_reviews WHERE location_reviews.order_status_history_id = order_status_history.id AND location_reviews.review_status = ?)`, models.ReviewRejected)
+}
+
+func (r postgresHistory) Create(ctx context.Context, update *models.OrderStatusHistory) error {
+	return r.db.WithContext(ctx).Create(update).Error
+}
+
+func (r postgresHistory) Latest(ctx context.Context, orderID uint) (*models.OrderStatusHistory, error) {
+	var latest models.OrderStatusHistory
+	err := r.db.WithContext(ctx).Scopes(withNotarization).Where("order_id = ?", orderID).Scopes(withoutRejected).Order("timestamp_history desc").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+func (r postgresHistory) ListByOrder(ctx context.Context, orderID uint) ([]models.OrderStatusHistory, error) {
+	var updates []models.OrderStatusHistory
+	err := r.db.WithContext(ctx).Preload("Storage").Scopes(withNotarization).Where("order_id = ?", orderID).Scopes(withoutRejected).Order("timestamp_history desc").Find(&updates).Error
+	return updates, err
+}
+
+func (r postgresHistory) ListReviewed(ctx context.Context, orderID uint) ([]ReviewedUpdate, error) {
+	var updates []ReviewedUpdate
+	err := r.db.WithContext(ctx).Model(&models.OrderStatusHistory{}).Joins(notarizationJoin).
+		Select(notarizedColumns+", EXISTS (SELECT 1 FROM location_reviews WHERE location_reviews.order_status_history_id = order_status_history.id AND location_reviews.review_status = ?) AS rejected", models.ReviewRejected).
+		Where("order_id = ?", orderID).Order("timestamp_history asc, order_status_history.id asc").Find(&updates).Error
+	return updates, err
+}
+
+func (r postgresHistory) RecordNotarization(ctx context.Context, notarization *models.UpdateNotarization) error {
+	return r.db.WithContext(ctx).Create(notarization).Error
+}
+
+type postgresStorages struct{ db *gorm.DB }
+
+func (r postgresStorages) Create(ctx context.Context, storage *models.Storage) error {
+	return r.db.WithContext(ctx).Create(storage).Error
+}
+
+func (r postgresStorages) Get(ctx context.Context, id uint) (*models.Storage, error) {
+	var storage models.Storage
+	if err := r.db.WithContext(ctx).First(&storage, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &storage, nil
+}
+
+func (r postgresStorages) List(ctx context.Context) ([]models.Storage, error) {
+	var storages []models.Storage
+	err := r.db.WithContext(ctx).Order("id asc").Find(&storages).Error
+	return storages, err
+}
+
+type postgresOrderProducts struct{ db *gorm.DB }
+
+func (r postgresOrderProducts) Create(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.db.WithContext(ctx).Create(orderProduct).Error
+}
+
+func (r postgresOrderProducts) Get(ctx context.Context, id uint) (*models.OrderProduct, error) {
+	var orderProduct models.OrderProduct
+	if err := r.db.WithContext(ctx).First(&orderProduct, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &orderProduct, nil
+}
+
+func (r postgresOrderProducts) ListByOrder(ctx context.Context, orderID uint) ([]models.OrderProduct, error) {
+	var orderProducts []models.OrderProduct
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Find(&orderProducts).Error
+	return orderProducts, err
+}
+
+func (r postgresOrderProducts) Save(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.db.WithContext(ctx).Save(orderProduct).Error
+}
+
+func (r postgresOrderProducts) Delete(ctx context.Context, orderProduct *models.OrderProduct) error {
+	return r.db.WithContext(ctx).Delete(orderProduct).Error
+}
+
+type postgresShipments struct{ db *gorm.DB }
+
+func (r postgresShipments) Create(ctx context.Context, shipment *models.Shipment) error {
+	return r.db.WithContext(ctx).Create(shipment).Error
+}
+
+func (r postgresShipments) Get(ctx context.Context, id uint) (*models.Shipment, error) {
+	var shipment models.Shipment
+	if err := r.db.WithContext(ctx).First(&shipment, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &shipment, nil
+}
+
+// withContents loads the products and the updates (newest first) of the shipments
+func withContents(db *gorm.DB) *gorm.DB {
+	return db.Preload("Products").
+		Preload("Updates", func(db *gorm.DB) *gorm.DB {
+			return db.Scopes(withNotarization, withoutRejected).Order("timestamp_history desc")
+		})
+}
+
+func (r postgresShipments) ListByOrder(ctx context.Context, orderID uint) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := withContents(r.db.WithContext(ctx)).Where("order_id = ?", orderID).Order("id asc").Find(&shipments).Error
+	return shipments, err
+}
+
+func (r postgresShipments) GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Shipment, error) {
+	var shipment models.Shipment
+	if err := withContents(r.db.WithContext(ctx)).Where("tracking_code = ?", trackingCode).First(&shipment).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &shipment, nil
+}
+
+type postgresLocationReviews struct{ db *gorm.DB }
+
+func (r postgresLocationReviews) Create(ctx context.Context, review *models.LocationReview) error {
+	return r.db.WithContext(ctx).Create(review).Error
+}
+
+func (r postgresLocationReviews) Get(ctx context.Context, id uint) (*models.LocationReview, error) {
+	var review models.LocationReview
+	if err := r.db.WithContext(ctx).First(&review, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &review, nil
+}
+
+func (r postgresLocationReviews) ListByStatus(ctx context.Context, status string) ([]models.LocationReview, error) {
+	var reviews []models.LocationReview
+	err := r.db.WithContext(ctx).Where("review_status = ?", status).Order("created_at asc").Find(&reviews).Error
+	return reviews, err
+}
+
+func (r postgresLocationReviews) ListByOrder(ctx context.Context, orderID uint) ([]models.LocationReview, error) {
+	var reviews []models.LocationReview
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at asc").Find(&reviews).Error
+	return reviews, err
+}
+
+func (r postgresLocationReviews) Save(ctx context.Context, review *models.LocationReview) error {
+	return r.db.WithContext(ctx).Save(review).Error
+}
+
+type postgresDeliveryProofs struct{ db *gorm.DB }
+
+func (r postgresDeliveryProofs) Create(ctx context.Context, proof *models.DeliveryProof) error {
+	return r.db.WithContext(ctx).Create(proof).Error
+}
+
+func (r postgresDeliveryProofs) Get(ctx context.Context, id uint) (*models.DeliveryProof, error) {
+	var proof models.DeliveryProof
+	if err := r.db.WithContext(ctx).First(&proof, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &proof, nil
+}
+
+func (r postgresDeliveryProofs) ListByOrder(ctx context.Context, orderID uint) ([]models.DeliveryProof, error) {
+	var proofs []models.DeliveryProof
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("created_at asc").Find(&proofs).Error
+	return proofs, err
+}
+
+type postgresAuditEvents struct{ db *gorm.DB }
+
+func (r postgresAuditEvents) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r postgresAuditEvents) RecordAnchor(ctx context.Context, ids []uint, transaction string, deploymentID *uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	anchoredAt := time.Now()
+	anchors := make([]models.AuditAnchor, len(ids))
+	for i, id := range ids {
+		anchors[i] = models.AuditAnchor{Audit_Event_ID: id, Blockchain_Transaction: transaction, Contract_Deployment_ID: deploymentID, Anchored_At: anchoredAt}
+	}
+	return r.db.WithContext(ctx).Create(&anchors).Error
+}
+
+// anchoredColumns reads the events with the anchor recorded apart for them, if any
+const anchoredColumns = `audit_events.*, COALESCE(audit_anchors.blockchain_transaction, audit_events.blockchain_transaction) AS blockchain_transaction, COALESCE(audit_anchors.contract_deployment_id, audit_events.contract_deployment_id) AS contract_deployment_id`
+
+func (r postgresAuditEvents) ListByOrder(ctx context.Context, orderID uint) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.WithContext(ctx).Select(anchoredColumns).
+		Joins("LEFT JOIN audit_anchors ON audit_anchors.audit_event_id = audit_events.id").
+		Where("order_id = ?", orderID).Order("created_at asc, audit_events.id asc").Find(&events).Error
+	return events, err
+}
+
+type postgresDeployments struct{ db *gorm.DB }
+
+func (r postgresDeployments) Create(ctx context.Context, deployment *models.ContractDeployment) error {
+	return r.db.WithContext(ctx).Create(deployment).Error
+}
+
+func (r postgresDeployments) Get(ctx context.Context, id uint) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	if err := r.db.WithContext(ctx).First(&deployment, id).Error; err != nil {
+		return nil, notFound(err)
+	}
+	return &deployment, nil
+}
+
+func (r postgresDeployments) GetByAddress(ctx context.Context, chainID int64, address string) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	err := r.db.WithContext(ctx).Where("chain_id = ? AND contract_address = ?", chainID, address).First(&deployment).Error
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &deployment, nil
+}
+
+func (r postgresDeployments) Active(ctx context.Context, chainID int64) (*models.ContractDeployment, error) {
+	return r.first(ctx, "chain_id = ? AND active", chainID)
+}
+
+func (r postgresDeployments) Legacy(ctx context.Context) (*models.ContractDeployment, error) {
+	return r.first(ctx, "legacy")
+}
+
+// first returns the deployment matching the condition, nil when there is none
+func (r postgresDeployments) first(ctx context.Context, query string, args ...interface{}) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	err := r.db.WithContext(ctx).Where(query, args...).First(&deployment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+func (r postgresDeployments) List(ctx context.Context) ([]models.ContractDeployment, error) {
+	var deployments []models.ContractDeployment
+	err := r.db.WithContext(ctx).Order("deployed_at desc, id desc").Find(&deployments).Error
+	return deployments, err
+}
+
+func (r postgresDeployments) ListByIDs(ctx context.Context, ids []uint) ([]models.ContractDeployment, error) {
+	var deployments []models.ContractDeployment
+	if len(ids) == 0 {
+		return deployments, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&deployments).Error
+	return deployments, err
+}
+
+func (r postgresDeployments) Activate(ctx context.Context, id uint) (*models.ContractDeployment, error) {
+	var deployment models.ContractDeployment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&deployment, id).Error; err != nil {
+			return notFound(err)
+		}
+		// one active deployment per chain (unique index), the previous one is deactivated first
+		err := tx.Model(&models.ContractDeployment{}).
+			Where("chain_id = ? AND active AND id <> ?", deployment.Chain_ID, id).
+			Update("active", false).Error
+		if err != nil {
+			return err
+		}
+		deployment.Active = true
+		return tx.Model(&deployment).Update("active", true).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deployment, nil
+}
+
+type postgresProducts struct{ db *gorm.DB }
+
+func (r postgresProducts) Create(ctx context.Context, product *models.Product) error {
+	return r.db.WithContext(ctx).Create(product).Error
+}
+
+func (r postgresProducts) List(ctx context.Context, search string, offset, limit int) ([]models.Product, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Product{}).Where("status IS NULL OR status <> ?", models.ProductDisabled)
+	if search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("lower(name) LIKE ? OR lower(sku) LIKE ?", pattern, pattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var products []models.Product
+	err := query.Preload("Variants").Order("name asc").Limit(limit).Offset(offset).Find(&products).Error
+	return products, total, err
+}
+
+func (r postgresProducts) ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error) {
+	var products []models.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := r.db.WithContext(ctx).Preload("Variants").Where("id IN ?", ids).Find(&products).Error
+	return products, err
+}
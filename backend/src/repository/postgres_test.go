@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"app/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func setupPostgres(t *testing.T) (*Postgres, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gdb, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return NewPostgres(gdb), mock
+}
+
+// the updates are read with the notarization recorded apart for them
+const notarizedHistorySQL = `SELECT order_status_history.\*, COALESCE\(update_notarizations.blockchain_transaction, order_status_history.blockchain_transaction\) AS blockchain_transaction, COALESCE\(update_notarizations.contract_deployment_id, order_status_history.contract_deployment_id\) AS contract_deployment_id FROM "order_status_history" LEFT JOIN update_notarizations ON update_notarizations.order_status_history_id = order_status_history.id`
+
+// the condition leaving out the updates whose location was rejected on review
+const withoutRejectedSQL = `AND \(NOT EXISTS \(SELECT 1 FROM location_reviews WHERE location_reviews.order_status_history_id = order_status_history.id AND location_reviews.review_status = \$2\)\)`
+
+func TestPostgres_OrderNotFound(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE "orders"."id" = \$1 ORDER BY "orders"."id" LIMIT \$2`).
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := store.Orders().Get(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_OrderByTrackingCode(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "orders" WHERE tracking_code = \$1 ORDER BY "orders"."id" LIMIT \$2`).
+		WithArgs("TRACK1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tracking_code"}).AddRow(1, "TRACK1"))
+	mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."order_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}).AddRow(3, 1))
+
+	order, err := store.Orders().GetByTrackingCode(context.Background(), "TRACK1")
+
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), order.Id)
+	assert.Len(t, order.Products, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_HistoryLatest(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(notarizedHistorySQL+` WHERE order_id = \$1 `+withoutRejectedSQL+` ORDER BY timestamp_history desc,"order_status_history"."id" LIMIT \$3`).
+		WithArgs(1, models.ReviewRejected, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_status", "timestamp_history"}).AddRow(4, 1, "SHIPPED", time.Now()))
+	mock.ExpectQuery(notarizedHistorySQL+` WHERE order_id = \$1`).
+		WithArgs(2, models.ReviewRejected, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "SHIPPED", latest.Order_Status)
+
+	latest, err = store.History().Latest(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Nil(t, latest, "an order without updates is not an error")
+}
+
+func TestPostgres_ListOrders(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "orders" ORDER BY created_at asc`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."order_id" = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}).AddRow(1, 1))
+	mock.ExpectQuery(notarizedHistorySQL+` WHERE "order_status_history"."order_id" = \$1 `+withoutRejectedSQL+` ORDER BY timestamp_history desc`).
+		WithArgs(1, models.ReviewRejected).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id"}).AddRow(1, 1))
+
+	orders, err := store.Orders().List(context.Background(), true)
+
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Len(t, orders[0].Products, 1)
+	assert.Len(t, orders[0].Updates, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_OrderUpdateWritesOnlyTheColumns(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	// the price is left to the trigger of the order products
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "orders" SET "shipping_weight"=\$1,"volumetric_weight"=\$2 WHERE "id" = \$3`).
+		WithArgs(2.5, 1.0, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order := &models.Orders{Id: 4, Price: 12, Shipping_Weight: 2.5, Volumetric_Weight: 1}
+	require.NoError(t, store.Orders().Update(context.Background(), order, "shipping_weight", "volumetric_weight"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_TransactionRollsBack(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := store.Transaction(context.Background(), func(tx Store) error {
+		require.NoError(t, tx.Orders().Create(context.Background(), &models.Orders{Tracking_Code: "A"}))
+		return errors.New("abort")
+	})
+
+	assert.EqualError(t, err, "abort")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_ActivateDeployment(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "contract_deployments" WHERE "contract_deployments"."id" = \$1 ORDER BY "contract_deployments"."id" LIMIT \$2`).
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chain_id", "contract_address"}).AddRow(2, 11155111, "0xB"))
+	mock.ExpectExec(`UPDATE "contract_deployments" SET "active"=\$1 WHERE chain_id = \$2 AND active AND id <> \$3`).
+		WithArgs(false, 11155111, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE "contract_deployments" SET "active"=\$1 WHERE "id" = \$2`).
+		WithArgs(true, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deployment, err := store.Deployments().Activate(context.Background(), 2)
+
+	require.NoError(t, err)
+	assert.True(t, deployment.Active)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_NoActiveDeployment(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "contract_deployments" WHERE chain_id = \$1 AND active ORDER BY "contract_deployments"."id" LIMIT \$2`).
+		WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	deployment, err := store.Deployments().Active(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Nil(t, deployment)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_ShipmentByTrackingCode(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "shipments" WHERE tracking_code = \$1 ORDER BY "shipments"."id" LIMIT \$2`).
+		WithArgs("SHIP1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "tracking_code"}).AddRow(2, 1, "SHIP1"))
+	mock.ExpectQuery(`SELECT \* FROM "order_products" WHERE "order_products"."shipment_id" = \$1`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "shipment_id"}).AddRow(3, 1, 2))
+	mock.ExpectQuery(notarizedHistorySQL+` WHERE "order_status_history"."shipment_id" = \$1 `+withoutRejectedSQL+` ORDER BY timestamp_history desc`).
+		WithArgs(2, models.ReviewRejected).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "shipment_id", "order_status"}).AddRow(5, 1, 2, "SHIPPED"))
+
+	shipment, err := store.Shipments().GetByTrackingCode(context.Background(), "SHIP1")
+
+	require.NoError(t, err)
+	assert.Len(t, shipment.Products, 1)
+	require.Len(t, shipment.Updates, 1)
+	assert.Equal(t, "SHIPPED", shipment.Updates[0].Order_Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_LocationReviewsByStatus(t *testing.T) {
+	store, mock := setupPostgres(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "location_reviews" WHERE review_status = \$1 ORDER BY created_at asc`).
+		WithArgs(models.ReviewPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "review_status"}).AddRow(1, models.ReviewPending))
+	mock.ExpectQuery(`SELECT \* FROM "delivery_proofs" WHERE "delivery_proofs"."id" = \$1 ORDER BY "delivery_proofs"."id" LIMIT \$2`).
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	reviews, err := store.LocationReviews().ListByStatus(context.Background(), models.ReviewPending)
+	require.NoError(t, err)
+	assert.Len(t, reviews, 1)
+
+	_, err = store.DeliveryProofs().Get(context.Background(), 9)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_HistoryRecordNotarization(t *testing.T) {
+	store, mock := setupPostgres(t)
+	deployment := uint(2)
+
+	// the updates are append only (update_history trigger), the notarization is inserted apart: any UPDATE of
+	// order_status_history is an unexpected query
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "update_notarizations" \("order_status_history_id","blockchain_transaction","contract_deployment_id","notarized_at"\) VALUES \(\$1,\$2,\$3,\$4\)`).
+		WithArgs(4, "0xabc", &deployment, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, store.History().RecordNotarization(context.Background(), &models.UpdateNotarization{
+		Order_Status_History_ID: 4, Blockchain_Transaction: "0xabc", Contract_Deployment_ID: &deployment, Notarized_At: time.Now(),
+	}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgres_AuditEventsRecordAnchor(t *testing.T) {
+	store, mock := setupPostgres(t)
+	deployment := uint(2)
+
+	// the audit events are append only (update_audit_events trigger), the anchor is inserted apart: any UPDATE of
+	// audit_events is an unexpected query
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "audit_anchors" \("audit_event_id","blockchain_transaction","contract_deployment_id","anchored_at"\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)`).
+		WithArgs(1, "0xabc", &deployment, sqlmock.AnyArg(), 2, "0xabc", &deployment, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT audit_events.\*, COALESCE\(audit_anchors.blockchain_transaction, audit_events.blockchain_transaction\) AS blockchain_transaction, .* FROM "audit_events" LEFT JOIN audit_anchors ON audit_anchors.audit_event_id = audit_events.id WHERE order_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "blockchain_transaction"}).AddRow(1, 1, "0xabc"))
+
+	require.NoError(t, store.AuditEvents().RecordAnchor(context.Background(), []uint{1, 2}, "0xabc", &deployment))
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "0xabc", *events[0].Blockchain_Transaction)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
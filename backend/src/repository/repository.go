@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"app/models"
+	"context"
+	"errors"
+)
+
+// ErrNotFound is returned when the requested row does not exist
+var ErrNotFound = errors.New("record not found")
+
+// Orders stores the orders
+type Orders interface {
+	Create(ctx context.Context, order *models.Orders) error
+	Get(ctx context.Context, id uint) (*models.Orders, error)
+	// GetWithProducts returns the order with its products
+	GetWithProducts(ctx context.Context, id uint) (*models.Orders, error)
+	// GetByTrackingCode returns the order of a tracking code with its products
+	GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Orders, error)
+	// List returns every order with its products and updates (newest update first), newest order first
+	// unless oldestFirst is set
+	List(ctx context.Context, oldestFirst bool) ([]models.Orders, error)
+	// Update writes the given columns of the order, the other ones are left as stored (the price is kept by the
+	// database from the order products)
+	Update(ctx context.Context, order *models.Orders, columns ...string) error
+}
+
+// History stores the status updates of the orders (append only). The updates whose location was rejected on review
+// are left out of the lookups (and of the updates of the orders and shipments), they do not count for their status
+type History interface {
+	Create(ctx context.Context, update *models.OrderStatusHistory) error
+	// Latest returns the most recent update of an order, nil when the order has no updates
+	Latest(ctx context.Context, orderID uint) (*models.OrderStatusHistory, error)
+	// ListByOrder returns the updates of an order with their storage, newest first
+	ListByOrder(ctx context.Context, orderID uint) ([]models.OrderStatusHistory, error)
+	// ListReviewed returns every update of an order, oldest first, with whether its location was rejected on review
+	// (the rejected ones included, they stay notarized)
+	ListReviewed(ctx context.Context, orderID uint) ([]ReviewedUpdate, error)
+	// RecordNotarization records the chain transaction an update was notarized in once it was stored, the update itself
+	// is not changed. The lookups return the update with the transaction and the contract deployment of it
+	RecordNotarization(ctx context.Context, notarization *models.UpdateNotarization) error
+}
+
+// ReviewedUpdate is an update with whether its location was rejected on review
+type ReviewedUpdate struct {
+	models.OrderStatusHistory
+	Rejected bool
+}
+
+// Storages stores the warehouses the orders go through
+type Storages interface {
+	Create(ctx context.Context, storage *models.Storage) error
+	Get(ctx context.Context, id uint) (*models.Storage, error)
+	// List returns the storages sorted by id
+	List(ctx context.Context) ([]models.Storage, error)
+}
+
+// OrderProducts stores the products of the orders
+type OrderProducts interface {
+	Create(ctx context.Context, orderProduct *models.OrderProduct) error
+	Get(ctx context.Context, id uint) (*models.OrderProduct, error)
+	ListByOrder(ctx context.Context, orderID uint) ([]models.OrderProduct, error)
+	Save(ctx context.Context, orderProduct *models.OrderProduct) error
+	Delete(ctx context.Context, orderProduct *models.OrderProduct) error
+}
+
+// Shipments stores the parcels the orders are split into
+type Shipments interface {
+	Create(ctx context.Context, shipment *models.Shipment) error
+	Get(ctx context.Context, id uint) (*models.Shipment, error)
+	// ListByOrder returns the shipments of an order with their products and updates (newest first), sorted by id
+	ListByOrder(ctx context.Context, orderID uint) ([]models.Shipment, error)
+	// GetByTrackingCode returns the shipment of a tracking code with its products and updates (newest first)
+	GetByTrackingCode(ctx context.Context, trackingCode string) (*models.Shipment, error)
+}
+
+// LocationReviews stores the reviews of the updates flagged by the geofence check
+type LocationReviews interface {
+	Create(ctx context.Context, review *models.LocationReview) error
+	Get(ctx context.Context, id uint) (*models.LocationReview, error)
+	// ListByStatus returns the reviews in a status, oldest first
+	ListByStatus(ctx context.Context, status string) ([]models.LocationReview, error)
+	// ListByOrder returns the reviews of an order, oldest first
+	ListByOrder(ctx context.Context, orderID uint) ([]models.LocationReview, error)
+	Save(ctx context.Context, review *models.LocationReview) error
+}
+
+// DeliveryProofs stores the proofs of delivery of the DELIVERED updates
+type DeliveryProofs interface {
+	Create(ctx context.Context, proof *models.DeliveryProof) error
+	Get(ctx context.Context, id uint) (*models.DeliveryProof, error)
+	// ListByOrder returns the proofs of an order, oldest first
+	ListByOrder(ctx context.Context, orderID uint) ([]models.DeliveryProof, error)
+}
+
+// AuditEvents stores the audit trail of the orders (append only, their anchor is recorded apart)
+type AuditEvents interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	// RecordAnchor records the chain transaction the events were anchored in, and the contract deployment, apart from
+	// the events: they are returned with it by ListByOrder
+	RecordAnchor(ctx context.Context, ids []uint, transaction string, deploymentID *uint) error
+	// ListByOrder returns the events of an order with their anchor, oldest first
+	ListByOrder(ctx context.Context, orderID uint) ([]models.AuditEvent, error)
+}
+
+// Deployments stores the registry of the deployments of the tracking contract
+type Deployments interface {
+	Create(ctx context.Context, deployment *models.ContractDeployment) error
+	Get(ctx context.Context, id uint) (*models.ContractDeployment, error)
+	// GetByAddress returns the deployment of a contract on a chain
+	GetByAddress(ctx context.Context, chainID int64, address string) (*models.ContractDeployment, error)
+	// Active returns the active deployment of a chain, nil when the chain has none
+	Active(ctx context.Context, chainID int64) (*models.ContractDeployment, error)
+	// Legacy returns the deployment of the updates notarized before the registry, nil when there is none
+	Legacy(ctx context.Context) (*models.ContractDeployment, error)
+	// ListByIDs returns the deployments with the given ids, the unknown ids are left out
+	ListByIDs(ctx context.Context, ids []uint) ([]models.ContractDeployment, error)
+	// List returns the deployments, newest first
+	List(ctx context.Context) ([]models.ContractDeployment, error)
+	// Activate makes a deployment the active one of its chain (the previous one is deactivated)
+	Activate(ctx context.Context, id uint) (*models.ContractDeployment, error)
+}
+
+// Products reads the local copy of the catalogue (written by the catalogue sync)
+type Products interface {
+	Create(ctx context.Context, product *models.Product) error
+	// List returns a page of the products that are not disabled, with their variants, sorted by name, and the
+	// number of them. search (when set) matches the name and SKU, ignoring case
+	List(ctx context.Context, search string, offset, limit int) ([]models.Product, int64, error)
+	// ListByIDs returns the products with the given ids with their variants, the unknown ids are left out
+	ListByIDs(ctx context.Context, ids []uint) ([]models.Product, error)
+}
+
+// Store gives access to the repositories
+type Store interface {
+	Orders() Orders
+	History() History
+	Storages() Storages
+	OrderProducts() OrderProducts
+	Shipments() Shipments
+	LocationReviews() LocationReviews
+	DeliveryProofs() DeliveryProofs
+	AuditEvents() AuditEvents
+	Deployments() Deployments
+	Products() Products
+	// Transaction runs fn with a store whose changes are committed when fn returns nil and rolled back otherwise
+	Transaction(ctx context.Context, fn func(tx Store) error) error
+}
@@ -0,0 +1,12 @@
+package requestModels
+
+type AddOrderProductRequest struct {
+	OrderID   uint  `json:"order_id" binding:"required"`
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"` // Required when the product has variants
+	Quantity  uint  `json:"quantity" binding:"gt=0"`
+}
+
+type UpdateOrderProductRequest struct {
+	Quantity uint `json:"quantity" binding:"gt=0"`
+}
@@ -0,0 +1,17 @@
+package requestModels
+
+import "app/models"
+
+// Proof of delivery sent with a DELIVERED update
+type DeliveryProofRequest struct {
+	RecipientName string   `json:"recipient_name"`
+	Signature     string   `json:"signature"` // Base64 encoded image
+	Photo         string   `json:"photo"`     // Base64 encoded image
+	Latitude      *float64 `json:"latitude" binding:"omitempty,latitude"`
+	Longitude     *float64 `json:"longitude" binding:"omitempty,longitude"`
+}
+
+type AddOrderUpdateRequest struct {
+	models.OrderStatusHistory
+	ProofOfDelivery *DeliveryProofRequest `json:"proof_of_delivery"`
+}
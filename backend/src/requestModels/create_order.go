@@ -1,18 +1,28 @@
 package requestModels
 
 type OrderProductRequest struct {
-	ProductID uint `json:"product_id"`
-	Quantity  uint `json:"quantity"`
+	ProductID uint  `json:"product_id" binding:"required"`
+	VariantID *uint `json:"variant_id"` // Required when the product has variants
+	Quantity  uint  `json:"quantity" binding:"gt=0"`
 }
 
+// OrderLineError explains why a line of an order was rejected
+type OrderLineError struct {
+	Line      int    `json:"line"` // index of the line in the products array
+	ProductID uint   `json:"product_id"`
+	VariantID *uint  `json:"variant_id,omitempty"`
+	Error     string `json:"error"`
+}
+
+// The coordinates are optional (0), missing ones are geocoded from the address
 type AddOrderRequest struct {
-	CustomerId          uint                  `json:"customer_id"`
-	SellerId      		uint                	`json:"seller_id"`
-	SellerAddress		string					`json:"seller_address"` 
-	SellerLatitude		float64					`json:"seller_latitude"`
-	SellerLongitude		float64					`json:"seller_longitude"`
-	DeliveryAddress		string					`json:"delivery_address"`
-	DeliveryLatitude	float64					`json:"delivery_latitude"`	
-	DeliveryLongitude	float64					`json:"delivery_longitude"`
-	Products         []OrderProductRequest `json:"products"` // Nested array of products
+	CustomerId          uint                  `json:"customer_id" binding:"required"`
+	SellerId      		uint                	`json:"seller_id" binding:"required"`
+	SellerAddress		string					`json:"seller_address" binding:"required"`
+	SellerLatitude		float64					`json:"seller_latitude" binding:"latitude"`
+	SellerLongitude		float64					`json:"seller_longitude" binding:"longitude"`
+	DeliveryAddress		string					`json:"delivery_address" binding:"required"`
+	DeliveryLatitude	float64					`json:"delivery_latitude" binding:"latitude"`
+	DeliveryLongitude	float64					`json:"delivery_longitude" binding:"longitude"`
+	Products         []OrderProductRequest `json:"products" binding:"min=1,dive"` // Nested array of products
 }
\ No newline at end of file
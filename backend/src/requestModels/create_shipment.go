@@ -0,0 +1,7 @@
+package requestModels
+
+type CreateShipmentRequest struct {
+	OrderID         uint   `json:"order_id" binding:"required"`
+	StorageID       *uint  `json:"storage_id"`                           // Warehouse the parcel ships from
+	OrderProductIDs []uint `json:"order_product_ids" binding:"min=1"` // Order products included in the parcel
+}
@@ -0,0 +1,7 @@
+package requestModels
+
+// DeployContractRequest is the optional body of a deployment of the tracking contract
+type DeployContractRequest struct {
+	// Activate makes the service notarize the updates in the new contract
+	Activate bool `json:"activate"`
+}
@@ -79,6 +79,8 @@ type Product struct {
     PackageFormat       string          `json:"package_format"`
     Length              float64         `json:"length"`
     Width               float64         `json:"width"`
+    Height              float64         `json:"height"`
+    Variants            []Variant       `json:"variants"`
 }
 
 // ProductWrapper matches the outer array structure [{ "product": { ... } }]
@@ -0,0 +1,6 @@
+package requestModels
+
+type ResolveLocationReviewRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=APPROVED REJECTED"`
+	Note     string `json:"note"`
+}
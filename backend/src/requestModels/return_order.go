@@ -0,0 +1,20 @@
+package requestModels
+
+type ReturnOrderRequest struct {
+	OrderID uint   `json:"order_id" binding:"required"`
+	Reason  string `json:"reason"`
+}
+
+type CompleteReturnRequest struct {
+	OrderID       uint   `json:"order_id" binding:"required"`
+	OrderLocation string `json:"order_location"` // Optional, defaults to the seller address
+	Note          string `json:"note"`
+}
+
+type FailedDeliveryRequest struct {
+	OrderID        uint   `json:"order_id" binding:"required"`
+	ReasonCode     string `json:"reason_code" binding:"required"`
+	Note           string `json:"note"`
+	OrderLocation  string `json:"order_location"`
+	RedeliveryDate string `json:"redelivery_date" binding:"omitempty,datetime=2006-01-02"` // Optional, defaults to the next day
+}
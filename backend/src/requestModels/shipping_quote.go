@@ -0,0 +1,17 @@
+package requestModels
+
+type ShippingQuoteRequest struct {
+	Products          []OrderProductRequest `json:"products" binding:"min=1,dive"`
+	DeliveryLatitude  float64               `json:"delivery_latitude" binding:"required,latitude"`
+	DeliveryLongitude float64               `json:"delivery_longitude" binding:"required,longitude"`
+}
+
+// Shipping cost of a parcel (weights in kg)
+type ShippingQuoteResponse struct {
+	OrderID          uint    `json:"order_id,omitempty"`
+	Zone             string  `json:"zone"`
+	Weight           float64 `json:"weight"`
+	VolumetricWeight float64 `json:"volumetric_weight"`
+	ChargeableWeight float64 `json:"chargeable_weight"`
+	Price            float64 `json:"price"`
+}
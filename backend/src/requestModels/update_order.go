@@ -1,9 +1,10 @@
 package requestModels
 
+// The coordinates are optional (0), missing ones are geocoded from the address
 type UpdateOrderRequest struct {
-	OrderID				uint					`json:"order_id"`
-	DeliveryAddress		string					`json:"delivery_address"`
-	DeliveryLatitude	float64					`json:"delivery_latitude"`	
-	DeliveryLongitude	float64					`json:"delivery_longitude"`
+	OrderID				uint					`json:"order_id" binding:"required"`
+	DeliveryAddress		string					`json:"delivery_address" binding:"required"`
+	DeliveryLatitude	float64					`json:"delivery_latitude" binding:"latitude"`
+	DeliveryLongitude	float64					`json:"delivery_longitude" binding:"longitude"`
 }
 
@@ -0,0 +1,24 @@
+package requestModels
+
+import (
+	"app/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// The requests are validated by gin when they are bound, with the rules of their binding tags. Besides the rules of
+// the validator, the models can use:
+//   - order_status: one of the order states (models.OrderStatuses)
+func init() {
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		RegisterValidations(validate)
+	}
+}
+
+// RegisterValidations registers the custom rules of the request models on a validator
+func RegisterValidations(validate *validator.Validate) {
+	validate.RegisterValidation("order_status", func(field validator.FieldLevel) bool {
+		return models.IsOrderStatus(field.Field().String())
+	})
+}
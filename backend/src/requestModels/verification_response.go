@@ -9,5 +9,42 @@ type VerificationResponse struct {
 	Message             string   `json:"message"`
 	Mismatches          []string `json:"mismatches,omitempty"`
 	TransactionHashes   []string `json:"transaction_hashes,omitempty"`
-	ContractAddress     string   `json:"contract_address,omitempty"`
+	ContractAddress     string   `json:"contract_address,omitempty"` // the contract of the latest update
+	Contracts           []ContractVerification `json:"contracts,omitempty"`
+	Shipments           []ShipmentVerification `json:"shipments,omitempty"`
+	DeliveryProofs      []DeliveryProofVerification `json:"delivery_proofs,omitempty"`
+	ExcludedUpdates     int      `json:"excluded_updates,omitempty"` // updates whose location was rejected on review (their hashes are not counted)
+}
+
+// Verification result of the updates notarized in a contract (each update is verified against the contract it was
+// notarized in)
+type ContractVerification struct {
+	ChainID          int64  `json:"chain_id"`
+	ContractAddress  string `json:"contract_address"`
+	DeploymentID     uint   `json:"deployment_id,omitempty"` // 0 when the contract is not in the registry
+	TotalUpdates     int    `json:"total_updates"`
+	VerifiedUpdates  int    `json:"verified_updates"`
+	BlockchainHashes int    `json:"blockchain_hashes"`
+}
+
+// Verification result of the updates of a single shipment
+type ShipmentVerification struct {
+	ShipmentID      uint     `json:"shipment_id"`
+	Verified        bool     `json:"verified"`
+	TotalUpdates    int      `json:"total_updates"`
+	VerifiedUpdates int      `json:"verified_updates"`
+	Mismatches      []string `json:"mismatches,omitempty"`
+}
+
+// Verification result of a proof of delivery
+type DeliveryProofVerification struct {
+	UpdateID      uint     `json:"update_id"`
+	Verified      bool     `json:"verified"` // the proof matches the hash notarized in the blockchain
+	ProofHash     string   `json:"proof_hash"`
+	RecipientName string   `json:"recipient_name,omitempty"`
+	HasSignature  bool     `json:"has_signature"`
+	HasPhoto      bool     `json:"has_photo"`
+	Latitude      *float64 `json:"latitude,omitempty"`
+	Longitude     *float64 `json:"longitude,omitempty"`
+	Message       string   `json:"message"`
 }
\ No newline at end of file
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"app/apierror"
+	"app/auth"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authenticate authenticates the caller of the request by its bearer token. A request without a token goes on
+// anonymous (the routes that need a caller require a role), a token that is not valid is rejected
+func authenticate(verifier *auth.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := auth.BearerToken(c.GetHeader("Authorization"))
+		if errors.Is(err, auth.ErrNoToken) {
+			c.Next()
+			return
+		}
+		if err == nil {
+			var identity auth.Identity
+			if identity, err = verifier.Verify(token); err == nil {
+				auth.SetIdentity(c, identity)
+				c.Next()
+				return
+			}
+		}
+		slog.InfoContext(c, "Rejected a bearer token", "route", c.FullPath(), "error", err)
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		apierror.Respond(c, http.StatusUnauthorized, "Invalid bearer token")
+	}
+}
+
+// requireRole lets through the authenticated callers with the role
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := auth.FromContext(c)
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			apierror.Respond(c, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if !identity.HasRole(role) {
+			apierror.Respond(c, http.StatusForbidden, "The "+role+" role is required")
+			return
+		}
+		c.Next()
+	}
+}
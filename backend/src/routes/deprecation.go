@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// V1Deprecated is when the v1 routes were deprecated in favour of /api/v2
+var V1Deprecated = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// deprecated marks a v1 route as deprecated (Deprecation header, RFC 9745) and links the v2 route that replaces it.
+// The parameters of the successor (:name) are filled in from the request, a successor whose parameters are not in the
+// path of the v1 route (e.g. the order of a POST body) is not linked, only the documentation is
+func deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", fmt.Sprintf("@%d", V1Deprecated.Unix()))
+		links := []string{`</api/docs>; rel="deprecation"`}
+		if path, ok := fillParams(c, successor); ok {
+			links = append(links, fmt.Sprintf(`<%s>; rel="successor-version"`, path))
+		}
+		c.Header("Link", strings.Join(links, ", "))
+		c.Next()
+	}
+}
+
+// fillParams replaces the parameters of a gin path with the values of the request, it reports false when the request
+// does not have them all
+func fillParams(c *gin.Context, path string) (string, bool) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		value := c.Param(segment[1:])
+		if value == "" {
+			return "", false
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/"), true
+}
+
+// orderParam makes the order of the v2 order routes (/orders/:id/...) available as order_id, the parameter the
+// handlers shared with v1 read it from
+func orderParam(c *gin.Context) {
+	c.Params = append(c.Params, gin.Param{Key: "order_id", Value: c.Param("id")})
+	c.Next()
+}
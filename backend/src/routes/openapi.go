@@ -0,0 +1,407 @@
+package routes
+
+import (
+	"app/auth"
+	"app/config"
+	"app/health"
+	"app/models"
+	"app/openapi"
+	"app/requestModels"
+	"app/supervisor"
+	"net/http"
+)
+
+var apiInfo = openapi.Info{
+	Title:       "Order Tracking API",
+	Version:     "1.0.0",
+	Description: "Tracking of the orders of MadeinPortugal.store, with their status updates notarized in the blockchain.",
+}
+
+// message is the body of the operations that only confirm the change
+var message = openapi.Fields{"message": ""}
+
+// operations documents the routes registered in RegisterRoutes (the v1 routes are deprecated). A route that is not
+// listed here is left out of the OpenAPI document (and fails TestOpenAPI_DocumentsEveryRoute)
+var operations = openapi.Operations{
+	// order history
+	"GET /api/order/history/:order_id": {
+		Summary: "List the status updates of an order, newest first", Tag: "history", Deprecated: true,
+		Response: openapi.Fields{"order_status_history": []models.OrderStatusHistory{}},
+	},
+	"POST /api/order/history/add": {
+		Summary: "Add a status update to an order (DELIVERED updates can carry a proof of delivery)", Tag: "history", Deprecated: true,
+		Request: requestModels.AddOrderUpdateRequest{}, Response: message,
+	},
+
+	// orders
+	"GET /api/orders": {
+		Summary: "List the orders with their products and updates", Tag: "orders", Deprecated: true,
+		Query:    []openapi.Parameter{{Name: "order_by", Description: "oldest to list the oldest orders first"}},
+		Response: openapi.Fields{"orders": []models.Orders{}},
+	},
+	"GET /api/order/:id": {
+		Summary: "Get an order", Tag: "orders", Deprecated: true,
+		Response: openapi.Fields{"order": models.Orders{}},
+	},
+	"GET /api/order/verify/:order_id": {
+		Summary: "Verify the updates of an order against the hashes notarized in the blockchain", Tag: "orders", Deprecated: true,
+		Response: requestModels.VerificationResponse{},
+	},
+	"POST /api/order/add": {
+		Summary: "Create an order", Tag: "orders", Deprecated: true,
+		Request:  requestModels.AddOrderRequest{},
+		Response: openapi.Fields{"message": "", "order": models.Orders{}, "tracking_code": ""},
+	},
+	"POST /api/order/update": {
+		Summary: "Change the delivery address of an order", Tag: "orders", Deprecated: true,
+		Request: requestModels.UpdateOrderRequest{}, Response: message,
+	},
+	"POST /api/order/cancel": {
+		Summary: "Cancel an order that was not shipped yet", Tag: "orders", Deprecated: true,
+		Request:  requestModels.CancelOrderRequest{},
+		Response: openapi.Fields{"message": "", "order_id": uint(0), "status": ""},
+	},
+
+	// proofs of delivery
+	"GET /api/order/proof/:order_id": {
+		Summary: "List the proofs of delivery of an order", Tag: "proofs", Deprecated: true,
+		Response: openapi.Fields{"delivery_proofs": []models.DeliveryProof{}},
+	},
+	"GET /api/proof/:id/:media": {
+		Summary: "Download the signature or the photo of a proof of delivery (media is signature or photo)",
+		Tag:     "proofs", Deprecated: true,
+		ContentType: "application/octet-stream",
+	},
+
+	// shipments
+	"GET /api/order/shipments/:order_id": {
+		Summary: "List the shipments of an order", Tag: "shipments", Deprecated: true,
+		Response: openapi.Fields{"shipments": []models.Shipment{}, "order_status": ""},
+	},
+	"POST /api/order/shipments/add": {
+		Summary: "Ship some products of an order in a parcel", Tag: "shipments", Deprecated: true,
+		Request: requestModels.CreateShipmentRequest{}, Status: http.StatusCreated,
+		Response: openapi.Fields{"shipment": models.Shipment{}},
+	},
+	"GET /api/shipment/:tracking_code": {
+		Summary: "Get a shipment by its tracking code", Tag: "shipments", Deprecated: true,
+		Response: openapi.Fields{"shipment": models.Shipment{}},
+	},
+
+	// shipping costs
+	"POST /api/shipping/quote": {
+		Summary: "Quote the shipping of some products", Tag: "shipping", Deprecated: true,
+		Request: requestModels.ShippingQuoteRequest{}, Response: openapi.Fields{"quote": requestModels.ShippingQuoteResponse{}},
+	},
+	"GET /api/order/shipping/:order_id": {
+		Summary: "Get the shipping cost of an order", Tag: "shipping", Deprecated: true,
+		Response: openapi.Fields{"quote": requestModels.ShippingQuoteResponse{}},
+	},
+
+	// location reviews
+	"GET /api/reviews/locations": {
+		Summary: "List the updates flagged by the geofence check", Tag: "reviews", Deprecated: true,
+		Query: []openapi.Parameter{{
+			Name: "status", Description: "PENDING (default), APPROVED or REJECTED",
+		}},
+		Response: openapi.Fields{"location_reviews": []models.LocationReview{}},
+	},
+	"GET /api/order/reviews/:order_id": {
+		Summary: "List the location reviews of an order", Tag: "reviews", Deprecated: true,
+		Response: openapi.Fields{"location_reviews": []models.LocationReview{}},
+	},
+	"POST /api/reviews/locations/:id/resolve": {
+		Summary: "Approve or reject a flagged update", Tag: "reviews", Deprecated: true,
+		Role:    auth.RoleAdmin,
+		Request: requestModels.ResolveLocationReviewRequest{}, Response: openapi.Fields{"location_review": models.LocationReview{}},
+	},
+
+	// returns and failed deliveries
+	"POST /api/order/return": {
+		Summary: "Request the return of a delivered order", Tag: "returns", Deprecated: true,
+		Request:  requestModels.ReturnOrderRequest{},
+		Response: openapi.Fields{"message": "", "order_id": uint(0), "status": ""},
+	},
+	"POST /api/order/failed-delivery": {
+		Summary: "Record a failed delivery attempt and schedule a new one", Tag: "returns", Deprecated: true,
+		Request: requestModels.FailedDeliveryRequest{},
+		Response: openapi.Fields{
+			"message": "", "order_id": uint(0), "attempt": 0, "max_attempts": 0, "status": "", "redelivery_date": "",
+		},
+	},
+
+	// order products
+	"GET /api/order-products": {
+		Summary: "List the products of an order", Tag: "order products", Deprecated: true,
+		Query:    []openapi.Parameter{{Name: "order_id", Type: "integer", Required: true}},
+		Response: openapi.Fields{"order_products": []models.OrderProduct{}},
+	},
+	"POST /api/order-products": {
+		Summary: "Add a product to a processing order", Tag: "order products", Deprecated: true,
+		Request: requestModels.AddOrderProductRequest{}, Status: http.StatusCreated,
+		Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"GET /api/order-products/:id": {
+		Summary: "Get a product of an order", Tag: "order products", Deprecated: true,
+		Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"PUT /api/order-products/:id": {
+		Summary: "Change the quantity of a product of a processing order", Tag: "order products", Deprecated: true,
+		Request: requestModels.UpdateOrderProductRequest{}, Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"DELETE /api/order-products/:id": {
+		Summary: "Remove a product from a processing order", Tag: "order products", Deprecated: true,
+		Response: message,
+	},
+	"GET /api/order/changes/:order_id": {
+		Summary: "List the changes made to the products of an order", Tag: "order products", Deprecated: true,
+		Response: openapi.Fields{"changes": []models.OrderChange{}},
+	},
+
+	// audit trail
+	"GET /api/order/audit/:order_id": {
+		Summary: "List the audit trail of an order and check it was not tampered with", Tag: "audit", Deprecated: true,
+		Response: openapi.Fields{"events": []models.AuditEvent{}, "intact": false, "tampered": []uint{}},
+	},
+
+	// products
+	"GET /api/products": {
+		Summary: "List the products of the catalogue", Tag: "products", Deprecated: true,
+		Query: []openapi.Parameter{
+			{Name: "page", Type: "integer", Description: "1 by default"},
+			{Name: "limit", Type: "integer", Description: "Products per page, 20 by default"},
+			{Name: "q", Description: "Search by name or SKU"},
+		},
+		Response: openapi.Fields{"products": []models.Product{}, "page": 0, "limit": 0, "total": int64(0)},
+	},
+	"GET /api/products/:id": {
+		Summary: "Get a product of the catalogue", Tag: "products", Deprecated: true,
+		Response: openapi.Fields{"product": models.Product{}},
+	},
+
+	// storages
+	"GET /api/storages": {
+		Summary: "List the storages", Tag: "storages", Deprecated: true,
+		Response: openapi.Fields{"storages": []models.Storage{}},
+	},
+
+	// blockchain (admin)
+	"GET /api/blockchain/status": {
+		Summary: "Get the status of the blockchain connection", Tag: "blockchain", Deprecated: true,
+		Role:     auth.RoleAdmin,
+		Response: requestModels.BlockchainStatusResponse{},
+	},
+
+	// v2 orders
+	"GET /api/v2/orders": {
+		Summary: "List the orders with their products and updates", Tag: "orders",
+		Query:    []openapi.Parameter{{Name: "order_by", Description: "oldest to list the oldest orders first"}},
+		Response: openapi.Fields{"orders": []models.Orders{}},
+	},
+	"POST /api/v2/orders": {
+		Summary: "Create an order", Tag: "orders",
+		Request: requestModels.AddOrderRequest{}, Status: http.StatusCreated,
+		Response: openapi.Fields{"order": models.Orders{}, "tracking_code": ""},
+	},
+	"GET /api/v2/orders/:id": {
+		Summary: "Get an order", Tag: "orders",
+		Response: openapi.Fields{"order": models.Orders{}},
+	},
+	"PATCH /api/v2/orders/:id": {
+		Summary: "Change the delivery address of an order that was not shipped yet", Tag: "orders",
+		Request: requestModels.UpdateOrderRequest{}, PathFields: []string{"order_id"},
+		Response: openapi.Fields{"order": models.Orders{}},
+	},
+	"POST /api/v2/orders/:id/cancellation": {
+		Summary: "Cancel an order that was not shipped yet", Tag: "orders",
+		Request: requestModels.CancelOrderRequest{}, PathFields: []string{"order_id"},
+		Response: openapi.Fields{"message": "", "order_id": uint(0), "status": ""},
+	},
+	"GET /api/v2/orders/:id/verification": {
+		Summary: "Verify the updates of an order against the hashes notarized in the blockchain", Tag: "orders",
+		Response: requestModels.VerificationResponse{},
+	},
+	"GET /api/v2/orders/:id/events": {
+		Summary: "List the status updates of an order, newest first", Tag: "history",
+		Response: openapi.Fields{"order_status_history": []models.OrderStatusHistory{}},
+	},
+	"POST /api/v2/orders/:id/events": {
+		Summary: "Add a status update to an order (202 with its location review when flagged by the geofence check)",
+		Tag:     "history",
+		Request: requestModels.AddOrderUpdateRequest{}, PathFields: []string{"Order_ID"}, Status: http.StatusCreated,
+		Response: openapi.Fields{"event": models.OrderStatusHistory{}},
+	},
+	"GET /api/v2/orders/:id/items": {
+		Summary: "List the products of an order", Tag: "order products",
+		Response: openapi.Fields{"order_products": []models.OrderProduct{}},
+	},
+	"POST /api/v2/orders/:id/items": {
+		Summary: "Add a product to a processing order", Tag: "order products",
+		Request: requestModels.AddOrderProductRequest{}, PathFields: []string{"order_id"}, Status: http.StatusCreated,
+		Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"GET /api/v2/orders/:id/items/:item_id": {
+		Summary: "Get a product of an order", Tag: "order products",
+		Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"PATCH /api/v2/orders/:id/items/:item_id": {
+		Summary: "Change the quantity of a product of a processing order", Tag: "order products",
+		Request: requestModels.UpdateOrderProductRequest{}, Response: openapi.Fields{"order_product": models.OrderProduct{}},
+	},
+	"DELETE /api/v2/orders/:id/items/:item_id": {
+		Summary: "Remove a product from a processing order", Tag: "order products",
+		Status: http.StatusNoContent,
+	},
+	"GET /api/v2/orders/:id/changes": {
+		Summary: "List the changes made to the products of an order", Tag: "order products",
+		Response: openapi.Fields{"changes": []models.OrderChange{}},
+	},
+	"GET /api/v2/orders/:id/audit": {
+		Summary: "List the audit trail of an order and check it was not tampered with", Tag: "audit",
+		Response: openapi.Fields{"events": []models.AuditEvent{}, "intact": false, "tampered": []uint{}},
+	},
+	"GET /api/v2/orders/:id/proofs": {
+		Summary: "List the proofs of delivery of an order", Tag: "proofs",
+		Response: openapi.Fields{"delivery_proofs": []models.DeliveryProof{}},
+	},
+	"GET /api/v2/orders/:id/shipments": {
+		Summary: "List the shipments of an order", Tag: "shipments",
+		Response: openapi.Fields{"shipments": []models.Shipment{}, "order_status": ""},
+	},
+	"POST /api/v2/orders/:id/shipments": {
+		Summary: "Ship some products of an order in a parcel", Tag: "shipments",
+		Request: requestModels.CreateShipmentRequest{}, PathFields: []string{"order_id"}, Status: http.StatusCreated,
+		Response: openapi.Fields{"shipment": models.Shipment{}},
+	},
+	"GET /api/v2/orders/:id/shipping": {
+		Summary: "Get the shipping cost of an order", Tag: "shipping",
+		Response: openapi.Fields{"quote": requestModels.ShippingQuoteResponse{}},
+	},
+	"GET /api/v2/orders/:id/location-reviews": {
+		Summary: "List the location reviews of an order", Tag: "reviews",
+		Response: openapi.Fields{"location_reviews": []models.LocationReview{}},
+	},
+	"POST /api/v2/orders/:id/return": {
+		Summary: "Request the return of a delivered order", Tag: "returns",
+		Request: requestModels.ReturnOrderRequest{}, PathFields: []string{"order_id"},
+		Response: openapi.Fields{"message": "", "order_id": uint(0), "status": ""},
+	},
+	"POST /api/v2/orders/:id/return/completion": {
+		Summary: "Register that a requested return is back with the seller", Tag: "returns",
+		Request: requestModels.CompleteReturnRequest{}, PathFields: []string{"order_id"},
+		Response: openapi.Fields{"message": "", "order_id": uint(0), "status": ""},
+	},
+	"POST /api/v2/orders/:id/failed-deliveries": {
+		Summary: "Record a failed delivery attempt and schedule a new one", Tag: "returns",
+		Request: requestModels.FailedDeliveryRequest{}, PathFields: []string{"order_id"},
+		Response: openapi.Fields{
+			"message": "", "order_id": uint(0), "attempt": 0, "max_attempts": 0, "status": "", "redelivery_date": "",
+		},
+	},
+
+	// v2 resources that are not under an order
+	"GET /api/v2/proofs/:id/:media": {
+		Summary: "Download the signature or the photo of a proof of delivery (media is signature or photo)", Tag: "proofs",
+		ContentType: "application/octet-stream",
+	},
+	"GET /api/v2/shipments/:tracking_code": {
+		Summary: "Get a shipment by its tracking code", Tag: "shipments",
+		Response: openapi.Fields{"shipment": models.Shipment{}},
+	},
+	"POST /api/v2/shipping/quotes": {
+		Summary: "Quote the shipping of some products", Tag: "shipping",
+		Request: requestModels.ShippingQuoteRequest{}, Response: openapi.Fields{"quote": requestModels.ShippingQuoteResponse{}},
+	},
+	"GET /api/v2/location-reviews": {
+		Summary: "List the updates flagged by the geofence check", Tag: "reviews",
+		Query: []openapi.Parameter{{
+			Name: "status", Description: "PENDING (default), APPROVED or REJECTED",
+		}},
+		Response: openapi.Fields{"location_reviews": []models.LocationReview{}},
+	},
+	"POST /api/v2/location-reviews/:id/resolution": {
+		Summary: "Approve or reject a flagged update", Tag: "reviews",
+		Role:    auth.RoleAdmin,
+		Request: requestModels.ResolveLocationReviewRequest{}, Response: openapi.Fields{"location_review": models.LocationReview{}},
+	},
+	"GET /api/v2/products": {
+		Summary: "List the products of the catalogue", Tag: "products",
+		Query: []openapi.Parameter{
+			{Name: "page", Type: "integer", Description: "1 by default"},
+			{Name: "limit", Type: "integer", Description: "Products per page, 20 by default"},
+			{Name: "q", Description: "Search by name or SKU"},
+		},
+		Response: openapi.Fields{"products": []models.Product{}, "page": 0, "limit": 0, "total": int64(0)},
+	},
+	"GET /api/v2/products/:id": {
+		Summary: "Get a product of the catalogue", Tag: "products",
+		Response: openapi.Fields{"product": models.Product{}},
+	},
+	"GET /api/v2/storages": {
+		Summary: "List the storages", Tag: "storages",
+		Response: openapi.Fields{"storages": []models.Storage{}},
+	},
+	"GET /api/v2/blockchain/status": {
+		Summary: "Get the status of the blockchain connection", Tag: "blockchain",
+		Role:     auth.RoleAdmin,
+		Response: requestModels.BlockchainStatusResponse{},
+	},
+	"GET /api/v2/blockchain/deployments": {
+		Summary: "List the registry of the deployments of the tracking contract, newest first", Tag: "blockchain",
+		Role:     auth.RoleAdmin,
+		Response: openapi.Fields{"deployments": []models.ContractDeployment{}},
+	},
+	"POST /api/v2/blockchain/deployments": {
+		Summary: "Deploy the tracking contract and record it in the registry, activated on request", Tag: "blockchain", Status: http.StatusCreated,
+		Role:     auth.RoleAdmin,
+		Request:  requestModels.DeployContractRequest{},
+		Response: openapi.Fields{"message": "", "address": "", "deployment": models.ContractDeployment{}},
+	},
+	"POST /api/v2/blockchain/deployments/:deployment_id/activate": {
+		Summary: "Activate a deployment, the updates of its chain are notarized in it", Tag: "blockchain", Status: http.StatusOK,
+		Role:     auth.RoleAdmin,
+		Response: openapi.Fields{"message": "", "deployment": models.ContractDeployment{}},
+	},
+
+	// admin
+	"GET /api/v2/admin/config": {
+		Summary: "Get the config the service was started with, the secrets redacted", Tag: "admin",
+		Role:     auth.RoleAdmin,
+		Response: openapi.Fields{"config": config.Config{}},
+	},
+	"GET /api/v2/admin/workers": {
+		Summary: "Get the state of the workers (servers, listeners, syncer)", Tag: "admin",
+		Role:     auth.RoleAdmin,
+		Response: openapi.Fields{"workers": []supervisor.Status{}},
+	},
+
+	// documentation
+	"GET /api/openapi.json": {
+		Summary: "Get this OpenAPI document", Tag: "documentation",
+		Response: openapi.Fields{},
+	},
+	"GET /api/docs": {
+		Summary: "Browse the API documentation", Tag: "documentation",
+		ContentType: "text/html",
+	},
+
+	// health
+	"GET /healthz": {
+		Summary: "Liveness probe, the process is up", Tag: "health",
+		Response: openapi.Fields{"status": ""},
+	},
+	"GET /readyz": {
+		Summary: "Readiness probe, checks the database, the broker and the ledger (503 when a required one is down)", Tag: "health",
+		Response: health.Report{},
+	},
+	"GET /metrics": {
+		Summary: "Prometheus metrics", Tag: "health",
+		ContentType: "text/plain",
+	},
+	"GET /ping": {
+		Summary: "Check the service is up", Tag: "health", Response: message,
+	},
+	"GET /": {
+		Summary: "Check the service is up", Tag: "health", Response: message,
+	},
+}
@@ -1,53 +1,198 @@
 package routes
 
 import (
+	"app/apierror"
+	"app/audit"
+	"app/auth"
+	"app/blobstore"
 	"app/blockchain"
+	"app/catalog"
+	"app/config"
+	"app/geocoding"
 	"app/handlers"
+	"app/health"
+	"app/metrics"
+	"app/openapi"
+	"app/repository"
+	"app/shipping"
+	"app/supervisor"
+	"app/telemetry"
+	"context"
+	"log/slog"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func RegisterRoutes(router *gin.Engine, db *gorm.DB, blockChainClient *blockchain.Client) {
-	orderHandler := handlers.OrderHandler{DB: db, Client: blockChainClient}
-	orderStatusHistory := handlers.OrderStatusHistoryHandler{DB: db, Client: blockChainClient}
-	storageHandler := handlers.StorageHandler{DB: db}
-	orderProductHandler := handlers.OrderProductHandler{DB: db}
-	productHandler := handlers.ProductHandler{DB: db}
-	blockchainHandler := handlers.BlockchainHandler{}
-	verificationHandler := handlers.VerificationHandler{DB: db, Client: blockChainClient}
+// RegisterRoutes registers the API routes with the components of the config. The notifier is optional (nil when
+// Pub/Sub is not configured), as is the supervisor (nil when the workers are not supervised, the background tasks
+// are then not drained)
+func RegisterRoutes(router *gin.Engine, db *gorm.DB, blockChainClient *blockchain.Client, notifier handlers.Notifier, cfg *config.Config, sup *supervisor.Supervisor) {
+	// every route is traced and its latency measured (before the routes are registered, the groups copy the
+	// middlewares). The handlers pass the gin context on, it falls back to the request context that has the trace
+	router.ContextWithFallback = true
+	router.Use(telemetry.Middleware(), metrics.Middleware())
 
-	apiRoutes := router.Group("/api")
+	productCatalog := catalog.Default()
+	store := repository.NewPostgres(db)
+	var tasks *supervisor.Tasks
+	workers, shutdown := health.Check{Name: "workers"}, health.Check{Name: "shutdown"}
+	if sup != nil {
+		tasks = sup.Tasks
+		workers, shutdown = sup.Check(), sup.DrainCheck()
+	}
+	auditLogger := audit.NewLogger(blockChainClient, cfg.Audit.ChainAnchoring, tasks)
 
+	orderHandler := handlers.OrderHandler{Store: store, Client: blockChainClient, Geocoder: geocoding.Default(), Catalog: productCatalog, Audit: auditLogger, Tasks: tasks}
+	blobStore := blobstore.NewFileSystemStore(cfg.Storage.BlobPath)
+
+	orderStatusHistory := handlers.OrderStatusHistoryHandler{Store: store, Client: blockChainClient, Blobs: blobStore}
+	storageHandler := handlers.StorageHandler{Store: store}
+	orderProductHandler := handlers.OrderProductHandler{Store: store, Catalog: productCatalog, Notifier: notifier, Audit: auditLogger}
+	productHandler := handlers.ProductHandler{Store: store, Catalog: productCatalog}
+	blockchainHandler := handlers.BlockchainHandler{Config: cfg.Blockchain, Store: store, Client: blockChainClient}
+	configHandler := handlers.ConfigHandler{Config: cfg}
+	workerHandler := handlers.WorkerHandler{Supervisor: sup}
+	verificationHandler := handlers.VerificationHandler{Store: store, Client: blockChainClient, Blobs: blobStore}
+	deliveryProofHandler := handlers.DeliveryProofHandler{Store: store, Blobs: blobStore}
+	returnHandler := handlers.ReturnHandler{Store: store, Client: blockChainClient, Audit: auditLogger}
+	shipmentHandler := handlers.ShipmentHandler{Store: store, Client: blockChainClient, Audit: auditLogger}
+	locationReviewHandler := handlers.LocationReviewHandler{Store: store, Client: blockChainClient}
+	auditHandler := handlers.AuditHandler{Store: store}
+	shippingHandler := handlers.ShippingHandler{Store: store, Catalog: productCatalog, Rates: shipping.RatesFromFile(cfg.Shipping.RatesFile)}
+
+	// every API request gets a request ID (X-Request-ID) and an actor for the audit trail. The callers are
+	// authenticated by their bearer token, the admin routes require the admin role
+	verifier := auth.NewVerifier(cfg.Auth)
+	if verifier == nil {
+		slog.Warn("The authentication is not configured (AUTH_TOKEN_SECRET is not set), the admin routes are closed")
+	}
+	apiRoutes := router.Group("/api", audit.Middleware(audit.SourceHTTP), authenticate(verifier))
+	adminRoutes := router.Group("/api", audit.Middleware(audit.SourceAdmin), authenticate(verifier), requireRole(auth.RoleAdmin))
+
+	// v1 routes, kept for the current clients. They are deprecated: their responses link the v2 route replacing them
 	//routes for the order history
-	apiRoutes.GET("/order/history/:order_id", orderStatusHistory.GetOrderStatusByOrderID)
-	apiRoutes.POST("/order/history/add", orderStatusHistory.AddOrderUpdate)
+	apiRoutes.GET("/order/history/:order_id", deprecated("/api/v2/orders/:order_id/events"), orderStatusHistory.GetOrderStatusByOrderID)
+	apiRoutes.POST("/order/history/add", deprecated("/api/v2/orders/:order_id/events"), orderStatusHistory.AddOrderUpdate)
 
 	//routes for the orders
-	apiRoutes.GET("/orders", orderHandler.GetAllOrders)
-	apiRoutes.GET("/order/:id", orderHandler.GetOrderByID)
-	apiRoutes.GET("/order/verify/:order_id", verificationHandler.VerifyOrder)
-	apiRoutes.POST("/order/add", orderHandler.AddOrder)
-	apiRoutes.POST("/order/update", orderHandler.UpdateOrder)
-	apiRoutes.POST("/order/cancel", orderHandler.CancelOrder)
+	apiRoutes.GET("/orders", deprecated("/api/v2/orders"), orderHandler.GetAllOrders)
+	apiRoutes.GET("/order/:id", deprecated("/api/v2/orders/:id"), orderHandler.GetOrderByID)
+	apiRoutes.GET("/order/verify/:order_id", deprecated("/api/v2/orders/:order_id/verification"), verificationHandler.VerifyOrder)
+	apiRoutes.POST("/order/add", deprecated("/api/v2/orders"), orderHandler.AddOrder)
+	apiRoutes.POST("/order/update", deprecated("/api/v2/orders/:order_id"), orderHandler.UpdateOrder)
+	apiRoutes.POST("/order/cancel", deprecated("/api/v2/orders/:order_id/cancellation"), orderHandler.CancelOrder)
+
+	//routes for the proofs of delivery
+	apiRoutes.GET("/order/proof/:order_id", deprecated("/api/v2/orders/:order_id/proofs"), deliveryProofHandler.GetDeliveryProofs)
+	apiRoutes.GET("/proof/:id/:media", deprecated("/api/v2/proofs/:id/:media"), deliveryProofHandler.GetDeliveryProofMedia)
+
+	//routes for the shipments (parcels of an order)
+	apiRoutes.GET("/order/shipments/:order_id", deprecated("/api/v2/orders/:order_id/shipments"), shipmentHandler.GetShipmentsByOrderID)
+	apiRoutes.POST("/order/shipments/add", deprecated("/api/v2/orders/:order_id/shipments"), shipmentHandler.CreateShipment)
+	apiRoutes.GET("/shipment/:tracking_code", deprecated("/api/v2/shipments/:tracking_code"), shipmentHandler.GetShipmentByTrackingCode)
+
+	//routes for the shipping costs
+	apiRoutes.POST("/shipping/quote", deprecated("/api/v2/shipping/quotes"), shippingHandler.QuoteShipping)
+	apiRoutes.GET("/order/shipping/:order_id", deprecated("/api/v2/orders/:order_id/shipping"), shippingHandler.GetOrderShipping)
+
+	//routes for the updates flagged by the geofence check
+	apiRoutes.GET("/reviews/locations", deprecated("/api/v2/location-reviews"), locationReviewHandler.GetLocationReviews) // Query param: ?status=PENDING|APPROVED|REJECTED
+	apiRoutes.GET("/order/reviews/:order_id", deprecated("/api/v2/orders/:order_id/location-reviews"), locationReviewHandler.GetLocationReviewsByOrderID)
+	adminRoutes.POST("/reviews/locations/:id/resolve", deprecated("/api/v2/location-reviews/:id/resolution"), locationReviewHandler.ResolveLocationReview)
+
+	//routes for returns and failed deliveries
+	apiRoutes.POST("/order/return", deprecated("/api/v2/orders/:order_id/return"), returnHandler.RequestReturn)
+	apiRoutes.POST("/order/failed-delivery", deprecated("/api/v2/orders/:order_id/failed-deliveries"), returnHandler.RecordFailedDelivery)
 
 	//routes for order products (using order-products path to avoid conflicts)
-	apiRoutes.GET("/order-products", orderProductHandler.GetOrderProducts) // Query param: ?order_id=X
-	apiRoutes.POST("/order-products", orderProductHandler.AddOrderProduct)
-	apiRoutes.GET("/order-products/:id", orderProductHandler.GetOrderProductByID)
-	apiRoutes.PUT("/order-products/:id", orderProductHandler.UpdateOrderProduct)
-	apiRoutes.DELETE("/order-products/:id", orderProductHandler.DeleteOrderProduct)
+	apiRoutes.GET("/order-products", deprecated("/api/v2/orders/:order_id/items"), orderProductHandler.GetOrderProducts) // Query param: ?order_id=X
+	apiRoutes.POST("/order-products", deprecated("/api/v2/orders/:order_id/items"), orderProductHandler.AddOrderProduct)
+	apiRoutes.GET("/order-products/:id", deprecated("/api/v2/orders/:order_id/items/:id"), orderProductHandler.GetOrderProductByID)
+	apiRoutes.PUT("/order-products/:id", deprecated("/api/v2/orders/:order_id/items/:id"), orderProductHandler.UpdateOrderProduct)
+	apiRoutes.DELETE("/order-products/:id", deprecated("/api/v2/orders/:order_id/items/:id"), orderProductHandler.DeleteOrderProduct)
+	apiRoutes.GET("/order/changes/:order_id", deprecated("/api/v2/orders/:order_id/changes"), orderProductHandler.GetOrderChanges)
+
+	//routes for the audit trail
+	apiRoutes.GET("/order/audit/:order_id", deprecated("/api/v2/orders/:order_id/audit"), auditHandler.GetOrderAuditEvents)
 
 	//routes for products
-	apiRoutes.GET("/products", productHandler.GetAllProducts)
-	apiRoutes.GET("/products/:id", productHandler.GetProductByID)
+	apiRoutes.GET("/products", deprecated("/api/v2/products"), productHandler.GetAllProducts)
+	apiRoutes.GET("/products/:id", deprecated("/api/v2/products/:id"), productHandler.GetProductByID)
 
 	//routes for the storages
-	apiRoutes.GET("/storages", storageHandler.GetAllStorages)
+	apiRoutes.GET("/storages", deprecated("/api/v2/storages"), storageHandler.GetAllStorages)
 
-	// Blockchain endpoints (should not be public in the production)
-	apiRoutes.GET("/blockchain/status", blockchainHandler.GetBlockchainStatus)
-	apiRoutes.GET("/blockchain/deploy", blockchainHandler.DeployContract)
+	// Blockchain endpoints (admin)
+	adminRoutes.GET("/blockchain/status", deprecated("/api/v2/blockchain/status"), blockchainHandler.GetBlockchainStatus)
+
+	// v2 routes: resources named by their path, changed with the verb of the change
+	v2Routes := router.Group("/api/v2", audit.Middleware(audit.SourceHTTP), authenticate(verifier))
+	v2AdminRoutes := router.Group("/api/v2", audit.Middleware(audit.SourceAdmin), authenticate(verifier), requireRole(auth.RoleAdmin))
+
+	//routes for the orders
+	v2Routes.GET("/orders", orderHandler.GetAllOrders)
+	v2Routes.POST("/orders", orderHandler.CreateOrder)
+
+	//routes of an order, the handlers shared with v1 read the order from order_id
+	orderRoutes := v2Routes.Group("/orders/:id", orderParam)
+	orderRoutes.GET("", orderHandler.GetOrderByID)
+	orderRoutes.PATCH("", orderHandler.UpdateDeliveryAddress)
+	orderRoutes.POST("/cancellation", orderHandler.CancelOrder)
+	orderRoutes.GET("/verification", verificationHandler.VerifyOrder)
+
+	orderRoutes.GET("/events", orderStatusHistory.GetOrderStatusByOrderID)
+	orderRoutes.POST("/events", orderStatusHistory.CreateOrderEvent)
+
+	orderRoutes.GET("/items", orderProductHandler.GetOrderProducts)
+	orderRoutes.POST("/items", orderProductHandler.AddOrderProduct)
+	orderRoutes.GET("/items/:item_id", orderProductHandler.GetOrderProductByID)
+	orderRoutes.PATCH("/items/:item_id", orderProductHandler.UpdateOrderProduct)
+	orderRoutes.DELETE("/items/:item_id", orderProductHandler.DeleteOrderItem)
+	orderRoutes.GET("/changes", orderProductHandler.GetOrderChanges)
+	orderRoutes.GET("/audit", auditHandler.GetOrderAuditEvents)
+
+	orderRoutes.GET("/proofs", deliveryProofHandler.GetDeliveryProofs)
+	orderRoutes.GET("/shipments", shipmentHandler.GetShipmentsByOrderID)
+	orderRoutes.POST("/shipments", shipmentHandler.CreateShipment)
+	orderRoutes.GET("/shipping", shippingHandler.GetOrderShipping)
+	orderRoutes.GET("/location-reviews", locationReviewHandler.GetLocationReviewsByOrderID)
+	orderRoutes.POST("/return", returnHandler.RequestReturn)
+	orderRoutes.POST("/return/completion", returnHandler.CompleteReturn)
+	orderRoutes.POST("/failed-deliveries", returnHandler.RecordFailedDelivery)
+
+	//routes for the other resources
+	v2Routes.GET("/proofs/:id/:media", deliveryProofHandler.GetDeliveryProofMedia)
+	v2Routes.GET("/shipments/:tracking_code", shipmentHandler.GetShipmentByTrackingCode)
+	v2Routes.POST("/shipping/quotes", shippingHandler.QuoteShipping)
+	v2Routes.GET("/location-reviews", locationReviewHandler.GetLocationReviews) // Query param: ?status=PENDING|APPROVED|REJECTED
+	v2AdminRoutes.POST("/location-reviews/:id/resolution", locationReviewHandler.ResolveLocationReview)
+	v2Routes.GET("/products", productHandler.GetAllProducts)
+	v2Routes.GET("/products/:id", productHandler.GetProductByID)
+	v2Routes.GET("/storages", storageHandler.GetAllStorages)
+	v2AdminRoutes.GET("/blockchain/status", blockchainHandler.GetBlockchainStatus)
+	v2AdminRoutes.GET("/blockchain/deployments", blockchainHandler.ListDeployments)
+	v2AdminRoutes.POST("/blockchain/deployments", blockchainHandler.DeployContract)
+	v2AdminRoutes.POST("/blockchain/deployments/:deployment_id/activate", blockchainHandler.ActivateDeployment)
+	v2AdminRoutes.GET("/admin/config", configHandler.GetConfig)
+	v2AdminRoutes.GET("/admin/workers", workerHandler.GetWorkers)
+
+	//routes for the API documentation (the OpenAPI document is generated from the registered routes)
+	apiRoutes.GET("/openapi.json", openapi.Handler(router, apiInfo, operations))
+	apiRoutes.GET("/docs", openapi.Docs)
+
+	//routes for the probes and the metrics (outside /api, they are not part of the API)
+	broker, _ := notifier.(health.Pinger)
+	checker := health.NewChecker(health.Database(db), health.Broker(broker), health.Ledger(ledgerBlockNumber(blockChainClient)), shutdown, workers)
+	router.GET("/healthz", health.Liveness)
+	router.GET("/readyz", checker.Readiness)
+	router.GET("/metrics", metrics.Handler())
+
+	// unknown routes get the same error body as the handlers
+	router.NoRoute(func(c *gin.Context) {
+		apierror.Respond(c, http.StatusNotFound, "Route not found")
+	})
 
 	//old routes for testing
 	router.GET("/ping", func(c *gin.Context) {
@@ -61,3 +206,11 @@ func RegisterRoutes(router *gin.Engine, db *gorm.DB, blockChainClient *blockchai
 		})
 	})
 }
+
+// ledgerBlockNumber is the function checking the blockchain node (nil when the blockchain is not configured)
+func ledgerBlockNumber(client *blockchain.Client) func(ctx context.Context) (uint64, error) {
+	if client == nil || client.EthClient == nil {
+		return nil
+	}
+	return client.EthClient.BlockNumber
+}
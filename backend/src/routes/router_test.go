@@ -1,15 +1,24 @@
 package routes
 
 import (
+    "app/auth"
     "app/blockchain"
+    "app/config"
+    "app/openapi"
+    "encoding/json"
     "github.com/gin-gonic/gin"
+    "github.com/golang-jwt/jwt/v4"
     "gorm.io/gorm"
+    "net/http"
+    "net/http/httptest"
+    "strings"
     "testing"
+    "time"
 )
 
 func TestRegisterRoutes_AllEndpointsExist(t *testing.T) {
     r := gin.Default()
-    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{})
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
 
     // Collect all registered routes
     routes := r.Routes()
@@ -23,16 +32,71 @@ func TestRegisterRoutes_AllEndpointsExist(t *testing.T) {
         "GET-/api/order/verify/:order_id":  true,
         "POST-/api/order/add":              true,
         "POST-/api/order/update":           true,
+        "POST-/api/order/return":           true,
+        "GET-/api/order/proof/:order_id":   true,
+        "GET-/api/proof/:id/:media":        true,
+        "GET-/api/order/shipments/:order_id": true,
+        "POST-/api/order/shipments/add":    true,
+        "GET-/api/shipment/:tracking_code": true,
+        "GET-/api/reviews/locations":       true,
+        "GET-/api/order/reviews/:order_id": true,
+        "POST-/api/reviews/locations/:id/resolve": true,
+        "POST-/api/shipping/quote":             true,
+        "GET-/api/order/shipping/:order_id":    true,
+        "GET-/api/order/audit/:order_id":      true,
+        "POST-/api/order/failed-delivery":  true,
         "GET-/api/order-products":          true,
         "POST-/api/order-products":         true,
         "GET-/api/order-products/:id":      true,
         "PUT-/api/order-products/:id":      true,
         "DELETE-/api/order-products/:id":   true,
+        "GET-/api/order/changes/:order_id": true,
         "GET-/api/products":                true,
         "GET-/api/products/:id":            true,
         "GET-/api/storages":                true,
         "GET-/api/blockchain/status":       true,
-        "GET-/api/blockchain/deploy":       true,
+        "GET-/api/v2/orders":                    true,
+        "POST-/api/v2/orders":                   true,
+        "GET-/api/v2/orders/:id":                true,
+        "PATCH-/api/v2/orders/:id":              true,
+        "POST-/api/v2/orders/:id/cancellation":  true,
+        "GET-/api/v2/orders/:id/verification":   true,
+        "GET-/api/v2/orders/:id/events":         true,
+        "POST-/api/v2/orders/:id/events":        true,
+        "GET-/api/v2/orders/:id/items":          true,
+        "POST-/api/v2/orders/:id/items":         true,
+        "GET-/api/v2/orders/:id/items/:item_id": true,
+        "PATCH-/api/v2/orders/:id/items/:item_id": true,
+        "DELETE-/api/v2/orders/:id/items/:item_id": true,
+        "GET-/api/v2/orders/:id/changes":        true,
+        "GET-/api/v2/orders/:id/audit":          true,
+        "GET-/api/v2/orders/:id/proofs":         true,
+        "GET-/api/v2/orders/:id/shipments":      true,
+        "POST-/api/v2/orders/:id/shipments":     true,
+        "GET-/api/v2/orders/:id/shipping":       true,
+        "GET-/api/v2/orders/:id/location-reviews": true,
+        "POST-/api/v2/orders/:id/return":        true,
+        "POST-/api/v2/orders/:id/return/completion": true,
+        "POST-/api/v2/orders/:id/failed-deliveries": true,
+        "GET-/api/v2/proofs/:id/:media":         true,
+        "GET-/api/v2/shipments/:tracking_code":  true,
+        "POST-/api/v2/shipping/quotes":          true,
+        "GET-/api/v2/location-reviews":          true,
+        "POST-/api/v2/location-reviews/:id/resolution": true,
+        "GET-/api/v2/products":                  true,
+        "GET-/api/v2/products/:id":              true,
+        "GET-/api/v2/storages":                  true,
+        "GET-/api/v2/blockchain/status":         true,
+        "GET-/api/v2/blockchain/deployments":    true,
+        "POST-/api/v2/blockchain/deployments":   true,
+        "POST-/api/v2/blockchain/deployments/:deployment_id/activate": true,
+        "GET-/api/v2/admin/config":              true,
+        "GET-/api/v2/admin/workers":             true,
+        "GET-/api/openapi.json":            true,
+        "GET-/api/docs":                    true,
+        "GET-/healthz":                     true,
+        "GET-/readyz":                      true,
+        "GET-/metrics":                     true,
         "GET-/ping":                        true,
         "GET-/":                            true,
     }
@@ -49,3 +113,199 @@ func TestRegisterRoutes_AllEndpointsExist(t *testing.T) {
         t.Errorf("missing routes: %v", expected)
     }
 }
+
+func TestRegisterRoutes_UnknownRoute(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/unknown", nil))
+
+    if w.Code != http.StatusNotFound {
+        t.Fatalf("expected 404, got %d", w.Code)
+    }
+    if !strings.Contains(w.Body.String(), `"code":"NOT_FOUND"`) {
+        t.Errorf("expected the error body, got %s", w.Body.String())
+    }
+}
+
+// Every registered route must be in the OpenAPI document: a new route needs its entry in the operations
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", w.Code)
+    }
+
+    var document openapi.Document
+    if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+        t.Fatalf("invalid document: %v", err)
+    }
+    for _, rt := range r.Routes() {
+        if !document.Has(rt.Method, rt.Path) {
+            t.Errorf("route %s %s is missing from the OpenAPI document", rt.Method, rt.Path)
+        }
+    }
+
+    // and every documented operation must still be a route
+    registered := map[string]bool{}
+    for _, rt := range r.Routes() {
+        registered[openapi.Key(rt.Method, rt.Path)] = true
+    }
+    for key := range operations {
+        if !registered[key] {
+            t.Errorf("operation %s is documented but not registered", key)
+        }
+    }
+}
+
+func TestOpenAPI_Docs(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+
+    if w.Code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", w.Code)
+    }
+    if !strings.Contains(w.Body.String(), "openapi.json") {
+        t.Errorf("expected the page to load the document, got %s", w.Body.String())
+    }
+}
+
+func TestRegisterRoutes_V1Deprecated(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    // an invalid id, the handler answers without the database
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/order/history/abc", nil))
+
+    if got := w.Header().Get("Deprecation"); got != "@1792368000" {
+        t.Errorf("expected the deprecation date, got %q", got)
+    }
+    link := w.Header().Get("Link")
+    if !strings.Contains(link, `</api/v2/orders/abc/events>; rel="successor-version"`) || !strings.Contains(link, `rel="deprecation"`) {
+        t.Errorf("expected the successor and the documentation links, got %q", link)
+    }
+
+    // the successor is not linked when the order is not in the path
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/order/cancel", strings.NewReader("{}")))
+    if link := w.Header().Get("Link"); strings.Contains(link, "successor-version") {
+        t.Errorf("expected only the documentation link, got %q", link)
+    }
+
+    // v2 routes are not deprecated
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v2/orders/abc/events", nil))
+    if w.Code != http.StatusBadRequest || w.Header().Get("Deprecation") != "" {
+        t.Errorf("expected a v2 response without deprecation, got %d %v", w.Code, w.Header())
+    }
+}
+
+func TestRegisterRoutes_Probes(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", w.Code)
+    }
+
+    // the request above is measured by its route
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+    if w.Code != http.StatusOK {
+        t.Fatalf("expected 200, got %d", w.Code)
+    }
+    if !strings.Contains(w.Body.String(), `tracking_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"}`) {
+        t.Errorf("expected the latency of /healthz, got %s", w.Body.String())
+    }
+}
+
+const tokenSecret = "0123456789abcdef0123456789abcdef"
+
+// bearer is the Authorization header of a caller with the roles
+func bearer(t *testing.T, subject string, roles ...string) string {
+    t.Helper()
+    token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+        "sub": subject, "roles": roles, "exp": time.Now().Add(time.Hour).Unix(),
+    }).SignedString([]byte(tokenSecret))
+    if err != nil {
+        t.Fatalf("failed to sign the token: %v", err)
+    }
+    return "Bearer " + token
+}
+
+func TestRegisterRoutes_AdminRoutesRequireTheAdminRole(t *testing.T) {
+    cfg := config.Default()
+    cfg.Auth.TokenSecret = tokenSecret
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, cfg, nil)
+
+    serve := func(method, path, authorization string) int {
+        req := httptest.NewRequest(method, path, nil)
+        if authorization != "" {
+            req.Header.Set("Authorization", authorization)
+        }
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w.Code
+    }
+
+    admin := []struct{ method, path string }{
+        {http.MethodPost, "/api/v2/blockchain/deployments"},
+        {http.MethodPost, "/api/v2/blockchain/deployments/1/activate"},
+        {http.MethodGet, "/api/v2/blockchain/deployments"},
+        {http.MethodGet, "/api/v2/admin/config"},
+        {http.MethodGet, "/api/v2/admin/workers"},
+        {http.MethodPost, "/api/v2/location-reviews/1/resolution"},
+        {http.MethodPost, "/api/reviews/locations/1/resolve"},
+        {http.MethodGet, "/api/blockchain/status"},
+    }
+    for _, route := range admin {
+        if code := serve(route.method, route.path, ""); code != http.StatusUnauthorized {
+            t.Errorf("%s %s without a token: expected 401, got %d", route.method, route.path, code)
+        }
+        if code := serve(route.method, route.path, bearer(t, "courier-1", "courier")); code != http.StatusForbidden {
+            t.Errorf("%s %s without the admin role: expected 403, got %d", route.method, route.path, code)
+        }
+        if code := serve(route.method, route.path, "Bearer forged"); code != http.StatusUnauthorized {
+            t.Errorf("%s %s with an invalid token: expected 401, got %d", route.method, route.path, code)
+        }
+    }
+
+    if code := serve(http.MethodGet, "/api/v2/admin/config", bearer(t, "ops-1", auth.RoleAdmin)); code != http.StatusOK {
+        t.Errorf("expected the admin to get the config, got %d", code)
+    }
+    // the deploy shim was a GET with side effects
+    if code := serve(http.MethodGet, "/api/blockchain/deploy", bearer(t, "ops-1", auth.RoleAdmin)); code != http.StatusNotFound {
+        t.Errorf("expected GET /api/blockchain/deploy to be gone, got %d", code)
+    }
+    // the public routes take anonymous callers, not invalid tokens
+    if code := serve(http.MethodGet, "/api/v2/orders/abc/events", ""); code != http.StatusBadRequest {
+        t.Errorf("expected an anonymous caller to reach the handler, got %d", code)
+    }
+    if code := serve(http.MethodGet, "/api/v2/orders/abc/events", "Bearer forged"); code != http.StatusUnauthorized {
+        t.Errorf("expected an invalid token to be rejected, got %d", code)
+    }
+}
+
+func TestRegisterRoutes_AdminRoutesClosedWithoutAuthentication(t *testing.T) {
+    r := gin.New()
+    RegisterRoutes(r, &gorm.DB{}, &blockchain.Client{}, nil, config.Default(), nil)
+
+    req := httptest.NewRequest(http.MethodGet, "/api/v2/admin/config", nil)
+    req.Header.Set("Authorization", bearer(t, "ops-1", auth.RoleAdmin))
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusUnauthorized {
+        t.Errorf("expected 401 when no token can be verified, got %d", w.Code)
+    }
+}
@@ -0,0 +1,44 @@
+package shipping
+
+import "math"
+
+// VolumetricDivisor converts a volume in cm³ into a weight in kg (the divisor used by the carriers for road transport)
+const VolumetricDivisor = 5000.0
+
+// Item is a line of a parcel. Weight is in kg and the dimensions in cm (the units of the Jumpseller catalogue)
+type Item struct {
+	Weight   float64
+	Length   float64
+	Width    float64
+	Height   float64
+	Quantity uint
+}
+
+// Parcel is the shipping weight of a group of items
+type Parcel struct {
+	Weight           float64 // actual weight (kg)
+	VolumetricWeight float64 // weight equivalent to the space the items take (kg)
+}
+
+// NewParcel adds up the actual and the volumetric weight of the items
+func NewParcel(items []Item) Parcel {
+	var parcel Parcel
+	for _, item := range items {
+		quantity := float64(item.Quantity)
+		parcel.Weight += math.Max(item.Weight, 0) * quantity
+		parcel.VolumetricWeight += math.Max(item.Length*item.Width*item.Height, 0) / VolumetricDivisor * quantity
+	}
+	parcel.Weight = round(parcel.Weight)
+	parcel.VolumetricWeight = round(parcel.VolumetricWeight)
+	return parcel
+}
+
+// ChargeableWeight is the weight the carriers charge: the largest of the actual and the volumetric weight
+func (p Parcel) ChargeableWeight() float64 {
+	return math.Max(p.Weight, p.VolumetricWeight)
+}
+
+// rounds to grams (the precision stored in the database)
+func round(kg float64) float64 {
+	return math.Round(kg*1000) / 1000
+}
@@ -0,0 +1,32 @@
+package shipping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewParcel(t *testing.T) {
+	parcel := NewParcel([]Item{
+		{Weight: 0.8, Length: 40, Width: 30, Height: 12, Quantity: 2}, // 14400 cm³ each
+		{Weight: 0.25, Quantity: 4},                                   // no dimensions in the catalogue
+	})
+
+	assert.InDelta(t, 2.6, parcel.Weight, 1e-9)
+	assert.InDelta(t, 5.76, parcel.VolumetricWeight, 1e-9)
+	assert.Equal(t, parcel.VolumetricWeight, parcel.ChargeableWeight(), "bulky parcels are charged by volume")
+}
+
+func TestNewParcel_Empty(t *testing.T) {
+	parcel := NewParcel(nil)
+
+	assert.Zero(t, parcel.Weight)
+	assert.Zero(t, parcel.ChargeableWeight())
+}
+
+func TestNewParcel_IgnoresNegativeValues(t *testing.T) {
+	parcel := NewParcel([]Item{{Weight: -1, Length: -10, Width: 10, Height: 10, Quantity: 1}})
+
+	assert.Zero(t, parcel.Weight)
+	assert.Zero(t, parcel.VolumetricWeight)
+}
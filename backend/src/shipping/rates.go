@@ -0,0 +1,142 @@
+package shipping
+
+import (
+	"app/geocoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"sort"
+)
+
+var (
+	ErrUnknownZone = errors.New("no rate table for the zone")
+	ErrOutsideArea = errors.New("destination is outside the shipping zones")
+)
+
+// Zone groups the destinations that share a rate table
+type Zone string
+
+const (
+	ZoneMainland Zone = "MAINLAND"
+	ZoneIslands  Zone = "ISLANDS"
+)
+
+// ZoneFor returns the zone of a destination (Madeira and the Azores are shipped as islands)
+func ZoneFor(lat, lon float64) (Zone, error) {
+	region, ok := geocoding.ServiceRegion(lat, lon)
+	if !ok {
+		return "", fmt.Errorf("%w: (%f, %f)", ErrOutsideArea, lat, lon)
+	}
+	if region == geocoding.RegionMainland {
+		return ZoneMainland, nil
+	}
+	return ZoneIslands, nil
+}
+
+// Bracket is the price of the parcels up to a chargeable weight
+type Bracket struct {
+	MaxWeight float64 `json:"max_weight"`
+	Price     float64 `json:"price"`
+}
+
+// RateTable is the price of a parcel by chargeable weight. Parcels heavier than the last bracket pay the
+// price of the last bracket plus ExtraPerKg for each started kg above it
+type RateTable struct {
+	Brackets   []Bracket `json:"brackets"`
+	ExtraPerKg float64   `json:"extra_per_kg"`
+}
+
+// Rates are the rate tables of each zone
+type Rates map[Zone]RateTable
+
+// DefaultRates returns the rates used when no rates file is configured
+func DefaultRates() Rates {
+	return Rates{
+		ZoneMainland: {
+			Brackets:   []Bracket{{2, 3.90}, {5, 5.50}, {10, 7.90}, {20, 11.90}, {30, 15.90}},
+			ExtraPerKg: 0.50,
+		},
+		ZoneIslands: {
+			Brackets:   []Bracket{{2, 6.90}, {5, 9.90}, {10, 14.90}, {20, 24.90}, {30, 34.90}},
+			ExtraPerKg: 1.20,
+		},
+	}
+}
+
+// LoadRates reads the rates from a JSON file with the format
+//
+//	{"MAINLAND": {"brackets": [{"max_weight": 2, "price": 3.9}, ...], "extra_per_kg": 0.5}, "ISLANDS": {...}}
+func LoadRates(path string) (Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("invalid rates file %s: %w", path, err)
+	}
+	if err := rates.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rates file %s: %w", path, err)
+	}
+	return rates, nil
+}
+
+// RatesFromFile loads the rates file (SHIPPING_RATES_FILE), falling back to the default rates when it is not set or
+// cannot be loaded
+func RatesFromFile(path string) Rates {
+	if path == "" {
+		return DefaultRates()
+	}
+
+	rates, err := LoadRates(path)
+	if err != nil {
+		slog.Warn("Failed to load the shipping rates, using the default rates", "path", path, "error", err)
+		return DefaultRates()
+	}
+	return rates
+}
+
+// Validate checks that every shipping zone has a table with increasing brackets
+func (r Rates) Validate() error {
+	for _, zone := range []Zone{ZoneMainland, ZoneIslands} {
+		table, ok := r[zone]
+		if !ok || len(table.Brackets) == 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownZone, zone)
+		}
+		if !sort.SliceIsSorted(table.Brackets, func(i, j int) bool { return table.Brackets[i].MaxWeight < table.Brackets[j].MaxWeight }) {
+			return fmt.Errorf("the brackets of %s are not sorted by weight", zone)
+		}
+		for _, bracket := range table.Brackets {
+			if bracket.MaxWeight <= 0 || bracket.Price < 0 {
+				return fmt.Errorf("invalid bracket in %s: %+v", zone, bracket)
+			}
+		}
+		if table.ExtraPerKg < 0 {
+			return fmt.Errorf("invalid extra_per_kg in %s", zone)
+		}
+	}
+	return nil
+}
+
+// Quote returns the price of shipping a parcel to a zone
+func (r Rates) Quote(zone Zone, parcel Parcel) (float64, error) {
+	table, ok := r[zone]
+	if !ok || len(table.Brackets) == 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownZone, zone)
+	}
+
+	weight := parcel.ChargeableWeight()
+	for _, bracket := range table.Brackets {
+		if weight <= bracket.MaxWeight {
+			return bracket.Price, nil
+		}
+	}
+
+	last := table.Brackets[len(table.Brackets)-1]
+	extraKg := math.Ceil(weight - last.MaxWeight)
+	return math.Round((last.Price+extraKg*table.ExtraPerKg)*100) / 100, nil
+}
@@ -0,0 +1,101 @@
+package shipping
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		zone     Zone
+	}{
+		{"Porto", 41.14961, -8.61099, ZoneMainland},
+		{"Funchal", 32.6669, -16.9241, ZoneIslands},
+		{"Ponta Delgada", 37.7412, -25.6756, ZoneIslands},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := ZoneFor(tt.lat, tt.lon)
+			require.NoError(t, err)
+			assert.Equal(t, tt.zone, zone)
+		})
+	}
+
+	_, err := ZoneFor(40.4168, -3.7038) // Madrid
+	assert.ErrorIs(t, err, ErrOutsideArea)
+}
+
+func TestQuote(t *testing.T) {
+	rates := Rates{ZoneMainland: {Brackets: []Bracket{{2, 4}, {10, 8}}, ExtraPerKg: 0.5}}
+
+	tests := []struct {
+		name   string
+		parcel Parcel
+		price  float64
+	}{
+		{"first bracket", Parcel{Weight: 1.5}, 4},
+		{"bracket limit", Parcel{Weight: 2}, 4},
+		{"volumetric weight", Parcel{Weight: 1, VolumetricWeight: 3}, 8},
+		{"above the last bracket", Parcel{Weight: 12.2}, 9.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := rates.Quote(ZoneMainland, tt.parcel)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.price, price, 1e-9)
+		})
+	}
+
+	_, err := rates.Quote(ZoneIslands, Parcel{Weight: 1})
+	assert.ErrorIs(t, err, ErrUnknownZone)
+}
+
+func TestDefaultRates_AreValid(t *testing.T) {
+	assert.NoError(t, DefaultRates().Validate())
+}
+
+func TestLoadRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"MAINLAND": {"brackets": [{"max_weight": 5, "price": 4.5}], "extra_per_kg": 1},
+		"ISLANDS": {"brackets": [{"max_weight": 5, "price": 9}], "extra_per_kg": 2}
+	}`), 0o600))
+
+	rates, err := LoadRates(path)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4.5, rates[ZoneMainland].Brackets[0].Price)
+	assert.Equal(t, 2.0, rates[ZoneIslands].ExtraPerKg)
+}
+
+func TestLoadRates_Invalid(t *testing.T) {
+	files := map[string]string{
+		"missing zone":   `{"MAINLAND": {"brackets": [{"max_weight": 5, "price": 4.5}]}}`,
+		"unsorted":       `{"MAINLAND": {"brackets": [{"max_weight": 5, "price": 4}, {"max_weight": 2, "price": 3}]}, "ISLANDS": {"brackets": [{"max_weight": 5, "price": 9}]}}`,
+		"negative price": `{"MAINLAND": {"brackets": [{"max_weight": 5, "price": -1}]}, "ISLANDS": {"brackets": [{"max_weight": 5, "price": 9}]}}`,
+		"not json":       `brackets`,
+	}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rates.json")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			_, err := LoadRates(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRatesFromFile_FallsBackToDefaults(t *testing.T) {
+	assert.Equal(t, DefaultRates(), RatesFromFile(filepath.Join(t.TempDir(), "missing.json")))
+	assert.Equal(t, DefaultRates(), RatesFromFile(""))
+}
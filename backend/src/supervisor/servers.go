@@ -0,0 +1,129 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTPServer is the worker of an HTTP server. On shutdown it stops accepting connections and waits for the requests
+// in flight until the drain deadline
+func HTTPServer(name string, server *http.Server) Worker {
+	return Worker{Name: name, Critical: true, Run: func(ctx context.Context) error {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return err
+		}
+		return serveHTTP(ctx, server, listener)
+	}}
+}
+
+func serveHTTP(ctx context.Context, server *http.Server, listener net.Listener) error {
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(listener) }()
+	slog.Info("HTTP server listening", "address", listener.Addr().String())
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	drainCtx, cancel := Drain(ctx)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		// the requests still running at the deadline are cut
+		server.Close()
+		return err
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// GRPCServer is the worker of a gRPC server on the listener. On shutdown it stops accepting calls, cancels the streams
+// of the interceptor of streams (they would keep the server open until the deadline) and waits for the calls in flight
+// until the drain deadline, the streams still open then are closed. streams may be nil
+func GRPCServer(name string, server *grpc.Server, listener net.Listener, streams *Streams) Worker {
+	return Worker{Name: name, Critical: true, Run: func(ctx context.Context) error {
+		served := make(chan error, 1)
+		go func() { served <- server.Serve(listener) }()
+		slog.Info("gRPC server listening", "address", listener.Addr().String())
+
+		select {
+		case err := <-served:
+			return err
+		case <-ctx.Done():
+		}
+
+		drainCtx, cancel := Drain(ctx)
+		defer cancel()
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		streams.Close()
+		select {
+		case <-stopped:
+		case <-drainCtx.Done():
+			server.Stop()
+			<-stopped
+		}
+		<-served
+		return nil
+	}}
+}
+
+// Streams cancels the long-lived streams of a gRPC server (e.g. the watches of the orders) when it drains. The
+// callers are told to call again, on another instance. Its zero value is not usable, use NewStreams
+type Streams struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewStreams creates the streams of a server, its Interceptor must be installed on the server
+func NewStreams() *Streams {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Streams{ctx: ctx, cancel: cancel}
+}
+
+// Close cancels the streams open and the ones opened after it
+func (s *Streams) Close() {
+	if s != nil {
+		s.cancel()
+	}
+}
+
+// Interceptor cancels the context of the streams on Close, the streams cut return codes.Unavailable
+func (s *Streams) Interceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := context.WithCancel(stream.Context())
+		defer cancel()
+		stop := context.AfterFunc(s.ctx, cancel)
+		defer stop()
+
+		err := handler(srv, &drainedStream{ServerStream: stream, ctx: ctx})
+		if err != nil && s.ctx.Err() != nil && stream.Context().Err() == nil {
+			return status.Error(codes.Unavailable, "the server is shutting down, call again")
+		}
+		return err
+	}
+}
+
+// drainedStream is a stream whose context is also cancelled when the server drains
+type drainedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *drainedStream) Context() context.Context {
+	return s.ctx
+}
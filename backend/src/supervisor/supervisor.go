@@ -0,0 +1,316 @@
+// Package supervisor runs the long-running components of the service (the HTTP and gRPC servers, the Pub/Sub
+// listeners, the catalogue syncer and the chain workers) under a shared context, and stops them together.
+//
+// On shutdown (SIGTERM) the service is first reported as not ready, and the workers keep serving for the pre-stop delay
+// while the load balancer stops routing to it. The context of the workers is then cancelled: they stop taking work and
+// drain what they have in flight until the drain deadline. The background tasks they started (see Tasks) are then drained too, before the
+// process exits. A critical worker that fails stops the service, the other ones are restarted with a backoff. The
+// state of every worker is reported by the readiness probe.
+package supervisor
+
+import (
+	"app/health"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout is the drain deadline when none is set (Cloud Run kills the container 10s after SIGTERM)
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Backoff between the restarts of a failed worker, doubled on each consecutive failure
+const (
+	DefaultRestartBackoff = time.Second
+	MaxRestartBackoff     = time.Minute
+)
+
+// States of a worker
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting" // it failed, it is restarted after the backoff
+	StateFailed     = "failed"     // a critical worker that failed, the service is stopping
+	StateStopped    = "stopped"
+)
+
+// Worker is a long-running component. Run blocks until ctx is done, then drains its work (it has until the deadline
+// of Drain(ctx)) and returns. Returning before ctx is done is a failure, even without error
+type Worker struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// Critical workers stop the service when they fail (the servers), the other ones are restarted
+	Critical bool
+}
+
+// Status is the state of a worker
+type Status struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Critical  bool      `json:"critical"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// Supervisor runs the workers. Its zero value is not usable, use New
+type Supervisor struct {
+	// ShutdownTimeout is the drain deadline of the workers and of the tasks
+	ShutdownTimeout time.Duration
+	// PreStopDelay is the time the workers keep serving once the service is not ready, before they drain (the time the
+	// load balancer takes to notice). It is not waited when a critical worker failed
+	PreStopDelay time.Duration
+	// RestartBackoff is the wait before the first restart of a failed worker
+	RestartBackoff time.Duration
+	// Tasks are the background tasks of the workers, drained after them
+	Tasks *Tasks
+
+	mu       sync.Mutex
+	workers  []Worker
+	statuses map[string]*Status
+	draining bool
+}
+
+// New creates a supervisor draining within the timeout (DefaultShutdownTimeout when it is not positive)
+func New(shutdownTimeout time.Duration) *Supervisor {
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	return &Supervisor{
+		ShutdownTimeout: shutdownTimeout,
+		RestartBackoff:  DefaultRestartBackoff,
+		Tasks:           &Tasks{},
+		statuses:        map[string]*Status{},
+	}
+}
+
+// Add adds a worker, before Run
+func (s *Supervisor) Add(worker Worker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers = append(s.workers, worker)
+	s.statuses[worker.Name] = &Status{Name: worker.Name, State: StateStopped, Critical: worker.Critical, Since: time.Now()}
+}
+
+type drainKey struct{}
+
+// drain is the deadline of the drain, set when the shutdown starts
+type drain struct {
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// Drain returns a context that is not cancelled with ctx, with the drain deadline of the supervisor running the
+// worker (a context without deadline outside of a supervisor). The workers drain their work in it
+func Drain(ctx context.Context) (context.Context, context.CancelFunc) {
+	drainCtx := context.WithoutCancel(ctx)
+	if d, ok := ctx.Value(drainKey{}).(*drain); ok {
+		d.mu.Lock()
+		deadline := d.deadline
+		d.mu.Unlock()
+		if !deadline.IsZero() {
+			return context.WithDeadline(drainCtx, deadline)
+		}
+	}
+	return context.WithCancel(drainCtx)
+}
+
+// Run runs the workers until ctx is done or a critical worker fails, then drains them and their tasks. It returns the
+// failure of the critical worker, or an error when the drain did not finish before the deadline
+func (s *Supervisor) Run(ctx context.Context) error {
+	// the workers are cancelled by Run only, once the drain deadline is set
+	d := &drain{}
+	runCtx, cancel := context.WithCancel(context.WithValue(context.WithoutCancel(ctx), drainKey{}, d))
+	defer cancel()
+
+	s.mu.Lock()
+	workers := append([]Worker(nil), s.workers...)
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	failures := make(chan error, len(workers))
+	for _, worker := range workers {
+		wg.Add(1)
+		go func(worker Worker) {
+			defer wg.Done()
+			if err := s.supervise(runCtx, worker); err != nil {
+				failures <- err
+			}
+		}(worker)
+	}
+
+	var failure error
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down, draining the workers", "timeout", s.ShutdownTimeout.String())
+	case failure = <-failures:
+		slog.Error("A critical worker failed, shutting down", "error", failure)
+	}
+
+	// the service is no longer ready, the workers keep serving the requests routed to it until the load balancer
+	// notices
+	s.mu.Lock()
+	s.draining = true
+	s.mu.Unlock()
+	if failure == nil && s.PreStopDelay > 0 {
+		slog.Info("Not ready, waiting before draining the workers", "delay", s.PreStopDelay.String())
+		select {
+		case failure = <-failures:
+			slog.Error("A critical worker failed, shutting down", "error", failure)
+		case <-time.After(s.PreStopDelay):
+		}
+	}
+
+	// the workers stop taking work, they have until the deadline to finish the work in flight
+	deadline := time.Now().Add(s.ShutdownTimeout)
+	d.mu.Lock()
+	d.deadline = deadline
+	d.mu.Unlock()
+	cancel()
+
+	drainCtx, cancelDrain := context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	defer cancelDrain()
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-drainCtx.Done():
+		return errors.Join(failure, fmt.Errorf("drain deadline exceeded, still running: %s", strings.Join(s.running(), ", ")))
+	}
+
+	// the tasks started by the requests and the messages (after the workers, they can start tasks while draining)
+	if err := s.Tasks.Wait(drainCtx); err != nil {
+		return errors.Join(failure, err)
+	}
+	slog.Info("All the workers and tasks are drained")
+	return failure
+}
+
+// supervise runs the worker until ctx is done, restarting it when it fails. Returns the failure of a critical worker
+func (s *Supervisor) supervise(ctx context.Context, worker Worker) error {
+	backoff := s.RestartBackoff
+	for {
+		s.setState(worker.Name, StateRunning, nil)
+		started := time.Now()
+		err := run(ctx, worker)
+
+		if ctx.Err() != nil {
+			if err != nil {
+				slog.Warn("Worker stopped with an error", "worker", worker.Name, "error", err)
+			}
+			s.setState(worker.Name, StateStopped, err)
+			return nil
+		}
+		if err == nil {
+			err = errors.New("stopped unexpectedly")
+		}
+		err = fmt.Errorf("worker %s: %w", worker.Name, err)
+
+		if worker.Critical {
+			s.setState(worker.Name, StateFailed, err)
+			return err
+		}
+
+		// a worker that ran for a while is restarted quickly again, a crash loop slows down
+		if time.Since(started) > MaxRestartBackoff {
+			backoff = s.RestartBackoff
+		}
+		s.setState(worker.Name, StateRestarting, err)
+		slog.Error("Worker failed, restarting it", "worker", worker.Name, "backoff", backoff.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			s.setState(worker.Name, StateStopped, err)
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, MaxRestartBackoff)
+	}
+}
+
+// run runs the worker once, a panic is a failure
+func run(ctx context.Context, worker Worker) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return worker.Run(ctx)
+}
+
+func (s *Supervisor) setState(name, state string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.statuses[name]
+	if state == StateRunning && status.State == StateRestarting {
+		status.Restarts++
+	}
+	status.State = state
+	status.Since = time.Now()
+	if err != nil {
+		status.LastError = err.Error()
+	}
+}
+
+// running returns the workers that did not stop
+func (s *Supervisor) running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name, status := range s.statuses {
+		if status.State != StateStopped && status.State != StateFailed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Statuses returns the state of every worker, by name
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]Status, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// DrainCheck is the check of the shutdown for the readiness probe: it fails once the service drains, so that no new
+// request is routed to it. It is required, unlike the check of the workers
+func (s *Supervisor) DrainCheck() health.Check {
+	return health.Check{Name: "shutdown", Required: true, Probe: func(context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.draining {
+			return errors.New("shutting down")
+		}
+		return nil
+	}}
+}
+
+// Check is the check of the workers for the readiness probe: it fails when a worker is not running. It is optional, a
+// listener being restarted only degrades the service
+func (s *Supervisor) Check() health.Check {
+	return health.Check{Name: "workers", Probe: func(context.Context) error {
+		var down []string
+		for _, status := range s.Statuses() {
+			if status.State != StateRunning {
+				down = append(down, fmt.Sprintf("%s is %s", status.Name, status.State))
+			}
+		}
+		if len(down) > 0 {
+			return errors.New(strings.Join(down, ", "))
+		}
+		return nil
+	}}
+}
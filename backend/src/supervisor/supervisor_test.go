@@ -0,0 +1,263 @@
+package supervisor
+
+import (
+	"app/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runAsync runs the supervisor and returns the result of Run
+func runAsync(s *Supervisor, ctx context.Context) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- s.Run(ctx) }()
+	return result
+}
+
+// waitFor waits until the condition holds
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	require.Eventually(t, condition, 2*time.Second, 5*time.Millisecond)
+}
+
+func state(s *Supervisor, name string) Status {
+	for _, status := range s.Statuses() {
+		if status.Name == name {
+			return status
+		}
+	}
+	return Status{}
+}
+
+func TestRun_DrainsTheWorkersOnShutdown(t *testing.T) {
+	s := New(time.Second)
+	var drained atomic.Bool
+	s.Add(Worker{Name: "listener", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		// the work in flight is finished in the drain context, which has the deadline of the supervisor
+		drainCtx, cancel := Drain(ctx)
+		defer cancel()
+		_, hasDeadline := drainCtx.Deadline()
+		drained.Store(hasDeadline && drainCtx.Err() == nil)
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	waitFor(t, func() bool { return state(s, "listener").State == StateRunning })
+	require.NoError(t, s.Check().Probe(context.Background()))
+	require.NoError(t, s.DrainCheck().Probe(context.Background()))
+
+	cancel()
+	require.NoError(t, <-result)
+	assert.True(t, drained.Load())
+	assert.Equal(t, StateStopped, state(s, "listener").State)
+	assert.EqualError(t, s.DrainCheck().Probe(context.Background()), "shutting down")
+	assert.EqualError(t, s.Check().Probe(context.Background()), "listener is stopped")
+}
+
+func TestReadiness_NotReadyOnceTheShutdownBegins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	s := New(time.Second)
+	draining := make(chan struct{})
+	release := make(chan struct{})
+	s.Add(Worker{Name: "listener", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		close(draining)
+		<-release
+		return nil
+	}})
+	router := gin.New()
+	router.GET("/readyz", health.NewChecker(s.DrainCheck(), s.Check()).Readiness)
+	readyz := func() (int, health.Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var report health.Report
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		return w.Code, report
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	waitFor(t, func() bool { return state(s, "listener").State == StateRunning })
+	code, report := readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.Ready, report.Status)
+
+	// the listener is still draining, the service must not take new requests
+	cancel()
+	<-draining
+	code, report = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.NotReady, report.Status)
+	assert.Equal(t, "shutting down", report.Checks["shutdown"].Error)
+
+	close(release)
+	require.NoError(t, <-result)
+}
+
+func TestRun_PreStopDelay(t *testing.T) {
+	s := New(time.Second)
+	s.PreStopDelay = 100 * time.Millisecond
+	var cancelledAt atomic.Int64
+	s.Add(Worker{Name: "http", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		cancelledAt.Store(time.Now().UnixNano())
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	waitFor(t, func() bool { return state(s, "http").State == StateRunning })
+
+	// the service is not ready first, the worker keeps serving until the delay is over
+	stopping := time.Now()
+	cancel()
+	waitFor(t, func() bool { return s.DrainCheck().Probe(context.Background()) != nil })
+	assert.Equal(t, StateRunning, state(s, "http").State)
+
+	require.NoError(t, <-result)
+	assert.GreaterOrEqual(t, time.Duration(cancelledAt.Load()-stopping.UnixNano()), s.PreStopDelay)
+}
+
+func TestRun_CriticalFailureStopsTheService(t *testing.T) {
+	s := New(time.Second)
+	var stopped atomic.Bool
+	s.Add(Worker{Name: "http", Critical: true, Run: func(ctx context.Context) error {
+		return errors.New("address already in use")
+	}})
+	s.Add(Worker{Name: "listener", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		stopped.Store(true)
+		return nil
+	}})
+
+	err := <-runAsync(s, context.Background())
+	assert.EqualError(t, err, "worker http: address already in use")
+	assert.True(t, stopped.Load(), "the other workers are stopped")
+	assert.Equal(t, StateFailed, state(s, "http").State)
+}
+
+func TestRun_RestartsTheFailedWorkers(t *testing.T) {
+	s := New(time.Second)
+	s.RestartBackoff = time.Millisecond
+	var runs atomic.Int32
+	s.Add(Worker{Name: "listener", Run: func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			panic("subscription deleted")
+		}
+		<-ctx.Done()
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	waitFor(t, func() bool { return runs.Load() == 3 && state(s, "listener").State == StateRunning })
+
+	status := state(s, "listener")
+	assert.Equal(t, 2, status.Restarts)
+	assert.Equal(t, "worker listener: panic: subscription deleted", status.LastError)
+
+	cancel()
+	require.NoError(t, <-result)
+}
+
+func TestRun_DrainDeadline(t *testing.T) {
+	s := New(50 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	s.Add(Worker{Name: "stuck", Run: func(ctx context.Context) error {
+		<-release
+		return nil
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	waitFor(t, func() bool { return state(s, "stuck").State == StateRunning })
+	cancel()
+
+	assert.EqualError(t, <-result, "drain deadline exceeded, still running: stuck")
+}
+
+func TestRun_DrainsTheTasks(t *testing.T) {
+	s := New(time.Second)
+	s.Add(Worker{Name: "http", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}})
+
+	var finished atomic.Bool
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	s.Tasks.Go(requestCtx, "store cancellation", func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		// the task is not cancelled with the request that started it
+		finished.Store(ctx.Err() == nil)
+		return nil
+	})
+	cancelRequest()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+	cancel()
+
+	require.NoError(t, <-result)
+	assert.True(t, finished.Load(), "Run returns after the tasks")
+}
+
+func TestTasks_Nil(t *testing.T) {
+	var tasks *Tasks
+	done := make(chan struct{})
+	tasks.Go(context.Background(), "untracked", func(context.Context) error {
+		close(done)
+		return nil
+	})
+	<-done
+	assert.NoError(t, tasks.Wait(context.Background()))
+}
+
+func TestHTTPServer_DrainsTheRequestsInFlight(t *testing.T) {
+	started := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := New(time.Second)
+	s.Add(Worker{Name: "http", Critical: true, Run: func(ctx context.Context) error {
+		return serveHTTP(ctx, server, listener)
+	}})
+	ctx, cancel := context.WithCancel(context.Background())
+	result := runAsync(s, ctx)
+
+	response := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get("http://" + listener.Addr().String())
+		if err == nil {
+			res.Body.Close()
+		}
+		response <- res
+	}()
+	<-started
+	cancel()
+
+	require.NoError(t, <-result)
+	res := <-response
+	require.NotNil(t, res, "the request in flight is answered")
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err, "the server no longer accepts connections")
+}
@@ -0,0 +1,80 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Tasks tracks the work that the requests and the messages leave running after they are answered (e.g. a
+// transaction submitted after the response). The supervisor waits for it before the process exits, so a deploy does
+// not kill it halfway. A nil Tasks runs the tasks without tracking them
+type Tasks struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running map[string]int
+}
+
+// Go runs the task in the background, in a context that keeps the values of ctx (trace, correlation IDs) but is not
+// cancelled with it. A failure is logged
+func (t *Tasks) Go(ctx context.Context, name string, task func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	if t == nil {
+		go runTask(ctx, name, task)
+		return
+	}
+
+	t.mu.Lock()
+	if t.running == nil {
+		t.running = map[string]int{}
+	}
+	t.running[name]++
+	t.wg.Add(1)
+	t.mu.Unlock()
+
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			t.running[name]--
+			t.mu.Unlock()
+			t.wg.Done()
+		}()
+		runTask(ctx, name, task)
+	}()
+}
+
+func runTask(ctx context.Context, name string, task func(ctx context.Context) error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			slog.ErrorContext(ctx, "Background task panicked", "task", name, "error", fmt.Sprint(recovered))
+		}
+	}()
+	if err := task(ctx); err != nil {
+		slog.WarnContext(ctx, "Background task failed", "task", name, "error", err)
+	}
+}
+
+// Wait waits for the running tasks until ctx is done
+func (t *Tasks) Wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		pending := 0
+		for _, count := range t.running {
+			pending += count
+		}
+		return fmt.Errorf("drain deadline exceeded, %d background tasks still running", pending)
+	}
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"errors"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "telemetry:span"
+
+// GormPlugin traces the queries of the requests and messages (the ones run with a context that has a span, through
+// db.WithContext). The statements are recorded with their placeholders, the values are left out
+type GormPlugin struct{}
+
+// NewGormPlugin creates the plugin, installed with db.Use
+func NewGormPlugin() *GormPlugin {
+	return &GormPlugin{}
+}
+
+func (p *GormPlugin) Name() string {
+	return "telemetry"
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	registrations := []error{
+		callback.Create().Before("gorm:create").Register("telemetry:before_create", startQuery("create")),
+		callback.Create().After("gorm:create").Register("telemetry:after_create", endQuery),
+		callback.Query().Before("gorm:query").Register("telemetry:before_query", startQuery("query")),
+		callback.Query().After("gorm:query").Register("telemetry:after_query", endQuery),
+		callback.Update().Before("gorm:update").Register("telemetry:before_update", startQuery("update")),
+		callback.Update().After("gorm:update").Register("telemetry:after_update", endQuery),
+		callback.Delete().Before("gorm:delete").Register("telemetry:before_delete", startQuery("delete")),
+		callback.Delete().After("gorm:delete").Register("telemetry:after_delete", endQuery),
+		callback.Row().Before("gorm:row").Register("telemetry:before_row", startQuery("row")),
+		callback.Row().After("gorm:row").Register("telemetry:after_row", endQuery),
+		callback.Raw().Before("gorm:raw").Register("telemetry:before_raw", startQuery("raw")),
+		callback.Raw().After("gorm:raw").Register("telemetry:after_raw", endQuery),
+	}
+	for _, err := range registrations {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return
+		}
+		_, span := Start(ctx, "db "+operation+" "+db.Statement.Table, trace.SpanKindClient, trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBCollectionName(db.Statement.Table),
+		))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()))
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil // not found is an answer, not a failure
+	}
+	End(span, err)
+}
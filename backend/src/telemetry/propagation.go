@@ -0,0 +1,74 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Inject writes the trace context of ctx into the attributes of a message
+func Inject(ctx context.Context, attributes map[string]string) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(attributes))
+}
+
+// Extract returns ctx with the trace context read from the attributes of a message
+func Extract(ctx context.Context, attributes map[string]string) context.Context {
+	if attributes == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(attributes))
+}
+
+// StartConsumer starts the span of a message received from a subscription, in the trace of its publisher
+func StartConsumer(ctx context.Context, subscription, messageID string, attributes map[string]string) (context.Context, trace.Span) {
+	return Start(Extract(ctx, attributes), "receive "+subscription, trace.SpanKindConsumer, trace.WithAttributes(
+		semconv.MessagingSystemGCPPubsub,
+		semconv.MessagingOperationTypeDeliver,
+		semconv.MessagingDestinationName(subscription),
+		semconv.MessagingMessageID(messageID),
+	))
+}
+
+// StartProducer starts the span of a message published to a topic and writes its trace context into the attributes
+func StartProducer(ctx context.Context, topic string, attributes map[string]string) (context.Context, trace.Span) {
+	ctx, span := Start(ctx, "publish "+topic, trace.SpanKindProducer, trace.WithAttributes(
+		semconv.MessagingSystemGCPPubsub,
+		semconv.MessagingOperationTypePublish,
+		semconv.MessagingDestinationName(topic),
+	))
+	Inject(ctx, attributes)
+	return ctx, span
+}
+
+// Middleware traces the HTTP requests, in the trace of the caller when it sends one (traceparent header). The span is
+// named by the route, not by the path
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := Start(ctx, c.Request.Method+" "+route, trace.SpanKindServer, trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+		))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
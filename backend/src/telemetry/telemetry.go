@@ -0,0 +1,99 @@
+// Package telemetry traces the hops of the requests and the messages with OpenTelemetry: the HTTP and gRPC requests,
+// the Pub/Sub messages (the trace context travels in the attributes of the messages), the database queries, the
+// blockchain calls and the notifications.
+//
+// The spans are exported as set by the Tracing section of the config (OTEL_TRACES_EXPORTER):
+//
+//   - otlp: to an OTLP/HTTP collector, configured with the standard OTEL_EXPORTER_OTLP_* variables
+//   - stdout: written to the standard output, for local debugging
+//   - none (the default): not exported, the trace context is still propagated
+package telemetry
+
+import (
+	"app/config"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName is the name of the service in the traces (OTEL_SERVICE_NAME replaces it)
+const ServiceName = "order-tracking"
+
+const instrumentationName = "app"
+
+func init() {
+	// the trace context is propagated even when the spans are not exported
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// Setup installs the tracer provider of the exporter of the config. The returned function flushes the spans and stops
+// the provider, it must be called before the process exits
+func Setup(ctx context.Context, tracing config.Tracing) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch tracing.Exporter {
+	case "", config.ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case config.ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case config.ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, expected %s, %s or %s", tracing.Exporter, config.ExporterOTLP, config.ExporterStdout, config.ExporterNone)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the %s trace exporter: %w", tracing.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to create the trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer is the tracer of the service (of the provider installed by Setup)
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start starts a span of an internal operation or of a call to a dependency (trace.SpanKindClient)
+func Start(ctx context.Context, name string, kind trace.SpanKind, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return Tracer().Start(ctx, name, append([]trace.SpanStartOption{trace.WithSpanKind(kind)}, options...)...)
+}
+
+// End ends a span, as failed when there is an error
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// OrderID is the attribute of the order of a span
+func OrderID(id uint) attribute.KeyValue {
+	return attribute.Int64("order.id", int64(id))
+}
@@ -0,0 +1,139 @@
+package telemetry
+
+import (
+	"app/config"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// recordSpans installs a tracer provider that keeps the ended spans
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware_ContinuesTheCallerTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/orders/:id", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /orders/:id", span.Name())
+	assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, int64(500), attributeValue(span, "http.response.status_code").AsInt64())
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
+
+func TestPubSubPropagation(t *testing.T) {
+	recorder := recordSpans(t)
+
+	// the publisher writes the trace context into the attributes of the message
+	attributes := map[string]string{}
+	_, producer := StartProducer(context.Background(), "tracking-notifications", attributes)
+	producer.End()
+	require.NotEmpty(t, attributes["traceparent"])
+
+	// and the subscriber continues it
+	_, consumer := StartConsumer(context.Background(), "order-updates", "msg-1", attributes)
+	consumer.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "publish tracking-notifications", spans[0].Name())
+	assert.Equal(t, "receive order-updates", spans[1].Name())
+	assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+	assert.Equal(t, "msg-1", attributeValue(spans[1], "messaging.message.id").AsString())
+}
+
+func TestStartConsumer_WithoutTraceContext(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, consumer := StartConsumer(context.Background(), "order-updates", "msg-1", nil)
+	consumer.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent().IsValid(), "a message without trace context starts a new trace")
+}
+
+func TestGormPlugin(t *testing.T) {
+	recorder := recordSpans(t)
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(NewGormPlugin()))
+
+	type Orders struct{ Id uint }
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnError(errors.New("connection reset"))
+	mock.ExpectQuery(`SELECT \* FROM "orders"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, parent := Start(context.Background(), "handler", trace.SpanKindInternal)
+	var orders []Orders
+	require.NoError(t, db.WithContext(ctx).Where("id = ?", 1).Find(&orders).Error)
+	assert.Error(t, db.WithContext(ctx).Find(&orders).Error)
+	parent.End()
+
+	// queries outside of a trace are not traced
+	require.NoError(t, db.Find(&orders).Error)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	query := spans[0]
+	assert.Equal(t, "db query orders", query.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), query.Parent().SpanID())
+	// the statement is recorded with its placeholders, not with the values
+	assert.Equal(t, `SELECT * FROM "orders" WHERE id = $1`, attributeValue(query, "db.query.text").AsString())
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.Tracing{Exporter: config.ExporterNone})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), config.Tracing{Exporter: "zipkin"})
+	assert.EqualError(t, err, `unknown trace exporter "zipkin", expected otlp, stdout or none`)
+}
@@ -0,0 +1,82 @@
+package tracking
+
+import (
+	"app/auth"
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizationKey is the metadata key of the bearer token, the same as the header of the REST API
+const authorizationKey = "authorization"
+
+// changingMethods are the calls that change the orders, their caller must be authenticated (the other calls read the
+// orders, their caller may be anonymous)
+var changingMethods = map[string]bool{
+	TrackingService_CreateOrder_FullMethodName:  true,
+	TrackingService_AppendStatus_FullMethodName: true,
+}
+
+// authenticate authenticates the caller of a call by the bearer token of its authorization metadata, as the REST API
+// does: a call without a token is anonymous, a call with a token that is not valid is rejected
+func authenticate(ctx context.Context, verifier *auth.Verifier) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var authorization string
+	if values := md.Get(authorizationKey); len(values) > 0 {
+		authorization = values[0]
+	}
+
+	token, err := auth.BearerToken(authorization)
+	if errors.Is(err, auth.ErrNoToken) {
+		return ctx, nil
+	}
+	if err == nil {
+		var identity auth.Identity
+		if identity, err = verifier.Verify(token); err == nil {
+			return auth.NewContext(ctx, identity), nil
+		}
+	}
+	slog.InfoContext(ctx, "Rejected a bearer token", "error", err)
+	return nil, status.Error(codes.Unauthenticated, "Invalid bearer token")
+}
+
+// authUnaryInterceptor authenticates the caller of the unary calls, the calls changing the orders are rejected when it
+// is anonymous
+func authUnaryInterceptor(verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+		if _, authenticated := auth.FromContext(ctx); changingMethods[info.FullMethod] && !authenticated {
+			return nil, status.Error(codes.Unauthenticated, "A bearer token is required to change the orders")
+		}
+		return handler(ctx, request)
+	}
+}
+
+// authStreamInterceptor authenticates the caller of the streaming calls
+func authStreamInterceptor(verifier *auth.Verifier) grpc.StreamServerInterceptor {
+	return func(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(stream.Context(), verifier)
+		if err != nil {
+			return err
+		}
+		return handler(server, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a stream whose context has the identity of the caller
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
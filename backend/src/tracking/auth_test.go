@@ -0,0 +1,99 @@
+package tracking
+
+import (
+	"app/auth"
+	"app/config"
+	"app/models"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tokenSecret = "0123456789abcdef0123456789abcdef"
+
+func bearer(t *testing.T, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": subject, "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(tokenSecret))
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestAuthenticate(t *testing.T) {
+	verifier := auth.NewVerifier(config.Auth{TokenSecret: tokenSecret})
+	incoming := func(authorization string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(authorizationKey, authorization))
+	}
+
+	ctx, err := authenticate(incoming(bearer(t, "checkout")), verifier)
+	require.NoError(t, err)
+	identity, ok := auth.FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "checkout", identity.Subject)
+
+	ctx, err = authenticate(context.Background(), verifier)
+	require.NoError(t, err, "the calls without a token are anonymous")
+	_, ok = auth.FromContext(ctx)
+	assert.False(t, ok)
+
+	_, err = authenticate(incoming("Bearer not-a-token"), verifier)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = authenticate(incoming(bearer(t, "checkout")), nil)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "no token is valid when the authentication is not configured")
+}
+
+func TestAuthInterceptors_RejectInvalidTokens(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	service.Verifier = auth.NewVerifier(config.Auth{TokenSecret: tokenSecret})
+	seedOrder(t, store, 1, models.StatusDelivered)
+	client := dial(t, service)
+	invalid := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, "Bearer not-a-token")
+	valid := metadata.AppendToOutgoingContext(context.Background(), authorizationKey, bearer(t, "checkout"))
+
+	_, err := client.GetOrder(invalid, &GetOrderRequest{Key: &GetOrderRequest_Id{Id: 1}})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetOrder(valid, &GetOrderRequest{Key: &GetOrderRequest_Id{Id: 1}})
+	assert.NoError(t, err)
+
+	stream, err := client.WatchOrder(invalid, &WatchOrderRequest{Key: &WatchOrderRequest_Id{Id: 1}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err = client.WatchOrder(valid, &WatchOrderRequest{Key: &WatchOrderRequest_Id{Id: 1}})
+	require.NoError(t, err)
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, update.GetStatus())
+}
+
+func TestAuthInterceptors_ChangesRequireAnAuthenticatedCaller(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	seedOrder(t, store, 1, models.StatusProcessing)
+	client := dial(t, service)
+
+	_, err := client.CreateOrder(context.Background(), &CreateOrderRequest{CustomerId: 1, SellerId: 2, SellerAddress: "Seller", DeliveryAddress: "Addr"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.AppendStatus(context.Background(), &AppendStatusRequest{OrderId: 1, Status: models.StatusShipped})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProcessing, latest.Order_Status, "the anonymous update is not appended")
+
+	// the reads stay open to the anonymous callers
+	_, err = client.GetOrder(context.Background(), &GetOrderRequest{Key: &GetOrderRequest_Id{Id: 1}})
+	assert.NoError(t, err)
+
+	_, err = client.AppendStatus(authenticated(t, context.Background(), "courier-service"), &AppendStatusRequest{OrderId: 1, Status: models.StatusShipped})
+	assert.NoError(t, err)
+}
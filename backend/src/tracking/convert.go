@@ -0,0 +1,147 @@
+package tracking
+
+import (
+	"app/models"
+	"app/requestModels"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// createOrderInput converts a CreateOrderRequest to the request model of the REST API
+func createOrderInput(request *CreateOrderRequest) requestModels.AddOrderRequest {
+	input := requestModels.AddOrderRequest{
+		CustomerId:        uint(request.GetCustomerId()),
+		SellerId:          uint(request.GetSellerId()),
+		SellerAddress:     request.GetSellerAddress(),
+		SellerLatitude:    request.GetSellerLatitude(),
+		SellerLongitude:   request.GetSellerLongitude(),
+		DeliveryAddress:   request.GetDeliveryAddress(),
+		DeliveryLatitude:  request.GetDeliveryLatitude(),
+		DeliveryLongitude: request.GetDeliveryLongitude(),
+		Products:          make([]requestModels.OrderProductRequest, 0, len(request.GetProducts())),
+	}
+	for _, line := range request.GetProducts() {
+		input.Products = append(input.Products, requestModels.OrderProductRequest{
+			ProductID: uint(line.GetProductId()),
+			VariantID: optionalID(line.VariantId),
+			Quantity:  uint(line.GetQuantity()),
+		})
+	}
+	return input
+}
+
+// appendStatusInput converts an AppendStatusRequest to the request model of the REST API
+func appendStatusInput(request *AppendStatusRequest) requestModels.AddOrderUpdateRequest {
+	var timestamp time.Time
+	if request.GetTimestamp() != nil {
+		timestamp = request.GetTimestamp().AsTime()
+	}
+
+	return requestModels.AddOrderUpdateRequest{OrderStatusHistory: models.OrderStatusHistory{
+		Order_ID:          uint(request.GetOrderId()),
+		Order_Status:      request.GetStatus(),
+		Note:              request.GetNote(),
+		Order_Location:    request.GetLocation(),
+		Storage_ID:        optionalID(request.StorageId),
+		Shipment_ID:       optionalID(request.ShipmentId),
+		Latitude:          request.Latitude,
+		Longitude:         request.Longitude,
+		Timestamp_History: timestamp,
+	}}
+}
+
+func orderMessage(order *models.Orders) *Order {
+	message := &Order{
+		Id:                uint64(order.Id),
+		TrackingCode:      order.Tracking_Code,
+		CustomerId:        uint64(order.Customer_ID),
+		SellerId:          uint64(order.Seller_ID),
+		SellerAddress:     order.Seller_Address,
+		DeliveryAddress:   order.Delivery_Address,
+		DeliveryLatitude:  order.Delivery_Latitude,
+		DeliveryLongitude: order.Delivery_Longitude,
+		CreatedAt:         timestamp(order.Created_At),
+		DeliveryEstimate:  timestamp(order.Delivery_Estimate),
+		ShippingWeight:    order.Shipping_Weight,
+		VolumetricWeight:  order.Volumetric_Weight,
+		Products:          make([]*OrderProduct, 0, len(order.Products)),
+	}
+	for _, product := range order.Products {
+		line := &OrderProduct{
+			Id:        uint64(product.ID),
+			ProductId: uint64(product.Product_ID),
+			VariantId: optionalUint64(product.Variant_ID),
+			Quantity:  uint32(product.Quantity),
+			Name:      product.Product_Name_At_Purchase,
+			Price:     product.Product_Price_At_Purchase,
+		}
+		if product.Variant_SKU != nil {
+			line.VariantSku = *product.Variant_SKU
+		}
+		message.Products = append(message.Products, line)
+	}
+	return message
+}
+
+func updateMessage(update *models.OrderStatusHistory) *StatusUpdate {
+	return &StatusUpdate{
+		Id:                    uint64(update.Id),
+		OrderId:               uint64(update.Order_ID),
+		Status:                update.Order_Status,
+		Note:                  update.Note,
+		Location:              update.Order_Location,
+		Timestamp:             timestamp(update.Timestamp_History),
+		BlockchainTransaction: update.Blockchain_Transaction,
+		StorageId:             optionalUint64(update.Storage_ID),
+		ShipmentId:            optionalUint64(update.Shipment_ID),
+	}
+}
+
+func verificationMessage(verification *requestModels.VerificationResponse) *Verification {
+	message := &Verification{
+		Verified:          verification.Verified,
+		Status:            verification.Status,
+		Message:           verification.Message,
+		TotalUpdates:      int32(verification.TotalUpdates),
+		VerifiedUpdates:   int32(verification.VerifiedUpdates),
+		BlockchainHashes:  int32(verification.BlockchainHashes),
+		Mismatches:        verification.Mismatches,
+		TransactionHashes: verification.TransactionHashes,
+		ContractAddress:   verification.ContractAddress,
+	}
+	for _, shipment := range verification.Shipments {
+		message.Shipments = append(message.Shipments, &ShipmentVerification{
+			ShipmentId:      uint64(shipment.ShipmentID),
+			Verified:        shipment.Verified,
+			TotalUpdates:    int32(shipment.TotalUpdates),
+			VerifiedUpdates: int32(shipment.VerifiedUpdates),
+			Mismatches:      shipment.Mismatches,
+		})
+	}
+	return message
+}
+
+// timestamp converts a time, the zero time is left unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func optionalID(id *uint64) *uint {
+	if id == nil {
+		return nil
+	}
+	value := uint(*id)
+	return &value
+}
+
+func optionalUint64(id *uint) *uint64 {
+	if id == nil {
+		return nil
+	}
+	value := uint64(*id)
+	return &value
+}
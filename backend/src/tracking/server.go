@@ -0,0 +1,301 @@
+// Package tracking is the gRPC interface of the order tracking (tracking.proto), for the services of the other
+// teams. It runs the same operations as the REST handlers, the server only converts the messages and the errors.
+//
+// The Go code of the messages and of the service is generated from tracking.proto:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative tracking.proto
+package tracking
+
+import (
+	"app/apierror"
+	"app/audit"
+	"app/auth"
+	"app/blobstore"
+	"app/blockchain"
+	"app/catalog"
+	"app/config"
+	"app/geocoding"
+	"app/handlers"
+	"app/logging"
+	"app/models"
+	"app/repository"
+	"app/supervisor"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// DefaultPollInterval is how often WatchOrder looks for new updates
+const DefaultPollInterval = 2 * time.Second
+
+// Metadata keys of the calls, the same as the headers of the REST API
+const (
+	requestIDKey = "x-request-id"
+	actorKey     = "x-actor"
+)
+
+// Server implements the TrackingService
+type Server struct {
+	UnimplementedTrackingServiceServer
+
+	Store        repository.Store
+	Orders       *handlers.OrderHandler
+	Updates      *handlers.OrderStatusHistoryHandler
+	Verification *handlers.VerificationHandler
+	// PollInterval is how often WatchOrder looks for new updates (DefaultPollInterval when it is not set)
+	PollInterval time.Duration
+	// Verifier authenticates the callers by their bearer token, all the callers are anonymous when it is nil
+	Verifier *auth.Verifier
+}
+
+// NewServer creates the service with the same dependencies and config as the REST handlers, its chain transactions are
+// drained with the tasks
+func NewServer(db *gorm.DB, blockChainClient *blockchain.Client, cfg *config.Config, tasks *supervisor.Tasks) *Server {
+	store := repository.NewPostgres(db)
+	blobStore := blobstore.NewFileSystemStore(cfg.Storage.BlobPath)
+
+	orders := &handlers.OrderHandler{Store: store, Client: blockChainClient, Geocoder: geocoding.Default(), Catalog: catalog.Default(), Audit: audit.NewLogger(blockChainClient, cfg.Audit.ChainAnchoring, tasks), Tasks: tasks}
+
+	return &Server{
+		Store:        store,
+		Orders:       orders,
+		Updates:      &handlers.OrderStatusHistoryHandler{Store: store, Client: blockChainClient, Blobs: blobStore},
+		Verification: &handlers.VerificationHandler{Store: store, Client: blockChainClient, Blobs: blobStore},
+		Verifier:     auth.NewVerifier(cfg.Auth),
+	}
+}
+
+// NewGRPCServer creates a gRPC server serving the service, the calls are traced and their caller is authenticated
+func NewGRPCServer(service *Server, options ...grpc.ServerOption) *grpc.Server {
+	options = append([]grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(auditInterceptor, authUnaryInterceptor(service.Verifier)),
+		grpc.ChainStreamInterceptor(authStreamInterceptor(service.Verifier)),
+	}, options...)
+	server := grpc.NewServer(options...)
+	RegisterTrackingServiceServer(server, service)
+	return server
+}
+
+func (s *Server) CreateOrder(ctx context.Context, request *CreateOrderRequest) (*Order, error) {
+	input := createOrderInput(request)
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return nil, validationError(err)
+	}
+
+	order, err := s.Orders.PlaceOrder(ctx, input)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return orderMessage(order), nil
+}
+
+func (s *Server) AppendStatus(ctx context.Context, request *AppendStatusRequest) (*AppendStatusResponse, error) {
+	input := appendStatusInput(request)
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return nil, validationError(err)
+	}
+	if _, err := s.Store.Orders().Get(ctx, input.Order_ID); err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	update, review, err := s.Updates.AppendUpdate(ctx, input)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	response := &AppendStatusResponse{Update: updateMessage(update)}
+	if review != nil {
+		response.LocationReview = &LocationReview{
+			Id:          uint64(review.Id),
+			Reason:      review.Reason,
+			DistanceKm:  review.Distance_Km,
+			ToleranceKm: review.Tolerance_Km,
+		}
+	}
+	return response, nil
+}
+
+func (s *Server) GetOrder(ctx context.Context, request *GetOrderRequest) (*Order, error) {
+	order, err := s.findOrder(ctx, request.GetId(), request.GetTrackingCode())
+	if err != nil {
+		return nil, err
+	}
+	return orderMessage(order), nil
+}
+
+func (s *Server) WatchOrder(request *WatchOrderRequest, stream grpc.ServerStreamingServer[StatusUpdate]) error {
+	ctx := stream.Context()
+	order, err := s.findOrder(ctx, request.GetId(), request.GetTrackingCode())
+	if err != nil {
+		return err
+	}
+
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	sent := map[uint]bool{}
+	for {
+		updates, err := s.Store.History().ListByOrder(ctx, order.Id)
+		if err != nil {
+			return grpcError(ctx, err)
+		}
+
+		// the updates are listed newest first, they are sent in the order they happened
+		for i := len(updates) - 1; i >= 0; i-- {
+			update := updates[i]
+			if sent[update.Id] {
+				continue
+			}
+			if err := stream.Send(updateMessage(&update)); err != nil {
+				return err
+			}
+			sent[update.Id] = true
+
+			if isFinal(update.Order_Status) {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) VerifyOrder(ctx context.Context, request *VerifyOrderRequest) (*Verification, error) {
+	if request.GetOrderId() == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Invalid order id")
+	}
+
+	verification, err := s.Verification.Verify(ctx, uint(request.GetOrderId()))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return verificationMessage(verification), nil
+}
+
+// findOrder returns an order with its products by its id or, when it has no id, by its tracking code
+func (s *Server) findOrder(ctx context.Context, id uint64, trackingCode string) (*models.Orders, error) {
+	var order *models.Orders
+	var err error
+	switch {
+	case id != 0:
+		order, err = s.Store.Orders().GetWithProducts(ctx, uint(id))
+	case trackingCode != "":
+		order, err = s.Store.Orders().GetByTrackingCode(ctx, trackingCode)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "An order id or a tracking code is required")
+	}
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return order, nil
+}
+
+// isFinal reports whether an order stays in a status (WatchOrder ends after it)
+func isFinal(orderStatus string) bool {
+	return orderStatus == models.StatusDelivered || orderStatus == models.StatusCancelled || orderStatus == models.StatusReturned
+}
+
+// auditInterceptor sets the audit metadata of the calls, from their x-request-id and x-actor metadata. The x-actor is
+// only recorded as claimed, the actor is the authenticated caller. The request ID is sent back in the header of the
+// response
+func auditInterceptor(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key, fallback string) string {
+		if values := md.Get(key); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+		return fallback
+	}
+
+	requestID := first(requestIDKey, uuid.New().String())
+	ctx = logging.NewContext(ctx, slog.String(logging.RequestIDKey, requestID))
+	ctx = audit.NewContext(ctx, audit.Metadata{
+		ClaimedActor: first(actorKey, ""),
+		Source:       audit.SourceGRPC,
+		RequestID:    requestID,
+	})
+	if err := grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, requestID)); err != nil {
+		slog.WarnContext(ctx, "Failed to send the request ID", "method", info.FullMethod, "error", err)
+	}
+	return handler(ctx, request)
+}
+
+// grpcError converts an error of the service layer to a gRPC status with the code of its HTTP status. The invalid
+// lines of an order are sent as the field violations of a BadRequest detail
+func grpcError(ctx context.Context, err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return status.Error(codes.NotFound, "Order not found")
+	}
+
+	var serviceErr *handlers.ServiceError
+	if !errors.As(err, &serviceErr) {
+		slog.ErrorContext(ctx, "Tracking service error", "error", err)
+		return status.Error(codes.Internal, "Internal server error")
+	}
+
+	st := status.New(statusCode(serviceErr.Status), serviceErr.Message)
+	if len(serviceErr.Lines) == 0 {
+		return st.Err()
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(serviceErr.Lines))
+	for _, line := range serviceErr.Lines {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: fmt.Sprintf("products[%d]", line.Line), Description: line.Error})
+	}
+	return withDetails(st, &errdetails.BadRequest{FieldViolations: violations})
+}
+
+// validationError converts the errors of the binding rules of a request model to an InvalidArgument status
+func validationError(err error) error {
+	fields := apierror.FieldErrors(err)
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+	for _, field := range fields {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+	}
+	return withDetails(status.New(codes.InvalidArgument, "Invalid request"), &errdetails.BadRequest{FieldViolations: violations})
+}
+
+func withDetails(st *status.Status, details *errdetails.BadRequest) error {
+	detailed, err := st.WithDetails(details)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// statusCode is the gRPC code of an HTTP status
+func statusCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusForbidden, http.StatusConflict:
+		return codes.FailedPrecondition
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,334 @@
+package tracking
+
+import (
+	"app/auth"
+	"app/catalog"
+	"app/config"
+	"app/handlers"
+	"app/models"
+	"app/repository"
+	"app/requestModels"
+	"app/supervisor"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestServer creates the service on an in-memory store (with an empty local catalogue)
+func newTestServer(t *testing.T, catalogClient *catalog.Client) (*Server, *repository.Memory) {
+	t.Helper()
+	store := repository.NewMemory()
+	return &Server{
+		Store:        store,
+		Orders:       &handlers.OrderHandler{Store: store, Catalog: catalogClient},
+		Updates:      &handlers.OrderStatusHistoryHandler{Store: store},
+		Verification: &handlers.VerificationHandler{Store: store},
+		PollInterval: 10 * time.Millisecond,
+		Verifier:     auth.NewVerifier(config.Auth{TokenSecret: tokenSecret}),
+	}, store
+}
+
+// authenticated is the context of the calls of the caller, with its bearer token (required by the calls changing the
+// orders)
+func authenticated(t *testing.T, ctx context.Context, subject string) context.Context {
+	t.Helper()
+	return metadata.AppendToOutgoingContext(ctx, authorizationKey, bearer(t, subject))
+}
+
+// dial serves the service on an in-process listener and returns a client connected to it
+func dial(t *testing.T, service *Server) TrackingServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewGRPCServer(service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return NewTrackingServiceClient(conn)
+}
+
+func seedOrder(t *testing.T, store *repository.Memory, id uint, statuses ...string) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, store.Orders().Create(ctx, &models.Orders{
+		Id:               id,
+		Customer_ID:      42,
+		Tracking_Code:    "TRACK1",
+		Delivery_Address: "Rua Nova",
+		Created_At:       time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC),
+	}))
+	require.NoError(t, store.OrderProducts().Create(ctx, &models.OrderProduct{Order_ID: id, Product_ID: 10, Quantity: 2, Product_Name_At_Purchase: "Cork Wallet"}))
+	for i, status := range statuses {
+		appendUpdate(t, store, id, status, time.Date(2025, 5, 1, 11+i, 0, 0, 0, time.UTC))
+	}
+}
+
+func appendUpdate(t *testing.T, store *repository.Memory, orderID uint, status string, timestamp time.Time) {
+	t.Helper()
+	require.NoError(t, store.History().Create(context.Background(), &models.OrderStatusHistory{
+		Order_ID: orderID, Order_Status: status, Timestamp_History: timestamp, Order_Location: "Porto",
+	}))
+}
+
+// fieldViolations returns the fields of the BadRequest detail of an error
+func fieldViolations(t *testing.T, err error) []string {
+	t.Helper()
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, violation := range badRequest.GetFieldViolations() {
+				fields = append(fields, violation.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestCreateOrder(t *testing.T) {
+	fake := catalog.NewFakeServer(requestModels.Product{ID: 10, Name: "Cork Wallet", Price: 19.9, Stock: 5})
+	defer fake.Close()
+	service, store := newTestServer(t, catalog.NewClient(fake.Config()))
+	client := dial(t, service)
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(authenticated(t, context.Background(), "checkout-service"), "x-request-id", "req-7", "x-actor", "checkout")
+	order, err := client.CreateOrder(ctx, &CreateOrderRequest{
+		CustomerId:        1,
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryAddress:   "Addr",
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []*OrderLine{{ProductId: 10, Quantity: 2}},
+	}, grpc.Header(&header))
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), order.GetId())
+	assert.NotEmpty(t, order.GetTrackingCode())
+	require.Len(t, order.GetProducts(), 1)
+	assert.Equal(t, "Cork Wallet", order.GetProducts()[0].GetName())
+	assert.Equal(t, []string{"req-7"}, header.Get("x-request-id"))
+
+	latest, err := store.History().Latest(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusProcessing, latest.Order_Status)
+
+	// the changes are audited as made through gRPC, with the request ID, the authenticated caller and the actor claimed
+	// by the call
+	events, err := store.AuditEvents().ListByOrder(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, "GRPC", events[0].Source)
+	assert.Equal(t, "req-7", events[0].Request_ID)
+	assert.Equal(t, "checkout-service", events[0].Actor)
+	require.NotNil(t, events[0].Claimed_Actor)
+	assert.Equal(t, "checkout", *events[0].Claimed_Actor)
+}
+
+func TestCreateOrder_InvalidRequest(t *testing.T) {
+	service, _ := newTestServer(t, nil)
+	client := dial(t, service)
+
+	_, err := client.CreateOrder(authenticated(t, context.Background(), "checkout-service"), &CreateOrderRequest{CustomerId: 1, SellerId: 2, SellerAddress: "Seller", DeliveryAddress: "Addr"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"products"}, fieldViolations(t, err))
+}
+
+func TestCreateOrder_UnknownProduct(t *testing.T) {
+	fake := catalog.NewFakeServer()
+	defer fake.Close()
+	service, _ := newTestServer(t, catalog.NewClient(fake.Config()))
+	client := dial(t, service)
+
+	_, err := client.CreateOrder(authenticated(t, context.Background(), "checkout-service"), &CreateOrderRequest{
+		CustomerId:        1,
+		SellerId:          2,
+		SellerAddress:     "Seller",
+		SellerLatitude:    41.1,
+		SellerLongitude:   -8.6,
+		DeliveryAddress:   "Addr",
+		DeliveryLatitude:  41.15,
+		DeliveryLongitude: -8.61,
+		Products:          []*OrderLine{{ProductId: 99, Quantity: 1}},
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"products[0]"}, fieldViolations(t, err))
+}
+
+func TestGetOrder(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	seedOrder(t, store, 1, models.StatusProcessing)
+	client := dial(t, service)
+
+	byID, err := client.GetOrder(context.Background(), &GetOrderRequest{Key: &GetOrderRequest_Id{Id: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, "TRACK1", byID.GetTrackingCode())
+	assert.Len(t, byID.GetProducts(), 1)
+
+	byCode, err := client.GetOrder(context.Background(), &GetOrderRequest{Key: &GetOrderRequest_TrackingCode{TrackingCode: "TRACK1"}})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), byCode.GetId())
+	assert.Equal(t, "Rua Nova", byCode.GetDeliveryAddress())
+}
+
+func TestGetOrder_Errors(t *testing.T) {
+	service, _ := newTestServer(t, nil)
+	client := dial(t, service)
+
+	_, err := client.GetOrder(context.Background(), &GetOrderRequest{Key: &GetOrderRequest_TrackingCode{TrackingCode: "UNKNOWN"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.GetOrder(context.Background(), &GetOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAppendStatus(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	seedOrder(t, store, 7, models.StatusProcessing)
+	client := dial(t, service)
+
+	response, err := client.AppendStatus(authenticated(t, context.Background(), "courier-service"), &AppendStatusRequest{
+		OrderId:  7,
+		Status:   models.StatusShipped,
+		Note:     "Left the warehouse",
+		Location: "Warehouse B",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), response.GetUpdate().GetId())
+	assert.Equal(t, models.StatusShipped, response.GetUpdate().GetStatus())
+	assert.NotNil(t, response.GetUpdate().GetTimestamp(), "the time of the update defaults to now")
+	assert.Nil(t, response.GetLocationReview())
+
+	latest, err := store.History().Latest(context.Background(), 7)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.StatusShipped, "Left the warehouse", "Warehouse B"}, []string{latest.Order_Status, latest.Note, latest.Order_Location})
+}
+
+func TestAppendStatus_Errors(t *testing.T) {
+	service, _ := newTestServer(t, nil)
+	client := dial(t, service)
+
+	_, err := client.AppendStatus(authenticated(t, context.Background(), "courier-service"), &AppendStatusRequest{OrderId: 7, Status: "LOST"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"Order_Status"}, fieldViolations(t, err))
+
+	_, err = client.AppendStatus(authenticated(t, context.Background(), "courier-service"), &AppendStatusRequest{OrderId: 7, Status: models.StatusShipped})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWatchOrder(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	seedOrder(t, store, 1, models.StatusProcessing, models.StatusShipped)
+	client := dial(t, service)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.WatchOrder(ctx, &WatchOrderRequest{Key: &WatchOrderRequest_TrackingCode{TrackingCode: "TRACK1"}})
+	require.NoError(t, err)
+
+	// the updates the order already has, oldest first
+	for _, expected := range []string{models.StatusProcessing, models.StatusShipped} {
+		update, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, expected, update.GetStatus())
+	}
+
+	// then the new ones, until the order is delivered
+	appendUpdate(t, store, 1, models.StatusOutForDelivery, time.Date(2025, 5, 2, 9, 0, 0, 0, time.UTC))
+	update, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOutForDelivery, update.GetStatus())
+
+	appendUpdate(t, store, 1, models.StatusDelivered, time.Date(2025, 5, 2, 15, 0, 0, 0, time.UTC))
+	update, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusDelivered, update.GetStatus())
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestWatchOrder_CutWhenTheServerDrains(t *testing.T) {
+	service, store := newTestServer(t, nil)
+	seedOrder(t, store, 1, models.StatusProcessing)
+
+	listener := bufconn.Listen(1024 * 1024)
+	streams := supervisor.NewStreams()
+	sup := supervisor.New(5 * time.Second)
+	sup.Add(supervisor.GRPCServer("grpc", NewGRPCServer(service, grpc.ChainStreamInterceptor(streams.Interceptor())), listener, streams))
+	ctx, shutdown := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- sup.Run(ctx) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	stream, err := NewTrackingServiceClient(conn).WatchOrder(context.Background(), &WatchOrderRequest{Key: &WatchOrderRequest_Id{Id: 1}})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// the watch would keep the server open until the drain deadline, it is cut and the caller watches again
+	started := time.Now()
+	shutdown()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	require.NoError(t, <-result)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestWatchOrder_NotFound(t *testing.T) {
+	service, _ := newTestServer(t, nil)
+	client := dial(t, service)
+
+	stream, err := client.WatchOrder(context.Background(), &WatchOrderRequest{Key: &WatchOrderRequest_Id{Id: 9}})
+	require.NoError(t, err)
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestVerifyOrder_NoBlockchain(t *testing.T) {
+	service, _ := newTestServer(t, nil)
+	client := dial(t, service)
+
+	verification, err := client.VerifyOrder(context.Background(), &VerifyOrderRequest{OrderId: 1})
+
+	require.NoError(t, err)
+	assert.False(t, verification.GetVerified())
+	assert.Equal(t, "BLOCKCHAIN_NOT_AVAILABLE", verification.GetStatus())
+
+	_, err = client.VerifyOrder(context.Background(), &VerifyOrderRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
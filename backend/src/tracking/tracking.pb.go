@@ -0,0 +1,1394 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v6.33.2
+// source: tracking.proto
+
+package tracking
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type OrderLine struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	ProductId uint64                 `protobuf:"varint,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	// Required when the product has variants
+	VariantId     *uint64 `protobuf:"varint,2,opt,name=variant_id,json=variantId,proto3,oneof" json:"variant_id,omitempty"`
+	Quantity      uint32  `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderLine) Reset() {
+	*x = OrderLine{}
+	mi := &file_tracking_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderLine) ProtoMessage() {}
+
+func (x *OrderLine) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderLine.ProtoReflect.Descriptor instead.
+func (*OrderLine) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *OrderLine) GetProductId() uint64 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *OrderLine) GetVariantId() uint64 {
+	if x != nil && x.VariantId != nil {
+		return *x.VariantId
+	}
+	return 0
+}
+
+func (x *OrderLine) GetQuantity() uint32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// The coordinates are optional (0), missing ones are geocoded from the address
+type CreateOrderRequest struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	CustomerId        uint64                 `protobuf:"varint,1,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	SellerId          uint64                 `protobuf:"varint,2,opt,name=seller_id,json=sellerId,proto3" json:"seller_id,omitempty"`
+	SellerAddress     string                 `protobuf:"bytes,3,opt,name=seller_address,json=sellerAddress,proto3" json:"seller_address,omitempty"`
+	SellerLatitude    float64                `protobuf:"fixed64,4,opt,name=seller_latitude,json=sellerLatitude,proto3" json:"seller_latitude,omitempty"`
+	SellerLongitude   float64                `protobuf:"fixed64,5,opt,name=seller_longitude,json=sellerLongitude,proto3" json:"seller_longitude,omitempty"`
+	DeliveryAddress   string                 `protobuf:"bytes,6,opt,name=delivery_address,json=deliveryAddress,proto3" json:"delivery_address,omitempty"`
+	DeliveryLatitude  float64                `protobuf:"fixed64,7,opt,name=delivery_latitude,json=deliveryLatitude,proto3" json:"delivery_latitude,omitempty"`
+	DeliveryLongitude float64                `protobuf:"fixed64,8,opt,name=delivery_longitude,json=deliveryLongitude,proto3" json:"delivery_longitude,omitempty"`
+	Products          []*OrderLine           `protobuf:"bytes,9,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CreateOrderRequest) Reset() {
+	*x = CreateOrderRequest{}
+	mi := &file_tracking_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateOrderRequest) ProtoMessage() {}
+
+func (x *CreateOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateOrderRequest.ProtoReflect.Descriptor instead.
+func (*CreateOrderRequest) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CreateOrderRequest) GetCustomerId() uint64 {
+	if x != nil {
+		return x.CustomerId
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetSellerId() uint64 {
+	if x != nil {
+		return x.SellerId
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetSellerAddress() string {
+	if x != nil {
+		return x.SellerAddress
+	}
+	return ""
+}
+
+func (x *CreateOrderRequest) GetSellerLatitude() float64 {
+	if x != nil {
+		return x.SellerLatitude
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetSellerLongitude() float64 {
+	if x != nil {
+		return x.SellerLongitude
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetDeliveryAddress() string {
+	if x != nil {
+		return x.DeliveryAddress
+	}
+	return ""
+}
+
+func (x *CreateOrderRequest) GetDeliveryLatitude() float64 {
+	if x != nil {
+		return x.DeliveryLatitude
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetDeliveryLongitude() float64 {
+	if x != nil {
+		return x.DeliveryLongitude
+	}
+	return 0
+}
+
+func (x *CreateOrderRequest) GetProducts() []*OrderLine {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+type OrderProduct struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ProductId  uint64                 `protobuf:"varint,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	VariantId  *uint64                `protobuf:"varint,3,opt,name=variant_id,json=variantId,proto3,oneof" json:"variant_id,omitempty"`
+	VariantSku string                 `protobuf:"bytes,4,opt,name=variant_sku,json=variantSku,proto3" json:"variant_sku,omitempty"`
+	Quantity   uint32                 `protobuf:"varint,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Name and price of the product when the order was placed
+	Name          string  `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	Price         float64 `protobuf:"fixed64,7,opt,name=price,proto3" json:"price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OrderProduct) Reset() {
+	*x = OrderProduct{}
+	mi := &file_tracking_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OrderProduct) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrderProduct) ProtoMessage() {}
+
+func (x *OrderProduct) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrderProduct.ProtoReflect.Descriptor instead.
+func (*OrderProduct) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OrderProduct) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *OrderProduct) GetProductId() uint64 {
+	if x != nil {
+		return x.ProductId
+	}
+	return 0
+}
+
+func (x *OrderProduct) GetVariantId() uint64 {
+	if x != nil && x.VariantId != nil {
+		return *x.VariantId
+	}
+	return 0
+}
+
+func (x *OrderProduct) GetVariantSku() string {
+	if x != nil {
+		return x.VariantSku
+	}
+	return ""
+}
+
+func (x *OrderProduct) GetQuantity() uint32 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *OrderProduct) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *OrderProduct) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+type Order struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Id                uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TrackingCode      string                 `protobuf:"bytes,2,opt,name=tracking_code,json=trackingCode,proto3" json:"tracking_code,omitempty"`
+	CustomerId        uint64                 `protobuf:"varint,3,opt,name=customer_id,json=customerId,proto3" json:"customer_id,omitempty"`
+	SellerId          uint64                 `protobuf:"varint,4,opt,name=seller_id,json=sellerId,proto3" json:"seller_id,omitempty"`
+	SellerAddress     string                 `protobuf:"bytes,5,opt,name=seller_address,json=sellerAddress,proto3" json:"seller_address,omitempty"`
+	DeliveryAddress   string                 `protobuf:"bytes,6,opt,name=delivery_address,json=deliveryAddress,proto3" json:"delivery_address,omitempty"`
+	DeliveryLatitude  float64                `protobuf:"fixed64,7,opt,name=delivery_latitude,json=deliveryLatitude,proto3" json:"delivery_latitude,omitempty"`
+	DeliveryLongitude float64                `protobuf:"fixed64,8,opt,name=delivery_longitude,json=deliveryLongitude,proto3" json:"delivery_longitude,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	DeliveryEstimate  *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=delivery_estimate,json=deliveryEstimate,proto3" json:"delivery_estimate,omitempty"`
+	// Weights of the parcel in kg
+	ShippingWeight   float64         `protobuf:"fixed64,11,opt,name=shipping_weight,json=shippingWeight,proto3" json:"shipping_weight,omitempty"`
+	VolumetricWeight float64         `protobuf:"fixed64,12,opt,name=volumetric_weight,json=volumetricWeight,proto3" json:"volumetric_weight,omitempty"`
+	Products         []*OrderProduct `protobuf:"bytes,13,rep,name=products,proto3" json:"products,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	mi := &file_tracking_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Order) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Order) GetTrackingCode() string {
+	if x != nil {
+		return x.TrackingCode
+	}
+	return ""
+}
+
+func (x *Order) GetCustomerId() uint64 {
+	if x != nil {
+		return x.CustomerId
+	}
+	return 0
+}
+
+func (x *Order) GetSellerId() uint64 {
+	if x != nil {
+		return x.SellerId
+	}
+	return 0
+}
+
+func (x *Order) GetSellerAddress() string {
+	if x != nil {
+		return x.SellerAddress
+	}
+	return ""
+}
+
+func (x *Order) GetDeliveryAddress() string {
+	if x != nil {
+		return x.DeliveryAddress
+	}
+	return ""
+}
+
+func (x *Order) GetDeliveryLatitude() float64 {
+	if x != nil {
+		return x.DeliveryLatitude
+	}
+	return 0
+}
+
+func (x *Order) GetDeliveryLongitude() float64 {
+	if x != nil {
+		return x.DeliveryLongitude
+	}
+	return 0
+}
+
+func (x *Order) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Order) GetDeliveryEstimate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeliveryEstimate
+	}
+	return nil
+}
+
+func (x *Order) GetShippingWeight() float64 {
+	if x != nil {
+		return x.ShippingWeight
+	}
+	return 0
+}
+
+func (x *Order) GetVolumetricWeight() float64 {
+	if x != nil {
+		return x.VolumetricWeight
+	}
+	return 0
+}
+
+func (x *Order) GetProducts() []*OrderProduct {
+	if x != nil {
+		return x.Products
+	}
+	return nil
+}
+
+type GetOrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*GetOrderRequest_Id
+	//	*GetOrderRequest_TrackingCode
+	Key           isGetOrderRequest_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetOrderRequest) Reset() {
+	*x = GetOrderRequest{}
+	mi := &file_tracking_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetOrderRequest) ProtoMessage() {}
+
+func (x *GetOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetOrderRequest.ProtoReflect.Descriptor instead.
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetOrderRequest) GetKey() isGetOrderRequest_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *GetOrderRequest) GetId() uint64 {
+	if x != nil {
+		if x, ok := x.Key.(*GetOrderRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *GetOrderRequest) GetTrackingCode() string {
+	if x != nil {
+		if x, ok := x.Key.(*GetOrderRequest_TrackingCode); ok {
+			return x.TrackingCode
+		}
+	}
+	return ""
+}
+
+type isGetOrderRequest_Key interface {
+	isGetOrderRequest_Key()
+}
+
+type GetOrderRequest_Id struct {
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type GetOrderRequest_TrackingCode struct {
+	TrackingCode string `protobuf:"bytes,2,opt,name=tracking_code,json=trackingCode,proto3,oneof"`
+}
+
+func (*GetOrderRequest_Id) isGetOrderRequest_Key() {}
+
+func (*GetOrderRequest_TrackingCode) isGetOrderRequest_Key() {}
+
+type AppendStatusRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	OrderId uint64                 `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	// One of the order states, e.g. SHIPPED or OUT FOR DELIVERY
+	Status     string  `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Note       string  `protobuf:"bytes,3,opt,name=note,proto3" json:"note,omitempty"`
+	Location   string  `protobuf:"bytes,4,opt,name=location,proto3" json:"location,omitempty"`
+	StorageId  *uint64 `protobuf:"varint,5,opt,name=storage_id,json=storageId,proto3,oneof" json:"storage_id,omitempty"`
+	ShipmentId *uint64 `protobuf:"varint,6,opt,name=shipment_id,json=shipmentId,proto3,oneof" json:"shipment_id,omitempty"`
+	// Where the courier was when sending the update, checked against the storage or the delivery address
+	Latitude  *float64 `protobuf:"fixed64,7,opt,name=latitude,proto3,oneof" json:"latitude,omitempty"`
+	Longitude *float64 `protobuf:"fixed64,8,opt,name=longitude,proto3,oneof" json:"longitude,omitempty"`
+	// The time of the update, now when it is not set
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendStatusRequest) Reset() {
+	*x = AppendStatusRequest{}
+	mi := &file_tracking_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendStatusRequest) ProtoMessage() {}
+
+func (x *AppendStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendStatusRequest.ProtoReflect.Descriptor instead.
+func (*AppendStatusRequest) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AppendStatusRequest) GetOrderId() uint64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *AppendStatusRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *AppendStatusRequest) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *AppendStatusRequest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *AppendStatusRequest) GetStorageId() uint64 {
+	if x != nil && x.StorageId != nil {
+		return *x.StorageId
+	}
+	return 0
+}
+
+func (x *AppendStatusRequest) GetShipmentId() uint64 {
+	if x != nil && x.ShipmentId != nil {
+		return *x.ShipmentId
+	}
+	return 0
+}
+
+func (x *AppendStatusRequest) GetLatitude() float64 {
+	if x != nil && x.Latitude != nil {
+		return *x.Latitude
+	}
+	return 0
+}
+
+func (x *AppendStatusRequest) GetLongitude() float64 {
+	if x != nil && x.Longitude != nil {
+		return *x.Longitude
+	}
+	return 0
+}
+
+func (x *AppendStatusRequest) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+type AppendStatusResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Update *StatusUpdate          `protobuf:"bytes,1,opt,name=update,proto3" json:"update,omitempty"`
+	// Set when the update was stored but flagged for review by the geofence check
+	LocationReview *LocationReview `protobuf:"bytes,2,opt,name=location_review,json=locationReview,proto3" json:"location_review,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *AppendStatusResponse) Reset() {
+	*x = AppendStatusResponse{}
+	mi := &file_tracking_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendStatusResponse) ProtoMessage() {}
+
+func (x *AppendStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendStatusResponse.ProtoReflect.Descriptor instead.
+func (*AppendStatusResponse) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AppendStatusResponse) GetUpdate() *StatusUpdate {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *AppendStatusResponse) GetLocationReview() *LocationReview {
+	if x != nil {
+		return x.LocationReview
+	}
+	return nil
+}
+
+type LocationReview struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Reason string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Distance between the courier and the expected location
+	DistanceKm    float64 `protobuf:"fixed64,3,opt,name=distance_km,json=distanceKm,proto3" json:"distance_km,omitempty"`
+	ToleranceKm   float64 `protobuf:"fixed64,4,opt,name=tolerance_km,json=toleranceKm,proto3" json:"tolerance_km,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LocationReview) Reset() {
+	*x = LocationReview{}
+	mi := &file_tracking_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LocationReview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LocationReview) ProtoMessage() {}
+
+func (x *LocationReview) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LocationReview.ProtoReflect.Descriptor instead.
+func (*LocationReview) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LocationReview) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *LocationReview) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *LocationReview) GetDistanceKm() float64 {
+	if x != nil {
+		return x.DistanceKm
+	}
+	return 0
+}
+
+func (x *LocationReview) GetToleranceKm() float64 {
+	if x != nil {
+		return x.ToleranceKm
+	}
+	return 0
+}
+
+type StatusUpdate struct {
+	state                 protoimpl.MessageState `protogen:"open.v1"`
+	Id                    uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	OrderId               uint64                 `protobuf:"varint,2,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	Status                string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Note                  string                 `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
+	Location              string                 `protobuf:"bytes,5,opt,name=location,proto3" json:"location,omitempty"`
+	Timestamp             *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	BlockchainTransaction string                 `protobuf:"bytes,7,opt,name=blockchain_transaction,json=blockchainTransaction,proto3" json:"blockchain_transaction,omitempty"`
+	StorageId             *uint64                `protobuf:"varint,8,opt,name=storage_id,json=storageId,proto3,oneof" json:"storage_id,omitempty"`
+	ShipmentId            *uint64                `protobuf:"varint,9,opt,name=shipment_id,json=shipmentId,proto3,oneof" json:"shipment_id,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *StatusUpdate) Reset() {
+	*x = StatusUpdate{}
+	mi := &file_tracking_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusUpdate) ProtoMessage() {}
+
+func (x *StatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusUpdate.ProtoReflect.Descriptor instead.
+func (*StatusUpdate) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StatusUpdate) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetOrderId() uint64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetBlockchainTransaction() string {
+	if x != nil {
+		return x.BlockchainTransaction
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetStorageId() uint64 {
+	if x != nil && x.StorageId != nil {
+		return *x.StorageId
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetShipmentId() uint64 {
+	if x != nil && x.ShipmentId != nil {
+		return *x.ShipmentId
+	}
+	return 0
+}
+
+type WatchOrderRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Key:
+	//
+	//	*WatchOrderRequest_Id
+	//	*WatchOrderRequest_TrackingCode
+	Key           isWatchOrderRequest_Key `protobuf_oneof:"key"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchOrderRequest) Reset() {
+	*x = WatchOrderRequest{}
+	mi := &file_tracking_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchOrderRequest) ProtoMessage() {}
+
+func (x *WatchOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchOrderRequest.ProtoReflect.Descriptor instead.
+func (*WatchOrderRequest) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchOrderRequest) GetKey() isWatchOrderRequest_Key {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *WatchOrderRequest) GetId() uint64 {
+	if x != nil {
+		if x, ok := x.Key.(*WatchOrderRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *WatchOrderRequest) GetTrackingCode() string {
+	if x != nil {
+		if x, ok := x.Key.(*WatchOrderRequest_TrackingCode); ok {
+			return x.TrackingCode
+		}
+	}
+	return ""
+}
+
+type isWatchOrderRequest_Key interface {
+	isWatchOrderRequest_Key()
+}
+
+type WatchOrderRequest_Id struct {
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type WatchOrderRequest_TrackingCode struct {
+	TrackingCode string `protobuf:"bytes,2,opt,name=tracking_code,json=trackingCode,proto3,oneof"`
+}
+
+func (*WatchOrderRequest_Id) isWatchOrderRequest_Key() {}
+
+func (*WatchOrderRequest_TrackingCode) isWatchOrderRequest_Key() {}
+
+type VerifyOrderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrderId       uint64                 `protobuf:"varint,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyOrderRequest) Reset() {
+	*x = VerifyOrderRequest{}
+	mi := &file_tracking_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyOrderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyOrderRequest) ProtoMessage() {}
+
+func (x *VerifyOrderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyOrderRequest.ProtoReflect.Descriptor instead.
+func (*VerifyOrderRequest) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *VerifyOrderRequest) GetOrderId() uint64 {
+	if x != nil {
+		return x.OrderId
+	}
+	return 0
+}
+
+type ShipmentVerification struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ShipmentId      uint64                 `protobuf:"varint,1,opt,name=shipment_id,json=shipmentId,proto3" json:"shipment_id,omitempty"`
+	Verified        bool                   `protobuf:"varint,2,opt,name=verified,proto3" json:"verified,omitempty"`
+	TotalUpdates    int32                  `protobuf:"varint,3,opt,name=total_updates,json=totalUpdates,proto3" json:"total_updates,omitempty"`
+	VerifiedUpdates int32                  `protobuf:"varint,4,opt,name=verified_updates,json=verifiedUpdates,proto3" json:"verified_updates,omitempty"`
+	Mismatches      []string               `protobuf:"bytes,5,rep,name=mismatches,proto3" json:"mismatches,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ShipmentVerification) Reset() {
+	*x = ShipmentVerification{}
+	mi := &file_tracking_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShipmentVerification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShipmentVerification) ProtoMessage() {}
+
+func (x *ShipmentVerification) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShipmentVerification.ProtoReflect.Descriptor instead.
+func (*ShipmentVerification) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ShipmentVerification) GetShipmentId() uint64 {
+	if x != nil {
+		return x.ShipmentId
+	}
+	return 0
+}
+
+func (x *ShipmentVerification) GetVerified() bool {
+	if x != nil {
+		return x.Verified
+	}
+	return false
+}
+
+func (x *ShipmentVerification) GetTotalUpdates() int32 {
+	if x != nil {
+		return x.TotalUpdates
+	}
+	return 0
+}
+
+func (x *ShipmentVerification) GetVerifiedUpdates() int32 {
+	if x != nil {
+		return x.VerifiedUpdates
+	}
+	return 0
+}
+
+func (x *ShipmentVerification) GetMismatches() []string {
+	if x != nil {
+		return x.Mismatches
+	}
+	return nil
+}
+
+type Verification struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Verified bool                   `protobuf:"varint,1,opt,name=verified,proto3" json:"verified,omitempty"`
+	// VERIFIED, PARTIALLY_VERIFIED, NOT_VERIFIED, EXTRA_HASHES, MISMATCH or BLOCKCHAIN_NOT_AVAILABLE
+	Status            string                  `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Message           string                  `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	TotalUpdates      int32                   `protobuf:"varint,4,opt,name=total_updates,json=totalUpdates,proto3" json:"total_updates,omitempty"`
+	VerifiedUpdates   int32                   `protobuf:"varint,5,opt,name=verified_updates,json=verifiedUpdates,proto3" json:"verified_updates,omitempty"`
+	BlockchainHashes  int32                   `protobuf:"varint,6,opt,name=blockchain_hashes,json=blockchainHashes,proto3" json:"blockchain_hashes,omitempty"`
+	Mismatches        []string                `protobuf:"bytes,7,rep,name=mismatches,proto3" json:"mismatches,omitempty"`
+	TransactionHashes []string                `protobuf:"bytes,8,rep,name=transaction_hashes,json=transactionHashes,proto3" json:"transaction_hashes,omitempty"`
+	ContractAddress   string                  `protobuf:"bytes,9,opt,name=contract_address,json=contractAddress,proto3" json:"contract_address,omitempty"`
+	Shipments         []*ShipmentVerification `protobuf:"bytes,10,rep,name=shipments,proto3" json:"shipments,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Verification) Reset() {
+	*x = Verification{}
+	mi := &file_tracking_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Verification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Verification) ProtoMessage() {}
+
+func (x *Verification) ProtoReflect() protoreflect.Message {
+	mi := &file_tracking_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Verification.ProtoReflect.Descriptor instead.
+func (*Verification) Descriptor() ([]byte, []int) {
+	return file_tracking_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Verification) GetVerified() bool {
+	if x != nil {
+		return x.Verified
+	}
+	return false
+}
+
+func (x *Verification) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Verification) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Verification) GetTotalUpdates() int32 {
+	if x != nil {
+		return x.TotalUpdates
+	}
+	return 0
+}
+
+func (x *Verification) GetVerifiedUpdates() int32 {
+	if x != nil {
+		return x.VerifiedUpdates
+	}
+	return 0
+}
+
+func (x *Verification) GetBlockchainHashes() int32 {
+	if x != nil {
+		return x.BlockchainHashes
+	}
+	return 0
+}
+
+func (x *Verification) GetMismatches() []string {
+	if x != nil {
+		return x.Mismatches
+	}
+	return nil
+}
+
+func (x *Verification) GetTransactionHashes() []string {
+	if x != nil {
+		return x.TransactionHashes
+	}
+	return nil
+}
+
+func (x *Verification) GetContractAddress() string {
+	if x != nil {
+		return x.ContractAddress
+	}
+	return ""
+}
+
+func (x *Verification) GetShipments() []*ShipmentVerification {
+	if x != nil {
+		return x.Shipments
+	}
+	return nil
+}
+
+var File_tracking_proto protoreflect.FileDescriptor
+
+const file_tracking_proto_rawDesc = "" +
+	"\n" +
+	"\x0etracking.proto\x12\vtracking.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"y\n" +
+	"\tOrderLine\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\x04R\tproductId\x12\"\n" +
+	"\n" +
+	"variant_id\x18\x02 \x01(\x04H\x00R\tvariantId\x88\x01\x01\x12\x1a\n" +
+	"\bquantity\x18\x03 \x01(\rR\bquantityB\r\n" +
+	"\v_variant_id\"\x88\x03\n" +
+	"\x12CreateOrderRequest\x12\x1f\n" +
+	"\vcustomer_id\x18\x01 \x01(\x04R\n" +
+	"customerId\x12\x1b\n" +
+	"\tseller_id\x18\x02 \x01(\x04R\bsellerId\x12%\n" +
+	"\x0eseller_address\x18\x03 \x01(\tR\rsellerAddress\x12'\n" +
+	"\x0fseller_latitude\x18\x04 \x01(\x01R\x0esellerLatitude\x12)\n" +
+	"\x10seller_longitude\x18\x05 \x01(\x01R\x0fsellerLongitude\x12)\n" +
+	"\x10delivery_address\x18\x06 \x01(\tR\x0fdeliveryAddress\x12+\n" +
+	"\x11delivery_latitude\x18\a \x01(\x01R\x10deliveryLatitude\x12-\n" +
+	"\x12delivery_longitude\x18\b \x01(\x01R\x11deliveryLongitude\x122\n" +
+	"\bproducts\x18\t \x03(\v2\x16.tracking.v1.OrderLineR\bproducts\"\xd7\x01\n" +
+	"\fOrderProduct\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x02 \x01(\x04R\tproductId\x12\"\n" +
+	"\n" +
+	"variant_id\x18\x03 \x01(\x04H\x00R\tvariantId\x88\x01\x01\x12\x1f\n" +
+	"\vvariant_sku\x18\x04 \x01(\tR\n" +
+	"variantSku\x12\x1a\n" +
+	"\bquantity\x18\x05 \x01(\rR\bquantity\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12\x14\n" +
+	"\x05price\x18\a \x01(\x01R\x05priceB\r\n" +
+	"\v_variant_id\"\xb9\x04\n" +
+	"\x05Order\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12#\n" +
+	"\rtracking_code\x18\x02 \x01(\tR\ftrackingCode\x12\x1f\n" +
+	"\vcustomer_id\x18\x03 \x01(\x04R\n" +
+	"customerId\x12\x1b\n" +
+	"\tseller_id\x18\x04 \x01(\x04R\bsellerId\x12%\n" +
+	"\x0eseller_address\x18\x05 \x01(\tR\rsellerAddress\x12)\n" +
+	"\x10delivery_address\x18\x06 \x01(\tR\x0fdeliveryAddress\x12+\n" +
+	"\x11delivery_latitude\x18\a \x01(\x01R\x10deliveryLatitude\x12-\n" +
+	"\x12delivery_longitude\x18\b \x01(\x01R\x11deliveryLongitude\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12G\n" +
+	"\x11delivery_estimate\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x10deliveryEstimate\x12'\n" +
+	"\x0fshipping_weight\x18\v \x01(\x01R\x0eshippingWeight\x12+\n" +
+	"\x11volumetric_weight\x18\f \x01(\x01R\x10volumetricWeight\x125\n" +
+	"\bproducts\x18\r \x03(\v2\x19.tracking.v1.OrderProductR\bproducts\"Q\n" +
+	"\x0fGetOrderRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\x04H\x00R\x02id\x12%\n" +
+	"\rtracking_code\x18\x02 \x01(\tH\x00R\ftrackingCodeB\x05\n" +
+	"\x03key\"\xfa\x02\n" +
+	"\x13AppendStatusRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x04R\aorderId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04note\x18\x03 \x01(\tR\x04note\x12\x1a\n" +
+	"\blocation\x18\x04 \x01(\tR\blocation\x12\"\n" +
+	"\n" +
+	"storage_id\x18\x05 \x01(\x04H\x00R\tstorageId\x88\x01\x01\x12$\n" +
+	"\vshipment_id\x18\x06 \x01(\x04H\x01R\n" +
+	"shipmentId\x88\x01\x01\x12\x1f\n" +
+	"\blatitude\x18\a \x01(\x01H\x02R\blatitude\x88\x01\x01\x12!\n" +
+	"\tlongitude\x18\b \x01(\x01H\x03R\tlongitude\x88\x01\x01\x128\n" +
+	"\ttimestamp\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\ttimestampB\r\n" +
+	"\v_storage_idB\x0e\n" +
+	"\f_shipment_idB\v\n" +
+	"\t_latitudeB\f\n" +
+	"\n" +
+	"_longitude\"\x8f\x01\n" +
+	"\x14AppendStatusResponse\x121\n" +
+	"\x06update\x18\x01 \x01(\v2\x19.tracking.v1.StatusUpdateR\x06update\x12D\n" +
+	"\x0flocation_review\x18\x02 \x01(\v2\x1b.tracking.v1.LocationReviewR\x0elocationReview\"|\n" +
+	"\x0eLocationReview\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x1f\n" +
+	"\vdistance_km\x18\x03 \x01(\x01R\n" +
+	"distanceKm\x12!\n" +
+	"\ftolerance_km\x18\x04 \x01(\x01R\vtoleranceKm\"\xdb\x02\n" +
+	"\fStatusUpdate\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x19\n" +
+	"\border_id\x18\x02 \x01(\x04R\aorderId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x12\n" +
+	"\x04note\x18\x04 \x01(\tR\x04note\x12\x1a\n" +
+	"\blocation\x18\x05 \x01(\tR\blocation\x128\n" +
+	"\ttimestamp\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x125\n" +
+	"\x16blockchain_transaction\x18\a \x01(\tR\x15blockchainTransaction\x12\"\n" +
+	"\n" +
+	"storage_id\x18\b \x01(\x04H\x00R\tstorageId\x88\x01\x01\x12$\n" +
+	"\vshipment_id\x18\t \x01(\x04H\x01R\n" +
+	"shipmentId\x88\x01\x01B\r\n" +
+	"\v_storage_idB\x0e\n" +
+	"\f_shipment_id\"S\n" +
+	"\x11WatchOrderRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\x04H\x00R\x02id\x12%\n" +
+	"\rtracking_code\x18\x02 \x01(\tH\x00R\ftrackingCodeB\x05\n" +
+	"\x03key\"/\n" +
+	"\x12VerifyOrderRequest\x12\x19\n" +
+	"\border_id\x18\x01 \x01(\x04R\aorderId\"\xc3\x01\n" +
+	"\x14ShipmentVerification\x12\x1f\n" +
+	"\vshipment_id\x18\x01 \x01(\x04R\n" +
+	"shipmentId\x12\x1a\n" +
+	"\bverified\x18\x02 \x01(\bR\bverified\x12#\n" +
+	"\rtotal_updates\x18\x03 \x01(\x05R\ftotalUpdates\x12)\n" +
+	"\x10verified_updates\x18\x04 \x01(\x05R\x0fverifiedUpdates\x12\x1e\n" +
+	"\n" +
+	"mismatches\x18\x05 \x03(\tR\n" +
+	"mismatches\"\x94\x03\n" +
+	"\fVerification\x12\x1a\n" +
+	"\bverified\x18\x01 \x01(\bR\bverified\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12#\n" +
+	"\rtotal_updates\x18\x04 \x01(\x05R\ftotalUpdates\x12)\n" +
+	"\x10verified_updates\x18\x05 \x01(\x05R\x0fverifiedUpdates\x12+\n" +
+	"\x11blockchain_hashes\x18\x06 \x01(\x05R\x10blockchainHashes\x12\x1e\n" +
+	"\n" +
+	"mismatches\x18\a \x03(\tR\n" +
+	"mismatches\x12-\n" +
+	"\x12transaction_hashes\x18\b \x03(\tR\x11transactionHashes\x12)\n" +
+	"\x10contract_address\x18\t \x01(\tR\x0fcontractAddress\x12?\n" +
+	"\tshipments\x18\n" +
+	" \x03(\v2!.tracking.v1.ShipmentVerificationR\tshipments2\xfe\x02\n" +
+	"\x0fTrackingService\x12B\n" +
+	"\vCreateOrder\x12\x1f.tracking.v1.CreateOrderRequest\x1a\x12.tracking.v1.Order\x12S\n" +
+	"\fAppendStatus\x12 .tracking.v1.AppendStatusRequest\x1a!.tracking.v1.AppendStatusResponse\x12<\n" +
+	"\bGetOrder\x12\x1c.tracking.v1.GetOrderRequest\x1a\x12.tracking.v1.Order\x12I\n" +
+	"\n" +
+	"WatchOrder\x12\x1e.tracking.v1.WatchOrderRequest\x1a\x19.tracking.v1.StatusUpdate0\x01\x12I\n" +
+	"\vVerifyOrder\x12\x1f.tracking.v1.VerifyOrderRequest\x1a\x19.tracking.v1.VerificationB\x0eZ\fapp/trackingb\x06proto3"
+
+var (
+	file_tracking_proto_rawDescOnce sync.Once
+	file_tracking_proto_rawDescData []byte
+)
+
+func file_tracking_proto_rawDescGZIP() []byte {
+	file_tracking_proto_rawDescOnce.Do(func() {
+		file_tracking_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_tracking_proto_rawDesc), len(file_tracking_proto_rawDesc)))
+	})
+	return file_tracking_proto_rawDescData
+}
+
+var file_tracking_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_tracking_proto_goTypes = []any{
+	(*OrderLine)(nil),             // 0: tracking.v1.OrderLine
+	(*CreateOrderRequest)(nil),    // 1: tracking.v1.CreateOrderRequest
+	(*OrderProduct)(nil),          // 2: tracking.v1.OrderProduct
+	(*Order)(nil),                 // 3: tracking.v1.Order
+	(*GetOrderRequest)(nil),       // 4: tracking.v1.GetOrderRequest
+	(*AppendStatusRequest)(nil),   // 5: tracking.v1.AppendStatusRequest
+	(*AppendStatusResponse)(nil),  // 6: tracking.v1.AppendStatusResponse
+	(*LocationReview)(nil),        // 7: tracking.v1.LocationReview
+	(*StatusUpdate)(nil),          // 8: tracking.v1.StatusUpdate
+	(*WatchOrderRequest)(nil),     // 9: tracking.v1.WatchOrderRequest
+	(*VerifyOrderRequest)(nil),    // 10: tracking.v1.VerifyOrderRequest
+	(*ShipmentVerification)(nil),  // 11: tracking.v1.ShipmentVerification
+	(*Verification)(nil),          // 12: tracking.v1.Verification
+	(*timestamppb.Timestamp)(nil), // 13: google.protobuf.Timestamp
+}
+var file_tracking_proto_depIdxs = []int32{
+	0,  // 0: tracking.v1.CreateOrderRequest.products:type_name -> tracking.v1.OrderLine
+	13, // 1: tracking.v1.Order.created_at:type_name -> google.protobuf.Timestamp
+	13, // 2: tracking.v1.Order.delivery_estimate:type_name -> google.protobuf.Timestamp
+	2,  // 3: tracking.v1.Order.products:type_name -> tracking.v1.OrderProduct
+	13, // 4: tracking.v1.AppendStatusRequest.timestamp:type_name -> google.protobuf.Timestamp
+	8,  // 5: tracking.v1.AppendStatusResponse.update:type_name -> tracking.v1.StatusUpdate
+	7,  // 6: tracking.v1.AppendStatusResponse.location_review:type_name -> tracking.v1.LocationReview
+	13, // 7: tracking.v1.StatusUpdate.timestamp:type_name -> google.protobuf.Timestamp
+	11, // 8: tracking.v1.Verification.shipments:type_name -> tracking.v1.ShipmentVerification
+	1,  // 9: tracking.v1.TrackingService.CreateOrder:input_type -> tracking.v1.CreateOrderRequest
+	5,  // 10: tracking.v1.TrackingService.AppendStatus:input_type -> tracking.v1.AppendStatusRequest
+	4,  // 11: tracking.v1.TrackingService.GetOrder:input_type -> tracking.v1.GetOrderRequest
+	9,  // 12: tracking.v1.TrackingService.WatchOrder:input_type -> tracking.v1.WatchOrderRequest
+	10, // 13: tracking.v1.TrackingService.VerifyOrder:input_type -> tracking.v1.VerifyOrderRequest
+	3,  // 14: tracking.v1.TrackingService.CreateOrder:output_type -> tracking.v1.Order
+	6,  // 15: tracking.v1.TrackingService.AppendStatus:output_type -> tracking.v1.AppendStatusResponse
+	3,  // 16: tracking.v1.TrackingService.GetOrder:output_type -> tracking.v1.Order
+	8,  // 17: tracking.v1.TrackingService.WatchOrder:output_type -> tracking.v1.StatusUpdate
+	12, // 18: tracking.v1.TrackingService.VerifyOrder:output_type -> tracking.v1.Verification
+	14, // [14:19] is the sub-list for method output_type
+	9,  // [9:14] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_tracking_proto_init() }
+func file_tracking_proto_init() {
+	if File_tracking_proto != nil {
+		return
+	}
+	file_tracking_proto_msgTypes[0].OneofWrappers = []any{}
+	file_tracking_proto_msgTypes[2].OneofWrappers = []any{}
+	file_tracking_proto_msgTypes[4].OneofWrappers = []any{
+		(*GetOrderRequest_Id)(nil),
+		(*GetOrderRequest_TrackingCode)(nil),
+	}
+	file_tracking_proto_msgTypes[5].OneofWrappers = []any{}
+	file_tracking_proto_msgTypes[8].OneofWrappers = []any{}
+	file_tracking_proto_msgTypes[9].OneofWrappers = []any{
+		(*WatchOrderRequest_Id)(nil),
+		(*WatchOrderRequest_TrackingCode)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_tracking_proto_rawDesc), len(file_tracking_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   13,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_tracking_proto_goTypes,
+		DependencyIndexes: file_tracking_proto_depIdxs,
+		MessageInfos:      file_tracking_proto_msgTypes,
+	}.Build()
+	File_tracking_proto = out.File
+	file_tracking_proto_goTypes = nil
+	file_tracking_proto_depIdxs = nil
+}
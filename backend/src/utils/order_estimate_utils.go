@@ -22,3 +22,23 @@ func EstimateDeliveryTime(lat1, lon1, lat2, lon2, avgSpeedKmH float64) float64 {
 	distanceKm := haversine(lat1,lon1, lat2, lon2)
     return distanceKm / avgSpeedKmH + 12 //adds an extra time to predict possible time in storages
 }
+
+// DistanceKm returns the great-circle distance between two points in kilometers
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+    return haversine(lat1, lon1, lat2, lon2)
+}
+
+// Parcels heavier than this (kg) are handled as freight and take longer to be collected and sorted
+const HeavyParcelWeightKg = 30.0
+
+// Extra hours added to the estimate of the heavy parcels
+const HeavyParcelExtraHours = 24.0
+
+// EstimateParcelDeliveryTime is EstimateDeliveryTime taking the chargeable weight (kg) of the parcel into account
+func EstimateParcelDeliveryTime(lat1, lon1, lat2, lon2, avgSpeedKmH, chargeableWeightKg float64) float64 {
+    estimate := EstimateDeliveryTime(lat1, lon1, lat2, lon2, avgSpeedKmH)
+    if chargeableWeightKg > HeavyParcelWeightKg {
+        estimate += HeavyParcelExtraHours
+    }
+    return estimate
+}
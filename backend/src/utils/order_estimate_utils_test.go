@@ -53,3 +53,16 @@ func TestEstimateDeliveryTime_DifferentSpeeds(t *testing.T) {
         t.Errorf("expected slower speed to give longer time, got slow=%f fast=%f", etSlow, etFast)
     }
 }
+
+func TestEstimateParcelDeliveryTime_HeavyParcel(t *testing.T) {
+    // Parcels up to the freight limit keep the normal estimate
+    light := EstimateParcelDeliveryTime(41.14961, -8.61099, 41.14961, -8.61099, 100, HeavyParcelWeightKg)
+    if !almostEqual(light, 12, 1e-9) {
+        t.Errorf("expected 12, got %f", light)
+    }
+
+    heavy := EstimateParcelDeliveryTime(41.14961, -8.61099, 41.14961, -8.61099, 100, HeavyParcelWeightKg+0.5)
+    if !almostEqual(heavy, 12+HeavyParcelExtraHours, 1e-9) {
+        t.Errorf("expected %f, got %f", 12+HeavyParcelExtraHours, heavy)
+    }
+}
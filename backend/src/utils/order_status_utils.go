@@ -0,0 +1,50 @@
+package utils
+
+import "app/models"
+
+// Progress of a shipment, used to find the least advanced shipment of an order
+var statusProgress = map[string]int{
+    models.StatusProcessing:      0,
+    models.StatusShipped:         1,
+    models.StatusInTransit:       2,
+    models.StatusFailedDelivery:  3,
+    models.StatusOutForDelivery:  4,
+    models.StatusDelivered:       5,
+    models.StatusReturnRequested: 6,
+}
+
+// DeriveOrderStatus computes the status of an order from the statuses of its shipments.
+// The order is as advanced as its least advanced shipment. Cancelled and returned shipments
+// are only taken into account when every shipment is in that state
+func DeriveOrderStatus(shipmentStatuses []string) string {
+    if len(shipmentStatuses) == 0 {
+        return ""
+    }
+
+    active := []string{}
+    returned := 0
+    for _, status := range shipmentStatuses {
+        switch status {
+        case models.StatusCancelled:
+        case models.StatusReturned:
+            returned++
+        default:
+            active = append(active, status)
+        }
+    }
+
+    if len(active) == 0 {
+        if returned > 0 {
+            return models.StatusReturned
+        }
+        return models.StatusCancelled
+    }
+
+    derived := active[0]
+    for _, status := range active[1:] {
+        if statusProgress[status] < statusProgress[derived] {
+            derived = status
+        }
+    }
+    return derived
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+    "testing"
+)
+
+func TestDeriveOrderStatus(t *testing.T) {
+    tests := []struct {
+        name     string
+        statuses []string
+        expected string
+    }{
+        {"no shipments", []string{}, ""},
+        {"single shipment", []string{"IN TRANSIT"}, "IN TRANSIT"},
+        {"least advanced shipment", []string{"DELIVERED", "SHIPPED", "OUT FOR DELIVERY"}, "SHIPPED"},
+        {"all delivered", []string{"DELIVERED", "DELIVERED"}, "DELIVERED"},
+        {"cancelled shipment ignored", []string{"CANCELLED", "DELIVERED"}, "DELIVERED"},
+        {"returned shipment ignored", []string{"RETURNED", "IN TRANSIT"}, "IN TRANSIT"},
+        {"failed delivery", []string{"FAILED DELIVERY", "DELIVERED"}, "FAILED DELIVERY"},
+        {"return requested after delivery", []string{"RETURN REQUESTED", "DELIVERED"}, "DELIVERED"},
+        {"return requested for every shipment", []string{"RETURN REQUESTED", "RETURN REQUESTED"}, "RETURN REQUESTED"},
+        {"all cancelled", []string{"CANCELLED", "CANCELLED"}, "CANCELLED"},
+        {"all returned or cancelled", []string{"RETURNED", "CANCELLED"}, "RETURNED"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := DeriveOrderStatus(tt.statuses); got != tt.expected {
+                t.Errorf("expected %q, got %q", tt.expected, got)
+            }
+        })
+    }
+}
@@ -784,6 +784,7 @@ export default function OrderPage() {
                                 'OUT FOR DELIVERY': { color: '#1976d2', severity: 'info' },
                                 'DELIVERED': { color: '#388e3c', severity: 'success' },
                                 'CANCELLED': { color: '#d32f2f', severity: 'error' },
+                                'RETURN REQUESTED': { color: '#f57c00', severity: 'warning' },
                                 'RETURNED': { color: '#f57c00', severity: 'warning' },
                                 'FAILED DELIVERY': { color: '#d32f2f', severity: 'error' }
                             };
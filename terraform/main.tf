@@ -92,6 +92,12 @@ resource "google_cloud_run_v2_service" "default" {
         value = "sepolia"
       }
 
+      # Authentication of the callers (bearer tokens of the identity provider)
+      env {
+        name  = "AUTH_TOKEN_SECRET"
+        value = var.auth_token_secret
+      }
+
       # Application Configuration
       env {
         name  = "ENVIRONMENT"
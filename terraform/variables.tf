@@ -80,6 +80,11 @@ variable "blockchain_private_key" {
   sensitive   = true
 }
 
+variable "auth_token_secret" {
+  description = "Key the bearer tokens of the identity provider are signed with (HS256, at least 32 bytes). Required in production"
+  type        = string
+  sensitive   = true
+}
 
 variable "blockchain_contract_address" {
   description = "Deployed smart contract address on Sepolia testnet (leave empty if not yet deployed)"